@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// -min-interval is used while any replica is changing or unhealthy, and
+// -max-interval once all of them have been caught up and healthy for
+// adaptiveCalmPeriod; -interval applies in between. Either may be left unset.
+var (
+	minInterval time.Duration
+	maxInterval time.Duration
+)
+
+const adaptiveCalmPeriod = 5 * time.Minute
+
+var (
+	// Current interval chosen by updateAdaptiveInterval, 0 before the first
+	// cycle; atomic because /readyz reads it through currentInterval
+	adaptiveBase atomic.Int64
+	calmSince    time.Time
+)
+
+// The poll interval before -throttle and -jitter are applied
+func currentInterval() time.Duration {
+	if base := time.Duration(adaptiveBase.Load()); base > 0 {
+		return base
+	}
+	return interval
+}
+
+// Lag moved since the previous poll, or something needs attention
+func replicaBusy(r *replica) bool {
+	if r.errorMatched || r.pollFailures > 0 {
+		return true
+	}
+	if !r.aurora && !r.polledAt.IsZero() && (r.ioRunning != "Yes" || r.sqlRunning != "Yes") {
+		return true
+	}
+	n := r.recentLags.Len()
+	return r.lagKnown && n >= 2 && r.recentLags.At(n-1) != r.recentLags.At(n-2)
+}
+
+// Pick the interval for the next cycle from the replicas' state; called once per monitoring cycle
+func updateAdaptiveInterval(replicas []*replica) {
+	if minInterval <= 0 && maxInterval <= 0 {
+		return
+	}
+	now := time.Now()
+	busy, caughtUp := false, true
+	for _, r := range replicas {
+		busy = busy || replicaBusy(r)
+		// Aurora readers always report some milliseconds of lag
+		caughtUp = caughtUp && r.lagKnown && r.lagSeconds < 1
+	}
+	next := interval
+	switch {
+	case busy:
+		calmSince = time.Time{}
+		if minInterval > 0 {
+			next = minInterval
+		}
+	case !caughtUp:
+		calmSince = time.Time{}
+	case calmSince.IsZero():
+		calmSince = now
+	case maxInterval > 0 && now.Sub(calmSince) >= adaptiveCalmPeriod:
+		next = maxInterval
+	}
+	if next != currentInterval() {
+		slog.Info("Changing poll interval", "interval", next, "busy", busy)
+	}
+	adaptiveBase.Store(int64(next))
+}
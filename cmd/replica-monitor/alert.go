@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"replica-monitor/pkg/notify"
+)
+
+// Most recent alert raised for a replica
+type alertState struct {
+	Event   string    `json:"event"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Record an alert for a replica and queue it for -alert-webhook and -notify, or its tenant's destinations
+func sendAlert(r *replica, event, message string) {
+	message = redact(message)
+	if incidentTracked(r) {
+		openIncident(r)
+	}
+	r.lastAlert = &alertState{Event: event, Message: message, Time: time.Now()}
+	recordTimeline(r, event, message)
+	recordEvent(r, journalEvent{Event: "alert_raised", Alert: event, Message: message}, 0)
+	attrs := []any{"event", event, "replica", displayName(r), "message", message}
+	if r.lagKnown {
+		attrs = append(attrs, "lag_seconds", r.lagSeconds)
+	}
+	slog.Warn("Alert raised", attrs...)
+	d := alertDispatcher(r)
+	if d == nil || !isLeader() || alertsSilenced.Load() {
+		return
+	}
+	alert := notify.Event{
+		Time:    time.Now(),
+		Replica: displayName(r),
+		Host:    r.host,
+		Event:   event,
+		Message: message,
+		Labels:  r.labels,
+
+		Instance: r.instance,
+
+		Session:  sessionID,
+		Incident: r.incident,
+	}
+	// The journal entry is filled in now, since delivery happens on another goroutine
+	d.Enqueue(alert, alertResult(newEvent(r, journalEvent{Alert: event}, 0)))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+	"replica-monitor/pkg/notify"
+)
+
+// Notifier keeping every alert delivered to it
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []notify.Event
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, event notify.Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return nil
+}
+
+// A matched error raises one alert per poll it lasts, and the thread stop,
+// match, and recovery around it are announced once each
+func TestAlertsFromMockSource(t *testing.T) {
+	const failure = "Coordinator stopped because there were error(s) in the worker(s). The most recent failure being: Worker 1 failed executing transaction"
+	source := &monitor.MockSource{Rows: []*monitor.StatusRow{
+		monitor.MockRow("Replica_IO_Running", "Yes", "Replica_SQL_Running", "Yes", "Last_SQL_Error", "", "Seconds_Behind_Source", "0"),
+		monitor.MockRow("Replica_IO_Running", "Yes", "Replica_SQL_Running", "No", "Last_SQL_Error", failure, "Seconds_Behind_Source", nil),
+		monitor.MockRow("Replica_IO_Running", "Yes", "Replica_SQL_Running", "No", "Last_SQL_Error", failure, "Seconds_Behind_Source", nil),
+		monitor.MockRow("Replica_IO_Running", "Yes", "Replica_SQL_Running", "Yes", "Last_SQL_Error", "", "Seconds_Behind_Source", "0"),
+	}}
+	rec := &recordingNotifier{}
+	r := &replica{name: "replica-1", host: "replica-1.example.com", source: source,
+		stats:  monitor.NewLagStats(etaRateWindow),
+		tenant: &tenant{name: "test", targets: []notify.Target{{Name: "recorder", Notifier: rec}}}}
+	var banners strings.Builder
+	saved := stdout
+	stdout = &banners
+	t.Cleanup(func() {
+		stdout = saved
+		delete(transitionLast, r)
+	})
+
+	var matched []bool
+	for range 4 {
+		matched = append(matched, showReplicaStatus(context.Background(), io.Discard, r))
+		announceTransitions([]*replica{r})
+	}
+	r.tenant.dispatcher.Drain(time.Second)
+
+	if want := []bool{false, true, true, false}; !slices.Equal(matched, want) {
+		t.Errorf("showReplicaStatus = %v; want %v", matched, want)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.events) != 2 {
+		t.Fatalf("%d alerts delivered; want 2: %+v", len(rec.events), rec.events)
+	}
+	for _, e := range rec.events {
+		if e.Event != "sql_error" || e.Replica != "replica-1" || !strings.Contains(e.Message, "Worker 1 failed") {
+			t.Errorf("alert %+v; want sql_error for replica-1's worker failure", e)
+		}
+	}
+	if r.lastAlert == nil || r.lastAlert.Event != "sql_error" {
+		t.Errorf("last alert %+v; want sql_error", r.lastAlert)
+	}
+	for _, want := range []string{"SQL thread stopped (No)", "error pattern matched", "SQL thread running again", "error cleared"} {
+		if n := strings.Count(banners.String(), want); n != 1 {
+			t.Errorf("%q announced %d times; want once:\n%s", want, n, banners.String())
+		}
+	}
+	if polls, skips, _, _ := source.Calls(); polls != 4 || skips != 0 {
+		t.Errorf("%d polls and %d skips; want 4 and none", polls, skips)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// -api-token-file, -api-htpasswd-file, and -oidc-issuer protect the -http and
+// -grpc endpoints; a request passing any of them is let in. /healthz stays
+// open, and /readyz answers unauthenticated probes with its status code only.
+var (
+	apiTokenFile    string
+	apiHtpasswdFile string
+)
+
+// Credentials loaded by setupAPIAuth: hashes of the API tokens, and bcrypt
+// hashes of the basic auth passwords by user
+var (
+	apiTokens  [][sha256.Size]byte
+	basicUsers map[string][]byte
+)
+
+// Whether the endpoints need credentials
+func apiAuthEnabled() bool {
+	return len(apiTokens) > 0 || basicUsers != nil || oidc != nil
+}
+
+// Load -api-token-file and -api-htpasswd-file and discover the -oidc-issuer
+func setupAPIAuth() error {
+	if apiTokenFile != "" {
+		lines, err := readCredentialLines(apiTokenFile)
+		if err != nil {
+			return fmt.Errorf("-api-token-file: %w", err)
+		}
+		for _, token := range lines {
+			apiTokens = append(apiTokens, sha256.Sum256([]byte(token)))
+		}
+	}
+	if apiHtpasswdFile != "" {
+		lines, err := readCredentialLines(apiHtpasswdFile)
+		if err != nil {
+			return fmt.Errorf("-api-htpasswd-file: %w", err)
+		}
+		basicUsers = make(map[string][]byte, len(lines))
+		for _, line := range lines {
+			user, hash, _ := strings.Cut(line, ":")
+			if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+				return fmt.Errorf("-api-htpasswd-file: %s needs a bcrypt hash, as htpasswd -B writes", user)
+			}
+			basicUsers[user] = []byte(hash)
+		}
+	}
+	if oidcIssuer != "" {
+		var err error
+		if oidc, err = newOIDCProvider(context.Background()); err != nil {
+			return fmt.Errorf("-oidc-issuer: %w", err)
+		}
+	}
+	return nil
+}
+
+// The lines of a credentials file, without blank lines and # comments
+func readCredentialLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s holds no credentials", path)
+	}
+	return lines, nil
+}
+
+// Who the Authorization header, or an X-API-Key header, names: "token" for an
+// API token, the user for basic auth, or the email or subject of an OIDC token
+func checkCredentials(authorization, apiKey string) (string, bool) {
+	if apiKey != "" && validToken(apiKey) {
+		return "token", true
+	}
+	scheme, credentials, _ := strings.Cut(authorization, " ")
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		if validToken(credentials) {
+			return "token", true
+		}
+		if oidc != nil {
+			if who, err := oidc.verifyBearer(credentials); err == nil {
+				return who, true
+			}
+		}
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			return "", false
+		}
+		user, pass, _ := strings.Cut(string(decoded), ":")
+		if hash, ok := basicUsers[user]; ok && bcrypt.CompareHashAndPassword(hash, []byte(pass)) == nil {
+			return user, true
+		}
+	}
+	return "", false
+}
+
+func validToken(token string) bool {
+	sum := sha256.Sum256([]byte(token))
+	valid := false
+	for _, t := range apiTokens {
+		if subtle.ConstantTimeCompare(sum[:], t[:]) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// Who made the request, and whether they may see the endpoints; always
+// allowed when no authentication is configured
+func authenticate(req *http.Request) (string, bool) {
+	if !apiAuthEnabled() {
+		return "", true
+	}
+	if who, ok := checkCredentials(req.Header.Get("Authorization"), req.Header.Get("X-API-Key")); ok {
+		return who, true
+	}
+	if oidc != nil {
+		return oidc.session(req)
+	}
+	return "", false
+}
+
+// Let in only authenticated requests; browsers are sent to sign in with
+// -oidc-issuer, or asked for a basic auth password
+func requireAuth(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := authenticate(req); ok {
+			h.ServeHTTP(w, req)
+			return
+		}
+		slog.Debug("Refused an unauthenticated request", "path", req.URL.Path, "remote", req.RemoteAddr)
+		if oidc != nil && req.Method == http.MethodGet && strings.Contains(req.Header.Get("Accept"), "text/html") {
+			oidc.login(w, req)
+			return
+		}
+		if basicUsers != nil {
+			w.Header().Add("WWW-Authenticate", `Basic realm="replica-monitor", charset="UTF-8"`)
+		}
+		if len(apiTokens) > 0 || oidc != nil {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="replica-monitor"`)
+		}
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+	})
+}
+
+var errUnauthenticated = status.Error(codes.Unauthenticated, "authentication required: pass an API token, basic auth, or an OIDC token in the authorization metadata")
+
+// Check a gRPC call's authorization or x-api-key metadata
+func grpcAuthenticate(ctx context.Context) error {
+	if !apiAuthEnabled() {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	if _, ok := checkCredentials(first("authorization"), first("x-api-key")); !ok {
+		return errUnauthenticated
+	}
+	return nil
+}
+
+func grpcAuthUnary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := grpcAuthenticate(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func grpcAuthStream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := grpcAuthenticate(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Clear the credentials setupAPIAuth loaded
+func resetAPIAuth() {
+	apiTokenFile, apiHtpasswdFile, apiTokens, basicUsers = "", "", nil, nil
+	oidcIssuer, oidcClientID, oidcClientSecretFile, oidcRedirectURL, oidcAllow, oidc = "", "", "", "", nil, nil
+}
+
+func TestAPIAuth(t *testing.T) {
+	defer resetAPIAuth()
+	dir := t.TempDir()
+	apiTokenFile = filepath.Join(dir, "tokens")
+	apiHtpasswdFile = filepath.Join(dir, "htpasswd")
+	hash, _ := bcrypt.GenerateFromPassword([]byte("opensesame"), bcrypt.MinCost)
+	os.WriteFile(apiTokenFile, []byte("# Prometheus\ns3cret-token\n\nother-token\n"), 0o600)
+	os.WriteFile(apiHtpasswdFile, []byte("alice:"+string(hash)+"\n"), 0o600)
+	if err := setupAPIAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := requireAuth(http.HandlerFunc(handleHealthz))
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"none", nil, http.StatusUnauthorized},
+		{"bearer", http.Header{"Authorization": {"Bearer other-token"}}, http.StatusOK},
+		{"api key", http.Header{"X-Api-Key": {"s3cret-token"}}, http.StatusOK},
+		{"wrong token", http.Header{"Authorization": {"Bearer # Prometheus"}}, http.StatusUnauthorized},
+		{"basic", http.Header{"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte("alice:opensesame"))}}, http.StatusOK},
+		{"wrong password", http.Header{"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte("alice:s3cret-token"))}}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/status", nil)
+		for k, v := range tt.header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusUnauthorized && len(rec.Header().Values("WWW-Authenticate")) != 2 {
+			t.Errorf("%s: WWW-Authenticate %q", tt.name, rec.Header().Values("WWW-Authenticate"))
+		}
+	}
+
+	// Probes learn only whether the monitor is ready
+	rec := httptest.NewRecorder()
+	handleReadyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusServiceUnavailable || body != "not ready\n" {
+		t.Errorf("unauthenticated /readyz: %d %q", rec.Code, body)
+	}
+}
+
+// An OpenID Connect provider signing ID tokens with key, for code "good-code"
+func fakeOIDCProvider(t *testing.T, key *rsa.PrivateKey, claims map[string]any) *httptest.Server {
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer": srv.URL, "authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint": srv.URL + "/token", "jwks_uri": srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, req *http.Request) {
+		id, secret, _ := req.BasicAuth()
+		if req.FormValue("code") != "good-code" || id != "monitor" || secret != "client-secret" || req.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signJWT(t, key, claims)})
+	})
+	srv = httptest.NewServer(mux)
+	return srv
+}
+
+func signJWT(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	defer resetAPIAuth()
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	claims := map[string]any{"sub": "1234", "email": "alice@example.com", "email_verified": true, "aud": "monitor", "exp": time.Now().Add(time.Hour).Unix()}
+	idp := fakeOIDCProvider(t, key, claims)
+	defer idp.Close()
+	claims["iss"] = idp.URL
+
+	oidcIssuer, oidcClientID, oidcRedirectURL = idp.URL, "monitor", "https://monitor.example.com/oauth2/callback"
+	oidcClientSecretFile = filepath.Join(t.TempDir(), "secret")
+	oidcAllow = []string{"@example.com"}
+	os.WriteFile(oidcClientSecretFile, []byte("client-secret\n"), 0o600)
+	if err := setupAPIAuth(); err != nil {
+		t.Fatal(err)
+	}
+	open := http.NewServeMux()
+	oidc.routes(open)
+	open.Handle("/", requireAuth(http.HandlerFunc(handleHealthz)))
+
+	// A browser is sent to the provider
+	req := httptest.NewRequest("GET", "/status?x=1", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	open.ServeHTTP(rec, req)
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || !strings.HasPrefix(location.String(), idp.URL+"/authorize?") {
+		t.Fatalf("sign-in redirect: %d %s", rec.Code, location)
+	}
+	claims["nonce"] = location.Query().Get("nonce")
+	loginCookie := rec.Result().Cookies()[0]
+
+	// And comes back with a code
+	callback := func(code, state string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/oauth2/callback?code="+code+"&state="+state, nil)
+		req.AddCookie(loginCookie)
+		rec := httptest.NewRecorder()
+		open.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := callback("good-code", "forged"); rec.Code != http.StatusBadRequest {
+		t.Errorf("forged state: %d", rec.Code)
+	}
+	rec = callback("good-code", location.Query().Get("state"))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/status?x=1" {
+		t.Fatalf("callback: %d %s %s", rec.Code, rec.Header().Get("Location"), rec.Body)
+	}
+	var session *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == oidcSessionCookie {
+			session = c
+		}
+	}
+	if session == nil || !session.Secure || !session.HttpOnly {
+		t.Fatalf("session cookie %v", session)
+	}
+
+	req = httptest.NewRequest("GET", "/status", nil)
+	req.AddCookie(session)
+	if who, ok := authenticate(req); !ok || who != "alice@example.com" {
+		t.Errorf("session: %q %v", who, ok)
+	}
+	_, sig, _ := strings.Cut(session.Value, ".")
+	forged, _ := json.Marshal(oidcSession{User: "mallory@example.org", Expires: time.Now().Add(time.Hour).Unix()})
+	session.Value = base64.RawURLEncoding.EncodeToString(forged) + "." + sig
+	req = httptest.NewRequest("GET", "/status", nil)
+	req.AddCookie(session)
+	if _, ok := authenticate(req); ok {
+		t.Error("accepted a tampered session cookie")
+	}
+
+	// ID tokens work as bearer tokens, for allowed users only
+	if who, ok := checkCredentials("Bearer "+signJWT(t, key, claims), ""); !ok || who != "alice@example.com" {
+		t.Errorf("bearer ID token: %q %v", who, ok)
+	}
+	claims["email"] = "mallory@example.org"
+	if _, ok := checkCredentials("Bearer "+signJWT(t, key, claims), ""); ok {
+		t.Error("let in a user -oidc-allow does not list")
+	}
+	claims["email"], claims["aud"] = "alice@example.com", "another-client"
+	if _, ok := checkCredentials("Bearer "+signJWT(t, key, claims), ""); ok {
+		t.Error("accepted a token issued for another client")
+	}
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	claims["aud"] = "monitor"
+	if _, ok := checkCredentials("Bearer "+signJWT(t, other, claims), ""); ok {
+		t.Error("accepted a token signed with another key")
+	}
+}
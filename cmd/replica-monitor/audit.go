@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	osuser "os/user"
+	"sync"
+	"time"
+)
+
+// -audit-log records every intervention on a replica (skips, starting
+// replication, pausing polling, silencing alerts) as a JSON line chained to
+// the one before by its SHA-256 hash, and signed with -audit-key when given,
+// so that an edited, removed, or inserted entry shows up in audit-verify
+var (
+	auditLog string
+	auditKey string
+)
+
+// One intervention. Hash covers the JSON of every other field, Prev included;
+// Sig is the Ed25519 signature of Hash.
+type auditEntry struct {
+	Seq      int64     `json:"seq"`
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	Trigger  string    `json:"trigger"` // auto, cli, or tui
+	Actor    string    `json:"actor"`   // user@host running the monitor
+	Replica  string    `json:"replica,omitempty"`
+	Host     string    `json:"host,omitempty"`
+	Session  string    `json:"session"`
+	Incident string    `json:"incident,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+	Prev     string    `json:"prev"`
+	Hash     string    `json:"hash,omitempty"`
+	Sig      string    `json:"sig,omitempty"`
+}
+
+var (
+	auditMu     sync.Mutex
+	auditOut    *os.File
+	auditSigner ed25519.PrivateKey
+	auditSeq    int64
+	auditPrev   string
+	auditActor  string
+	// The last failed write, until one lands again; skips and starts are
+	// refused meanwhile, since they would go unrecorded
+	auditErr error
+)
+
+// Open -audit-log, continuing the chain from its last entry
+func openAudit() error {
+	if auditLog == "" {
+		return nil
+	}
+	if auditKey != "" {
+		key, err := readPEMKey(auditKey, x509.ParsePKCS8PrivateKey)
+		if err != nil {
+			return fmt.Errorf("-audit-key: %w", err)
+		}
+		signer, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return errors.New("-audit-key must be an Ed25519 private key")
+		}
+		auditSigner = signer
+	}
+	f, err := os.OpenFile(auditLog, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	last, err := lastAuditEntry(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w; check it with audit-verify", auditLog, err)
+	}
+	auditSeq, auditPrev = last.Seq, last.Hash
+	auditOut = f
+	auditActor = "replica-monitor"
+	if u, err := osuser.Current(); err == nil {
+		auditActor = u.Username
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		auditActor += "@" + hostname
+	}
+	return nil
+}
+
+func closeAudit() {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditOut != nil {
+		auditOut.Close()
+		auditOut = nil
+	}
+}
+
+// The last entry of an audit log, zero for an empty one
+func lastAuditEntry(r io.Reader) (auditEntry, error) {
+	var last auditEntry
+	var line []byte
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+			line = append(line[:0], scanner.Bytes()...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return last, err
+	}
+	if line == nil {
+		return last, nil
+	}
+	if err := json.Unmarshal(line, &last); err != nil || last.Hash == "" {
+		return last, errors.New("the last entry is unreadable")
+	}
+	return last, nil
+}
+
+// Append an intervention to -audit-log, if open; r is nil for actions on the
+// monitor as a whole. Safe from any goroutine.
+func audit(r *replica, action, trigger, detail string) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditOut == nil {
+		return nil
+	}
+	entry := auditEntry{
+		Seq: auditSeq + 1, Time: time.Now().UTC(), Action: action, Trigger: trigger,
+		Actor: auditActor, Session: sessionID, Detail: redact(detail), Prev: auditPrev,
+	}
+	if r != nil {
+		entry.Replica, entry.Host, entry.Incident = displayName(r), r.host, r.incident
+	}
+	entry.Hash = auditHash(entry)
+	if auditSigner != nil {
+		sum, _ := hex.DecodeString(entry.Hash)
+		entry.Sig = base64.StdEncoding.EncodeToString(ed25519.Sign(auditSigner, sum))
+	}
+	line, _ := json.Marshal(entry)
+	if err := appendAudit(append(line, '\n')); err != nil {
+		auditErr = err
+		slog.Error("Failed to write audit log; refusing skips and starts until it can be written", "action", action, "replica", entry.Replica, "err", err)
+		return err
+	}
+	auditSeq, auditPrev, auditErr = entry.Seq, entry.Hash, nil
+	// Logged too, so that the chain's head can be compared with a copy kept
+	// elsewhere: a chain cannot show its own last entries were cut off
+	slog.Info("Audited", "action", action, "replica", entry.Replica, "seq", entry.Seq, "hash", entry.Hash)
+	return nil
+}
+
+// Append line to -audit-log and sync it, since the entry may be all that is
+// left of a crash. A partly written line is cut off again, so that the next
+// entry does not follow a torn one and break the chain for audit-verify.
+func appendAudit(line []byte) error {
+	end, err := auditOut.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if n, err := auditOut.Write(line); err != nil {
+		if n == 0 {
+			return err
+		}
+		if terr := auditOut.Truncate(end); terr != nil {
+			return fmt.Errorf("%w; the partial entry could not be cut off: %v", err, terr)
+		}
+		return err
+	}
+	if err := auditOut.Sync(); err != nil {
+		auditOut.Truncate(end)
+		return err
+	}
+	return nil
+}
+
+// Record that action is about to run on r, as an <action>_intent entry, so
+// that an action cut short by a crash still leaves a trace; an error means the
+// action must not run. The outcome is recorded by a later entry.
+func auditIntent(r *replica, action, trigger, detail string) error {
+	if err := auditAllows(r, trigger); err != nil {
+		return err
+	}
+	if err := audit(r, action+"_intent", trigger, detail); err != nil {
+		return fmt.Errorf("-audit-log %s cannot be written: %w", auditLog, err)
+	}
+	return nil
+}
+
+// Whether an action on r may go ahead: after a failed write to -audit-log it
+// may only once an audit_recovered entry, recording the failure that left a
+// gap, has been written
+func auditAllows(r *replica, trigger string) error {
+	auditMu.Lock()
+	failed := auditErr
+	auditMu.Unlock()
+	if failed == nil {
+		return nil
+	}
+	if err := audit(r, "audit_recovered", trigger, "an earlier entry was not written: "+failed.Error()); err != nil {
+		return fmt.Errorf("-audit-log %s cannot be written: %w", auditLog, err)
+	}
+	return nil
+}
+
+// Hex SHA-256 of the entry's JSON without its hash and signature
+func auditHash(entry auditEntry) string {
+	entry.Hash, entry.Sig = "", ""
+	data, _ := json.Marshal(entry)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Read a PEM-encoded key with parse
+func readPEMKey[K any](path string, parse func([]byte) (K, error)) (K, error) {
+	var zero K
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return zero, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return zero, fmt.Errorf("%s holds no PEM key", path)
+	}
+	return parse(block.Bytes)
+}
+
+// Check an audit log: the hash of every entry, the chain of hashes, the
+// sequence numbers, and, given the public key, every signature. Returns the
+// number of entries and the last one's hash.
+func verifyAudit(r io.Reader, pub ed25519.PublicKey) (int64, string, error) {
+	var prev auditEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return prev.Seq, prev.Hash, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch {
+		case entry.Seq != prev.Seq+1:
+			return prev.Seq, prev.Hash, fmt.Errorf("line %d: entry %d follows entry %d", line, entry.Seq, prev.Seq)
+		case entry.Prev != prev.Hash:
+			return prev.Seq, prev.Hash, fmt.Errorf("line %d: entry %d does not chain to entry %d", line, entry.Seq, prev.Seq)
+		case auditHash(entry) != entry.Hash:
+			return prev.Seq, prev.Hash, fmt.Errorf("line %d: entry %d was altered after it was written", line, entry.Seq)
+		}
+		if pub != nil {
+			sum, _ := hex.DecodeString(entry.Hash)
+			sig, err := base64.StdEncoding.DecodeString(entry.Sig)
+			if err != nil || !ed25519.Verify(pub, sum, sig) {
+				return prev.Seq, prev.Hash, fmt.Errorf("line %d: entry %d is not signed by the key", line, entry.Seq)
+			}
+		}
+		prev = entry
+	}
+	return prev.Seq, prev.Hash, scanner.Err()
+}
+
+// Flags of audit-verify
+var (
+	auditVerifyLog string
+	auditVerifyKey string
+)
+
+func auditVerifyFlags() *flag.FlagSet {
+	fs := newCommandFlags("audit-verify")
+	fs.StringVar(&auditVerifyLog, "audit-log", "", "Audit log to verify (required)")
+	fs.StringVar(&auditVerifyKey, "public-key", "", "PEM Ed25519 public key of -audit-key, to check every entry's signature")
+	return fs
+}
+
+func runAuditVerify(fs *flag.FlagSet) error {
+	if auditVerifyLog == "" {
+		return usageError(fs, "")
+	}
+
+	var pub ed25519.PublicKey
+	if auditVerifyKey != "" {
+		key, err := readPEMKey(auditVerifyKey, x509.ParsePKIXPublicKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-public-key: %v\n", err)
+			return exitWith(2)
+		}
+		var ok bool
+		if pub, ok = key.(ed25519.PublicKey); !ok {
+			fmt.Fprintln(os.Stderr, "-public-key must be an Ed25519 public key")
+			return exitWith(2)
+		}
+	}
+	f, err := os.Open(auditVerifyLog)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitWith(2)
+	}
+	defer f.Close()
+	n, head, err := verifyAudit(f, pub)
+	if err != nil {
+		fmt.Printf("❌ %s: %v (the %d entries before it verified)\n", auditVerifyLog, err, n)
+		return exitWith(1)
+	}
+	signatures := "signatures not checked"
+	if pub != nil {
+		signatures = "every entry signed"
+	}
+	fmt.Printf("✅ %s: %d entries verified, %s; last hash %s\n", auditVerifyLog, n, signatures, head)
+	return nil
+}
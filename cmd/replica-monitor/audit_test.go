@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Write three signed entries to a fresh audit log and return its lines
+func writeTestAudit(t *testing.T) ([]string, ed25519.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	auditKey = filepath.Join(dir, "audit.pem")
+	auditLog = filepath.Join(dir, "audit.jsonl")
+	t.Cleanup(func() { auditKey, auditLog, auditSigner = "", "", nil })
+	if err := os.WriteFile(auditKey, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &replica{name: "replica-1", host: "replica-1.example.com"}
+	if err := openAudit(); err != nil {
+		t.Fatal(err)
+	}
+	audit(r, "skip", "auto", "Coordinator stopped")
+	audit(nil, "pause_polling", "tui", "")
+	closeAudit()
+	// Reopening continues the chain instead of starting a new one
+	if err := openAudit(); err != nil {
+		t.Fatal(err)
+	}
+	audit(r, "start_replication", "cli", "")
+	closeAudit()
+
+	data, err := os.ReadFile(auditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n"), pub
+}
+
+func TestAuditVerify(t *testing.T) {
+	lines, pub := writeTestAudit(t)
+	n, head, err := verifyAudit(strings.NewReader(strings.Join(lines, "\n")), pub)
+	if err != nil || n != 3 || head == "" {
+		t.Fatalf("verifyAudit = %d, %q, %v; want 3 verified entries", n, head, err)
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	tampered := map[string][]string{
+		"edited":    {lines[0], strings.Replace(lines[1], "pause_polling", "resume_polling", 1), lines[2]},
+		"removed":   {lines[0], lines[2]},
+		"reordered": {lines[1], lines[0], lines[2]},
+	}
+	for name, lines := range tampered {
+		if _, _, err := verifyAudit(strings.NewReader(strings.Join(lines, "\n")), pub); err == nil {
+			t.Errorf("%s audit log verified", name)
+		}
+	}
+	if _, _, err := verifyAudit(strings.NewReader(strings.Join(lines, "\n")), otherPub); err == nil {
+		t.Error("audit log verified with another key")
+	}
+}
+
+func TestLastAuditEntry(t *testing.T) {
+	lines, _ := writeTestAudit(t)
+	last, err := lastAuditEntry(strings.NewReader(strings.Join(lines, "\n") + "\n\n"))
+	if err != nil || last.Seq != 3 || last.Action != "start_replication" {
+		t.Errorf("lastAuditEntry = %+v, %v; want entry 3", last, err)
+	}
+	if _, err := lastAuditEntry(bytes.NewReader([]byte(lines[0][:20]))); err == nil {
+		t.Error("truncated last entry accepted")
+	}
+}
+
+// A failed write leaves no entry and refuses skips until the log can be written
+// again, when the gap and the intent to skip are recorded before the skip
+func TestAuditWriteFailure(t *testing.T) {
+	auditLog = filepath.Join(t.TempDir(), "audit.jsonl")
+	t.Cleanup(func() { auditLog, auditErr = "", nil })
+	if err := openAudit(); err != nil {
+		t.Fatal(err)
+	}
+	defer closeAudit()
+	source := &monitor.MockSource{}
+	r := &replica{name: "replica-1", source: source}
+	if err := audit(r, "start_replication", "cli", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	writable := auditOut
+	readOnly, err := os.Open(auditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditOut = readOnly
+	if err := audit(r, "skip", "auto", ""); err == nil || auditSeq != 1 {
+		t.Fatalf("audit to an unwritable log = %v, seq %d; want an error and seq 1", err, auditSeq)
+	}
+	if err := r.skipReplError(io.Discard, "auto"); err == nil {
+		t.Error("skip ran while the audit log was unwritable")
+	}
+	if _, skips, _, _ := source.Calls(); skips != 0 {
+		t.Errorf("%d skips sent while the audit log was unwritable", skips)
+	}
+
+	auditOut = writable
+	readOnly.Close()
+	if err := r.skipReplError(io.Discard, "auto"); err != nil {
+		t.Fatalf("skip once the audit log is writable again: %v", err)
+	}
+	data, err := os.ReadFile(auditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _, err := verifyAudit(bytes.NewReader(data), nil); err != nil || n != 4 {
+		t.Errorf("verifyAudit = %d, %v; want 4 entries", n, err)
+	}
+	// The gap, then the intent to skip, then the skip
+	recovered := strings.Index(string(data), `"action":"audit_recovered"`)
+	intent := strings.Index(string(data), `"action":"skip_intent"`)
+	skip := strings.Index(string(data), `"action":"skip"`)
+	if recovered < 0 || intent < recovered || skip < intent {
+		t.Errorf("want audit_recovered, skip_intent, and skip in order:\n%s", data)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// List the available reader instances of the Aurora cluster. Readers added or
+// removed by autoscaling show up here on the next re-scan.
+func (d *rdsDiscovery) findAuroraReaders(ctx context.Context) ([]types.DBInstance, error) {
+	clusters, err := d.client.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(d.auroraCluster),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters.DBClusters) == 0 {
+		return nil, fmt.Errorf("cluster %s not found", d.auroraCluster)
+	}
+
+	readers := make(map[string]bool)
+	for _, member := range clusters.DBClusters[0].DBClusterMembers {
+		if !aws.ToBool(member.IsClusterWriter) {
+			readers[aws.ToString(member.DBInstanceIdentifier)] = true
+		}
+	}
+
+	var found []types.DBInstance
+	paginator := rds.NewDescribeDBInstancesPaginator(d.client, &rds.DescribeDBInstancesInput{
+		Filters: []types.Filter{{Name: aws.String("db-cluster-id"), Values: []string{d.auroraCluster}}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, inst := range page.DBInstances {
+			if readers[aws.ToString(inst.DBInstanceIdentifier)] && inst.Endpoint != nil &&
+				aws.ToString(inst.DBInstanceStatus) == "available" {
+				found = append(found, inst)
+			}
+		}
+	}
+	return found, nil
+}
+
+// Query and print an Aurora reader's own lag from replica_host_status
+func showAuroraReaderStatus(out io.Writer, r *replica) {
+	now := time.Now()
+	r.lagKnown = false
+
+	var lagMillis float64
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	err := retryTransient(ctx, r, func() error {
+		return r.db.QueryRowContext(ctx, "SELECT REPLICA_LAG_IN_MILLISECONDS FROM information_schema.replica_host_status WHERE SERVER_ID = @@aurora_server_id").Scan(&lagMillis)
+	})
+	if err != nil {
+		pollFailed(r, "replica_host_status query", err)
+		return
+	}
+	pollSucceeded(r)
+	r.lagSeconds = lagMillis / 1000
+	r.lagKnown = true
+	r.recordLag(r.lagSeconds)
+	r.polledAt = now
+	r.lastStatus = map[string]string{"REPLICA_LAG_IN_MILLISECONDS": fmt.Sprintf("%.1f", lagMillis)}
+
+	fmt.Fprintf(out, "\n[%s] Aurora Reader Status (%s):\n", now.Format("2006-01-02 15:04:05"), r.name)
+	fmt.Fprintln(out, strings.Repeat("=", 50))
+	if len(r.labels) > 0 {
+		fmt.Fprintf(out, "Labels: %s\n", formatLabels(r.labels))
+	}
+	if r.instance != nil {
+		fmt.Fprintf(out, "Instance: %s\n", r.instance)
+	}
+	fmt.Fprintf(out, "Replica_Lag: %.1f ms\n", lagMillis)
+	printLagTrend(out, r)
+	printCloudWatchLag(out, r)
+	printOSMetrics(out, r)
+	printInsights(out, r)
+
+	seconds := int(lagMillis / 1000)
+	recordHistory(historySample{Time: now, Replica: r.name, Host: r.host, Labels: r.labels, SecondsBehind: &seconds})
+}
+
+// Print the worst reader lag across the cluster for this cycle
+func showAuroraClusterLag(out io.Writer, cluster string, replicas []*replica) {
+	var worst *replica
+	for _, r := range replicas {
+		if r.aurora && r.lagKnown && (worst == nil || r.lagSeconds > worst.lagSeconds) {
+			worst = r
+		}
+	}
+	if worst == nil {
+		fmt.Fprintf(out, "\n🧮 Cluster %s: no reader lag available\n", cluster)
+		return
+	}
+	fmt.Fprintf(out, "\n🧮 Cluster %s max reader lag: %.1f ms (%s)\n", cluster, worst.lagSeconds*1000, worst.name)
+}
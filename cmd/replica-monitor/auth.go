@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql/driver"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// How replicas are logged in to: -auth password (the default) sends -password,
+// -auth iam a fresh RDS IAM authentication token per connection, in clear text
+// over TLS as the mysql_clear_password plugin requires
+var (
+	authMode        = "password"
+	allowCleartext  bool
+	serverPublicKey string
+)
+
+var authModes = []string{"password", "iam"}
+
+// IAM authentication tokens are valid for this long, and generated per
+// connection rather than reused
+const iamTokenLifetime = 15 * time.Minute
+
+// SHA-256 of an empty payload, which an authentication token signs
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Presign an rds-db:connect request for user at the host:port endpoint, as the
+// AWS SDK's rds/auth package does; the URL without its scheme is the password
+func buildIAMToken(ctx context.Context, endpoint, user string) (string, error) {
+	host, _, _ := strings.Cut(endpoint, ":")
+	region := endpointRegion(host)
+	cfg, err := loadAWSConfig(region)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Region == "" {
+		return "", fmt.Errorf("no AWS region for IAM authentication to %s; set -aws-region", host)
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("AWS credentials for IAM authentication: %w", err)
+	}
+	query := url.Values{"Action": {"connect"}, "DBUser": {user}, "X-Amz-Expires": {fmt.Sprint(int(iamTokenLifetime.Seconds()))}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+endpoint+"/?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	signed, _, err := v4.NewSigner().PresignHTTP(ctx, creds, req, emptyPayloadHash, "rds-db", cfg.Region, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(signed, "https://"), nil
+}
+
+// The region in an RDS or RDS Proxy endpoint such as
+// mydb.abc123xyz.us-east-1.rds.amazonaws.com, "" for other hosts
+func endpointRegion(host string) string {
+	parts := strings.Split(strings.ToLower(host), ".")
+	for i := 2; i+1 < len(parts); i++ {
+		if parts[i] == "rds" && parts[i+1] == "amazonaws" {
+			return parts[i-1]
+		}
+	}
+	return ""
+}
+
+// Whether host is an RDS Proxy endpoint, such as
+// myproxy.proxy-abc123xyz.us-east-1.rds.amazonaws.com, or a custom endpoint of
+// one, which adds a label in front of proxy-
+func isRDSProxy(host string) bool {
+	return endpointRegion(host) != "" && strings.Contains(strings.ToLower(host), ".proxy-")
+}
+
+// Said after a replica's address when it is reached through RDS Proxy
+func viaProxy(r *replica) string {
+	if r.proxy {
+		return " through RDS Proxy"
+	}
+	return ""
+}
+
+// Connector logging in to MySQL with the password of the moment: a new IAM
+// authentication token each time, since tokens expire long before pooled
+// connections are replaced, or the latest -password-file
+type loginConnector struct {
+	cfg      *mysql.Config
+	password func(ctx context.Context) (string, error)
+}
+
+func (c loginConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	passwd, err := c.password(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg := c.cfg.Clone()
+	cfg.Passwd = passwd
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c loginConnector) Driver() driver.Driver { return &mysql.MySQLDriver{} }
+
+// Name the -server-public-key key is registered under with the MySQL driver
+const serverPublicKeyName = "replica-monitor"
+
+var (
+	serverPublicKeyOnce sync.Once
+	serverPublicKeyErr  error
+)
+
+// Register -server-public-key, so that caching_sha2_password and sha256_password
+// encrypt the password with it instead of asking the server for its key over an
+// unencrypted connection
+func loadServerPublicKey() error {
+	serverPublicKeyOnce.Do(func() {
+		data, err := os.ReadFile(serverPublicKey)
+		if err != nil {
+			serverPublicKeyErr = err
+			return
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			serverPublicKeyErr = fmt.Errorf("%s holds no PEM public key", serverPublicKey)
+			return
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			serverPublicKeyErr = fmt.Errorf("%s: %w", serverPublicKey, err)
+			return
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			serverPublicKeyErr = fmt.Errorf("%s is not an RSA public key", serverPublicKey)
+			return
+		}
+		mysql.RegisterServerPubKey(serverPublicKeyName, rsaKey)
+	})
+	return serverPublicKeyErr
+}
+
+// Advice for a failed login, appended to the error; "" when there is none
+func authHint(err error, host string) string {
+	var mysqlErr *mysql.MySQLError
+	var pgErr *pgconn.PgError
+	var unknownCA x509.UnknownAuthorityError
+	accessDenied := errors.As(err, &mysqlErr) && mysqlErr.Number == 1045 ||
+		errors.As(err, &pgErr) && (pgErr.Code == "28P01" || pgErr.Code == "28000")
+	switch {
+	case errors.Is(err, mysql.ErrCleartextPassword):
+		return "the account logs in with mysql_clear_password, as IAM and PAM accounts do: use -auth iam, or -allow-cleartext-passwords"
+	case errors.Is(err, mysql.ErrOldPassword):
+		return "the account uses mysql_old_password, which is insecure and not supported: move it to caching_sha2_password"
+	case errors.Is(err, mysql.ErrUnknownPlugin):
+		return "the account's authentication plugin is not supported; caching_sha2_password, mysql_native_password, sha256_password, and mysql_clear_password are"
+	case errors.As(err, &unknownCA):
+		return "the server's certificate is not signed by a trusted CA; for RDS, pass -db-tls-ca the CA bundle from https://truststore.pki.rds.amazonaws.com/global/global-bundle.pem"
+	case accessDenied && authMode == "iam":
+		return "check that the AWS identity is allowed rds-db:connect as this user, and that the user was created to log in with IAM (AWSAuthenticationPlugin on MySQL, the rds_iam role on PostgreSQL)"
+	case accessDenied && isRDSProxy(host):
+		return "RDS Proxy checks the password against the Secrets Manager secret it is configured with, or requires IAM authentication (-auth iam)"
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Where AWS credentials come from for every AWS integration: a shared config
+// profile, a default region, and a role to assume, e.g. in another account
+var (
+	awsProfile    string
+	awsRegion     string
+	awsRoleARN    string
+	awsExternalID string
+)
+
+// AWS configuration shared by the RDS, CloudWatch, and other API clients, loaded once
+var (
+	awsConfigOnce sync.Once
+	awsBaseConfig aws.Config
+	awsConfigErr  error
+)
+
+// The default AWS configuration, with the region overridden when one is given
+func loadAWSConfig(region string) (aws.Config, error) {
+	awsConfigOnce.Do(func() {
+		awsBaseConfig, awsConfigErr = newAWSConfig()
+	})
+	cfg := awsBaseConfig.Copy()
+	if region != "" {
+		cfg.Region = region
+	}
+	return cfg, awsConfigErr
+}
+
+// RDS API clients by region
+var rdsClients = make(map[string]*rds.Client)
+
+func rdsClient(region string) (*rds.Client, error) {
+	if c, ok := rdsClients[region]; ok {
+		return c, nil
+	}
+	cfg, err := loadAWSConfig(region)
+	if err != nil {
+		return nil, err
+	}
+	c := rds.NewFromConfig(cfg)
+	rdsClients[region] = c
+	return c, nil
+}
+
+// DescribeDBInstances results by instance identifier, refreshed after rdsInstanceTTL
+var rdsInstanceCache = make(map[string]cachedInstance)
+
+const rdsInstanceTTL = 10 * time.Minute
+
+type cachedInstance struct {
+	inst    types.DBInstance
+	fetched time.Time
+}
+
+// Describe an RDS instance, from the cache when it was looked up recently
+func describeRDSInstance(ctx context.Context, id, region string) (types.DBInstance, error) {
+	if c, ok := rdsInstanceCache[id]; ok && time.Since(c.fetched) < rdsInstanceTTL {
+		return c.inst, nil
+	}
+	client, err := rdsClient(region)
+	if err != nil {
+		return types.DBInstance{}, err
+	}
+	out, err := client.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{DBInstanceIdentifier: aws.String(id)})
+	if err != nil {
+		return types.DBInstance{}, err
+	}
+	if len(out.DBInstances) == 0 {
+		return types.DBInstance{}, fmt.Errorf("instance %s not found", id)
+	}
+	rdsInstanceCache[id] = cachedInstance{out.DBInstances[0], time.Now()}
+	return out.DBInstances[0], nil
+}
+
+func newAWSConfig() (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+	if awsProfile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(awsProfile))
+	}
+	if awsRegion != "" {
+		opts = append(opts, config.WithRegion(awsRegion))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return cfg, fmt.Errorf("loading AWS config: %w", err)
+	}
+	if awsRoleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), awsRoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = "replica-monitor"
+			if awsExternalID != "" {
+				o.ExternalID = aws.String(awsExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return cfg, nil
+}
+
+// The RDS instance identifier and region of a replica: known for replicas found
+// through the RDS API, and otherwise read from an RDS endpoint such as
+// mydb.abc123xyz.us-east-1.rds.amazonaws.com. ok is false for other hosts,
+// including RDS Proxy endpoints, which name the proxy rather than an instance.
+func rdsInstanceOf(r *replica) (id, region string, ok bool) {
+	if r.region != "" && r.name != "" {
+		return r.name, r.region, true
+	}
+	if isRDSProxy(r.host) {
+		return "", "", false
+	}
+	parts := strings.Split(strings.ToLower(r.host), ".")
+	if len(parts) >= 6 && parts[3] == "rds" && parts[4] == "amazonaws" {
+		return parts[0], parts[2], true
+	}
+	return "", "", false
+}
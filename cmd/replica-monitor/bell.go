@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// -bell rings the terminal bell, and -flash briefly reverses the screen, on these transitions
+var (
+	bellEnabled  bool
+	flashEnabled bool
+)
+
+// Per-replica state from the previous cycle, to ring only on transitions
+type bellState struct {
+	running      bool
+	errorMatched bool
+	behind       bool
+}
+
+var bellLast = make(map[*replica]bellState)
+
+// Ring when replication stops, an error pattern is matched, or a lagging replica catches up
+func ringOnTransitions(replicas []*replica) {
+	if !bellEnabled && !flashEnabled {
+		return
+	}
+	ring := false
+	seen := make(map[*replica]bool)
+	for _, r := range replicas {
+		seen[r] = true
+		if r.polledAt.IsZero() {
+			continue
+		}
+		cur := bellState{
+			running:      r.aurora || (r.ioRunning == "Yes" && r.sqlRunning == "Yes"),
+			errorMatched: r.errorMatched,
+			behind:       r.lagKnown && r.lagSeconds > 0,
+		}
+		prev, ok := bellLast[r]
+		bellLast[r] = cur
+		if !ok {
+			continue
+		}
+		if (prev.running && !cur.running) ||
+			(!prev.errorMatched && cur.errorMatched) ||
+			(prev.behind && r.lagKnown && r.lagSeconds == 0) {
+			ring = true
+		}
+	}
+	for r := range bellLast {
+		if !seen[r] {
+			delete(bellLast, r)
+		}
+	}
+	if !ring {
+		return
+	}
+
+	// Straight to the terminal, even when the report itself is discarded
+	if bellEnabled {
+		fmt.Fprint(os.Stdout, "\a")
+	}
+	if flashEnabled {
+		fmt.Fprint(os.Stdout, "\033[?5h")
+		time.Sleep(150 * time.Millisecond)
+		fmt.Fprint(os.Stdout, "\033[?5l")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Seconds_Behind_Source is measured against the original source's event
+// timestamps, so while a chained replica is applying events its lag already
+// includes the lag of every hop above it, and while it is idle it reads 0.
+// The end-to-end lag at a hop is therefore the larger of its own lag and its
+// upstream's end-to-end lag, and each hop contributes what it adds on top.
+
+// Per-hop breakdown of one replication chain, ordered from the source down
+type chainHop struct {
+	replica      *replica
+	endToEnd     float64
+	contribution float64
+}
+
+// The replicas from the first hop below the source down to leaf
+func chainPath(leaf *replica) []*replica {
+	var path []*replica
+	for r := leaf; r != nil; r = r.upstream {
+		path = append([]*replica{r}, path...)
+	}
+	return path
+}
+
+// Compute each hop's end-to-end lag and contribution; ok is false when any hop's lag is unknown
+func traceChain(path []*replica) (hops []chainHop, ok bool) {
+	upstreamLag := 0.0
+	for _, r := range path {
+		if !r.lagKnown {
+			return nil, false
+		}
+		endToEnd := r.lagSeconds
+		if upstreamLag > endToEnd {
+			endToEnd = upstreamLag
+		}
+		hops = append(hops, chainHop{replica: r, endToEnd: endToEnd, contribution: endToEnd - upstreamLag})
+		upstreamLag = endToEnd
+	}
+	return hops, true
+}
+
+// Print the end-to-end lag of every chain deeper than one hop, naming the hop that contributes most
+func printChainLag(out io.Writer, replicas []*replica) {
+	hasDownstream := make(map[*replica]bool)
+	for _, r := range replicas {
+		if r.upstream != nil {
+			hasDownstream[r.upstream] = true
+		}
+	}
+
+	for _, leaf := range replicas {
+		if leaf.upstream == nil || hasDownstream[leaf] {
+			continue
+		}
+
+		path := chainPath(leaf)
+		names := []string{"source"}
+		for _, r := range path {
+			names = append(names, displayName(r))
+		}
+		chain := strings.Join(names, " → ")
+
+		hops, ok := traceChain(path)
+		if !ok {
+			fmt.Fprintf(out, "🔗 Chain %s: end-to-end lag unknown (a hop reported NULL)\n", chain)
+			continue
+		}
+
+		worst := hops[0]
+		var parts []string
+		for _, hop := range hops {
+			parts = append(parts, fmt.Sprintf("%s +%s", displayName(hop.replica), formatDuration(hop.contribution)))
+			if hop.contribution > worst.contribution {
+				worst = hop
+			}
+		}
+		total := hops[len(hops)-1].endToEnd
+		fmt.Fprintf(out, "🔗 Chain %s: end-to-end lag %s (%s)", chain, formatDuration(total), strings.Join(parts, ", "))
+		if total > 0 {
+			fmt.Fprintf(out, " — largest hop: %s (%.0f%%)", displayName(worst.replica), 100*worst.contribution/total)
+		}
+		fmt.Fprintln(out)
+	}
+}
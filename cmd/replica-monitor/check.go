@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Exit codes of the check command, following the Nagios plugin convention
+const (
+	checkOK       = int(monitor.CheckOK)
+	checkWarning  = int(monitor.CheckWarning)
+	checkCritical = int(monitor.CheckCritical)
+	checkUnknown  = int(monitor.CheckUnknown)
+)
+
+var checkStateNames = map[int]string{
+	checkOK:       monitor.CheckOK.String(),
+	checkWarning:  monitor.CheckWarning.String(),
+	checkCritical: monitor.CheckCritical.String(),
+	checkUnknown:  monitor.CheckUnknown.String(),
+}
+
+// Outcome of checking one replica
+type checkResult struct {
+	replica *replica
+	state   int
+	message string
+}
+
+// Thresholds for the check command, in seconds; zero disables a threshold
+var (
+	checkWarnLag int
+	checkMaxLag  int
+)
+
+// Output format of the check command
+var checkFormat string
+
+func checkFlags() *flag.FlagSet {
+	fs := newCommandFlags("check")
+	addConnectionFlags(fs)
+	fs.IntVar(&checkWarnLag, "warn-lag", 0, "Warning when lag exceeds this many seconds (0 disables)")
+	fs.IntVar(&checkMaxLag, "max-lag", 0, "Critical when lag exceeds this many seconds (0 disables)")
+	addLagSourceFlags(fs)
+	fs.BoolVar(&requireReplicationTLS, "require-replication-tls", false, "Critical when a replica's stream from its source is unencrypted")
+	fs.StringVar(&sourceHost, "source-host", "", "Also check the replication source")
+	fs.IntVar(&sourcePort, "source-port", 3306, "MySQL port of -source-host")
+	fs.StringVar(&checkFormat, "format", "text", "Output format: text, nagios, sensu, zabbix, or zabbix-discovery")
+	fs.StringVar(&sensuCheckName, "sensu-check", "replica-lag", "Check name used in Sensu events")
+	fs.StringVar(&zabbixHost, "zabbix-host", "-", "Host name used in Zabbix sender lines (\"-\" uses the agent's Hostname)")
+	return fs
+}
+
+func runCheck(fs *flag.FlagSet) error {
+	switch checkFormat {
+	case "text", "nagios", "sensu", "zabbix", "zabbix-discovery":
+	default:
+		fs.Usage()
+		return exitWith(checkUnknown)
+	}
+	assumeYes = true // a check never prompts, e.g. to confirm -topology
+
+	// Only the verdict goes to standard output
+	if err := setupOutput(); err != nil {
+		return &exitError{code: checkUnknown, err: err}
+	}
+	report := stdout
+	stdout = io.Discard
+	replicas, err := pollTargets(fs, io.Discard)
+	stdout = report
+	if errors.Is(err, errNothingToMonitor) {
+		return exitWith(checkUnknown)
+	}
+	if err == nil && len(replicas) == 0 {
+		err = errors.New("find any replicas")
+	}
+	if err != nil {
+		writeCheckOutput(stdout, nil, nil, err)
+		return exitWith(checkUnknown)
+	}
+
+	var results []checkResult
+	for _, r := range replicas {
+		results = append(results, evaluateReplica(r))
+		r.close()
+	}
+	writeCheckOutput(stdout, replicas, results, nil)
+	return exitWith(worstState(results))
+}
+
+// Write the verdict in -format to out, or that the check failed to run
+func writeCheckOutput(out io.Writer, replicas []*replica, results []checkResult, failure error) {
+	switch {
+	case checkFormat == "sensu":
+		writeSensuEvent(out, results, failure)
+	case failure != nil && checkFormat == "nagios":
+		fmt.Fprintf(out, "REPLICA UNKNOWN - failed to %v\n", failure)
+	case failure != nil:
+		fmt.Fprintf(out, "❓ UNKNOWN: failed to %v\n", failure)
+	case checkFormat == "nagios":
+		printNagios(out, results)
+	case checkFormat == "zabbix":
+		writeZabbixLines(out, replicas, false)
+	case checkFormat == "zabbix-discovery":
+		writeZabbixDiscovery(out, replicas)
+	default:
+		for _, res := range results {
+			fmt.Fprintf(out, "%s %s %s: %s\n", checkIcon(res.state), checkStateNames[res.state], displayName(res.replica), res.message)
+		}
+	}
+}
+
+// Print standard plugin output: a status line with perfdata, then one long-output
+// line per replica when there are several
+func printNagios(out io.Writer, results []checkResult) {
+	worst := worstState(results)
+	var perf []string
+	for _, res := range results {
+		label := "lag"
+		if len(results) > 1 {
+			label = "'" + strings.ReplaceAll(displayName(res.replica), "'", "''") + " lag'"
+		}
+		perf = append(perf, label+"="+nagiosPerfValue(res.replica))
+	}
+
+	if len(results) == 1 {
+		res := results[0]
+		fmt.Fprintf(out, "REPLICA %s - %s: %s | %s\n", checkStateNames[worst], displayName(res.replica), res.message, strings.Join(perf, " "))
+		return
+	}
+	counts := make(map[int]int)
+	for _, res := range results {
+		counts[res.state]++
+	}
+	var summary []string
+	for _, state := range []int{checkCritical, checkUnknown, checkWarning, checkOK} {
+		if counts[state] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[state], strings.ToLower(checkStateNames[state])))
+		}
+	}
+	fmt.Fprintf(out, "REPLICA %s - %d replicas: %s | %s\n", checkStateNames[worst], len(results), strings.Join(summary, ", "), strings.Join(perf, " "))
+	for _, res := range results {
+		fmt.Fprintf(out, "%s %s: %s\n", checkStateNames[res.state], displayName(res.replica), res.message)
+	}
+}
+
+// Lag perfdata value with warning and critical thresholds, "U" when lag is unknown
+func nagiosPerfValue(r *replica) string {
+	value := "U"
+	if r.lagKnown {
+		value = fmt.Sprintf("%.0fs", r.lagSeconds)
+	}
+	threshold := func(seconds int) string {
+		if seconds == 0 {
+			return ""
+		}
+		return fmt.Sprint(seconds)
+	}
+	return fmt.Sprintf("%s;%s;%s;0", value, threshold(checkWarnLag), threshold(checkMaxLag))
+}
+
+// Classify one replica after a poll: stopped threads and matched errors are
+// critical, replicas that could not be polled or report NULL lag are unknown
+func evaluateReplica(r *replica) checkResult {
+	state, message := monitor.Check(r.health(), monitor.CheckOptions{
+		WarnLag:        time.Duration(checkWarnLag) * time.Second,
+		MaxLag:         time.Duration(checkMaxLag) * time.Second,
+		RequireTLS:     requireReplicationTLS,
+		FormatDuration: formatDuration,
+	})
+	return checkResult{replica: r, state: int(state), message: message}
+}
+
+// Combine results so that critical outranks unknown, which outranks warning
+func worstState(results []checkResult) int {
+	states := make([]monitor.CheckState, len(results))
+	for i, res := range results {
+		states[i] = monitor.CheckState(res.state)
+	}
+	return int(monitor.WorstState(states...))
+}
+
+func checkIcon(state int) string {
+	switch state {
+	case checkOK:
+		return "✅"
+	case checkWarning:
+		return "⚠️ "
+	case checkCritical:
+		return "❌"
+	default:
+		return "❓"
+	}
+}
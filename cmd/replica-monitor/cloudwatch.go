@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// Cross-check lag against the CloudWatch ReplicaLag metric with -cloudwatch-lag,
+// alerting when the two differ by more than -cloudwatch-divergence
+var (
+	cloudWatchLag        bool
+	cloudWatchDivergence time.Duration
+)
+
+// ReplicaLag is published once a minute
+const cloudWatchInterval = time.Minute
+
+// The latest ReplicaLag datapoint of a replica
+type cloudWatchSample struct {
+	seconds  float64
+	at       time.Time
+	diverged bool // the last comparison exceeded -cloudwatch-divergence
+}
+
+var (
+	cloudWatchSamples   = make(map[*replica]*cloudWatchSample)
+	cloudWatchClients   = make(map[string]*cloudwatch.Client) // by region
+	cloudWatchLastCheck time.Time
+)
+
+// Fetch ReplicaLag for every RDS replica at most once a minute and compare it
+// with the lag SHOW REPLICA STATUS reported in this cycle
+func checkCloudWatchLag(replicas []*replica) {
+	if !cloudWatchLag || time.Since(cloudWatchLastCheck) < cloudWatchInterval {
+		return
+	}
+	cloudWatchLastCheck = time.Now()
+
+	seen := make(map[*replica]bool)
+	for _, r := range replicas {
+		id, region, ok := rdsInstanceOf(r)
+		if !ok {
+			continue
+		}
+		seen[r] = true
+		seconds, at, err := fetchReplicaLag(id, region, r.aurora)
+		if err != nil {
+			slog.Warn("Failed to fetch CloudWatch ReplicaLag", "replica", displayName(r), "instance", id, "err", err)
+			continue
+		}
+		if at.IsZero() {
+			continue
+		}
+		s := cloudWatchSamples[r]
+		if s == nil {
+			s = &cloudWatchSample{}
+			cloudWatchSamples[r] = s
+		}
+		s.seconds, s.at = seconds, at
+		compareCloudWatchLag(r, s)
+	}
+	for r := range cloudWatchSamples {
+		if !seen[r] {
+			delete(cloudWatchSamples, r)
+		}
+	}
+}
+
+// The most recent ReplicaLag datapoint from the last 5 minutes, or AuroraReplicaLag
+// (in milliseconds) for Aurora readers; at is zero when there is none
+func fetchReplicaLag(id, region string, aurora bool) (seconds float64, at time.Time, err error) {
+	if aurora {
+		millis, at, err := latestRDSMetric(id, region, "AuroraReplicaLag")
+		return millis / 1000, at, err
+	}
+	return latestRDSMetric(id, region, "ReplicaLag")
+}
+
+// The most recent one-minute maximum of an AWS/RDS metric for an instance from
+// the last 5 minutes; at is zero when there is none
+func latestRDSMetric(id, region, metric string) (value float64, at time.Time, err error) {
+	client, ok := cloudWatchClients[region]
+	if !ok {
+		cfg, err := loadAWSConfig(region)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		client = cloudwatch.NewFromConfig(cfg)
+		cloudWatchClients[region] = client
+	}
+
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	now := time.Now()
+	out, err := client.GetMetricStatistics(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/RDS"),
+		MetricName: aws.String(metric),
+		Dimensions: []types.Dimension{{Name: aws.String("DBInstanceIdentifier"), Value: aws.String(id)}},
+		StartTime:  aws.Time(now.Add(-5 * time.Minute)),
+		EndTime:    aws.Time(now),
+		Period:     aws.Int32(60),
+		Statistics: []types.Statistic{types.StatisticMaximum},
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	for _, dp := range out.Datapoints {
+		if t := aws.ToTime(dp.Timestamp); t.After(at) {
+			value, at = aws.ToFloat64(dp.Maximum), t
+		}
+	}
+	return value, at, nil
+}
+
+// Alert once when CloudWatch and SHOW REPLICA STATUS start to disagree, and log
+// when they agree again
+func compareCloudWatchLag(r *replica, s *cloudWatchSample) {
+	if !r.lagKnown {
+		return
+	}
+	diff := math.Abs(s.seconds - r.lagSeconds)
+	diverged := diff > cloudWatchDivergence.Seconds()
+	switch {
+	case diverged && !s.diverged:
+		sendAlert(r, "cloudwatch_lag_divergence", fmt.Sprintf("CloudWatch ReplicaLag is %s but SHOW REPLICA STATUS reports %s",
+			formatDuration(s.seconds), formatDuration(r.lagSeconds)))
+	case !diverged && s.diverged:
+		slog.Info("CloudWatch ReplicaLag agrees with SHOW REPLICA STATUS again", "replica", displayName(r), "lag_seconds", r.lagSeconds)
+	}
+	s.diverged = diverged
+}
+
+// Print the latest ReplicaLag under the replica's own lag
+func printCloudWatchLag(out io.Writer, r *replica) {
+	s := cloudWatchSamples[r]
+	if s == nil {
+		return
+	}
+	marker := "☁️ "
+	if s.diverged {
+		marker = "⚠️ "
+	}
+	fmt.Fprintf(out, "%s CloudWatch ReplicaLag: %s (as of %s)\n", marker, formatDuration(s.seconds), s.at.Local().Format("15:04"))
+}
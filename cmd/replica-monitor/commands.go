@@ -0,0 +1,674 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+	"replica-monitor/pkg/notify"
+)
+
+// A replica-monitor subcommand with its own flag set
+type command struct {
+	name    string
+	summary string
+	usage   string // arguments shown after the command name in help
+	// A new flag set binding the command's flags to the variables run reads,
+	// also used for help, completions, and man pages
+	flags func() *flag.FlagSet
+	run   func(fs *flag.FlagSet) error // given the flags once they are parsed
+}
+
+// A command's failure with the exit status it calls for: 2 for usage errors, or
+// a command's own, such as check's Nagios states. err, when set, is logged by
+// runCommand; without it the command has already said why.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// Exit with code once the command's deferred cleanup has run
+func exitWith(code int) error {
+	if code == 0 {
+		return nil
+	}
+	return &exitError{code: code}
+}
+
+// Print msg, when given, and the command's usage, for exit status 2
+func usageError(fs *flag.FlagSet, msg string) error {
+	if msg != "" {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	fs.Usage()
+	return exitWith(2)
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"watch", "Continuously monitor replicas and skip matched errors (default)", "[flags]", watchFlags, runWatch},
+		{"check", "Poll once and exit 0 (ok), 1 (warning), 2 (critical), or 3 (unknown)", "[-warn-lag <seconds>] [-max-lag <seconds>] [flags]", checkFlags, runCheck},
+		{"wait", "Poll until every replica's lag is at most -max-lag, exiting 0, or 1 on -timeout and 3 when replication stops", "[-max-lag <duration>] [-timeout <duration>] [flags]", waitFlags, runWait},
+		{"report", "Poll once and print the full report, including fleet and chain summaries", "[flags]", reportFlags, runReport},
+		{"skip", "Run mysql.rds_skip_repl_error on one replica", "[-name <replica>] [flags]", skipFlags, runSkip},
+		{"start-replica", "Start the replication threads on one replica", "[-name <replica>] [flags]", startReplicaFlags, runStartReplica},
+		{"export", "Export samples from a history file as CSV or JSON lines", "-history <file> [flags]", exportFlags, runExport},
+		{"serve", "Monitor without terminal output, answering only on -http and -grpc", "[flags]", serveFlags, runServe},
+		{"sidecar", "Serve in a Kubernetes pod, configured from REPLICA_MONITOR_* environment variables", "[flags]", sidecarFlags, runSidecar},
+		{"service", "Windows only: install, start, stop, or uninstall the monitor as a Windows service", "[-name <service>] install <command> [flags] | start | stop | status | uninstall", serviceFlags, runService},
+		{"operator", "Run a sidecar Deployment for every ReplicaMonitor resource in a Kubernetes cluster", "-image <image> [-namespace <namespace>] | -print-crd", operatorFlags, runOperator},
+		{"doctor", "Check connectivity, TLS, login, privileges, RDS procedures, clock skew, and AWS credentials", "[flags]", doctorFlags, runDoctor},
+		{"compare", "Compare lag between two time windows of a history file", "-history <file> -a <start..end> -b <start..end>", compareFlags, runCompare},
+		{"audit-verify", "Check that an -audit-log is unaltered and, given the public key, signed", "-audit-log <file> [-public-key <pem>]", auditVerifyFlags, runAuditVerify},
+		{"self-update", "Replace this binary with the latest GitHub release, after verifying it", "[-check] [-version <tag>] [-public-key <pem>] [-yes]", selfUpdateFlags, runSelfUpdate},
+		{"version", "Print the version of this build", "", versionFlags, runVersion},
+		{"completion", "Print a shell completion script", "bash | zsh | fish", completionFlags, runCompletion},
+		{"docs", "Write a man page for replica-monitor and each of its commands", "[-dir <directory>]", docsFlags, runDocs},
+	}
+}
+
+// Run the command named by the first argument and return the process's exit
+// status, once the command has cleaned up. Plain flags without a command run
+// watch, as before commands existed.
+func runCommand(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return 0
+	}
+	if strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "-help" && args[0] != "--help" {
+		return runParsed(findCommand("watch"), args)
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		if len(args) > 1 {
+			if cmd := findCommand(args[1]); cmd != nil {
+				cmd.flags().Usage()
+				return 0
+			}
+			fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[1])
+			printUsage(os.Stderr)
+			return 2
+		}
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return 2
+	}
+	return runParsed(cmd, args[1:])
+}
+
+// Parse a command's arguments into a new flag set and run it with them
+func runParsed(cmd *command, args []string) int {
+	fs := cmd.flags()
+	fs.Parse(args)
+	return exitStatus(cmd.run(fs))
+}
+
+// The exit status for a command's result, logging a failure the command has
+// not reported itself
+func exitStatus(err error) int {
+	var exit *exitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exit):
+		if exit.err != nil {
+			slog.Error("Failed", "err", err)
+		}
+		return exit.code
+	}
+	slog.Error("Failed", "err", err)
+	return 1
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: replica-monitor <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'replica-monitor help <command>' for a command's flags.")
+	fmt.Fprintln(w, "Example: replica-monitor watch -host mydb.example.com -user admin -password mypass")
+}
+
+// New flag set for a command whose -h output shows its usage line and flags
+func newCommandFlags(name string) *flag.FlagSet {
+	cmd := findCommand(name)
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: replica-monitor %s %s\n\n%s\n\nFlags:\n", cmd.name, cmd.usage, cmd.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// Service name and display name given to the service command
+var (
+	serviceName        string
+	serviceDisplayName string
+)
+
+// Flags of service, defined on every platform so that help, completions, and
+// man pages show them everywhere
+func serviceFlags() *flag.FlagSet {
+	fs := newCommandFlags("service")
+	fs.StringVar(&serviceName, "name", "replica-monitor", "Name of the Windows service, and of its event log source")
+	fs.StringVar(&serviceDisplayName, "display-name", "Replica Monitor", "Name shown in the Services console, for install")
+	return fs
+}
+
+// Flags choosing which replicas to connect to and how their status is printed,
+// shared by every command that polls
+func addConnectionFlags(fs *flag.FlagSet) {
+	fs.StringVar(&host, "host", "", "MySQL host (required unless -discover-rds, -aurora-cluster, or -config is set)")
+	fs.StringVar(&user, "user", "", "MySQL username (required)")
+	fs.StringVar(&password, "password", "", "MySQL password (required unless -password-file or -auth iam)")
+	fs.StringVar(&passwordFile, "password-file", "", "Read the password from this file, e.g. a mounted Kubernetes secret, and reconnect with the new one when it changes")
+	fs.StringVar(&actionUser, "action-user", "", "Log in as this user, instead of -user, only to skip errors or start replication, so -user needs no more than to read replica status")
+	fs.StringVar(&actionPassword, "action-password", "", "Password of -action-user")
+	fs.StringVar(&actionPasswordFile, "action-password-file", "", "Read the password of -action-user from this file, afresh for every action")
+	fs.Func("auth", "How to log in: password (-password, the default) or iam (an RDS IAM authentication token for -user, over TLS)", func(v string) error {
+		if !slices.Contains(authModes, v) {
+			return fmt.Errorf("expected one of %s", strings.Join(authModes, ", "))
+		}
+		authMode = v
+		return nil
+	})
+	fs.BoolVar(&allowCleartext, "allow-cleartext-passwords", false, "Send -password in clear text when the account uses mysql_clear_password, e.g. PAM; use only over TLS")
+	fs.StringVar(&serverPublicKey, "server-public-key", "", "PEM file with the server's RSA public key, for caching_sha2_password without TLS instead of asking the server for it")
+	fs.IntVar(&port, "port", 3306, "MySQL port (default: 3306)")
+	fs.Func("db-tls", "TLS to the replicas: auto (PGSSLMODE for PostgreSQL, required under -auth iam, otherwise off; the default), off, preferred, required (verifying the certificate), or skip-verify", func(v string) error {
+		if !slices.Contains(dbTLSModes, v) {
+			return fmt.Errorf("expected one of %s", strings.Join(dbTLSModes, ", "))
+		}
+		dbTLS = v
+		return nil
+	})
+	fs.StringVar(&dbTLSCA, "db-tls-ca", "", "PEM bundle of CAs to verify the replicas' certificates with under -db-tls required, e.g. the RDS global bundle (default: the system's)")
+	fs.Func("tls-min-version", "Oldest TLS version accepted for database connections and the -http and -grpc servers: 1.0, 1.1, 1.2 (the default), or 1.3", setTLSMinVersion)
+	fs.Func("tls-ciphers", "Comma-separated TLS 1.2 cipher suites allowed, by IANA name, e.g. TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 (default: Go's)", setTLSCiphers)
+	fs.BoolVar(&tlsFIPS, "tls-fips", false, "Allow only FIPS 140-3 approved TLS: 1.2 or later, ECDHE with AES-GCM, and P-256 or P-384")
+	fs.Func("engine", fmt.Sprintf("Database engine of the replicas: auto (detected from the server handshake, the default), %s", strings.Join(monitor.Engines(), ", ")), func(v string) error {
+		if _, ok := monitor.LookupEngine(v); !ok && v != "auto" {
+			return fmt.Errorf("unknown engine %q", v)
+		}
+		engineName = v
+		return nil
+	})
+	fs.BoolVar(&simulate, "simulate", false, "Monitor simulated replicas with made-up lag, errors, and recoveries instead of connecting to a database")
+	fs.IntVar(&simulateReplicas, "simulate-replicas", 3, "Number of replicas -simulate makes up")
+	fs.Float64Var(&simulateSpeed, "simulate-speed", 1, "Run -simulate this many times faster than real time")
+	fs.BoolVar(&readOnly, "read-only", false, "Send only read statements to the database, refusing skips, CALLs, locks, and anything else at the driver; auto-skip is off")
+	fs.Func("privilege-check", "On connect, report missing and excessive grants of -user: warn (the default), strict (also refuse replicas missing any), or off", func(v string) error {
+		if !slices.Contains(privilegeChecks, v) {
+			return fmt.Errorf("expected one of %s", strings.Join(privilegeChecks, ", "))
+		}
+		privilegeCheck = v
+		return nil
+	})
+	fs.DurationVar(&queryTimeout, "query-timeout", 10*time.Second, "Give up on a connection attempt or statement after this long (0 waits forever)")
+	fs.StringVar(&configPath, "config", "", "JSON config file listing replicas and their labels")
+	fs.Var(&labelFlags, "label", "Attach this key=value label to every monitored replica (repeatable)")
+	fs.BoolVar(&discoverRDS, "discover-rds", false, "Find read replicas with the RDS API instead of using -host")
+	fs.Var(&discoverTags, "tag", "Only discover replicas with this key=value tag (repeatable)")
+	fs.StringVar(&sourceInstance, "source-instance", "", "Only discover replicas of this source DB instance identifier")
+	fs.StringVar(&awsProfile, "aws-profile", "", "AWS shared config profile for the RDS, CloudWatch, and other AWS integrations")
+	fs.StringVar(&awsRegion, "aws-region", "", "AWS region for discovery and the other AWS integrations, instead of the SDK default")
+	fs.StringVar(&awsRoleARN, "aws-role-arn", "", "Assume this IAM role for every AWS call, e.g. to reach replicas and metrics in another account")
+	fs.StringVar(&awsExternalID, "aws-external-id", "", "External ID to pass when assuming -aws-role-arn")
+	fs.StringVar(&auroraCluster, "aurora-cluster", "", "Monitor every reader instance of this Aurora MySQL cluster")
+	fs.BoolVar(&topology, "topology", false, "Treat -host as the source and discover its replicas, including chained ones")
+	fs.BoolVar(&assumeYes, "yes", false, "Answer yes to confirmation prompts")
+	fs.Var(&fieldList, "fields", "Comma-separated SHOW REPLICA STATUS columns to print instead of the key fields")
+	fs.BoolVar(&wide, "wide", false, "Print every SHOW REPLICA STATUS column, grouped by category")
+	fs.BoolVar(&wide, "all", false, "Same as -wide")
+	fs.BoolVar(&diffMode, "diff", false, "After the first full report, print only the fields that changed since the previous poll")
+	fs.StringVar(&colorMode, "color", "auto", "Color report lines by severity: auto (terminal without NO_COLOR), always, or never")
+	fs.BoolVar(&noEmoji, "no-emoji", false, "Strip emoji from the report, e.g. for log files and ticketing systems")
+	fs.Func("log-format", "Operational log encoding on stderr: text (key=value) or json, for centralized logging", func(v string) error {
+		if !slices.Contains(logFormats, v) {
+			return fmt.Errorf("expected one of %s", strings.Join(logFormats, ", "))
+		}
+		logFormat = v
+		return nil
+	})
+	fs.Func("duration-style", "How durations and ETAs are printed: verbose (3h 42m 10s, the default), compact (3h42m), or iso (PT3H42M10S)", func(v string) error {
+		if !slices.Contains(durationStyles, v) {
+			return fmt.Errorf("expected one of %s", strings.Join(durationStyles, ", "))
+		}
+		durationStyle = v
+		return nil
+	})
+	fs.Func("log-level", "Minimum level of operational logs: debug, info (the default), warn, or error", func(v string) error {
+		if !slices.Contains(logLevels, v) {
+			return fmt.Errorf("expected one of %s", strings.Join(logLevels, ", "))
+		}
+		return logLevel.UnmarshalText([]byte(v))
+	})
+	fs.StringVar(&logFile, "log-file", "", "Write operational logs to this file instead of standard error, rotating and gzipping it")
+	fs.IntVar(&logMaxSizeMB, "log-max-size", 100, "Rotate -log-file when it reaches this many megabytes")
+	fs.IntVar(&logMaxAgeDays, "log-max-age", 7, "Delete rotated -log-file files older than this many days (0 keeps them)")
+	fs.StringVar(&syslogAddr, "syslog", "", "Also send operational logs and alerts to syslog (RFC 5424): local, udp://host:port, or tcp://host:port")
+	fs.StringVar(&journaldMode, "journald", "auto", "Log to journald with structured fields: auto (when systemd connected standard error to the journal), always, or never")
+	fs.StringVar(&eventLogSource, "eventlog", "", "Windows only: also write alerts and errors to the Application event log under this source, e.g. replica-monitor")
+	fs.IntVar(&sparklineWidth, "sparkline", 30, "Show a sparkline of this many recent lag samples under the summary (0 disables)")
+	fs.BoolFunc("v", "Verbose: also print every raw SHOW REPLICA STATUS field", func(string) error {
+		verbosity = max(verbosity, 1)
+		return nil
+	})
+	fs.BoolFunc("vv", "Debug: also log every SQL statement, its timing, and connection reuse decisions", func(string) error {
+		verbosity = 2
+		return nil
+	})
+}
+
+// Flags for the tamper-evident record of interventions, shared by every
+// command that can change a replica
+func addAuditFlags(fs *flag.FlagSet) {
+	fs.StringVar(&auditLog, "audit-log", "", "Append every skip, start, pause, and silence to this hash-chained JSON-lines file, checked by audit-verify")
+	fs.StringVar(&auditKey, "audit-key", "", "PEM PKCS #8 Ed25519 private key to sign every -audit-log entry with")
+}
+
+// Flags for the long-running monitoring loop shared by watch and serve
+func addMonitorFlags(fs *flag.FlagSet) {
+	addAuditFlags(fs)
+	fs.DurationVar(&interval, "interval", 5*time.Second, "Time between polls")
+	fs.DurationVar(&jitter, "jitter", 0, "Add a random delay of up to this much to every interval")
+	fs.DurationVar(&runDuration, "duration", 0, "Stop after running this long, e.g. 8h")
+	fs.StringVar(&runUntil, "until", "", "Stop at this local time, e.g. \"2024-06-01 06:00\"")
+	fs.BoolVar(&daemonMode, "daemon", false, "Run in the background, detached from the terminal, writing the report and logs to -log-file")
+	fs.StringVar(&pidFile, "pid-file", "", "Write the process ID to this file while running, and refuse to start while another monitor holds it")
+	fs.DurationVar(&shutdownTimeout, "shutdown-timeout", 0, "Exit anyway if stopping after SIGTERM takes longer than this, e.g. within Kubernetes' grace period (0 waits)")
+	fs.BoolVar(&rdsEvents, "rds-events", false, "Print RDS events (failovers, reboots, parameter changes, storage) for the monitored instances and -source-instance, and name them as the probable cause when a replica falls behind")
+	fs.BoolVar(&cloudWatchLag, "cloudwatch-lag", false, "Show the CloudWatch ReplicaLag metric with each RDS replica's lag and alert when the two disagree")
+	fs.DurationVar(&cloudWatchDivergence, "cloudwatch-divergence", time.Minute, "With -cloudwatch-lag, alert when CloudWatch and SHOW REPLICA STATUS differ by more than this")
+	fs.BoolVar(&enhancedMonitoring, "enhanced-monitoring", false, "While an RDS replica is above -lag-threshold, show its Enhanced Monitoring CPU, disk, and swap metrics")
+	fs.BoolVar(&performanceInsights, "performance-insights", false, "While an RDS replica is above -lag-threshold, show its top SQL and wait events from Performance Insights")
+	fs.BoolVar(&rdsMetadata, "rds-metadata", false, "Show each RDS replica's instance class, storage, Multi-AZ setting, and engine version in reports and alerts")
+	fs.StringVar(&eventBridgeBus, "eventbridge-bus", "", "Put state changes, skips, restarts, and alerts on this EventBridge bus (name or ARN) for AWS automation")
+	fs.StringVar(&kinesisStream, "kinesis-stream", "", "Write every history sample and journal event as JSON to this Kinesis data stream (name or ARN)")
+	fs.StringVar(&firehoseStream, "firehose-stream", "", "Write every history sample and journal event as JSON lines to this Firehose delivery stream, e.g. to land them in S3")
+	fs.BoolVar(&storageRisk, "storage-risk", false, "Show each RDS replica's CloudWatch FreeStorageSpace and alert when relay logs are about to fill it")
+	addLagSourceFlags(fs)
+	fs.BoolVar(&requireReplicationTLS, "require-replication-tls", false, "Alert when a replica's stream from its source is unencrypted (Source_SSL_Allowed not Yes), and show its TLS settings in reports")
+	fs.BoolVar(&notifyOnStop, "notify-on-stop", false, "Send the run summary to -alert-webhook and -notify when the monitor stops")
+	fs.DurationVar(&minInterval, "min-interval", 0, "Poll this often while lag is changing or a replica has a problem, e.g. 2s")
+	fs.DurationVar(&maxInterval, "max-interval", 0, "Poll this often once every replica has been caught up and healthy for 5 minutes, e.g. 60s")
+	fs.Func("skip-lock", "Let only one monitor skip errors on each replica, automatically or from the TUI: none (the default), file (a lock file, for monitors on one host), or mysql (GET_LOCK on the replica)", func(v string) error {
+		if !slices.Contains(skipLockModes, v) {
+			return fmt.Errorf("expected one of %s", strings.Join(skipLockModes, ", "))
+		}
+		skipLockMode = v
+		return nil
+	})
+	fs.BoolVar(&throttle, "throttle", false, "Poll less often, up to 8x -interval, while a replica looks overloaded")
+	fs.DurationVar(&throttleRTT, "throttle-rtt", time.Second, "With -throttle, a SHOW REPLICA STATUS round trip slower than this counts as overloaded")
+	fs.IntVar(&throttleThreads, "throttle-threads", 64, "With -throttle, more Threads_running than this counts as overloaded")
+	fs.IntVar(&timelineSize, "timeline-size", 200, "Skips and alerts kept in memory for the dashboard timeline")
+	fs.IntVar(&etaRateWindow, "eta-window", 12, "Recent catch-up rates kept in memory for the ETA band")
+	fs.StringVar(&history, "history", "", "Append every sample to this JSON-lines history file")
+	fs.BoolVar(&prometheusMetrics, "prometheus", false, "Serve each replica's latest sample as Prometheus metrics at /metrics on -http")
+	fs.StringVar(&statsdAddr, "statsd", "", "Send every sample as gauges to this StatsD host:port over UDP")
+	fs.StringVar(&statsdPrefix, "statsd-prefix", "replica_monitor", "Metric name prefix for -statsd")
+	fs.StringVar(&cloudWatchNamespace, "cloudwatch-namespace", "", "Publish every sample as CloudWatch custom metrics in this namespace")
+	fs.StringVar(&influxURL, "influx-url", "", "Write every sample to this InfluxDB write URL, e.g. http://influx:8086/api/v2/write?org=ops&bucket=replicas")
+	fs.StringVar(&influxToken, "influx-token", "", "InfluxDB API token for -influx-url")
+	fs.StringVar(&eventsFile, "events-file", "", "Append state changes, threshold crossings, skips, and alert deliveries to this JSON-lines file")
+	fs.DurationVar(&discoverInterval, "discover-interval", 5*time.Minute, "How often to re-scan RDS for added or removed replicas")
+	fs.StringVar(&alertWebhook, "alert-webhook", "", "POST a JSON alert to this URL when an error is matched or a skip fails")
+	fs.Func("notify", fmt.Sprintf("Also send alerts to this URL, through the notifier registered for its scheme (%s); repeatable", strings.Join(notify.Schemes(), ", ")), addNotifyTarget)
+	fs.Func("notify-file", "Also send alerts to the -notify URL held in this file, e.g. a mounted Kubernetes secret; repeatable", addNotifyFile)
+	fs.DurationVar(&lagThreshold, "lag-threshold", 5*time.Minute, "Lag above which a replica counts as behind in the fleet summary")
+	fs.BoolVar(&leaderElection, "leader-election", false, "Coordinate with other monitors so only the lock holder skips errors and sends alerts")
+	fs.StringVar(&leaderLockHost, "leader-lock-host", "", "MySQL host holding the leader lock (default: -host)")
+	fs.StringVar(&leaderLockName, "leader-lock-name", "replica-monitor-leader", "Name of the GET_LOCK advisory lock used for leader election")
+	fs.StringVar(&sourceHost, "source-host", "", "Also connect to the replication source to detect source-side problems")
+	fs.IntVar(&sourcePort, "source-port", 3306, "MySQL port of -source-host")
+	fs.StringVar(&httpAddr, "http", "", "Serve the latest status as JSON on this address, e.g. :8080")
+	fs.StringVar(&grpcAddr, "grpc", "", "Serve the ReplicaMonitor gRPC API on this address, e.g. :9090")
+	fs.StringVar(&tlsCert, "tls-cert", "", "PEM certificate chain to serve -http and -grpc over TLS with")
+	fs.StringVar(&tlsKey, "tls-key", "", "PEM private key of -tls-cert")
+	fs.StringVar(&tlsClientCA, "tls-client-ca", "", "Require -http and -grpc clients to present a certificate signed by a CA in this PEM bundle (needs -tls-cert)")
+	fs.StringVar(&apiTokenFile, "api-token-file", "", "Require -http and -grpc clients to send one of the API tokens in this file, one per line, as a bearer token or X-API-Key")
+	fs.StringVar(&apiHtpasswdFile, "api-htpasswd-file", "", "Accept basic auth for the users in this htpasswd file of bcrypt hashes (htpasswd -B)")
+	fs.StringVar(&oidcIssuer, "oidc-issuer", "", "Sign dashboard users in with this OpenID Connect provider, e.g. https://accounts.google.com, and accept its ID tokens as bearer tokens")
+	fs.StringVar(&oidcClientID, "oidc-client-id", "", "Client ID registered with -oidc-issuer")
+	fs.StringVar(&oidcClientSecretFile, "oidc-client-secret-file", "", "File holding the client secret of -oidc-client-id (none for a public client)")
+	fs.StringVar(&oidcRedirectURL, "oidc-redirect-url", "", "URL of the dashboard's sign-in callback, as registered with -oidc-issuer, e.g. https://monitor.example.com/oauth2/callback")
+	fs.Func("oidc-allow", "Only let in this verified email address, or anyone at this @domain (repeatable; default: anyone -oidc-issuer signs in)", func(v string) error {
+		if !strings.Contains(v, "@") {
+			return errors.New("expected an email address or @domain")
+		}
+		oidcAllow = append(oidcAllow, v)
+		return nil
+	})
+	fs.StringVar(&otlpEndpoint, "otlp-endpoint", "", "Export an OpenTelemetry trace of every cycle over OTLP/gRPC to host:port (TLS) or http://host:port")
+	fs.IntVar(&alertQueueSize, "alert-queue", 100, "Alerts waiting for delivery before new ones are dropped")
+	fs.IntVar(&alertWorkers, "alert-workers", 2, "Alerts delivered concurrently")
+	fs.StringVar(&lokiURL, "loki-url", "", "Push operational logs and alerts to this Grafana Loki base URL, e.g. http://loki:3100")
+	fs.BoolVar(&lokiPolls, "loki-polls", false, "With -loki-url, also push a line for every poll of every replica")
+	fs.StringVar(&zabbixOutput, "zabbix-output", "", "Append zabbix_sender lines to this file or FIFO after every cycle")
+	fs.StringVar(&zabbixHost, "zabbix-host", "-", "Host name used in Zabbix sender lines (\"-\" uses the agent's Hostname)")
+}
+
+func watchFlags() *flag.FlagSet {
+	fs := newCommandFlags("watch")
+	addConnectionFlags(fs)
+	addMonitorFlags(fs)
+	fs.BoolVar(&tuiMode, "tui", false, "Show an interactive terminal UI instead of scrolling output")
+	fs.BoolVar(&quiet, "quiet", false, "Print only state changes, threshold crossings, alerts, and skips instead of the per-poll report")
+	fs.StringVar(&outputFormat, "format", "text", "Report format: text, or line for one aligned line per replica per poll")
+	fs.BoolVar(&refreshMode, "refresh", false, "Clear the screen and redraw the report every poll, like top, instead of scrolling")
+	fs.BoolVar(&bellEnabled, "bell", false, "Ring the terminal bell when replication stops, an error is matched, or a replica catches up")
+	fs.BoolVar(&flashEnabled, "flash", false, "Briefly flash the terminal on the same transitions as -bell")
+	return fs
+}
+
+func runWatch(fs *flag.FlagSet) error {
+	needSkip = !readOnly
+	if err := setupOutput(); err != nil {
+		return err
+	}
+	if outputFormat != "text" && outputFormat != "line" {
+		return usageError(fs, "")
+	}
+	return runMonitor(fs, false)
+}
+
+func serveFlags() *flag.FlagSet {
+	fs := newCommandFlags("serve")
+	addConnectionFlags(fs)
+	addMonitorFlags(fs)
+	return fs
+}
+
+func runServe(fs *flag.FlagSet) error {
+	needSkip = !readOnly
+	if err := setupOutput(); err != nil {
+		return err
+	}
+	return runMonitor(fs, true)
+}
+
+// Poll every replica once without skipping anything, printing the report to out
+func pollTargets(fs *flag.FlagSet, out io.Writer) ([]*replica, error) {
+	replicas, _, err := connectTargets(fs, out)
+	if err != nil {
+		return nil, err
+	}
+	if sourceHost != "" {
+		conn, err := connectReplica("source", sourceHost, sourcePort)
+		if err != nil {
+			for _, r := range replicas {
+				r.close()
+			}
+			return nil, fmt.Errorf("connect to source %s:%d: %w", sourceHost, sourcePort, err)
+		}
+		sourceMonitor = &sourceHealth{conn: conn}
+	}
+	pollOnce(context.Background(), out, replicas, false)
+	return replicas, nil
+}
+
+func reportFlags() *flag.FlagSet {
+	fs := newCommandFlags("report")
+	addConnectionFlags(fs)
+	fs.DurationVar(&lagThreshold, "lag-threshold", 5*time.Minute, "Lag above which a replica counts as behind in the fleet summary")
+	fs.StringVar(&sourceHost, "source-host", "", "Also report on the replication source")
+	fs.IntVar(&sourcePort, "source-port", 3306, "MySQL port of -source-host")
+	fs.StringVar(&outputFormat, "format", "text", "Report format: text, or line for one aligned line per replica per poll")
+	return fs
+}
+
+func runReport(fs *flag.FlagSet) error {
+	if err := setupOutput(); err != nil {
+		return err
+	}
+	if outputFormat != "text" && outputFormat != "line" {
+		return usageError(fs, "")
+	}
+
+	replicas, err := pollTargets(fs, stdout)
+	if err != nil {
+		return startupError(err)
+	}
+	for _, r := range replicas {
+		r.close()
+	}
+	return nil
+}
+
+// The error a command returns when connecting to its replicas failed: exit
+// status 2 when connectTargets printed the usage, as for any usage error
+func startupError(err error) error {
+	if errors.Is(err, errNothingToMonitor) {
+		return exitWith(2)
+	}
+	return fmt.Errorf("startup: %w", err)
+}
+
+// Connect to the replicas and pick the one named by -name, or the only one
+func chooseReplica(fs *flag.FlagSet, name string) (*replica, []*replica, error) {
+	replicas, _, err := connectTargets(fs, stdout)
+	if err != nil {
+		return nil, nil, startupError(err)
+	}
+	for _, r := range replicas {
+		if (name == "" && len(replicas) == 1) || displayName(r) == name || r.host == name {
+			return r, replicas, nil
+		}
+	}
+	for _, r := range replicas {
+		r.close()
+	}
+	if name == "" {
+		return nil, nil, fmt.Errorf("%d replicas matched; choose one with -name", len(replicas))
+	}
+	return nil, nil, fmt.Errorf("no monitored replica is named %s", name)
+}
+
+// Replica named by -name for skip and start-replica
+var replicaName string
+
+func skipFlags() *flag.FlagSet {
+	fs := newCommandFlags("skip")
+	addConnectionFlags(fs)
+	addAuditFlags(fs)
+	fs.StringVar(&replicaName, "name", "", "Name or host of the replica to skip on, when several are configured")
+	return fs
+}
+
+func runSkip(fs *flag.FlagSet) error {
+	needSkip = true
+	if err := setupOutput(); err != nil {
+		return err
+	}
+	if readOnly {
+		fmt.Fprintf(os.Stderr, "%s changes the replica and cannot run with -read-only\n", fs.Name())
+		return exitWith(2)
+	}
+
+	if err := openAudit(); err != nil {
+		return fmt.Errorf("open audit log %s: %w", auditLog, err)
+	}
+	defer closeAudit()
+	r, replicas, err := chooseReplica(fs, replicaName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, r := range replicas {
+			r.close()
+		}
+	}()
+
+	showReplicaStatus(context.Background(), stdout, r)
+	if !assumeYes && !confirm(fmt.Sprintf("Skip the current replication error on %s?", displayName(r))) {
+		return nil
+	}
+	// skipReplError reports the failure
+	if err := r.skipReplError(stdout, "cli"); err != nil {
+		return exitWith(1)
+	}
+	return nil
+}
+
+func startReplicaFlags() *flag.FlagSet {
+	fs := newCommandFlags("start-replica")
+	addConnectionFlags(fs)
+	addAuditFlags(fs)
+	fs.StringVar(&replicaName, "name", "", "Name or host of the replica to start, when several are configured")
+	return fs
+}
+
+func runStartReplica(fs *flag.FlagSet) error {
+	needStart = true
+	if err := setupOutput(); err != nil {
+		return err
+	}
+	if readOnly {
+		fmt.Fprintf(os.Stderr, "%s changes the replica and cannot run with -read-only\n", fs.Name())
+		return exitWith(2)
+	}
+
+	if err := openAudit(); err != nil {
+		return fmt.Errorf("open audit log %s: %w", auditLog, err)
+	}
+	defer closeAudit()
+	r, replicas, err := chooseReplica(fs, replicaName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, r := range replicas {
+			r.close()
+		}
+	}()
+
+	if !assumeYes && !confirm(fmt.Sprintf("Start replication on %s?", displayName(r))) {
+		return nil
+	}
+	// startReplication reports the failure
+	if err := r.startReplication(stdout, "cli"); err != nil {
+		return exitWith(1)
+	}
+	return nil
+}
+
+// Flags of export
+var (
+	exportHistory string
+	exportHost    string
+	exportFrom    string
+	exportTo      string
+	exportFormat  string
+	exportOutput  string
+)
+
+func exportFlags() *flag.FlagSet {
+	fs := newCommandFlags("export")
+	fs.StringVar(&exportHistory, "history", "", "History file written by -history (required)")
+	fs.StringVar(&exportHost, "host", "", "Only export samples from this host")
+	fs.StringVar(&exportFrom, "from", "", "Only export samples at or after this time")
+	fs.StringVar(&exportTo, "to", "", "Only export samples before this time")
+	fs.StringVar(&exportFormat, "format", "csv", "Output format: csv or jsonl")
+	fs.StringVar(&exportOutput, "output", "", "Write to this file instead of standard output")
+	return fs
+}
+
+func runExport(fs *flag.FlagSet) error {
+	if exportHistory == "" || (exportFormat != "csv" && exportFormat != "jsonl") {
+		return usageError(fs, "")
+	}
+	var start, end time.Time
+	var err error
+	if exportFrom != "" {
+		if start, err = parseWindowTime(exportFrom); err != nil {
+			return usageError(fs, fmt.Sprintf("Invalid -from: %v", err))
+		}
+	}
+	if exportTo != "" {
+		if end, err = parseWindowTime(exportTo); err != nil {
+			return usageError(fs, fmt.Sprintf("Invalid -to: %v", err))
+		}
+	}
+
+	out := io.Writer(os.Stdout)
+	if exportOutput != "" {
+		f, err := os.Create(exportOutput)
+		if err != nil {
+			return fmt.Errorf("create export file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	csvOut := csv.NewWriter(out)
+	jsonOut := json.NewEncoder(out)
+	if exportFormat == "csv" {
+		csvOut.Write([]string{"time", "host", "seconds_behind", "io_running", "sql_running", "error_matched", "last_sql_error", "labels"})
+	}
+	err = scanHistory(exportHistory, func(s historySample) {
+		if (exportHost != "" && s.Host != exportHost) || (!start.IsZero() && s.Time.Before(start)) || (!end.IsZero() && !s.Time.Before(end)) {
+			return
+		}
+		if exportFormat == "jsonl" {
+			jsonOut.Encode(s)
+			return
+		}
+		lag := ""
+		if s.SecondsBehind != nil {
+			lag = strconv.Itoa(*s.SecondsBehind)
+		}
+		csvOut.Write([]string{s.Time.Format(time.RFC3339), s.Host, lag, s.IORunning, s.SQLRunning,
+			strconv.FormatBool(s.ErrorMatched), s.LastSQLError, formatLabels(s.Labels)})
+	})
+	csvOut.Flush()
+	if err != nil {
+		return fmt.Errorf("read history %s: %w", exportHistory, err)
+	}
+	if err := csvOut.Error(); err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Half-open time window [start, end) parsed from "start..end"
+type timeWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+// Lag statistics for the samples that fell inside one window
+type windowStats struct {
+	samples      int
+	nullSamples  int
+	minLag       int
+	maxLag       int
+	avgLag       float64
+	p95Lag       int
+	ratePerSec   float64 // least-squares slope of lag over time, negative means catching up
+	ioStoppedPct float64
+	sqlStopped   float64
+	errorMatches int
+}
+
+var windowLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// Flags of compare
+var (
+	compareHistory string
+	compareA       string
+	compareB       string
+	compareHost    string
+)
+
+func compareFlags() *flag.FlagSet {
+	fs := newCommandFlags("compare")
+	fs.StringVar(&compareHistory, "history", "", "History file written by -history (required)")
+	fs.StringVar(&compareA, "a", "", "Baseline window, e.g. \"2024-05-01..2024-05-02\" (required)")
+	fs.StringVar(&compareB, "b", "", "Comparison window, e.g. \"2024-05-08..2024-05-09\" (required)")
+	fs.StringVar(&compareHost, "host", "", "Only compare samples from this host")
+	return fs
+}
+
+func runCompare(fs *flag.FlagSet) error {
+	if compareHistory == "" || compareA == "" || compareB == "" {
+		return usageError(fs, "-history, -a, and -b are required")
+	}
+
+	windowA, err := parseWindow(compareA)
+	if err != nil {
+		return fmt.Errorf("invalid window A: %w", err)
+	}
+	windowB, err := parseWindow(compareB)
+	if err != nil {
+		return fmt.Errorf("invalid window B: %w", err)
+	}
+
+	samples, err := loadHistory(compareHistory)
+	if err != nil {
+		return fmt.Errorf("load history %s: %w", compareHistory, err)
+	}
+
+	statsA := computeWindowStats(samples, windowA, compareHost)
+	statsB := computeWindowStats(samples, windowB, compareHost)
+	printComparison(windowA, windowB, statsA, statsB)
+	return nil
+}
+
+// Parse "start..end"; the end is exclusive, so "2024-05-01..2024-05-02" covers one day
+func parseWindow(spec string) (timeWindow, error) {
+	parts := strings.SplitN(spec, "..", 2)
+	if len(parts) != 2 {
+		return timeWindow{}, fmt.Errorf("expected <start>..<end>, got %q", spec)
+	}
+	start, err := parseWindowTime(parts[0])
+	if err != nil {
+		return timeWindow{}, err
+	}
+	end, err := parseWindowTime(parts[1])
+	if err != nil {
+		return timeWindow{}, err
+	}
+	if !end.After(start) {
+		return timeWindow{}, fmt.Errorf("end of %q is not after its start", spec)
+	}
+	return timeWindow{start: start, end: end}, nil
+}
+
+func parseWindowTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range windowLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", s)
+}
+
+func computeWindowStats(samples []historySample, w timeWindow, hostFilter string) windowStats {
+	var stats windowStats
+	var lags []int
+	var ioStopped, sqlStopped int
+	var sumT, sumL, sumTT, sumTL float64
+
+	for _, s := range samples {
+		if s.Time.Before(w.start) || !s.Time.Before(w.end) {
+			continue
+		}
+		if hostFilter != "" && s.Host != hostFilter {
+			continue
+		}
+		stats.samples++
+		if s.IORunning != "" && s.IORunning != "Yes" {
+			ioStopped++
+		}
+		if s.SQLRunning != "" && s.SQLRunning != "Yes" {
+			sqlStopped++
+		}
+		if s.ErrorMatched {
+			stats.errorMatches++
+		}
+		if s.SecondsBehind == nil {
+			stats.nullSamples++
+			continue
+		}
+
+		lag := *s.SecondsBehind
+		lags = append(lags, lag)
+		t := s.Time.Sub(w.start).Seconds()
+		sumT += t
+		sumL += float64(lag)
+		sumTT += t * t
+		sumTL += t * float64(lag)
+	}
+
+	if stats.samples > 0 {
+		stats.ioStoppedPct = 100 * float64(ioStopped) / float64(stats.samples)
+		stats.sqlStopped = 100 * float64(sqlStopped) / float64(stats.samples)
+	}
+	if len(lags) == 0 {
+		return stats
+	}
+
+	sort.Ints(lags)
+	n := float64(len(lags))
+	stats.minLag = lags[0]
+	stats.maxLag = lags[len(lags)-1]
+	stats.avgLag = sumL / n
+	stats.p95Lag = lags[int(math.Ceil(0.95*n))-1]
+	if denom := n*sumTT - sumT*sumT; denom != 0 {
+		stats.ratePerSec = (n*sumTL - sumT*sumL) / denom
+	}
+	return stats
+}
+
+func printComparison(a, b timeWindow, sa, sb windowStats) {
+	fmt.Printf("Window A: %s → %s\n", a.start.Format("2006-01-02 15:04:05"), a.end.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Window B: %s → %s\n", b.start.Format("2006-01-02 15:04:05"), b.end.Format("2006-01-02 15:04:05"))
+	fmt.Println(strings.Repeat("=", 70))
+
+	if sa.samples == 0 || sb.samples == 0 {
+		fmt.Printf("Not enough data: window A has %d samples, window B has %d samples\n", sa.samples, sb.samples)
+		return
+	}
+
+	fmt.Printf("%-22s %14s %14s %14s\n", "", "A", "B", "Change")
+	fmt.Printf("%-22s %14d %14d %14s\n", "Samples", sa.samples, sb.samples, "")
+	fmt.Printf("%-22s %14d %14d %14s\n", "NULL lag samples", sa.nullSamples, sb.nullSamples, "")
+	printLagRow("Min lag (s)", float64(sa.minLag), float64(sb.minLag))
+	printLagRow("Avg lag (s)", sa.avgLag, sb.avgLag)
+	printLagRow("P95 lag (s)", float64(sa.p95Lag), float64(sb.p95Lag))
+	printLagRow("Max lag (s)", float64(sa.maxLag), float64(sb.maxLag))
+	fmt.Printf("%-22s %14.2f %14.2f %14s\n", "Lag trend (s/s)", sa.ratePerSec, sb.ratePerSec, "")
+	fmt.Printf("%-22s %13.1f%% %13.1f%% %14s\n", "IO thread stopped", sa.ioStoppedPct, sb.ioStoppedPct, "")
+	fmt.Printf("%-22s %13.1f%% %13.1f%% %14s\n", "SQL thread stopped", sa.sqlStopped, sb.sqlStopped, "")
+	fmt.Printf("%-22s %14d %14d %14s\n", "Error pattern matches", sa.errorMatches, sb.errorMatches, "")
+	fmt.Println()
+
+	// Summarize the verdict from the catch-up trend and average lag
+	switch {
+	case sa.ratePerSec < 0 && sb.ratePerSec < sa.ratePerSec:
+		fmt.Printf("📈 B caught up %.2fx faster than A\n", sb.ratePerSec/sa.ratePerSec)
+	case sa.ratePerSec < 0 && sb.ratePerSec < 0:
+		fmt.Printf("📉 B caught up %.2fx slower than A\n", sa.ratePerSec/sb.ratePerSec)
+	case sb.avgLag < sa.avgLag:
+		fmt.Println("📈 B had lower average lag than A")
+	case sb.avgLag > sa.avgLag:
+		fmt.Println("📉 B had higher average lag than A")
+	default:
+		fmt.Println("➖ No measurable difference between A and B")
+	}
+}
+
+func printLagRow(name string, a, b float64) {
+	change := "n/a"
+	if a != 0 {
+		change = fmt.Sprintf("%+.1f%%", 100*(b-a)/a)
+	}
+	fmt.Printf("%-22s %14.0f %14.0f %14s\n", name, a, b, change)
+}
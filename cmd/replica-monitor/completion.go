@@ -0,0 +1,270 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Arguments some commands take besides flags, offered by completions
+var commandArgs = map[string][]string{
+	"completion": {"bash", "zsh", "fish"},
+	"service":    {"install", "uninstall", "start", "stop", "status"},
+}
+
+// A command's flags in the order help lists them
+func sortedFlags(fs *flag.FlagSet) []*flag.Flag {
+	var flags []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) { flags = append(flags, f) })
+	return flags
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// Whether a flag's value is a path, completed with file names
+func takesPath(f *flag.Flag) bool {
+	for _, suffix := range []string{"-file", "-key", "-ca", "-cert", "-log"} {
+		if strings.HasSuffix(f.Name, suffix) {
+			return true
+		}
+	}
+	return slices.Contains([]string{"config", "history", "output", "zabbix-output"}, f.Name)
+}
+
+func completionFlags() *flag.FlagSet {
+	return newCommandFlags("completion")
+}
+
+func runCompletion(fs *flag.FlagSet) error {
+	var err error
+	switch fs.Arg(0) {
+	case "bash":
+		err = writeBashCompletion(os.Stdout)
+	case "zsh":
+		err = writeZshCompletion(os.Stdout)
+	case "fish":
+		err = writeFishCompletion(os.Stdout)
+	default:
+		return usageError(fs, "")
+	}
+	if err != nil {
+		return fmt.Errorf("write completions: %w", err)
+	}
+	return nil
+}
+
+func commandNames() string {
+	names := []string{"help"}
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	return strings.Join(names, " ")
+}
+
+func writeBashCompletion(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# bash completion for replica-monitor, from: replica-monitor completion bash\n")
+	b.WriteString("_replica_monitor() {\n")
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(&b, "\tif [[ $COMP_CWORD -eq 1 || ( $COMP_CWORD -eq 2 && ${COMP_WORDS[1]} == help ) ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\tfi\n", commandNames())
+	b.WriteString("\tlocal flags= paths= values= args=\n\tcase \"${COMP_WORDS[1]}\" in\n")
+	for _, cmd := range commands {
+		var flags, paths, values []string
+		for _, f := range sortedFlags(cmd.flags()) {
+			flags = append(flags, "-"+f.Name)
+			switch {
+			case isBoolFlag(f):
+			case takesPath(f):
+				paths = append(paths, "-"+f.Name)
+			default:
+				values = append(values, "-"+f.Name)
+			}
+		}
+		fmt.Fprintf(&b, "\t%s)\n\t\tflags=%q\n\t\tpaths=%q\n\t\tvalues=%q\n\t\targs=%q\n\t\t;;\n", cmd.name,
+			strings.Join(flags, " "), strings.Join(paths, " "), strings.Join(values, " "), strings.Join(commandArgs[cmd.name], " "))
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [[ \" $paths \" == *\" $prev \"* ]]; then\n\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\t\treturn\n\tfi\n")
+	b.WriteString("\tif [[ \" $values \" == *\" $prev \"* ]]; then\n\t\treturn\n\tfi\n")
+	b.WriteString("\tif [[ $cur == -* ]]; then\n\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n\telse\n\t\tCOMPREPLY=($(compgen -W \"$args\" -- \"$cur\"))\n\tfi\n")
+	b.WriteString("}\ncomplete -o default -F _replica_monitor replica-monitor\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Quote s in single quotes for zsh or bash
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeZshCompletion(w io.Writer) error {
+	describe := strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, ":", `\:`)
+	var b strings.Builder
+	b.WriteString("#compdef replica-monitor\n# zsh completion for replica-monitor, from: replica-monitor completion zsh\n\n")
+	b.WriteString("_replica_monitor() {\n\tlocal -a commands\n\tcommands=(\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "\t\t%s\n", shellQuote(strings.ReplaceAll(cmd.name, ":", `\:`)+":"+cmd.summary))
+	}
+	b.WriteString("\t\t'help:Show the flags of a command'\n\t)\n\tif (( CURRENT == 2 )); then\n\t\t_describe command commands\n\t\treturn\n\tfi\n")
+	b.WriteString("\tlocal cmd=$words[2]\n\tshift words\n\t(( CURRENT-- ))\n\tcase $cmd in\n")
+	b.WriteString("\thelp)\n\t\t_describe command commands\n\t\t;;\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "\t%s)\n\t\t_arguments", cmd.name)
+		// Flags may be repeated, as the flag package allows
+		for _, f := range sortedFlags(cmd.flags()) {
+			valueName, usage := flag.UnquoteUsage(f)
+			spec := "*-" + f.Name + "[" + describe.Replace(usage) + "]"
+			switch {
+			case isBoolFlag(f):
+			case takesPath(f):
+				spec += ":" + valueName + ":_files"
+			default:
+				spec += ":" + valueName + ":"
+			}
+			fmt.Fprintf(&b, " \\\n\t\t\t%s", shellQuote(spec))
+		}
+		if args := commandArgs[cmd.name]; len(args) > 0 {
+			fmt.Fprintf(&b, " \\\n\t\t\t%s", shellQuote("1:"+cmd.name+":("+strings.Join(args, " ")+")"))
+		}
+		b.WriteString("\n\t\t;;\n")
+	}
+	b.WriteString("\tesac\n}\n\n")
+	b.WriteString("if [[ $funcstack[1] == _replica_monitor ]]; then\n\t_replica_monitor \"$@\"\nelse\n\tcompdef _replica_monitor replica-monitor\nfi\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFishCompletion(w io.Writer) error {
+	quote := func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+	}
+	var b strings.Builder
+	b.WriteString("# fish completion for replica-monitor, from: replica-monitor completion fish\n")
+	b.WriteString("complete -c replica-monitor -f\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "complete -c replica-monitor -n __fish_use_subcommand -a %s -d %s\n", cmd.name, quote(cmd.summary))
+	}
+	names := strings.TrimPrefix(commandNames(), "help ")
+	fmt.Fprintf(&b, "complete -c replica-monitor -n __fish_use_subcommand -a help -d 'Show the flags of a command'\n")
+	fmt.Fprintf(&b, "complete -c replica-monitor -n '__fish_seen_subcommand_from help' -a %s\n", quote(names))
+	for _, cmd := range commands {
+		cond := quote("__fish_seen_subcommand_from " + cmd.name)
+		for _, f := range sortedFlags(cmd.flags()) {
+			_, usage := flag.UnquoteUsage(f)
+			fmt.Fprintf(&b, "complete -c replica-monitor -n %s -o %s -d %s", cond, f.Name, quote(usage))
+			switch {
+			case isBoolFlag(f):
+			case takesPath(f):
+				b.WriteString(" -r -F")
+			default:
+				b.WriteString(" -x")
+			}
+			b.WriteString("\n")
+		}
+		if args := commandArgs[cmd.name]; len(args) > 0 {
+			fmt.Fprintf(&b, "complete -c replica-monitor -n %s -a %s\n", cond, quote(strings.Join(args, " ")))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Directory docs writes the man pages to
+var docsDir string
+
+func docsFlags() *flag.FlagSet {
+	fs := newCommandFlags("docs")
+	fs.StringVar(&docsDir, "dir", "man", "Directory to write the man pages to, created if missing")
+	return fs
+}
+
+func runDocs(fs *flag.FlagSet) error {
+	// Building docs' own flags for its man page resets docsDir to its default
+	dir := docsDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create the man page directory: %w", err)
+	}
+	pages := map[string]string{"replica-monitor.1": mainManPage()}
+	for _, cmd := range commands {
+		pages["replica-monitor-"+cmd.name+".1"] = commandManPage(cmd)
+	}
+	for name, page := range pages {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(page), 0o644); err != nil {
+			return fmt.Errorf("write a man page: %w", err)
+		}
+	}
+	fmt.Printf("✅ Wrote %d man pages to %s\n", len(pages), dir)
+	return nil
+}
+
+// Escape text for roff, so that backslashes and leading dots or quotes print
+// as themselves
+func roff(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+func manHeader(b *strings.Builder, title string) {
+	fmt.Fprintf(b, ".TH %s 1 \"\" \"replica-monitor %s\" \"replica-monitor Manual\"\n", strings.ToUpper(title), roff(version))
+}
+
+func mainManPage() string {
+	var b strings.Builder
+	manHeader(&b, "replica-monitor")
+	b.WriteString(".SH NAME\nreplica-monitor \\- monitor MySQL replicas and skip matched replication errors\n")
+	b.WriteString(".SH SYNOPSIS\n.B replica-monitor\n.I command\n[flags]\n")
+	b.WriteString(".SH DESCRIPTION\nPolls the replication status of MySQL, MariaDB, Aurora, and PostgreSQL replicas, reports lag and errors, alerts, and skips replication errors matching known patterns on RDS.\nFlags given without a command run\n.BR watch .\n")
+	b.WriteString(".SH COMMANDS\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, ".TP\n.B %s\n%s\nSee\n.BR replica-monitor-%s (1).\n", cmd.name, roff(cmd.summary), cmd.name)
+	}
+	b.WriteString(".SH SEE ALSO\n")
+	for i, cmd := range commands {
+		sep := ","
+		if i == len(commands)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(&b, ".BR replica-monitor-%s (1)%s\n", cmd.name, sep)
+	}
+	return b.String()
+}
+
+func commandManPage(cmd *command) string {
+	var b strings.Builder
+	manHeader(&b, "replica-monitor-"+cmd.name)
+	fmt.Fprintf(&b, ".SH NAME\nreplica-monitor-%s \\- %s\n", cmd.name, roff(cmd.summary))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B replica-monitor %s\n", cmd.name)
+	if cmd.usage != "" {
+		fmt.Fprintf(&b, "%s\n", roff(cmd.usage))
+	}
+	if flags := sortedFlags(cmd.flags()); len(flags) > 0 {
+		b.WriteString(".SH OPTIONS\n")
+		for _, f := range flags {
+			valueName, usage := flag.UnquoteUsage(f)
+			name := `\-` + strings.ReplaceAll(f.Name, "-", `\-`)
+			if isBoolFlag(f) {
+				fmt.Fprintf(&b, ".TP\n.B %s\n", name)
+			} else {
+				fmt.Fprintf(&b, ".TP\n.BI %s \" %s\"\n", name, valueName)
+			}
+			b.WriteString(roff(usage))
+			if f.DefValue != "" && !slices.Contains([]string{"0", "0s", "false", "[]"}, f.DefValue) {
+				fmt.Fprintf(&b, " (default %s)", roff(f.DefValue))
+			}
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString(".SH SEE ALSO\n.BR replica-monitor (1)\n")
+	return b.String()
+}
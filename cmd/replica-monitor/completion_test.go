@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Every command's flags, which completions and man pages are generated from,
+// come from its constructor without running it
+func TestCommandFlags(t *testing.T) {
+	for _, cmd := range commands {
+		if fs := cmd.flags(); fs == nil || fs.Name() != cmd.name {
+			t.Errorf("%s: no flag set of its own", cmd.name)
+		}
+	}
+	for name, flag := range map[string]string{"watch": "host", "compare": "history", "service": "display-name", "docs": "dir"} {
+		if findCommand(name).flags().Lookup(flag) == nil {
+			t.Errorf("%s: no -%s", name, flag)
+		}
+	}
+}
+
+func TestBashCompletion(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("no bash")
+	}
+	var script strings.Builder
+	if err := writeBashCompletion(&script); err != nil {
+		t.Fatal(err)
+	}
+	complete := func(words ...string) string {
+		t.Helper()
+		args := append([]string{"-c", script.String() + `COMP_WORDS=("$@"); COMP_CWORD=$(( $# - 1 )); _replica_monitor; echo "${COMPREPLY[*]}"`, "bash", "replica-monitor"}, words...)
+		out, err := exec.Command(bash, args...).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	for _, tc := range []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"aud"}, "audit-verify"},
+		{[]string{"help", "self"}, "self-update"},
+		{[]string{"compare", "-hi"}, "-history"},
+		{[]string{"watch", "-host", ""}, ""},
+		{[]string{"completion", "z"}, "zsh"},
+	} {
+		if got := complete(tc.words...); got != tc.want {
+			t.Errorf("completing %q = %q, want %q", tc.words, got, tc.want)
+		}
+	}
+}
+
+// Generating docs' own man page must not lose the -dir it was given
+func TestDocsKeepsDir(t *testing.T) {
+	dir := t.TempDir()
+	fs := docsFlags()
+	fs.Parse([]string{"-dir", dir})
+	if err := runDocs(fs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "replica-monitor-docs.1")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Replicas, labels, and settings read from the -config file
+type fileConfig struct {
+	Labels   map[string]string `json:"labels"` // applied to every replica, including discovered ones
+	Replicas []replicaConfig   `json:"replicas"`
+
+	// Applied again whenever the file changes (see reload.go)
+	LagThreshold  string   `json:"lag_threshold"`
+	ErrorPatterns []string `json:"error_patterns"`
+	AlertWebhook  string   `json:"alert_webhook"`
+	Notify        []string `json:"notify"`
+
+	// Teams sharing this monitor, each with replicas of its own (see tenant.go)
+	Tenants []tenantConfig `json:"tenants"`
+}
+
+type replicaConfig struct {
+	Name       string            `json:"name"`
+	Host       string            `json:"host"`
+	Port       int               `json:"port"`
+	Labels     map[string]string `json:"labels"`
+	LagSources []string          `json:"lag_sources"` // as -lag-sources, for this replica only
+}
+
+func loadConfig(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg fileConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for i, rc := range cfg.Replicas {
+		if rc.Host == "" {
+			return nil, fmt.Errorf("replica %d in %s has no host", i+1, path)
+		}
+		if rc.Port == 0 {
+			cfg.Replicas[i].Port = 3306
+		}
+	}
+	for _, tc := range cfg.Tenants {
+		for i, rc := range tc.Replicas {
+			if rc.Host == "" {
+				return nil, fmt.Errorf("replica %d of tenant %s in %s has no host", i+1, tc.Name, path)
+			}
+			if rc.Port == 0 {
+				tc.Replicas[i].Port = 3306
+			}
+		}
+	}
+	return &cfg, nil
+}
+
+// Repeatable key=value flag, used for -tag and -label
+type keyValueFlags map[string]string
+
+func (kv *keyValueFlags) String() string {
+	return formatLabels(*kv)
+}
+
+func (kv *keyValueFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	if *kv == nil {
+		*kv = keyValueFlags{}
+	}
+	(*kv)[key] = val
+	return nil
+}
+
+// Comma-separated list flag such as -fields A,B,C
+type commaListFlag []string
+
+func (l *commaListFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *commaListFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// Combine label sets; later sets override earlier ones
+func mergeLabels(sets ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, set := range sets {
+		for k, v := range set {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// Render labels as "k1=v1, k2=v2" in key order
+func formatLabels(labels map[string]string) string {
+	parts := make([]string, 0, len(labels))
+	for k, v := range labels {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
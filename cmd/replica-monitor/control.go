@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// Operator controls shared between the monitoring loop and interactive front ends
+var (
+	pollingPaused  atomic.Bool
+	alertsSilenced atomic.Bool
+
+	manualSkips = make(chan string, 8) // replica names awaiting an operator-requested skip
+	loopWake    = make(chan struct{}, 1)
+)
+
+// Queue a skip for the named replica and wake the loop to run it
+func requestManualSkip(name string) {
+	select {
+	case manualSkips <- name:
+	default:
+	}
+	wakeLoop()
+}
+
+func wakeLoop() {
+	select {
+	case loopWake <- struct{}{}:
+	default:
+	}
+}
+
+// Time until the next cycle: -interval (or the adaptive interval, stretched by
+// -throttle) plus a random share of -jitter, so monitors started together drift
+// apart instead of querying the same replica in lockstep
+func nextInterval() time.Duration {
+	base := currentInterval() * time.Duration(throttleFactor.Load())
+	if jitter <= 0 {
+		return base
+	}
+	return base + rand.N(jitter)
+}
+
+// Sleep until the next cycle is due or the loop is woken early
+func waitForNextCycle(ctx context.Context, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-loopWake:
+	case <-ctx.Done():
+	}
+}
+
+// Run any operator-requested skips on the loop's goroutine, reporting them to out
+func runManualSkips(out io.Writer, replicas []*replica) {
+	for {
+		select {
+		case name := <-manualSkips:
+			for _, r := range replicas {
+				switch {
+				case displayName(r) != name:
+				case readOnly:
+					slog.Warn("Operator requested skip refused by -read-only", "replica", name)
+				case !isLeader():
+					slog.Warn("Operator requested skip refused on a standby monitor", "replica", name)
+					fmt.Fprintln(out, "💤 Standby monitor: leaving the skip to the leader")
+				case !holdsSkipLock(r):
+					slog.Warn("Operator requested skip refused: another monitor holds the skip lock", "replica", name, "skip_lock", skipLockMode)
+					fmt.Fprintf(out, "💤 Another monitor holds the %s skip lock for %s: leaving the skip to it\n", skipLockMode, name)
+				default:
+					slog.Info("Operator requested skip", "replica", name)
+					r.skipReplError(out, "tui")
+				}
+			}
+		default:
+			return
+		}
+	}
+}
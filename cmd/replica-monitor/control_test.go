@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestManualSkipRefusals(t *testing.T) {
+	defer func() {
+		elector = nil
+		select {
+		case <-loopWake:
+		default:
+		}
+	}()
+	r := &replica{name: "orders"}
+
+	// A standby leaves the skip to the leader, as it does an auto-skip
+	elector = &leaderElector{}
+	var out strings.Builder
+	requestManualSkip("orders")
+	runManualSkips(&out, []*replica{r})
+	if !strings.Contains(out.String(), "Standby monitor") {
+		t.Errorf("standby ran an operator's skip: %q", out.String())
+	}
+
+	// So does a monitor without the replica's skip lock
+	elector = nil
+	r.skipLock = &fakeSkipLock{}
+	out.Reset()
+	requestManualSkip("orders")
+	runManualSkips(&out, []*replica{r})
+	if !strings.Contains(out.String(), "Another monitor holds") {
+		t.Errorf("operator's skip ran without the skip lock: %q", out.String())
+	}
+}
+
+// A skip lock held by another monitor
+type fakeSkipLock struct{}
+
+func (*fakeSkipLock) hold() bool { return false }
+func (*fakeSkipLock) release()   {}
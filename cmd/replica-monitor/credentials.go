@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// -password-file: read the password from a file, such as a mounted Kubernetes
+// secret, and watch it; new connections log in with whatever it holds
+var passwordFile string
+
+// How often -password-file is read for changes. Kubernetes updates mounted
+// secrets within a minute or so of a rotation, by swapping a symlink, so
+// polling the contents catches what change notifications on the file can miss.
+const passwordFileInterval = 10 * time.Second
+
+// The password new connections log in with: -password, or the contents of
+// -password-file as last read
+var passwordMu sync.RWMutex
+
+func currentPassword() string {
+	passwordMu.RLock()
+	defer passwordMu.RUnlock()
+	return password
+}
+
+// Connection pools logging in with the password, so that a rotation can
+// replace their connections; keyed by address
+var (
+	poolsMu sync.Mutex
+	pools   = map[*sql.DB]string{}
+)
+
+func registerPool(db *sql.DB, addr string) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	pools[db] = addr
+}
+
+func unregisterPool(db *sql.DB) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	delete(pools, db)
+}
+
+// Read -password-file, without its trailing newline
+func readPasswordFile() (string, error) {
+	return readSecretFile(passwordFile)
+}
+
+// Read a file holding a single secret, without its trailing newline
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return secret, nil
+}
+
+// Take the password from -password-file, when given
+func loadPasswordFile() error {
+	if passwordFile == "" {
+		return nil
+	}
+	if password != "" || authMode == "iam" {
+		return errors.New("-password-file cannot be combined with -password or -auth iam")
+	}
+	secret, err := readPasswordFile()
+	if err != nil {
+		return fmt.Errorf("read -password-file: %w", err)
+	}
+	addSecret(secret)
+	passwordMu.Lock()
+	password = secret
+	passwordMu.Unlock()
+	return nil
+}
+
+// Poll -password-file in the background
+func watchPasswordFile(ctx context.Context) {
+	ticker := time.NewTicker(passwordFileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkPasswordFile(ctx)
+		}
+	}
+}
+
+// Switch to a new password when -password-file changes; a file that cannot be
+// read keeps the last password
+func checkPasswordFile(ctx context.Context) {
+	secret, err := readPasswordFile()
+	if err != nil {
+		slog.Warn("Failed to read -password-file; keeping the current password", "path", passwordFile, "err", err)
+		return
+	}
+	if secret == currentPassword() {
+		return
+	}
+	addSecret(secret)
+	passwordMu.Lock()
+	password = secret
+	passwordMu.Unlock()
+	slog.Info("Password file changed; reconnecting with the new password", "path", passwordFile)
+	reconnectPools(ctx)
+}
+
+// Close the idle connections of every pool, which logged in with the old
+// password, and check that a new one logs in with the new password.
+// Connections busy with a query stay open; they are logged in already.
+func reconnectPools(ctx context.Context) {
+	poolsMu.Lock()
+	current := maps.Clone(pools)
+	poolsMu.Unlock()
+
+	for db, addr := range current {
+		db.SetMaxIdleConns(0)
+		pingCtx, cancel := withQueryTimeout(ctx)
+		err := db.PingContext(pingCtx)
+		cancel()
+		// database/sql's default
+		db.SetMaxIdleConns(2)
+		if err != nil {
+			slog.Error("Failed to log in with the new password", "addr", addr, "err", err)
+		}
+	}
+}
+
+// -action-user: a second account, allowed to skip errors and start
+// replication, that the monitor logs in as only while it does so, so that the
+// always-connected -user needs no more than to read replica status
+var (
+	actionUser         string
+	actionPassword     string
+	actionPasswordFile string
+)
+
+// Check the -action-user flags make sense together
+func checkActionUser() error {
+	switch {
+	case actionUser == "" && (actionPassword != "" || actionPasswordFile != ""):
+		return errors.New("-action-password and -action-password-file need -action-user")
+	case actionUser == "":
+		return nil
+	case actionPassword != "" && actionPasswordFile != "":
+		return errors.New("-action-password cannot be combined with -action-password-file")
+	case actionPassword == "" && actionPasswordFile == "" && authMode != "iam":
+		return errors.New("-action-user needs -action-password or -action-password-file unless -auth iam")
+	}
+	return nil
+}
+
+// The -action-user account, with -action-password-file read afresh, so that an
+// action after a rotation logs in with the new password
+func actionAccount() (account, error) {
+	login := account{user: actionUser, password: actionPassword}
+	if actionPasswordFile != "" {
+		secret, err := readSecretFile(actionPasswordFile)
+		if err != nil {
+			return login, fmt.Errorf("read -action-password-file: %w", err)
+		}
+		addSecret(secret)
+		login.password = secret
+	}
+	return login, nil
+}
+
+// Log in to r as -action-user
+func openActionDB(ctx context.Context, r *replica) (*sql.DB, error) {
+	login, err := actionAccount()
+	if err != nil {
+		return nil, err
+	}
+	db, err := openDB(ctx, r.host, r.port, r.engine, login)
+	if err != nil {
+		return nil, fmt.Errorf("log in as -action-user %s: %w", actionUser, err)
+	}
+	return db, nil
+}
+
+// The source to send an action on r to, and a function to call once it is
+// done: a connection as -action-user, closed again afterwards, or r's own
+// source without -action-user and for simulated replicas
+func (r *replica) actionSource(ctx context.Context) (monitor.StatusSource, func(), error) {
+	if actionUser == "" || r.db == nil {
+		return r.source, func() {}, nil
+	}
+	db, err := openActionDB(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("Logged in as -action-user", "replica", displayName(r), "user", actionUser)
+	return r.engine.Open(db), func() { db.Close() }, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A driver counting the connections it opens
+type countingConnector struct{ opened *int }
+
+func (c countingConnector) Connect(context.Context) (driver.Conn, error) {
+	*c.opened++
+	var seen []string
+	return recordingConn{&seen}, nil
+}
+func (c countingConnector) Driver() driver.Driver { return nil }
+
+func TestPasswordFile(t *testing.T) {
+	defer func() { password, passwordFile = "", "" }()
+	passwordFile = filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("first-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadPasswordFile(); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentPassword(); got != "first-secret" {
+		t.Fatalf("password = %q", got)
+	}
+	if got := redact("login with first-secret"); got != "login with "+redacted {
+		t.Errorf("password not redacted: %q", got)
+	}
+	if err := loadPasswordFile(); err == nil {
+		t.Error("-password-file was combined with -password")
+	}
+
+	var opened int
+	db := sql.OpenDB(countingConnector{&opened})
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	registerPool(db, "replica:3306")
+	defer unregisterPool(db)
+
+	// Unchanged, or unreadable: nothing happens
+	checkPasswordFile(context.Background())
+	os.WriteFile(passwordFile, nil, 0o600)
+	checkPasswordFile(context.Background())
+	if got := currentPassword(); got != "first-secret" || opened != 1 {
+		t.Errorf("password = %q after %d connections, want the first one kept", got, opened)
+	}
+
+	os.WriteFile(passwordFile, []byte("second-secret"), 0o600)
+	checkPasswordFile(context.Background())
+	if got := currentPassword(); got != "second-secret" {
+		t.Errorf("password = %q after rotation", got)
+	}
+	if opened != 2 {
+		t.Errorf("%d connections opened, want a new one after rotation", opened)
+	}
+	if got := redact("second-secret"); got != redacted {
+		t.Errorf("new password not redacted: %q", got)
+	}
+}
+
+func TestActionAccount(t *testing.T) {
+	defer func() { actionUser, actionPassword, actionPasswordFile = "", "", "" }()
+	actionPassword = "action-secret"
+	if err := checkActionUser(); err == nil {
+		t.Error("-action-password accepted without -action-user")
+	}
+	actionUser = "monitor_action"
+	actionPasswordFile = filepath.Join(t.TempDir(), "action-password")
+	if err := checkActionUser(); err == nil {
+		t.Error("-action-password combined with -action-password-file")
+	}
+
+	actionPassword = ""
+	if err := checkActionUser(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := actionAccount(); err == nil {
+		t.Error("missing -action-password-file read")
+	}
+	// Read for every action, so a rotation needs no restart
+	for _, secret := range []string{"first-action-secret", "second-action-secret"} {
+		os.WriteFile(actionPasswordFile, []byte(secret+"\n"), 0o600)
+		login, err := actionAccount()
+		if err != nil || login.user != "monitor_action" || login.password != secret {
+			t.Errorf("actionAccount = %+v, %v; want %s", login, err, secret)
+		}
+		if got := redact(secret); got != redacted {
+			t.Errorf("action password not redacted: %q", got)
+		}
+	}
+}
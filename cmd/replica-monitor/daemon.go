@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// -daemon detaches watch or serve from the terminal for init scripts without
+// systemd: the command starts again in the background, writing its report and
+// logs to -log-file, and returns once that has started. -pid-file, with or
+// without -daemon, holds the process ID, locked while the monitor runs.
+var (
+	daemonMode bool
+	pidFile    string
+)
+
+// Set in the environment of the background process -daemon starts, which
+// reports on descriptor 3 once it is running
+const daemonEnv = "REPLICA_MONITOR_DAEMONIZED"
+
+// Whether this process is the background one
+var daemonized bool
+
+func init() {
+	daemonized = os.Getenv(daemonEnv) != ""
+	// Not passed on to anything the monitor runs
+	os.Unsetenv(daemonEnv)
+}
+
+// Start the monitor again in the background and return once it is running, or
+// with its failure
+func startDaemon() error {
+	switch {
+	case logFile == "":
+		fmt.Fprintln(os.Stderr, "-daemon needs -log-file for the report and logs, with no terminal to write them to")
+		return exitWith(2)
+	case tuiMode:
+		fmt.Fprintln(os.Stderr, "-daemon cannot be used with -tui")
+		return exitWith(2)
+	}
+	if pid, held := pidFileHeld(); held {
+		fmt.Fprintf(os.Stderr, "Already running as pid %s (%s)\n", pid, pidFile)
+		return exitWith(1)
+	}
+	attr, err := daemonProcAttr()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitWith(2)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find this binary: %w", err)
+	}
+	crashFile := logFile + ".crash"
+	crashOut, err := os.OpenFile(crashFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open crash file %s: %w", crashFile, err)
+	}
+	defer crashOut.Close()
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		return err
+	}
+	defer devNull.Close()
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("start in the background: %w", err)
+	}
+
+	cmd := daemonCommand(exe)
+	// Go's own crash reports are written straight to the descriptors, so they
+	// get a file of their own: the log file is rotated by renaming it, which
+	// descriptors held open across the rotation would not follow
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = devNull, crashOut, crashOut
+	cmd.ExtraFiles = []*os.File{readyW}
+	cmd.SysProcAttr = attr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start in the background: %w", err)
+	}
+	readyW.Close()
+	status, _ := io.ReadAll(ready)
+	if strings.TrimSpace(string(status)) == "ready" {
+		fmt.Printf("✅ Running in the background as pid %d, logging to %s\n", cmd.Process.Pid, logFile)
+		return nil
+	}
+	err = cmd.Wait()
+	fmt.Fprintf(os.Stderr, "❌ Failed to start in the background (%v); see %s and %s\n", err, logFile, crashFile)
+	return exitWith(1)
+}
+
+// The command starting the monitor again in the background, with the command
+// line as given: os.Args holds asterisks for the secret flags by now
+func daemonCommand(exe string) *exec.Cmd {
+	cmd := exec.Command(exe, launchArgs...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	return cmd
+}
+
+// Files named by the flags of the monitor, made absolute before the background
+// process leaves the directory it was started in
+var daemonPaths = []*string{
+	&passwordFile, &actionPasswordFile, &serverPublicKey, &dbTLSCA, &configPath,
+	&logFile, &auditLog, &auditKey, &pidFile, &history, &eventsFile, &zabbixOutput,
+	&tlsCert, &tlsKey, &tlsClientCA, &apiTokenFile, &apiHtpasswdFile, &oidcClientSecretFile,
+}
+
+// Move the background process to the root directory, so that it does not keep
+// the directory it was started in busy, e.g. from being unmounted, while the
+// files it was given relative paths to stay where they were
+func leaveWorkingDir() error {
+	for _, path := range daemonPaths {
+		if *path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(*path)
+		if err != nil {
+			return err
+		}
+		*path = abs
+	}
+	return os.Chdir("/")
+}
+
+// Tell the process that started this one in the background that it is running
+func daemonReady() {
+	if !daemonized {
+		return
+	}
+	f := os.NewFile(3, "daemon-ready")
+	fmt.Fprintln(f, "ready")
+	f.Close()
+}
+
+// Write -pid-file and keep it locked while the monitor runs, so that a second
+// monitor refuses to start and one left behind by a crash is taken over.
+// Returns what removes it.
+func writePIDFile() (func(), error) {
+	if pidFile == "" {
+		return func() {}, nil
+	}
+	f, err := os.OpenFile(pidFile, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		pid, _ := readPIDFile()
+		return nil, fmt.Errorf("already running as pid %s", pid)
+	}
+	if err := f.Truncate(0); err == nil {
+		_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		// Removed before the lock is released, so that a monitor starting
+		// meanwhile cannot have its file removed
+		os.Remove(pidFile)
+		f.Close()
+	}, nil
+}
+
+// Whether a running monitor holds -pid-file's lock, and the pid it wrote
+func pidFileHeld() (string, bool) {
+	if pidFile == "" {
+		return "", false
+	}
+	f, err := os.OpenFile(pidFile, os.O_RDWR, 0)
+	if err != nil {
+		// Missing, or for the monitor itself to report
+		return "", false
+	}
+	defer f.Close()
+	if lockFile(f) == nil {
+		return "", false
+	}
+	pid, _ := readPIDFile()
+	return pid, true
+}
+
+func readPIDFile() (string, error) {
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		return "unknown", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPIDFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("a locked file cannot be read on Windows")
+	}
+	pidFile = filepath.Join(t.TempDir(), "replica-monitor.pid")
+	t.Cleanup(func() { pidFile = "" })
+	self := strconv.Itoa(os.Getpid())
+
+	// A file left behind by a crashed monitor, unlocked, is taken over
+	if err := os.WriteFile(pidFile, []byte("99999\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, held := pidFileHeld(); held {
+		t.Fatal("stale PID file reported as held")
+	}
+	remove, err := writePIDFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid, held := pidFileHeld(); !held || pid != self {
+		t.Errorf("pidFileHeld = %q, %v; want %s held", pid, held, self)
+	}
+	if _, err := writePIDFile(); err == nil {
+		t.Error("second monitor wrote the held PID file")
+	}
+
+	remove()
+	if _, err := os.Stat(pidFile); !os.IsNotExist(err) {
+		t.Errorf("PID file left after removal: %v", err)
+	}
+	if _, held := pidFileHeld(); held {
+		t.Error("removed PID file reported as held")
+	}
+}
+
+// The background process gets the secret flags as given, not the asterisks
+// they are scrubbed to
+func TestDaemonCommandKeepsSecrets(t *testing.T) {
+	savedArgs := os.Args
+	t.Cleanup(func() {
+		os.Args, launchArgs = savedArgs, nil
+		password, influxToken, actionPassword = "", "", ""
+	})
+	// Built at run time, as the kernel's copy of the arguments is, so that
+	// scrubbing can write to them
+	given := []string{"watch", "-host", "db1", "-password", "daemon-secret", "-action-password=daemon-action-secret", "-daemon"}
+	os.Args = []string{"replica-monitor"}
+	for _, arg := range given {
+		os.Args = append(os.Args, string([]byte(arg)))
+	}
+	password, actionPassword = os.Args[5], os.Args[6][len("-action-password="):]
+
+	protectSecrets()
+	if runtime.GOOS == "linux" && slices.Contains(os.Args, "daemon-secret") {
+		t.Errorf("arguments not scrubbed: %q", os.Args)
+	}
+	cmd := daemonCommand("/usr/local/bin/replica-monitor")
+	if want := append([]string{"/usr/local/bin/replica-monitor"}, given...); !slices.Equal(cmd.Args, want) {
+		t.Errorf("background process started with %q\nwant %q", cmd.Args, want)
+	}
+	if !slices.Contains(cmd.Env, daemonEnv+"=1") {
+		t.Errorf("background process not told it is one: %q", cmd.Env)
+	}
+}
+
+// A monitor that fails to start removes its PID file rather than leaving a
+// dead pid in it
+func TestRunMonitorStartupFailureRemovesPIDFile(t *testing.T) {
+	pidFile = filepath.Join(t.TempDir(), "replica-monitor.pid")
+	configPath = filepath.Join(t.TempDir(), "missing.json")
+	savedInterval := interval
+	interval = time.Second
+	t.Cleanup(func() { pidFile, configPath, interval = "", "", savedInterval })
+
+	if err := runMonitor(flag.NewFlagSet("watch", flag.ContinueOnError), false); err == nil {
+		t.Fatal("runMonitor started without its config file")
+	}
+	if _, err := os.Stat(pidFile); !os.IsNotExist(err) {
+		t.Errorf("PID file left after a failed start: %v", err)
+	}
+}
+
+// The background process leaves its working directory for the root, keeping
+// the files it was given relative paths to
+func TestLeaveWorkingDir(t *testing.T) {
+	t.Chdir(t.TempDir())
+	dir, _ := os.Getwd()
+	t.Cleanup(func() { logFile, configPath = "", "" })
+	logFile, configPath = "monitor.log", filepath.Join("conf", "replicas.json")
+
+	if err := leaveWorkingDir(); err != nil {
+		t.Fatal(err)
+	}
+	if wd, _ := os.Getwd(); wd != filepath.VolumeName(dir)+string(filepath.Separator) {
+		t.Errorf("working directory %s; want the root", wd)
+	}
+	if logFile != filepath.Join(dir, "monitor.log") || configPath != filepath.Join(dir, "conf", "replicas.json") {
+		t.Errorf("paths %s and %s; want them under %s", logFile, configPath, dir)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// Start the background process in a session of its own, without the
+// terminal, so that closing the terminal does not send it SIGHUP
+func daemonProcAttr() (*syscall.SysProcAttr, error) {
+	return &syscall.SysProcAttr{Setsid: true}, nil
+}
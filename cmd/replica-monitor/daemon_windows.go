@@ -0,0 +1,10 @@
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+func daemonProcAttr() (*syscall.SysProcAttr, error) {
+	return nil, errors.New("-daemon is not available on Windows; run the monitor as a Windows service with the service command instead")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"sync"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+//go:embed web
+var webAssets embed.FS
+
+// Number of skip/alert events kept for the dashboard timeline, from -timeline-size
+var timelineSize = 200
+
+// One skip or alert shown on the dashboard timeline
+type timelineEvent struct {
+	Time    time.Time `json:"time"`
+	Replica string    `json:"replica"`
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+}
+
+var (
+	timelineMu sync.Mutex
+	timeline   monitor.Ring[timelineEvent] // created on first use, once -timeline-size is known
+)
+
+// Remember a skip or alert for the dashboard, dropping the oldest beyond timelineSize
+func recordTimeline(r *replica, kind, message string) {
+	quietNotice(displayName(r), "%s: %s", kind, message)
+	timelineMu.Lock()
+	defer timelineMu.Unlock()
+	if timeline.Cap() == 0 {
+		timeline = monitor.NewRing[timelineEvent](timelineSize)
+	}
+	timeline.Push(timelineEvent{Time: time.Now(), Replica: displayName(r), Kind: kind, Message: message})
+}
+
+// GET /api/v1/timeline: recent skips and alerts, newest last
+func handleTimeline(w http.ResponseWriter, req *http.Request) {
+	timelineMu.Lock()
+	events := timeline.Values()
+	timelineMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// Static files for the built-in dashboard, served at /
+func dashboardHandler() http.Handler {
+	assets, err := fs.Sub(webAssets, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServerFS(assets)
+}
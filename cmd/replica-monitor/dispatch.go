@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"replica-monitor/pkg/notify"
+)
+
+// Alerts are delivered by a pool of workers from a bounded queue, so a slow or
+// unreachable endpoint never delays the monitoring loop
+var (
+	alertQueueSize = 100
+	alertWorkers   = 2
+)
+
+// Destinations from -notify, opened while the flags are parsed
+var notifyTargets []notify.Target
+
+// Open a -notify URL through the notifier registered for its scheme
+func addNotifyTarget(target string) error {
+	t, err := notify.Open(target)
+	if err != nil {
+		return err
+	}
+	notifyTargets = append(notifyTargets, t)
+	return nil
+}
+
+// Open the URL held in a -notify-file, e.g. a mounted Kubernetes secret, keeping
+// the URL, tokens and all, out of the report and logs
+func addNotifyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	target := strings.TrimSpace(string(data))
+	addSecret(target)
+	if err := addNotifyTarget(target); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Whether alerts have anywhere to go
+func alertsConfigured() bool {
+	return alertWebhook != "" || len(notifyTargets) > 0 || len(fileNotifyTargets) > 0
+}
+
+// Started on the first alert that has somewhere to go
+var (
+	dispatcher     *notify.Dispatcher
+	dispatcherOnce sync.Once
+)
+
+func startDispatcher() *notify.Dispatcher {
+	targets := slices.Concat(notifyTargets, fileNotifyTargets)
+	if alertWebhook != "" {
+		targets = append(targets, notify.Target{Name: "webhook", Notifier: notify.Webhook{URL: alertWebhook}})
+	}
+	return newDispatcher(targets)
+}
+
+// Dispatcher for targets sized by -alert-queue and -alert-workers
+func newDispatcher(targets []notify.Target) *notify.Dispatcher {
+	return notify.NewDispatcher(targets, notify.Options{
+		QueueSize: max(alertQueueSize, 1),
+		Workers:   max(alertWorkers, 1),
+	})
+}
+
+// Log a delivery result and journal it as alert_delivered or alert_failed, using
+// event as the template
+func alertResult(event journalEvent) notify.Result {
+	return func(target string, alert notify.Event, err error) {
+		e := event
+		e.Time = time.Now()
+		switch {
+		case err == nil:
+			slog.Info("Alert delivered", "target", target, "event", alert.Event, "replica", alert.Replica)
+			e.Event = "alert_delivered"
+		case err == notify.ErrQueueFull:
+			slog.Error("Alert queue is full, dropping alert", "target", target, "event", alert.Event, "replica", alert.Replica)
+			e.Event, e.Message = "alert_failed", err.Error()
+		default:
+			slog.Error("Failed to deliver alert", "target", target, "event", alert.Event, "replica", alert.Replica, "err", err)
+			e.Event, e.Message = "alert_failed", err.Error()
+		}
+		writeEvent(e)
+	}
+}
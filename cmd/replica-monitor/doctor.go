@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/go-sql-driver/mysql"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Clock skew from a replica that doctor warns about, and beyond which signed
+// AWS requests, IAM authentication tokens among them, are rejected
+const (
+	clockSkewWarning  = time.Second
+	clockSkewCritical = 5 * time.Minute
+)
+
+// State of a check doctor skipped because an earlier one failed or it does
+// not apply, besides the check command's states
+const diagnosisSkipped = -1
+
+// Outcome of one doctor check
+type diagnosis struct {
+	name   string
+	state  int
+	detail string
+	hint   string // what to do about a warning or failure
+}
+
+// A replica doctor checks, from -host, -config, or RDS discovery
+type doctorTarget struct {
+	name string
+	host string
+	port int
+}
+
+func doctorFlags() *flag.FlagSet {
+	fs := newCommandFlags("doctor")
+	addConnectionFlags(fs)
+	return fs
+}
+
+func runDoctor(fs *flag.FlagSet) error {
+	needSkip = !readOnly
+	if err := setupOutput(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "🩺 replica-monitor", version)
+	general, targets := doctorSettings()
+	all := printDiagnoses(general)
+	for _, t := range targets {
+		fmt.Fprintf(stdout, "\n%s (%s)\n", t.name, net.JoinHostPort(t.host, strconv.Itoa(t.port)))
+		all = append(all, printDiagnoses(diagnoseReplica(t))...)
+	}
+
+	var failed, warned, passed int
+	for _, d := range all {
+		switch d.state {
+		case checkCritical:
+			failed++
+		case checkWarning:
+			warned++
+		case checkOK:
+			passed++
+		}
+	}
+	fmt.Fprintln(stdout)
+	if failed > 0 {
+		fmt.Fprintf(stdout, "❌ %d failed, %d warnings, %d passed\n", failed, warned, passed)
+		return exitWith(1)
+	}
+	fmt.Fprintf(stdout, "✅ %d passed, %d warnings\n", passed, warned)
+	return nil
+}
+
+func printDiagnoses(ds []diagnosis) []diagnosis {
+	for _, d := range ds {
+		icon := checkIcon(d.state)
+		if d.state == diagnosisSkipped {
+			icon = "➖"
+		}
+		fmt.Fprintf(stdout, "  %s %-18s %s\n", icon, d.name, d.detail)
+		if d.hint != "" && d.state != checkOK {
+			fmt.Fprintf(stdout, "     → %s\n", d.hint)
+		}
+	}
+	return ds
+}
+
+// Check the settings that apply to every replica and work out which replicas
+// to check
+func doctorSettings() ([]diagnosis, []doctorTarget) {
+	var ds []diagnosis
+	var targets []doctorTarget
+	if configPath != "" {
+		cfg, err := loadConfig(configPath)
+		if err == nil {
+			err = validateSettings(cfg)
+		}
+		if err != nil {
+			ds = append(ds, diagnosis{"Config file", checkCritical, err.Error(), "fix the file; watch refuses to start with it"})
+		} else {
+			ds = append(ds, diagnosis{"Config file", checkOK, fmt.Sprintf("%s: %d replicas", configPath, len(cfg.allReplicas())), ""})
+			for _, rc := range cfg.allReplicas() {
+				targets = append(targets, doctorTarget{rc.Name, rc.Host, rc.Port})
+			}
+		}
+	}
+	if host != "" && len(targets) == 0 {
+		targets = append(targets, doctorTarget{host, host, port})
+	}
+
+	switch {
+	case passwordFile == "" && password == "" && authMode != "iam":
+		ds = append(ds, diagnosis{"Password", checkCritical, "none given", "pass -password or -password-file, or log in with -auth iam"})
+	case passwordFile != "":
+		if err := loadPasswordFile(); err != nil {
+			ds = append(ds, diagnosis{"Password file", checkCritical, err.Error(), "check the path and that this user can read it"})
+		} else {
+			ds = append(ds, diagnosis{"Password file", checkOK, passwordFile, ""})
+		}
+	}
+	if err := checkActionUser(); err != nil {
+		ds = append(ds, diagnosis{"Action account", checkCritical, err.Error(), ""})
+	}
+	if _, err := tlsPolicy(); err != nil {
+		ds = append(ds, diagnosis{"TLS settings", checkCritical, err.Error(), ""})
+	}
+
+	ds = append(ds, diagnoseAWS())
+	if discoverRDS || auroraCluster != "" {
+		d, found := discoverDoctorTargets()
+		ds = append(ds, d)
+		targets = append(targets, found...)
+	}
+	if len(targets) == 0 {
+		ds = append(ds, diagnosis{"Replicas", checkCritical, "none given", "pass -host, -config, -discover-rds, or -aurora-cluster"})
+	}
+	if user == "" {
+		ds = append(ds, diagnosis{"User", checkCritical, "no -user", ""})
+	}
+	return ds, targets
+}
+
+// Check that AWS credentials are found and accepted, when the flags use AWS
+func diagnoseAWS() diagnosis {
+	const name = "AWS credentials"
+	if authMode != "iam" && !discoverRDS && auroraCluster == "" && !rdsMetadata && awsProfile == "" && awsRoleARN == "" {
+		return diagnosis{name, diagnosisSkipped, "not used by these flags", ""}
+	}
+	cfg, err := loadAWSConfig(awsRegion)
+	if err != nil {
+		return diagnosis{name, checkCritical, err.Error(), "check -aws-profile and ~/.aws/config"}
+	}
+	if cfg.Region == "" {
+		// STS answers in every region; the call only needs one
+		cfg.Region = "us-east-1"
+	}
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	out, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return diagnosis{name, checkCritical, err.Error(),
+			"set AWS_PROFILE or -aws-profile, give the host an instance or pod role, or check the trust policy of -aws-role-arn; a skewed clock also fails signed requests"}
+	}
+	return diagnosis{name, checkOK, aws.ToString(out.Arn), ""}
+}
+
+// List the replicas RDS discovery would monitor now
+func discoverDoctorTargets() (diagnosis, []doctorTarget) {
+	const name = "RDS discovery"
+	d, err := newRDSDiscovery(discoverTags, sourceInstance, auroraCluster)
+	if err != nil {
+		return diagnosis{name, checkCritical, err.Error(), ""}, nil
+	}
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	instances, err := d.findReplicas(ctx)
+	if err != nil {
+		return diagnosis{name, checkCritical, err.Error(), "the AWS identity needs rds:DescribeDBInstances"}, nil
+	}
+	var targets []doctorTarget
+	for _, inst := range instances {
+		if inst.Endpoint == nil {
+			continue
+		}
+		targets = append(targets, doctorTarget{aws.ToString(inst.DBInstanceIdentifier), aws.ToString(inst.Endpoint.Address), int(aws.ToInt32(inst.Endpoint.Port))})
+	}
+	if len(targets) == 0 {
+		return diagnosis{name, checkWarning, "no matching replicas", "check -tag, -source-instance, or -aurora-cluster"}, nil
+	}
+	return diagnosis{name, checkOK, fmt.Sprintf("%d replicas", len(targets)), ""}, targets
+}
+
+// Check one replica step by step, skipping what depends on a failed step
+func diagnoseReplica(t doctorTarget) []diagnosis {
+	addr := net.JoinHostPort(t.host, strconv.Itoa(t.port))
+	skipRest := func(ds []diagnosis, reason string, names ...string) []diagnosis {
+		for _, name := range names {
+			ds = append(ds, diagnosis{name, diagnosisSkipped, reason, ""})
+		}
+		return ds
+	}
+	later := []string{"Engine", "TLS", "Login", "Privileges", "RDS procedures", "Clock skew"}
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", addr, queryTimeout)
+	if err != nil {
+		ds := []diagnosis{{"Connectivity", checkCritical, err.Error(), dialHint(err)}}
+		return skipRest(ds, "not reachable", later...)
+	}
+	conn.Close()
+	ds := []diagnosis{{"Connectivity", checkOK, fmt.Sprintf("reachable in %s", time.Since(start).Round(time.Millisecond)), ""}}
+
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	engine, err := replicaEngine(ctx, addr)
+	if err != nil {
+		ds = append(ds, diagnosis{"Engine", checkCritical, err.Error(), "the port answers but not as MySQL or PostgreSQL; check -port, or set -engine"})
+		return skipRest(ds, "no engine", later[1:]...)
+	}
+	ds = append(ds, diagnosis{"Engine", checkOK, engine.Name, ""})
+
+	db, err := openDB(ctx, t.host, t.port, engine, account{user: user, password: currentPassword()})
+	if err != nil {
+		if isTLSError(err) {
+			ds = append(ds, diagnosis{"TLS", checkCritical, err.Error(), tlsHint(err, t.host)})
+			return skipRest(ds, "no TLS connection", later[2:]...)
+		}
+		ds = append(ds, diagnosis{"TLS", diagnosisSkipped, "not checked without a login", ""})
+		hint := authHint(err, t.host)
+		if hint == "" {
+			hint = "check -user and the password, and that the account may log in from this host"
+		}
+		ds = append(ds, diagnosis{"Login", checkCritical, err.Error(), hint})
+		return skipRest(ds, "not logged in", later[3:]...)
+	}
+	defer db.Close()
+	ds = append(ds, diagnoseTLS(ctx, db, engine), diagnosis{"Login", checkOK, "as " + user, ""})
+
+	actionDB := db
+	if actionUser != "" && engine.Name != "postgres" {
+		login, err := actionAccount()
+		if err == nil {
+			actionDB, err = openDB(ctx, t.host, t.port, engine, login)
+		}
+		if err != nil {
+			ds = append(ds, diagnosis{"Action login", checkCritical, err.Error(), authHint(err, t.host)})
+			return append(ds, diagnoseClock(ctx, db, engine))
+		}
+		defer actionDB.Close()
+		ds = append(ds, diagnosis{"Action login", checkOK, "as " + actionUser, ""})
+	}
+	ds = append(ds, diagnosePrivileges(ctx, db, actionDB, engine)...)
+	ds = append(ds, diagnoseProcedures(ctx, actionDB, engine), diagnoseClock(ctx, db, engine))
+	return ds
+}
+
+// Advice for a failed TCP connection
+func dialHint(err error) string {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr):
+		return "the host name does not resolve; check -host, and the DNS resolver of a private endpoint"
+	case errors.Is(err, os.ErrDeadlineExceeded) || strings.Contains(err.Error(), "i/o timeout"):
+		return "nothing answered; check security groups, network ACLs, and routing between this host and the replica"
+	case strings.Contains(err.Error(), "connection refused"):
+		return "nothing listens on the port; check -port"
+	}
+	return ""
+}
+
+// Whether a failed login failed in the TLS handshake, before authenticating
+func isTLSError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var unknownCA x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verifyErr) || errors.As(err, &recordErr) || errors.As(err, &unknownCA) ||
+		errors.As(err, &hostErr) || errors.As(err, &invalidErr) ||
+		errors.Is(err, mysql.ErrNoTLS) || strings.Contains(err.Error(), "tls: ")
+}
+
+func tlsHint(err error, host string) string {
+	var hostErr x509.HostnameError
+	switch {
+	case errors.Is(err, mysql.ErrNoTLS):
+		return "the server does not offer TLS; enable it there, or relax -db-tls"
+	case errors.As(err, &hostErr):
+		return "the certificate does not name " + host + "; connect by the name it was issued for, e.g. the RDS endpoint rather than a CNAME"
+	}
+	if hint := authHint(err, host); hint != "" {
+		return hint
+	}
+	return "check -db-tls, -db-tls-ca, and -tls-min-version against what the server supports"
+}
+
+// Report whether the login's connection is encrypted, and with what
+func diagnoseTLS(ctx context.Context, db *sql.DB, engine monitor.Engine) diagnosis {
+	const name = "TLS"
+	var cipher string
+	var err error
+	if engine.Driver == "pgx" {
+		err = db.QueryRowContext(ctx, "SELECT coalesce((SELECT cipher FROM pg_stat_ssl WHERE pid = pg_backend_pid()), '')").Scan(&cipher)
+	} else {
+		var variable string
+		err = db.QueryRowContext(ctx, "SHOW SESSION STATUS LIKE 'Ssl_cipher'").Scan(&variable, &cipher)
+	}
+	switch {
+	case err != nil:
+		return diagnosis{name, checkUnknown, "cannot tell whether the connection is encrypted: " + err.Error(), ""}
+	case cipher != "":
+		return diagnosis{name, checkOK, fmt.Sprintf("encrypted with %s (-db-tls %s)", cipher, dbTLS), ""}
+	case dbTLS == "off":
+		return diagnosis{name, checkOK, "not encrypted, as -db-tls off asks", ""}
+	}
+	return diagnosis{name, checkWarning, fmt.Sprintf("not encrypted (-db-tls %s)", dbTLS),
+		"the password and replica status cross the network in clear text; use -db-tls required, with -db-tls-ca for RDS"}
+}
+
+// Check the grants of the accounts against what watch would need
+func diagnosePrivileges(ctx context.Context, db, actionDB *sql.DB, engine monitor.Engine) []diagnosis {
+	if engine.Name == "postgres" {
+		return []diagnosis{{"Privileges", diagnosisSkipped, "not checked on PostgreSQL", ""}}
+	}
+	if actionDB == db {
+		return []diagnosis{diagnoseGrants(ctx, "Privileges", db, privilegeNeeds(engine, true, true))}
+	}
+	ds := []diagnosis{diagnoseGrants(ctx, "Privileges", db, privilegeNeeds(engine, true, false))}
+	if needs := privilegeNeeds(engine, false, true); len(needs) > 0 {
+		ds = append(ds, diagnoseGrants(ctx, "Action privileges", actionDB, needs))
+	}
+	return ds
+}
+
+func diagnoseGrants(ctx context.Context, name string, db *sql.DB, needs []privilegeNeed) diagnosis {
+	grants, err := monitor.ReadGrants(ctx, db)
+	if err != nil {
+		return diagnosis{name, checkUnknown, "cannot read the account's grants: " + err.Error(), ""}
+	}
+	var missing, grantsToAdd []string
+	for _, n := range needs {
+		if !n.metBy(grants) {
+			missing = append(missing, fmt.Sprintf("%s (%s)", n.privileges[0], n.purpose))
+			grantsToAdd = append(grantsToAdd, n.privileges[0].String())
+		}
+	}
+	if len(missing) > 0 {
+		return diagnosis{name, checkCritical, grants.User + " lacks " + strings.Join(missing, ", "),
+			"GRANT " + strings.Join(grantsToAdd, "; GRANT ") + " TO the account"}
+	}
+	if excess := excessGrants(grants, needs); len(excess) > 0 {
+		return diagnosis{name, checkWarning, fmt.Sprintf("%s has more than needed: %s", grants.User, strings.Join(excess, "; ")),
+			"revoke what the monitor does not use; see Privileges in the README"}
+	}
+	return diagnosis{name, checkOK, grants.User + " has exactly the privileges needed", ""}
+}
+
+// Check that the RDS procedures skipping errors and starting replication can
+// be called; outside RDS, only MySQL cannot do without them
+func diagnoseProcedures(ctx context.Context, db *sql.DB, engine monitor.Engine) diagnosis {
+	const name = "RDS procedures"
+	if engine.Name != "mysql" && engine.Name != "mariadb" {
+		return diagnosis{name, diagnosisSkipped, "not used on " + engine.Name, ""}
+	}
+	rows, err := db.QueryContext(ctx, "SELECT ROUTINE_NAME FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = 'mysql' AND ROUTINE_NAME IN ('rds_skip_repl_error', 'rds_start_replication')")
+	if err != nil {
+		return diagnosis{name, checkUnknown, "cannot list the procedures: " + err.Error(), ""}
+	}
+	defer rows.Close()
+	found := map[string]bool{}
+	for rows.Next() {
+		var routine string
+		if rows.Scan(&routine) == nil {
+			found[strings.ToLower(routine)] = true
+		}
+	}
+	switch {
+	case found["rds_skip_repl_error"] && found["rds_start_replication"]:
+		return diagnosis{name, checkOK, "mysql.rds_skip_repl_error and mysql.rds_start_replication", ""}
+	case found["rds_skip_repl_error"]:
+		return diagnosis{name, checkOK, "mysql.rds_skip_repl_error; replication starts with START REPLICA", ""}
+	case readOnly:
+		return diagnosis{name, checkOK, "none, and none needed with -read-only", ""}
+	case engine.Name == "mariadb":
+		return diagnosis{name, checkOK, "none; errors are skipped with sql_slave_skip_counter", ""}
+	}
+	return diagnosis{name, checkCritical, "mysql.rds_skip_repl_error is missing or not executable",
+		"outside RDS, MySQL errors cannot be skipped; on RDS, GRANT EXECUTE ON PROCEDURE mysql.rds_skip_repl_error, or run with -read-only"}
+}
+
+// Compare the replica's clock with this host's, allowing for the round trip
+func diagnoseClock(ctx context.Context, db *sql.DB, engine monitor.Engine) diagnosis {
+	const name = "Clock skew"
+	start := time.Now()
+	var server time.Time
+	var err error
+	if engine.Driver == "pgx" {
+		err = db.QueryRowContext(ctx, "SELECT now()").Scan(&server)
+	} else {
+		var text string
+		if err = db.QueryRowContext(ctx, "SELECT UTC_TIMESTAMP(6)").Scan(&text); err == nil {
+			server, err = time.Parse("2006-01-02 15:04:05.999999", text)
+		}
+	}
+	if err != nil {
+		return diagnosis{name, checkUnknown, "cannot read the server's clock: " + err.Error(), ""}
+	}
+	rtt := time.Since(start)
+	skew := server.Sub(start.Add(rtt / 2))
+	detail := fmt.Sprintf("%s (±%s)", skew.Round(time.Millisecond), (rtt / 2).Round(time.Millisecond))
+	hint := "synchronize both clocks with NTP, e.g. chrony with the Amazon Time Sync Service; times in alerts and history disagree with the server's"
+	switch {
+	case skew.Abs()-rtt/2 > clockSkewCritical:
+		return diagnosis{name, checkCritical, detail, hint + ", and signed AWS requests and IAM tokens are rejected"}
+	case skew.Abs()-rtt/2 > clockSkewWarning:
+		return diagnosis{name, checkWarning, detail, hint}
+	}
+	return diagnosis{name, checkOK, detail, ""}
+}
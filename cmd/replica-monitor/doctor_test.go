@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiagnoseUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().(*net.TCPAddr)
+	l.Close()
+	saved := queryTimeout
+	queryTimeout = time.Second
+	t.Cleanup(func() { queryTimeout = saved })
+
+	ds := diagnoseReplica(doctorTarget{"replica-1", "127.0.0.1", addr.Port})
+	if ds[0].name != "Connectivity" || ds[0].state != checkCritical || !strings.Contains(ds[0].hint, "-port") {
+		t.Fatalf("first diagnosis = %+v, want a refused connection", ds[0])
+	}
+	for _, d := range ds[1:] {
+		if d.state != diagnosisSkipped {
+			t.Errorf("%s ran after the connection failed: %+v", d.name, d)
+		}
+	}
+}
+
+func TestDiagnoseNotADatabase(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+			conn.Close()
+		}
+	}()
+	saved := queryTimeout
+	queryTimeout = time.Second
+	t.Cleanup(func() { queryTimeout = saved })
+
+	ds := diagnoseReplica(doctorTarget{"replica-1", "127.0.0.1", l.Addr().(*net.TCPAddr).Port})
+	if ds[0].state != checkOK || ds[1].name != "Engine" || ds[1].state != checkCritical {
+		t.Fatalf("diagnoses = %+v, want reachable without a database engine", ds[:2])
+	}
+	if len(ds) != 7 {
+		t.Errorf("%d diagnoses, want every check listed", len(ds))
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// How lag, ETAs, and summaries print durations: verbose ("3h 42m 10s"), compact
+// ("3h42m"), or iso (ISO 8601, "PT3H42M10S", with RFC 3339 ETA timestamps)
+var durationStyle = "verbose"
+
+var durationStyles = []string{"verbose", "compact", "iso"}
+
+// Format a duration in seconds in the -duration-style; sub-second lag (Aurora) is
+// shown in milliseconds
+func formatDuration(seconds float64) string {
+	return formatDurationAs(durationStyle, seconds)
+}
+
+// The line format needs durations without spaces, so verbose becomes compact there
+func formatLineDuration(seconds float64) string {
+	if durationStyle == "verbose" {
+		return formatDurationAs("compact", seconds)
+	}
+	return formatDuration(seconds)
+}
+
+func formatDurationAs(style string, seconds float64) string {
+	if style == "iso" {
+		return isoDuration(seconds)
+	}
+	if seconds > 0 && seconds < 1 {
+		return fmt.Sprintf("%.0fms", seconds*1000)
+	}
+	s := int(seconds)
+	days, hours, minutes, secs := s/86400, s%86400/3600, s%3600/60, s%60
+
+	if style == "compact" {
+		switch {
+		case days > 0:
+			return fmt.Sprintf("%dd%dh", days, hours)
+		case hours > 0:
+			return fmt.Sprintf("%dh%dm", hours, minutes)
+		case minutes > 0:
+			return fmt.Sprintf("%dm%ds", minutes, secs)
+		}
+		return fmt.Sprintf("%ds", secs)
+	}
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm %ds", days, hours, minutes, secs)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm %ds", hours, minutes, secs)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, secs)
+	}
+	return fmt.Sprintf("%ds", secs)
+}
+
+// ISO 8601 duration such as P1DT2H3M4S, with fractional seconds under a minute
+func isoDuration(seconds float64) string {
+	if seconds < 60 {
+		return "PT" + strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.3f", seconds), "0"), ".") + "S"
+	}
+	s := int(seconds)
+	days, hours, minutes, secs := s/86400, s%86400/3600, s%3600/60, s%60
+	var b strings.Builder
+	b.WriteString("P")
+	if days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+	}
+	if hours > 0 || minutes > 0 || secs > 0 {
+		b.WriteString("T")
+		if hours > 0 {
+			fmt.Fprintf(&b, "%dH", hours)
+		}
+		if minutes > 0 {
+			fmt.Fprintf(&b, "%dM", minutes)
+		}
+		if secs > 0 {
+			fmt.Fprintf(&b, "%dS", secs)
+		}
+	}
+	return b.String()
+}
+
+// Point in time at which a replica is expected to catch up
+func formatETA(t time.Time) string {
+	if durationStyle == "iso" {
+		return t.Format(time.RFC3339)
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
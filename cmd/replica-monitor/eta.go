@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Short-term rates kept for the ETA band's variance, from -eta-window
+var etaRateWindow = 12
+
+// Print the catch-up window, e.g. "ETA: 2h 10m 0s – 3h 5m 0s (... – ...)"
+func printETABand(w io.Writer, s *monitor.LagStats, lag float64, now time.Time) {
+	earliest, latest, ok := s.ETAWindow(lag, now)
+	if !ok {
+		return
+	}
+	soonest := formatDuration(earliest.Sub(now).Seconds())
+	if latest.IsZero() {
+		fmt.Fprintf(w, "  ⏰ ETA: %s at the earliest (%s); at the slowest recent rate it is not catching up\n",
+			soonest, formatETA(earliest))
+		return
+	}
+	if latest.Sub(earliest) < time.Second {
+		fmt.Fprintf(w, "  ⏰ ETA: %s (%s)\n", soonest, formatETA(earliest))
+		return
+	}
+	fmt.Fprintf(w, "  ⏰ ETA: %s – %s (%s – %s)\n",
+		soonest, formatDuration(latest.Sub(now).Seconds()), formatETA(earliest), formatETA(latest))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+
+	"replica-monitor/pkg/export"
+)
+
+// EventBridge bus name or ARN from -eventbridge-bus; journal events are put on it
+var eventBridgeBus string
+
+// The source of every event put on the bus; the detail type is the event name
+const eventBridgeSource = "replica-monitor"
+
+var eventBridge *export.Batcher[journalEvent]
+
+// PutEvents accepts up to 10 entries per call
+func startEventBridge() error {
+	cfg, err := loadAWSConfig("")
+	if err != nil {
+		return err
+	}
+	client := eventbridge.NewFromConfig(cfg)
+	eventBridge = export.NewBatcher("eventbridge", 10, func(ctx context.Context, events []journalEvent) error {
+		entries := make([]types.PutEventsRequestEntry, 0, len(events))
+		for _, e := range events {
+			detail, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, types.PutEventsRequestEntry{
+				EventBusName: aws.String(eventBridgeBus),
+				Source:       aws.String(eventBridgeSource),
+				DetailType:   aws.String(e.Event),
+				Detail:       aws.String(string(detail)),
+				Time:         aws.Time(e.Time),
+			})
+		}
+		out, err := client.PutEvents(ctx, &eventbridge.PutEventsInput{Entries: entries})
+		if err != nil {
+			return err
+		}
+		if out.FailedEntryCount > 0 {
+			return fmt.Errorf("%d of %d events rejected", out.FailedEntryCount, len(entries))
+		}
+		return nil
+	})
+	return nil
+}
+
+// Put a journal event on the bus; alert delivery results stay in the journal only
+func publishEvent(event journalEvent) {
+	if eventBridge == nil || event.Event == "alert_delivered" || event.Event == "alert_failed" {
+		return
+	}
+	eventBridge.Add(event)
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+)
+
+func newEventLogHandler(source string) (slog.Handler, error) {
+	return nil, errors.New("the Windows Event Log is only available on Windows")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// Event IDs by severity, so Event Viewer filters can pick out alerts and errors
+const (
+	eventIDWarning = 2
+	eventIDError   = 3
+)
+
+// Writes warnings (including alerts) and errors to the Application log
+type eventLogHandler struct {
+	log    *eventlog.Log
+	attrs  []slog.Attr
+	prefix string
+}
+
+// Register the source if it is not yet (which needs administrator rights once)
+// and open it
+func newEventLogHandler(source string) (slog.Handler, error) {
+	err := eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info)
+	if err != nil && !strings.Contains(err.Error(), "registry key already exists") {
+		slog.Warn("Could not register event source; run once as administrator", "source", source, "err", err)
+	}
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLogHandler{log: l}, nil
+}
+
+func (h *eventLogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= max(slog.LevelWarn, logLevel.Level())
+}
+
+func (h *eventLogHandler) Handle(_ context.Context, rec slog.Record) error {
+	var b strings.Builder
+	b.WriteString(rec.Message)
+	add := func(a slog.Attr) {
+		if !a.Equal(slog.Attr{}) {
+			fmt.Fprintf(&b, "\r\n%s: %s", h.prefix+a.Key, a.Value.String())
+		}
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	rec.Attrs(func(a slog.Attr) bool {
+		add(a)
+		return true
+	})
+	if rec.Level >= slog.LevelError {
+		return h.log.Error(eventIDError, b.String())
+	}
+	return h.log.Warning(eventIDWarning, b.String())
+}
+
+func (h *eventLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &next
+}
+
+func (h *eventLogHandler) WithGroup(name string) slog.Handler {
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
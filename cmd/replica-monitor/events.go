@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// JSON-lines file of discrete events from -events-file, kept apart from the periodic -history samples
+var eventsFile string
+
+// One discrete event: a state change, threshold crossing, skip, or alert and its delivery
+type journalEvent struct {
+	Time       time.Time         `json:"time"`
+	Replica    string            `json:"replica"`
+	Host       string            `json:"host"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Session    string            `json:"session"`
+	Incident   string            `json:"incident,omitempty"`
+	Event      string            `json:"event"`
+	Alert      string            `json:"alert,omitempty"` // the alert's event for alert_* events
+	Message    string            `json:"message,omitempty"`
+	LagSeconds *float64          `json:"lag_seconds,omitempty"`
+	// How long the state that just ended had lasted, for transitions
+	PreviousStateSeconds *float64 `json:"previous_state_seconds,omitempty"`
+}
+
+var (
+	eventsMu  sync.Mutex
+	eventsOut *os.File
+)
+
+func openEvents(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	eventsOut = f
+	return nil
+}
+
+func closeEvents() {
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+	if eventsOut != nil {
+		eventsOut.Close()
+		eventsOut = nil
+	}
+}
+
+// Append an event for a replica to the events file, if one is open. A positive
+// lasted is recorded as the duration of the state that ended.
+func recordEvent(r *replica, event journalEvent, lasted time.Duration) {
+	writeEvent(newEvent(r, event, lasted))
+}
+
+// Fill in the event's time and the replica's identity and current state
+func newEvent(r *replica, event journalEvent, lasted time.Duration) journalEvent {
+	event.Time = time.Now()
+	event.Message = redact(event.Message)
+	event.Replica = displayName(r)
+	event.Host = r.host
+	event.Labels = r.labels
+	event.Session = sessionID
+	event.Incident = r.incident
+	if r.lagKnown {
+		lag := r.lagSeconds
+		event.LagSeconds = &lag
+	}
+	if lasted > 0 {
+		seconds := lasted.Seconds()
+		event.PreviousStateSeconds = &seconds
+	}
+	return event
+}
+
+// Append a complete event to the events file, if one is open, and publish it;
+// safe from any goroutine
+func writeEvent(event journalEvent) {
+	publishEvent(event)
+	writeStreams("event", event.Host, event)
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+	if eventsOut == nil {
+		return
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Failed to encode event", "event", event.Event, "err", err)
+		return
+	}
+	if _, err := eventsOut.Write(append(line, '\n')); err != nil {
+		slog.Error("Failed to write event", "event", event.Event, "err", err)
+	}
+}
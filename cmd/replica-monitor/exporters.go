@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+
+	"replica-monitor/pkg/export"
+)
+
+// Every sample also goes to each of these that is enabled, besides -history
+var (
+	prometheusMetrics   bool
+	statsdAddr          string
+	statsdPrefix        string
+	cloudWatchNamespace string
+	influxURL           string
+	influxToken         string
+)
+
+var (
+	exporters *export.Fanout
+	// Latest samples for /metrics on -http, with -prometheus
+	prometheus *export.Prometheus
+)
+
+// Open the enabled exporters; each gets its own queue, so one that is down
+// never holds up the others or the monitoring loop
+func startExporters() error {
+	var list []export.Exporter
+	if history != "" {
+		f, err := export.OpenFile(history)
+		if err != nil {
+			return fmt.Errorf("open history file: %w", err)
+		}
+		list = append(list, f)
+	}
+	if prometheusMetrics {
+		if httpAddr == "" {
+			return fmt.Errorf("-prometheus needs -http")
+		}
+		prometheus = export.NewPrometheus()
+		list = append(list, prometheus)
+	}
+	if statsdAddr != "" {
+		s, err := export.DialStatsD(statsdAddr, statsdPrefix)
+		if err != nil {
+			return fmt.Errorf("set up StatsD: %w", err)
+		}
+		list = append(list, s)
+	}
+	if cloudWatchNamespace != "" {
+		cfg, err := loadAWSConfig("")
+		if err != nil {
+			return fmt.Errorf("set up CloudWatch metrics: %w", err)
+		}
+		list = append(list, &export.CloudWatch{Client: cloudwatch.NewFromConfig(cfg), Namespace: cloudWatchNamespace})
+	}
+	if influxURL != "" {
+		list = append(list, &export.Influx{URL: influxURL, Token: influxToken})
+	}
+	if len(list) > 0 {
+		exporters = export.NewFanout(list...)
+	}
+	return nil
+}
+
+func closeExporters() {
+	if exporters != nil {
+		exporters.Close(10 * time.Second)
+	}
+}
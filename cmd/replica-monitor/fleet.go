@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Print every monitored replica's lag, rates, and ETA side by side, flagging the straggler
+func printReplicaComparison(out io.Writer, replicas []*replica) {
+	var straggler *replica
+	for _, r := range replicas {
+		if r.lagKnown && (straggler == nil || r.lagSeconds > straggler.lagSeconds) {
+			straggler = r
+		}
+	}
+
+	fmt.Fprintf(out, "\n[%s] Replica Comparison:\n", time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Fprintln(out, strings.Repeat("=", 96))
+	fmt.Fprintf(out, "%-28s %-14s %14s %12s %12s %12s\n", "Replica", "Region", "Lag", "Instant", "Average", "Average ETA")
+	for _, r := range replicas {
+		lag := "NULL"
+		if r.lagKnown {
+			lag = formatDuration(r.lagSeconds)
+		}
+		region := r.region
+		if region == "" {
+			region = "-"
+		}
+		eta := "-"
+		if r.lagKnown && r.lagSeconds > 0 && r.stats.AverageRate < 0 {
+			eta = formatDuration(r.lagSeconds / -r.stats.AverageRate)
+		}
+		marker := ""
+		if r == straggler && len(replicas) > 1 && r.lagSeconds > 0 {
+			marker = "  🐢 straggler"
+		}
+		fmt.Fprintf(out, "%-28s %-14s %14s %12s %12s %12s%s\n",
+			truncate(displayName(r), 28), region, lag,
+			formatRate(r.stats.Rate), formatRate(r.stats.AverageRate), eta, marker)
+	}
+	fmt.Fprintln(out)
+}
+
+// Print a one-line overview of the whole fleet for this cycle
+func printFleetSummary(out io.Writer, replicas []*replica) {
+	var worst *replica
+	behind, stopped, unknown := 0, 0, 0
+	for _, r := range replicas {
+		if !r.lagKnown {
+			unknown++
+		} else {
+			if worst == nil || r.lagSeconds > worst.lagSeconds {
+				worst = r
+			}
+			if r.lagSeconds > replicaLagThreshold(r).Seconds() {
+				behind++
+			}
+		}
+		if (r.ioRunning != "" && r.ioRunning != "Yes") || (r.sqlRunning != "" && r.sqlRunning != "Yes") {
+			stopped++
+		}
+	}
+
+	worstLag := "n/a"
+	if worst != nil {
+		worstLag = fmt.Sprintf("%s (%s)", formatDuration(worst.lagSeconds), displayName(worst))
+	}
+	status := "✅"
+	if behind > 0 || stopped > 0 {
+		status = "⚠️ "
+	}
+	// Tenants with thresholds of their own make a single figure misleading
+	threshold := ">" + lagThreshold.String()
+	for _, r := range replicas {
+		if replicaLagThreshold(r) != lagThreshold {
+			threshold = "their threshold"
+			break
+		}
+	}
+	fmt.Fprintf(out, "%s Fleet: %d replicas | worst lag %s | %d behind %s | %d with stopped threads | %d without lag\n\n",
+		status, len(replicas), worstLag, behind, threshold, stopped, unknown)
+}
+
+func displayName(r *replica) string {
+	if r.name != "" {
+		return r.name
+	}
+	return r.host
+}
+
+// Rate of lag change; negative means catching up, zero means no data yet
+func formatRate(rate float64) string {
+	if rate == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.2f/s", rate)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-1] + "…"
+}
+
+// Render values as a Unicode block sparkline scaled to their own range
+func sparkline(values []float64) string {
+	const blocks = "▁▂▃▄▅▆▇█"
+	ticks := []rune(blocks)
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	var b strings.Builder
+	for _, v := range values {
+		idx := 0
+		if hi > lo {
+			idx = int((v - lo) / (hi - lo) * float64(len(ticks)-1))
+		}
+		b.WriteRune(ticks[idx])
+	}
+	return b.String()
+}
+
+// Print the recent lag samples as a sparkline with its direction under the summary
+func printLagTrend(out io.Writer, r *replica) {
+	if sparklineWidth <= 0 || r.recentLags.Len() < 2 {
+		return
+	}
+	lags := r.recentLags.Values()
+	lags = lags[max(0, len(lags)-sparklineWidth):]
+	first, last := lags[0], lags[len(lags)-1]
+	marker := "➖"
+	if last < first {
+		marker = "📉"
+	} else if last > first {
+		marker = "📈"
+	}
+	fmt.Fprintf(out, "%s Lag trend (last %d polls): %s  %s → %s\n",
+		marker, len(lags), sparkline(lags), formatDuration(first), formatDuration(last))
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+// GraphQL view of the monitored fleet, backed by the published status snapshot
+const graphqlSchema = `
+schema {
+	query: Query
+}
+
+type Query {
+	# Replicas matching every given filter; label takes "key=value"
+	replicas(minLag: Float, maxLag: Float, label: String, stopped: Boolean, errorMatched: Boolean): [Replica!]!
+	replica(name: String!): Replica
+	fleet: Fleet!
+}
+
+type Fleet {
+	replicas: Int!
+	worstLag: Float
+	worstReplica: String
+	stopped: Int!
+	errorMatched: Int!
+	generatedAt: String
+}
+
+type Replica {
+	name: String!
+	host: String!
+	port: Int!
+	region: String
+	labels: [Label!]!
+	polledAt: String
+	# NULL when the replica reported NULL or could not be polled
+	secondsBehind: Float
+	ioRunning: String
+	sqlRunning: String
+	ratePerSecond: Float!
+	averageRatePerSecond: Float!
+	instantEta: String
+	averageEta: String
+	errorMatched: Boolean!
+	stopped: Boolean!
+	lastSqlError: String
+	lastIoError: String
+	lastAlert: Alert
+	# Columns of the last SHOW REPLICA STATUS row, optionally just one
+	status(field: String): [StatusField!]!
+}
+
+type Label {
+	key: String!
+	value: String!
+}
+
+type Alert {
+	event: String!
+	message: String!
+	time: String!
+}
+
+type StatusField {
+	name: String!
+	value: String!
+}
+`
+
+func graphqlHandler() http.Handler {
+	schema := graphql.MustParseSchema(graphqlSchema, &graphqlQuery{}, graphql.UseFieldResolvers())
+	return &relay.Handler{Schema: schema}
+}
+
+type graphqlQuery struct{}
+
+func currentSnapshot() statusResponse {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	return statusSnapshot
+}
+
+func (q *graphqlQuery) Replicas(args struct {
+	MinLag       *float64
+	MaxLag       *float64
+	Label        *string
+	Stopped      *bool
+	ErrorMatched *bool
+}) []*graphqlReplica {
+	var result []*graphqlReplica
+	for _, rs := range currentSnapshot().Replicas {
+		r := &graphqlReplica{rs}
+		if args.MinLag != nil && (rs.SecondsBehind == nil || *rs.SecondsBehind < *args.MinLag) {
+			continue
+		}
+		if args.MaxLag != nil && (rs.SecondsBehind == nil || *rs.SecondsBehind > *args.MaxLag) {
+			continue
+		}
+		if args.Label != nil {
+			key, val, _ := strings.Cut(*args.Label, "=")
+			if v, ok := rs.Labels[key]; !ok || v != val {
+				continue
+			}
+		}
+		if args.Stopped != nil && r.Stopped() != *args.Stopped {
+			continue
+		}
+		if args.ErrorMatched != nil && rs.ErrorMatched != *args.ErrorMatched {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+func (q *graphqlQuery) Replica(args struct{ Name string }) *graphqlReplica {
+	for _, rs := range currentSnapshot().Replicas {
+		if rs.Name == args.Name {
+			return &graphqlReplica{rs}
+		}
+	}
+	return nil
+}
+
+func (q *graphqlQuery) Fleet() *graphqlFleet {
+	snapshot := currentSnapshot()
+	f := &graphqlFleet{replicas: int32(len(snapshot.Replicas))}
+	if !snapshot.GeneratedAt.IsZero() {
+		generated := snapshot.GeneratedAt.Format(time.RFC3339)
+		f.generatedAt = &generated
+	}
+	for _, rs := range snapshot.Replicas {
+		r := &graphqlReplica{rs}
+		if r.Stopped() {
+			f.stopped++
+		}
+		if rs.ErrorMatched {
+			f.errorMatched++
+		}
+		if rs.SecondsBehind != nil && (f.worstLag == nil || *rs.SecondsBehind > *f.worstLag) {
+			lag, name := *rs.SecondsBehind, rs.Name
+			f.worstLag, f.worstReplica = &lag, &name
+		}
+	}
+	return f
+}
+
+type graphqlFleet struct {
+	replicas     int32
+	worstLag     *float64
+	worstReplica *string
+	stopped      int32
+	errorMatched int32
+	generatedAt  *string
+}
+
+func (f *graphqlFleet) Replicas() int32       { return f.replicas }
+func (f *graphqlFleet) WorstLag() *float64    { return f.worstLag }
+func (f *graphqlFleet) WorstReplica() *string { return f.worstReplica }
+func (f *graphqlFleet) Stopped() int32        { return f.stopped }
+func (f *graphqlFleet) ErrorMatched() int32   { return f.errorMatched }
+func (f *graphqlFleet) GeneratedAt() *string  { return f.generatedAt }
+
+type graphqlReplica struct {
+	rs replicaStatus
+}
+
+func (r *graphqlReplica) Name() string                  { return r.rs.Name }
+func (r *graphqlReplica) Host() string                  { return r.rs.Host }
+func (r *graphqlReplica) Port() int32                   { return int32(r.rs.Port) }
+func (r *graphqlReplica) Region() *string               { return optionalString(r.rs.Region) }
+func (r *graphqlReplica) PolledAt() *string             { return optionalTime(&r.rs.PolledAt) }
+func (r *graphqlReplica) SecondsBehind() *float64       { return r.rs.SecondsBehind }
+func (r *graphqlReplica) IoRunning() *string            { return optionalString(r.rs.IORunning) }
+func (r *graphqlReplica) SqlRunning() *string           { return optionalString(r.rs.SQLRunning) }
+func (r *graphqlReplica) RatePerSecond() float64        { return r.rs.RatePerSecond }
+func (r *graphqlReplica) AverageRatePerSecond() float64 { return r.rs.AverageRatePerSecond }
+func (r *graphqlReplica) InstantEta() *string           { return optionalTime(r.rs.InstantETA) }
+func (r *graphqlReplica) AverageEta() *string           { return optionalTime(r.rs.AverageETA) }
+func (r *graphqlReplica) ErrorMatched() bool            { return r.rs.ErrorMatched }
+func (r *graphqlReplica) LastSqlError() *string         { return optionalString(r.rs.Status["Last_SQL_Error"]) }
+func (r *graphqlReplica) LastIoError() *string          { return optionalString(r.rs.Status["Last_IO_Error"]) }
+
+func (r *graphqlReplica) Stopped() bool {
+	return (r.rs.IORunning != "" && r.rs.IORunning != "Yes") || (r.rs.SQLRunning != "" && r.rs.SQLRunning != "Yes")
+}
+
+func (r *graphqlReplica) Labels() []*graphqlKeyValue {
+	return sortedKeyValues(r.rs.Labels, "")
+}
+
+func (r *graphqlReplica) Status(args struct{ Field *string }) []*graphqlKeyValue {
+	only := ""
+	if args.Field != nil {
+		only = *args.Field
+	}
+	return sortedKeyValues(r.rs.Status, only)
+}
+
+func (r *graphqlReplica) LastAlert() *graphqlAlert {
+	if r.rs.LastAlert == nil {
+		return nil
+	}
+	return &graphqlAlert{r.rs.LastAlert}
+}
+
+type graphqlAlert struct {
+	a *alertState
+}
+
+func (a *graphqlAlert) Event() string   { return a.a.Event }
+func (a *graphqlAlert) Message() string { return a.a.Message }
+func (a *graphqlAlert) Time() string    { return a.a.Time.Format(time.RFC3339) }
+
+// Serves both Label (key/value) and StatusField (name/value)
+type graphqlKeyValue struct {
+	key, value string
+}
+
+func (kv *graphqlKeyValue) Key() string   { return kv.key }
+func (kv *graphqlKeyValue) Name() string  { return kv.key }
+func (kv *graphqlKeyValue) Value() string { return kv.value }
+
+func sortedKeyValues(m map[string]string, only string) []*graphqlKeyValue {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if only == "" || k == only {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	result := make([]*graphqlKeyValue, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, &graphqlKeyValue{k, m[k]})
+	}
+	return result
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func optionalTime(t *time.Time) *string {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	s := t.Format(time.RFC3339)
+	return &s
+}
@@ -0,0 +1,148 @@
+package main
+
+//go:generate sh -c "cd ../.. && buf generate"
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	apiv1 "replica-monitor/api/v1"
+)
+
+// gRPC implementation of the ReplicaMonitor service, backed by the published status snapshots
+type grpcServer struct {
+	apiv1.UnimplementedReplicaMonitorServer
+}
+
+// Serve the gRPC API in the background, once the address is bound
+func startGRPCServer(addr string) error {
+	tlsConfig, err := serverTLS()
+	if err != nil {
+		return fmt.Errorf("set up TLS for gRPC: %w", err)
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen for gRPC on %s: %w", addr, err)
+	}
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(grpcAuthUnary), grpc.StreamInterceptor(grpcAuthStream)}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	srv := grpc.NewServer(opts...)
+	apiv1.RegisterReplicaMonitorServer(srv, &grpcServer{})
+
+	go func() {
+		slog.Info("Serving gRPC", "addr", addr, "tls", tlsConfig != nil, "client_certs", tlsClientCA != "")
+		if err := srv.Serve(lis); err != nil {
+			fatal("gRPC server failed", "err", err)
+		}
+	}()
+	return nil
+}
+
+func (s *grpcServer) ListReplicas(ctx context.Context, req *apiv1.ListReplicasRequest) (*apiv1.ListReplicasResponse, error) {
+	statusMu.RLock()
+	snapshot := statusSnapshot
+	statusMu.RUnlock()
+
+	resp := &apiv1.ListReplicasResponse{}
+	for _, rs := range snapshot.Replicas {
+		resp.Replicas = append(resp.Replicas, replicaToProto(rs))
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) GetStatus(ctx context.Context, req *apiv1.GetStatusRequest) (*apiv1.ReplicaStatus, error) {
+	statusMu.RLock()
+	snapshot := statusSnapshot
+	statusMu.RUnlock()
+
+	for _, rs := range snapshot.Replicas {
+		if rs.Name == req.GetName() {
+			return statusToProto(rs), nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "replica %q is not being monitored", req.GetName())
+}
+
+func (s *grpcServer) Watch(req *apiv1.WatchRequest, stream apiv1.ReplicaMonitor_WatchServer) error {
+	updates, unsubscribe := subscribeStatus()
+	defer unsubscribe()
+
+	wanted := make(map[string]bool)
+	for _, name := range req.GetNames() {
+		wanted[name] = true
+	}
+	previous := make(map[string]replicaStatus)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case snapshot := <-updates:
+			for _, rs := range snapshot.Replicas {
+				if len(wanted) > 0 && !wanted[rs.Name] {
+					continue
+				}
+				prev, seen := previous[rs.Name]
+				previous[rs.Name] = rs
+				if req.GetChangesOnly() && seen && !stateChanged(prev, rs) {
+					continue
+				}
+				if err := stream.Send(statusToProto(rs)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func replicaToProto(rs replicaStatus) *apiv1.Replica {
+	return &apiv1.Replica{
+		Name:   rs.Name,
+		Host:   rs.Host,
+		Port:   int32(rs.Port),
+		Region: rs.Region,
+		Labels: rs.Labels,
+	}
+}
+
+func statusToProto(rs replicaStatus) *apiv1.ReplicaStatus {
+	out := &apiv1.ReplicaStatus{
+		Replica:              replicaToProto(rs),
+		PolledAt:             timestampOrNil(&rs.PolledAt),
+		SecondsBehind:        rs.SecondsBehind,
+		IoRunning:            rs.IORunning,
+		SqlRunning:           rs.SQLRunning,
+		RatePerSecond:        rs.RatePerSecond,
+		AverageRatePerSecond: rs.AverageRatePerSecond,
+		InstantEta:           timestampOrNil(rs.InstantETA),
+		AverageEta:           timestampOrNil(rs.AverageETA),
+		ErrorMatched:         rs.ErrorMatched,
+		Status:               rs.Status,
+	}
+	if rs.LastAlert != nil {
+		out.LastAlert = &apiv1.Alert{
+			Event:   rs.LastAlert.Event,
+			Message: rs.LastAlert.Message,
+			Time:    timestamppb.New(rs.LastAlert.Time),
+		}
+	}
+	return out
+}
+
+func timestampOrNil(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"replica-monitor/pkg/export"
+)
+
+// One poll result as stored in the JSON-lines history file and handed to the
+// exporters
+type historySample = export.Sample
+
+// Hand a sample to the streams and exporters, -history among them
+func recordHistory(sample historySample) {
+	writeStreams("sample", sample.Host, sample)
+	if exporters != nil {
+		exporters.Add(sample)
+	}
+}
+
+// Read every sample from a history file, skipping lines that fail to parse
+func loadHistory(path string) ([]historySample, error) {
+	var samples []historySample
+	err := scanHistory(path, func(sample historySample) {
+		samples = append(samples, sample)
+	})
+	return samples, err
+}
+
+// Call fn for each sample in a history file without holding the whole file in memory
+func scanHistory(path string, fn func(historySample)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		var sample historySample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			slog.Warn("Skipping malformed history line", "path", path, "line", lineNo, "err", err)
+			continue
+		}
+		fn(sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	return nil
+}
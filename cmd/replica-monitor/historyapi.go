@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Upper bound on points per response, so a tiny step over a long range can't exhaust memory
+const maxHistoryPoints = 10000
+
+// Body of GET /api/v1/history
+type historyResponse struct {
+	Host   string         `json:"host"`
+	From   time.Time      `json:"from"`
+	To     time.Time      `json:"to"`
+	Step   string         `json:"step"`
+	Points []historyPoint `json:"points"`
+}
+
+// Lag aggregated over one step-sized bucket; buckets without samples are omitted
+type historyPoint struct {
+	Time    time.Time `json:"time"`
+	AvgLag  *float64  `json:"avg_lag"` // nil when every sample in the bucket was NULL
+	MinLag  *int      `json:"min_lag"`
+	MaxLag  *int      `json:"max_lag"`
+	Samples int       `json:"samples"`
+}
+
+// Serve a downsampled lag series from the -history file:
+// /api/v1/history?host=...&from=...&to=...&step=1m
+// from/to accept the same formats as compare windows and default to the last hour.
+func handleHistory(w http.ResponseWriter, req *http.Request) {
+	if history == "" {
+		http.Error(w, "history is not enabled; start the monitor with -history", http.StatusNotFound)
+		return
+	}
+
+	q := req.URL.Query()
+	hostFilter := q.Get("host")
+	if hostFilter == "" {
+		http.Error(w, "host is required", http.StatusBadRequest)
+		return
+	}
+
+	to := time.Now()
+	if v := q.Get("to"); v != "" {
+		t, err := parseWindowTime(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid to: %v", err), http.StatusBadRequest)
+			return
+		}
+		to = t
+	}
+	from := to.Add(-time.Hour)
+	if v := q.Get("from"); v != "" {
+		t, err := parseWindowTime(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid from: %v", err), http.StatusBadRequest)
+			return
+		}
+		from = t
+	}
+	step := time.Minute
+	if v := q.Get("step"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid step %q", v), http.StatusBadRequest)
+			return
+		}
+		step = d
+	}
+	if !to.After(from) {
+		http.Error(w, "to must be after from", http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from)/step > maxHistoryPoints {
+		http.Error(w, fmt.Sprintf("range/step yields more than %d points; use a larger step", maxHistoryPoints), http.StatusBadRequest)
+		return
+	}
+
+	points, err := downsampleHistory(history, hostFilter, from, to, step)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reading history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(historyResponse{
+		Host:   hostFilter,
+		From:   from,
+		To:     to,
+		Step:   step.String(),
+		Points: points,
+	})
+}
+
+// Bucket one host's samples in [from, to) into step-sized points
+func downsampleHistory(path, hostFilter string, from, to time.Time, step time.Duration) ([]historyPoint, error) {
+	type bucket struct {
+		sum      float64
+		count    int
+		min, max int
+		samples  int
+	}
+	buckets := make(map[int64]*bucket)
+
+	err := scanHistory(path, func(s historySample) {
+		if s.Host != hostFilter || s.Time.Before(from) || !s.Time.Before(to) {
+			return
+		}
+		idx := int64(s.Time.Sub(from) / step)
+		b := buckets[idx]
+		if b == nil {
+			b = &bucket{}
+			buckets[idx] = b
+		}
+		b.samples++
+		if s.SecondsBehind == nil {
+			return
+		}
+		lag := *s.SecondsBehind
+		if b.count == 0 || lag < b.min {
+			b.min = lag
+		}
+		if b.count == 0 || lag > b.max {
+			b.max = lag
+		}
+		b.sum += float64(lag)
+		b.count++
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	points := []historyPoint{}
+	n := int64(to.Sub(from)/step) + 1
+	for idx := int64(0); idx < n; idx++ {
+		b := buckets[idx]
+		if b == nil {
+			continue
+		}
+		p := historyPoint{Time: from.Add(time.Duration(idx) * step), Samples: b.samples}
+		if b.count > 0 {
+			avg := b.sum / float64(b.count)
+			minLag, maxLag := b.min, b.max
+			p.AvgLag, p.MinLag, p.MaxLag = &avg, &minLag, &maxLag
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Body of GET /status
+type statusResponse struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Replicas    []replicaStatus `json:"replicas"`
+}
+
+// Latest poll result and computed statistics for one replica
+type replicaStatus struct {
+	Name                 string             `json:"name"`
+	Host                 string             `json:"host"`
+	Port                 int                `json:"port"`
+	Region               string             `json:"region,omitempty"`
+	Labels               map[string]string  `json:"labels,omitempty"`
+	PolledAt             time.Time          `json:"polled_at"`
+	SecondsBehind        *float64           `json:"seconds_behind"`
+	IORunning            string             `json:"io_running,omitempty"`
+	SQLRunning           string             `json:"sql_running,omitempty"`
+	RatePerSecond        float64            `json:"rate_per_second"`
+	AverageRatePerSecond float64            `json:"average_rate_per_second"`
+	InstantETA           *time.Time         `json:"instant_eta,omitempty"`
+	AverageETA           *time.Time         `json:"average_eta,omitempty"`
+	ErrorMatched         bool               `json:"error_matched"`
+	ReplicationEncrypted *bool              `json:"replication_encrypted,omitempty"`
+	LagSources           map[string]float64 `json:"lag_sources,omitempty"` // each source's reading when seconds_behind is reconciled from several
+	LagSourcesDisagree   bool               `json:"lag_sources_disagree,omitempty"`
+	PollFailures         int                `json:"poll_failures,omitempty"`
+	LastAlert            *alertState        `json:"last_alert,omitempty"`
+	Status               map[string]string  `json:"status"`
+}
+
+// How old the last successful poll may be before /readyz reports not ready: 30
+// seconds, or three intervals when polling less often than that
+func readyMaxAge() time.Duration {
+	return max(30*time.Second, 3*(currentInterval()*time.Duration(throttleFactor.Load())+jitter))
+}
+
+var (
+	statusMu          sync.RWMutex
+	statusSnapshot    = statusResponse{Replicas: []replicaStatus{}}
+	statusSubscribers = make(map[chan statusResponse]bool)
+)
+
+// Receive every published snapshot; call the returned function to unsubscribe.
+// A slow subscriber only ever sees the most recent snapshot.
+func subscribeStatus() (<-chan statusResponse, func()) {
+	ch := make(chan statusResponse, 1)
+	statusMu.Lock()
+	statusSubscribers[ch] = true
+	statusMu.Unlock()
+
+	return ch, func() {
+		statusMu.Lock()
+		delete(statusSubscribers, ch)
+		statusMu.Unlock()
+	}
+}
+
+// Copy the replicas' latest state for the HTTP handlers; called once per monitoring cycle
+func publishStatus(replicas []*replica) {
+	now := time.Now()
+	snapshot := statusResponse{GeneratedAt: now, Replicas: make([]replicaStatus, 0, len(replicas))}
+	for _, r := range replicas {
+		rs := replicaStatus{
+			Name:                 displayName(r),
+			Host:                 r.host,
+			Port:                 r.port,
+			Region:               r.region,
+			Labels:               r.labels,
+			PolledAt:             r.polledAt,
+			IORunning:            r.ioRunning,
+			SQLRunning:           r.sqlRunning,
+			RatePerSecond:        r.stats.Rate,
+			AverageRatePerSecond: r.stats.AverageRate,
+			ErrorMatched:         r.errorMatched,
+			PollFailures:         r.pollFailures,
+			LastAlert:            r.lastAlert,
+			Status:               r.lastStatus,
+		}
+		if rec := r.lagReconciled; rec != nil {
+			rs.LagSources = make(map[string]float64, len(rec.readings))
+			for _, reading := range rec.readings {
+				rs.LagSources[reading.source] = reading.seconds
+			}
+			rs.LagSourcesDisagree = rec.disagree
+		}
+		if r.replicationTLS.known {
+			encrypted := r.replicationTLS.encrypted
+			rs.ReplicationEncrypted = &encrypted
+		}
+		if r.lagKnown {
+			lag := r.lagSeconds
+			rs.SecondsBehind = &lag
+			if r.stats.Rate < 0 && !r.stats.EstimatedTime.IsZero() {
+				eta := r.stats.EstimatedTime
+				rs.InstantETA = &eta
+			}
+			if eta, ok := r.stats.AverageETA(lag, now); ok {
+				rs.AverageETA = &eta
+			}
+		}
+		snapshot.Replicas = append(snapshot.Replicas, rs)
+	}
+
+	statusMu.Lock()
+	statusSnapshot = snapshot
+	for ch := range statusSubscribers {
+		// Replace an unread snapshot rather than blocking the monitoring loop
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- snapshot
+	}
+	statusMu.Unlock()
+}
+
+// Whether anything beyond the lag value itself changed between two polls
+func stateChanged(a, b replicaStatus) bool {
+	return a.IORunning != b.IORunning ||
+		a.SQLRunning != b.SQLRunning ||
+		a.ErrorMatched != b.ErrorMatched ||
+		(a.SecondsBehind == nil) != (b.SecondsBehind == nil) ||
+		a.LastAlert != b.LastAlert
+}
+
+// Serve the status endpoint in the background, once the address is bound
+func startHTTPServer(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /status", redactResponses(http.HandlerFunc(handleStatus)))
+	mux.Handle("GET /events", redactResponses(http.HandlerFunc(handleEvents)))
+	mux.Handle("GET /api/v1/history", redactResponses(http.HandlerFunc(handleHistory)))
+	mux.Handle("GET /api/v1/timeline", redactResponses(http.HandlerFunc(handleTimeline)))
+	mux.Handle("POST /graphql", redactResponses(graphqlHandler()))
+	if prometheus != nil {
+		mux.Handle("GET /metrics", prometheus)
+	}
+	mux.Handle("GET /", dashboardHandler())
+
+	// Probes and signing in need no credentials
+	open := http.NewServeMux()
+	open.HandleFunc("GET /healthz", handleHealthz)
+	open.Handle("GET /readyz", redactResponses(http.HandlerFunc(handleReadyz)))
+	if oidc != nil {
+		oidc.routes(open)
+	}
+	open.Handle("/", requireAuth(mux))
+
+	tlsConfig, err := serverTLS()
+	if err != nil {
+		return fmt.Errorf("set up TLS for the HTTP server: %w", err)
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen for HTTP on %s: %w", addr, err)
+	}
+	srv := &http.Server{Addr: addr, Handler: open, TLSConfig: tlsConfig}
+
+	go func() {
+		if tlsConfig != nil {
+			slog.Info("Serving dashboard and status", "url", "https://"+addr+"/", "client_certs", tlsClientCA != "")
+			err = srv.ServeTLS(lis, "", "")
+		} else {
+			slog.Info("Serving dashboard and status", "url", "http://"+addr+"/")
+			err = srv.Serve(lis)
+		}
+		if err != nil {
+			fatal("HTTP server failed", "err", err)
+		}
+	}()
+	return nil
+}
+
+func handleStatus(w http.ResponseWriter, req *http.Request) {
+	statusMu.RLock()
+	snapshot := statusSnapshot
+	statusMu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(snapshot)
+}
+
+// The process is alive and serving requests
+func handleHealthz(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "ok")
+}
+
+// Every replica was reached recently and the monitoring loop is still cycling
+func handleReadyz(w http.ResponseWriter, req *http.Request) {
+	statusMu.RLock()
+	snapshot := statusSnapshot
+	statusMu.RUnlock()
+
+	now := time.Now()
+	maxAge := readyMaxAge()
+	var problems []string
+	if shuttingDown.Load() {
+		problems = append(problems, "shutting down")
+	}
+	if snapshot.GeneratedAt.IsZero() {
+		problems = append(problems, "no monitoring cycle has completed yet")
+	} else if age := now.Sub(snapshot.GeneratedAt); age > maxAge {
+		problems = append(problems, fmt.Sprintf("last monitoring cycle was %s ago", age.Round(time.Second)))
+	}
+	if !snapshot.GeneratedAt.IsZero() && len(snapshot.Replicas) == 0 {
+		problems = append(problems, "no replicas are being monitored")
+	}
+	for _, rs := range snapshot.Replicas {
+		if rs.PolledAt.IsZero() || now.Sub(rs.PolledAt) > maxAge {
+			problems = append(problems, fmt.Sprintf("%s has not been polled successfully in the last %s", rs.Name, maxAge))
+		} else if sidecarMode && rs.PollFailures > 0 {
+			// A sidecar's pod is ready only while its database answers
+			problems = append(problems, fmt.Sprintf("%s did not answer the last poll", rs.Name))
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	if _, ok := authenticate(req); !ok && len(problems) > 0 {
+		// Replica names are for authenticated clients only
+		problems = []string{"not ready"}
+	}
+	if len(problems) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		for _, p := range problems {
+			fmt.Fprintln(w, p)
+		}
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
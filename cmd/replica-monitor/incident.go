@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Identifies this run of the monitor in every log line, alert, and event
+var sessionID = newCorrelationID()
+
+// Open incident IDs by replica display name, for log records that name a replica
+var openIncidents sync.Map
+
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// The replica has a problem worth an incident: an error matched, a thread
+// stopped, or lag above -lag-threshold
+func hasProblem(r *replica) bool {
+	if r.errorMatched {
+		return true
+	}
+	if !r.aurora && !r.polledAt.IsZero() && (r.ioRunning != "Yes" || r.sqlRunning != "Yes") {
+		return true
+	}
+	return r.lagKnown && r.lagSeconds > replicaLagThreshold(r).Seconds()
+}
+
+// Source-side alerts belong to the source and self-health alerts to the monitor,
+// neither of which is polled, so they have no incident lifecycle of their own
+func incidentTracked(r *replica) bool {
+	return r != selfReplica && (sourceMonitor == nil || r != sourceMonitor.conn)
+}
+
+// Open an incident for the replica unless one is already open
+func openIncident(r *replica) {
+	if r.incident != "" {
+		return
+	}
+	r.incident = newCorrelationID()
+	r.incidentStart = time.Now()
+	openIncidents.Store(displayName(r), r.incident)
+	slog.Warn("Incident opened", "replica", displayName(r))
+	recordEvent(r, journalEvent{Event: "incident_opened"}, 0)
+}
+
+// Open incidents for replicas that just developed a problem; called each cycle
+// before transitions are announced so their events carry the incident
+func openNewIncidents(replicas []*replica) {
+	for _, r := range replicas {
+		if hasProblem(r) {
+			openIncident(r)
+		}
+	}
+}
+
+// Close the incidents of replicas that are healthy again: threads running, no
+// matched error, and caught up after falling behind
+func resolveIncidents(replicas []*replica) {
+	for _, r := range replicas {
+		if r.incident == "" || r.polledAt.IsZero() {
+			continue
+		}
+		if st := transitionLast[r]; hasProblem(r) || (st != nil && st.Behind()) {
+			continue
+		}
+		lasted := time.Since(r.incidentStart)
+		slog.Info("Incident resolved", "replica", displayName(r), "duration", lasted.Round(time.Second))
+		recordEvent(r, journalEvent{Event: "incident_resolved"}, lasted)
+		openIncidents.Delete(displayName(r))
+		r.incident = ""
+	}
+}
+
+// Adds the session to every record and the open incident to records naming a replica
+type correlationHandler struct {
+	slog.Handler
+}
+
+func (h correlationHandler) Handle(ctx context.Context, rec slog.Record) error {
+	incident := ""
+	rec.Attrs(func(a slog.Attr) bool {
+		if a.Key != "replica" {
+			return true
+		}
+		if id, ok := openIncidents.Load(a.Value.String()); ok {
+			incident = id.(string)
+		}
+		return false
+	})
+	rec.AddAttrs(slog.String("session", sessionID))
+	if incident != "" {
+		rec.AddAttrs(slog.String("incident", incident))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h correlationHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return correlationHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h correlationHandler) WithGroup(name string) slog.Handler {
+	return correlationHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Show the top SQL and wait events from Performance Insights for RDS replicas
+// that are behind -lag-threshold
+var performanceInsights bool
+
+const (
+	insightsInterval = time.Minute
+	insightsWindow   = 5 * time.Minute
+	insightsTop      = 3
+)
+
+// One dimension value and its share of database load, in average active sessions
+type insightsKey struct {
+	name string
+	load float64
+}
+
+type insightsSample struct {
+	sql, waits []insightsKey
+	fetched    time.Time
+	ok         bool // false when the fetch failed
+}
+
+var (
+	insightsSamples = make(map[*replica]*insightsSample)
+	insightsClient  = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Refresh the load breakdown of every RDS replica above -lag-threshold, at most
+// once a minute each; replicas that caught up drop theirs
+func checkInsights(replicas []*replica) {
+	if !performanceInsights {
+		return
+	}
+	behind := make(map[*replica]bool)
+	for _, r := range replicas {
+		if !r.lagKnown || r.lagSeconds <= replicaLagThreshold(r).Seconds() {
+			continue
+		}
+		id, region, ok := rdsInstanceOf(r)
+		if !ok {
+			continue
+		}
+		behind[r] = true
+		if s := insightsSamples[r]; s != nil && time.Since(s.fetched) < insightsInterval {
+			continue
+		}
+		s, err := fetchInsights(id, region)
+		if err != nil {
+			slog.Warn("Failed to fetch Performance Insights data", "replica", displayName(r), "instance", id, "err", err)
+			s = &insightsSample{} // try again after insightsInterval, not every cycle
+		}
+		s.fetched = time.Now()
+		insightsSamples[r] = s
+	}
+	for r := range insightsSamples {
+		if !behind[r] {
+			delete(insightsSamples, r)
+		}
+	}
+}
+
+func fetchInsights(id, region string) (*insightsSample, error) {
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	inst, err := describeRDSInstance(ctx, id, region)
+	if err != nil {
+		return nil, err
+	}
+	if !aws.ToBool(inst.PerformanceInsightsEnabled) {
+		return nil, errors.New("Performance Insights is not enabled on the instance")
+	}
+	resource := aws.ToString(inst.DbiResourceId)
+	s := &insightsSample{ok: true}
+	if s.sql, err = topDimensionKeys(ctx, region, resource, "db.sql_tokenized", "db.sql_tokenized.statement"); err != nil {
+		return nil, err
+	}
+	if s.waits, err = topDimensionKeys(ctx, region, resource, "db.wait_event", "db.wait_event.name"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Call DescribeDimensionKeys for the largest contributors to db.load.avg over the
+// last insightsWindow, grouped by group and named by the dimension field. The
+// API is called directly since it is a single JSON request.
+func topDimensionKeys(ctx context.Context, region, resource, group, field string) ([]insightsKey, error) {
+	end := time.Now()
+	body, err := json.Marshal(map[string]any{
+		"ServiceType":     "RDS",
+		"Identifier":      resource,
+		"StartTime":       end.Add(-insightsWindow).Unix(),
+		"EndTime":         end.Unix(),
+		"Metric":          "db.load.avg",
+		"PeriodInSeconds": 60,
+		"GroupBy":         map[string]any{"Group": group, "Limit": insightsTop},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := loadAWSConfig(region)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving AWS credentials: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://pi.%s.amazonaws.com/", cfg.Region), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "PerformanceInsightsv20180227.DescribeDimensionKeys")
+	hash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "pi", cfg.Region, time.Now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := insightsClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DescribeDimensionKeys: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var out struct {
+		Keys []struct {
+			Dimensions map[string]string `json:"Dimensions"`
+			Total      float64           `json:"Total"`
+		} `json:"Keys"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("parsing DescribeDimensionKeys response: %w", err)
+	}
+	keys := make([]insightsKey, 0, len(out.Keys))
+	for _, k := range out.Keys {
+		keys = append(keys, insightsKey{name: k.Dimensions[field], load: k.Total})
+	}
+	return keys, nil
+}
+
+// Print the top SQL and waits of a replica that is behind, under its lag
+func printInsights(out io.Writer, r *replica) {
+	s := insightsSamples[r]
+	if s == nil || !s.ok || len(s.sql)+len(s.waits) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "🔎 Performance Insights, last %s (average active sessions):\n", formatDuration(insightsWindow.Seconds()))
+	for _, k := range s.sql {
+		fmt.Fprintf(out, "  SQL  %5.2f  %s\n", k.load, truncate(strings.Join(strings.Fields(k.name), " "), 100))
+	}
+	for _, k := range s.waits {
+		fmt.Fprintf(out, "  wait %5.2f  %s\n", k.load, k.name)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Look up each RDS replica's instance class, storage, Multi-AZ setting, and engine
+// version with -rds-metadata, for reports and alert payloads
+var rdsMetadata bool
+
+// Fetch or refresh the instance metadata of every RDS replica; lookups are cached
+// for rdsInstanceTTL, so this only calls the API every few minutes
+func refreshInstanceInfo(replicas []*replica) {
+	if !rdsMetadata {
+		return
+	}
+	for _, r := range replicas {
+		id, region, ok := rdsInstanceOf(r)
+		if !ok || time.Since(r.instanceChecked) < rdsInstanceTTL {
+			continue
+		}
+		r.instanceChecked = time.Now()
+		ctx, cancel := withQueryTimeout(context.Background())
+		inst, err := describeRDSInstance(ctx, id, region)
+		cancel()
+		if err != nil {
+			slog.Warn("Failed to look up RDS instance metadata", "replica", displayName(r), "instance", id, "err", err)
+			continue
+		}
+		r.instance = &monitor.InstanceInfo{
+			Class:         aws.ToString(inst.DBInstanceClass),
+			StorageType:   aws.ToString(inst.StorageType),
+			StorageGB:     aws.ToInt32(inst.AllocatedStorage),
+			IOPS:          aws.ToInt32(inst.Iops),
+			MultiAZ:       aws.ToBool(inst.MultiAZ),
+			Engine:        aws.ToString(inst.Engine),
+			EngineVersion: aws.ToString(inst.EngineVersion),
+		}
+	}
+}
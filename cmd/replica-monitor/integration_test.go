@@ -0,0 +1,225 @@
+//go:build integration
+
+// Integration tests against a real MySQL source and replica in containers. They
+// need Docker and are left out of a plain go test:
+//
+//	go test -tags integration -v ./cmd/replica-monitor
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/network"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"replica-monitor/pkg/monitor"
+	"replica-monitor/pkg/notify"
+)
+
+const rootPassword = "integration"
+
+func TestReplicationBreakage(t *testing.T) {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	if !testing.Verbose() {
+		stdout = io.Discard
+	}
+
+	// One webhook for every version, since the alert dispatcher is started once
+	alerts := make(chan notify.Event, 100)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var alert notify.Event
+		if err := json.NewDecoder(req.Body).Decode(&alert); err == nil {
+			alerts <- alert
+		}
+	}))
+	defer webhook.Close()
+	alertWebhook = webhook.URL
+	user, password = "root", rootPassword
+
+	for _, image := range []string{"mysql:5.7", "mysql:8.0"} {
+		t.Run(image, func(t *testing.T) {
+			testReplicationBreakage(t, image, alerts)
+		})
+	}
+}
+
+func testReplicationBreakage(t *testing.T, image string, alerts chan notify.Event) {
+	ctx := context.Background()
+	nw, err := network.New(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testcontainers.CleanupNetwork(t, nw)
+
+	source, _, _ := startMySQL(t, nw, image, "source", "--server-id=1", "--log-bin=mysql-bin", "--binlog-format=ROW")
+	// Parallel workers make the SQL thread report "Coordinator stopped", one of
+	// the default error patterns, on both versions
+	replicaDB, host, port := startMySQL(t, nw, image, "replica", "--server-id=2", "--relay-log=relay-bin", "--slave-parallel-workers=4")
+
+	mustExec(t, source,
+		"CREATE USER 'repl'@'%' IDENTIFIED WITH mysql_native_password BY 'repl'",
+		"GRANT REPLICATION SLAVE ON *.* TO 'repl'@'%'")
+	status, err := queryStrings(source, "SHOW MASTER STATUS")
+	if err != nil || len(status) == 0 {
+		t.Fatalf("reading the source binlog position: %v", err)
+	}
+	mustExec(t, replicaDB,
+		fmt.Sprintf("CHANGE MASTER TO MASTER_HOST='source', MASTER_USER='repl', MASTER_PASSWORD='repl', MASTER_LOG_FILE='%s', MASTER_LOG_POS=%s",
+			status[0]["File"], status[0]["Position"]),
+		"START SLAVE",
+		// Stands in for the RDS procedure, which does the same
+		`CREATE PROCEDURE mysql.rds_skip_repl_error()
+		BEGIN
+			STOP SLAVE SQL_THREAD;
+			SET GLOBAL sql_slave_skip_counter = 1;
+			START SLAVE SQL_THREAD;
+		END`)
+	mustExec(t, source,
+		"CREATE DATABASE shop",
+		"CREATE TABLE shop.orders (id INT PRIMARY KEY)")
+	waitFor(t, "the table to replicate", func() bool {
+		return countOrders(replicaDB) == 0
+	})
+
+	// A row written straight to the replica makes the same insert on the source
+	// fail there with a duplicate key
+	mustExec(t, replicaDB, "INSERT INTO shop.orders VALUES (1)")
+	mustExec(t, source, "INSERT INTO shop.orders VALUES (1)")
+	waitFor(t, "the SQL thread to stop", func() bool {
+		return replicaField(replicaDB, "Replica_SQL_Running") == "No"
+	})
+
+	r, err := connectReplica("replica", host, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.close()
+
+	// Polled as by check, the error is reported and alerted on but left alone
+	if pollOnce(ctx, io.Discard, []*replica{r}, false) {
+		t.Fatal("skipped without autoSkip")
+	}
+	if !r.errorMatched || r.sqlRunning != "No" {
+		t.Fatalf("error not detected: matched=%v sql_running=%q", r.errorMatched, r.sqlRunning)
+	}
+	select {
+	case alert := <-alerts:
+		if alert.Event != "sql_error" || alert.Host != host {
+			t.Fatalf("unexpected alert %+v", alert)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("no sql_error alert delivered")
+	}
+	if replicaField(replicaDB, "Replica_SQL_Running") != "No" {
+		t.Fatal("replication restarted without autoSkip")
+	}
+
+	// Polled as by watch, the failing transaction is skipped and replication carries on
+	if !pollOnce(ctx, io.Discard, []*replica{r}, true) {
+		t.Fatal("error not skipped with autoSkip")
+	}
+	if r.skips != 1 {
+		t.Fatalf("skips = %d, want 1", r.skips)
+	}
+	waitFor(t, "the SQL thread to restart", func() bool {
+		return replicaField(replicaDB, "Replica_SQL_Running") == "Yes"
+	})
+	mustExec(t, source, "INSERT INTO shop.orders VALUES (2)")
+	waitFor(t, "replication to catch up after the skip", func() bool {
+		return countOrders(replicaDB) == 2
+	})
+	if pollOnce(ctx, io.Discard, []*replica{r}, true) || r.errorMatched {
+		t.Fatal("error still reported after the skip")
+	}
+}
+
+// Start a MySQL server reachable from the other containers as alias, and connect
+// to it from the test as root
+func startMySQL(t *testing.T, nw *testcontainers.DockerNetwork, image, alias string, args ...string) (*sql.DB, string, int) {
+	t.Helper()
+	ctx := context.Background()
+	ctr, err := testcontainers.Run(ctx, image,
+		testcontainers.WithEnv(map[string]string{"MYSQL_ROOT_PASSWORD": rootPassword}),
+		testcontainers.WithExposedPorts("3306/tcp"),
+		testcontainers.WithCmdArgs(args...),
+		network.WithNetwork([]string{alias}, nw),
+		// The entrypoint's temporary server skips networking, so a listening
+		// port means the real one is up
+		testcontainers.WithWaitStrategyAndDeadline(3*time.Minute, wait.ForListeningPort("3306/tcp")),
+	)
+	testcontainers.CleanupContainer(t, ctr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint, err := ctr.PortEndpoint(ctx, "3306/tcp", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, portStr, _ := net.SplitHostPort(endpoint)
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := mysql.NewConfig()
+	cfg.User = "root"
+	cfg.Passwd = rootPassword
+	cfg.Net = "tcp"
+	cfg.Addr = endpoint
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	waitFor(t, alias+" to accept connections", func() bool {
+		return db.Ping() == nil
+	})
+	return db, host, port
+}
+
+func mustExec(t *testing.T, db *sql.DB, statements ...string) {
+	t.Helper()
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Minute)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// A replica status column, through the same source the monitor reads
+func replicaField(db *sql.DB, name string) string {
+	row, err := (&monitor.SQLSource{DB: db}).ReplicaStatus(context.Background())
+	if err != nil || row == nil {
+		return ""
+	}
+	return row.Value(name)
+}
+
+// Rows in shop.orders, -1 until the table exists
+func countOrders(db *sql.DB) int {
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM shop.orders").Scan(&n); err != nil {
+		return -1
+	}
+	return n
+}
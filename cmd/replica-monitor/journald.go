@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// -journald: auto (when systemd connected stderr to the journal), always, or never
+var journaldMode = "auto"
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+// Attribute keys with a conventional journal field name; others are upper-cased
+var journaldFieldNames = map[string]string{
+	"replica":     "REPLICA_HOST",
+	"lag_seconds": "LAG_SECONDS",
+	"event":       "EVENT",
+}
+
+// Writes records to journald's native protocol so their attributes become
+// queryable fields, e.g. journalctl -u replica-monitor EVENT=sql_error
+type journaldHandler struct {
+	conn   *net.UnixConn
+	attrs  []slog.Attr
+	prefix string
+}
+
+// Whether to log to journald, replacing standard error
+func useJournald() bool {
+	switch journaldMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return logFile == "" && stderrIsJournal()
+}
+
+func newJournaldHandler() (*journaldHandler, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journaldHandler{conn: conn}, nil
+}
+
+func (h *journaldHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= logLevel.Level()
+}
+
+func (h *journaldHandler) Handle(_ context.Context, rec slog.Record) error {
+	var b bytes.Buffer
+	journaldField(&b, "MESSAGE", rec.Message)
+	journaldField(&b, "PRIORITY", fmt.Sprint(syslogSeverity(rec.Level)))
+	journaldField(&b, "SYSLOG_IDENTIFIER", "replica-monitor")
+	add := func(a slog.Attr) {
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		journaldField(&b, journaldFieldName(h.prefix+a.Key), a.Value.String())
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	rec.Attrs(func(a slog.Attr) bool {
+		add(a)
+		return true
+	})
+	_, err := h.conn.Write(b.Bytes())
+	return err
+}
+
+func (h *journaldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &next
+}
+
+func (h *journaldHandler) WithGroup(name string) slog.Handler {
+	next := *h
+	next.prefix = h.prefix + name + "_"
+	return &next
+}
+
+// Journal field names are upper-case letters, digits, and underscores, not
+// starting with an underscore (reserved for trusted fields)
+func journaldFieldName(key string) string {
+	if name, ok := journaldFieldNames[key]; ok {
+		return name
+	}
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "F" + name
+	}
+	return name
+}
+
+// Values containing a newline use the length-prefixed binary form
+func journaldField(b *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(b, "%s=%s\n", name, value)
+		return
+	}
+	b.WriteString(name + "\n")
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value + "\n")
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// systemd sets JOURNAL_STREAM to the device and inode of the stream it connected
+func stderrIsJournal() bool {
+	var dev, ino uint64
+	if _, err := fmt.Sscanf(os.Getenv("JOURNAL_STREAM"), "%d:%d", &dev, &ino); err != nil {
+		return false
+	}
+	info, err := os.Stderr.Stat()
+	if err != nil {
+		return false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && uint64(st.Dev) == dev && uint64(st.Ino) == ino
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// There is no journal outside Linux
+func stderrIsJournal() bool {
+	return false
+}
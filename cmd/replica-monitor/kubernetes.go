@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// sidecar runs serve for Kubernetes: configured from REPLICA_MONITOR_*
+// environment variables and mounted files, labelled with the pod's labels from
+// the downward API, ready only while every replica answers, and done within
+// -shutdown-timeout of SIGTERM
+var (
+	sidecarMode     bool
+	podLabelsFile   string
+	shutdownTimeout time.Duration
+
+	// Labels of the pod from -pod-labels-file and its identity from the
+	// environment, under the config file's and -label's
+	podLabels map[string]string
+
+	// Set once the loop is stopping, so that /readyz takes the pod out of
+	// its Services before the connections close
+	shuttingDown atomic.Bool
+)
+
+// Prefix of the environment variables flags can be set from
+const envFlagPrefix = "REPLICA_MONITOR_"
+
+// Environment variables the downward API commonly sets, and the labels they
+// become
+var podIdentityEnv = [][2]string{{"POD_NAME", "pod"}, {"POD_NAMESPACE", "namespace"}, {"NODE_NAME", "node"}}
+
+func sidecarFlags() *flag.FlagSet {
+	fs := newCommandFlags("sidecar")
+	addConnectionFlags(fs)
+	addMonitorFlags(fs)
+	fs.StringVar(&podLabelsFile, "pod-labels-file", "/etc/podinfo/labels", "Downward API file of the pod's labels to attach to every replica's metrics, ignored when absent")
+	setFlagDefault(fs, "http", ":8080")
+	setFlagDefault(fs, "log-format", "json")
+	setFlagDefault(fs, "shutdown-timeout", "25s")
+	return fs
+}
+
+func runSidecar(fs *flag.FlagSet) error {
+	if err := applyEnvFlags(fs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitWith(2)
+	}
+	sidecarMode = true
+	needSkip = !readOnly
+	if err := setupOutput(); err != nil {
+		return err
+	}
+
+	labels, err := readPodLabels(podLabelsFile)
+	if err != nil {
+		return fmt.Errorf("read pod labels: %w", err)
+	}
+	for _, env := range podIdentityEnv {
+		if v := os.Getenv(env[0]); v != "" {
+			labels[env[1]] = v
+		}
+	}
+	podLabels = labels
+	return runMonitor(fs, true)
+}
+
+// Change a flag's default, as shown in its help, before the command line is parsed
+func setFlagDefault(fs *flag.FlagSet, name, value string) {
+	f := fs.Lookup(name)
+	f.Value.Set(value)
+	f.DefValue = value
+}
+
+// The environment variable a flag can be set from, e.g. REPLICA_MONITOR_PASSWORD_FILE
+func envFlagName(name string) string {
+	return envFlagPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Set every flag not given on the command line from its environment variable;
+// repeatable flags take one value this way
+func applyEnvFlags(fs *flag.FlagSet) error {
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		v, ok := os.LookupEnv(envFlagName(f.Name))
+		if given[f.Name] || !ok {
+			return
+		}
+		if err := fs.Set(f.Name, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", envFlagName(f.Name), err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Read a downward API labels file, one key="value" per line
+func readPodLabels(path string) (map[string]string, error) {
+	labels := map[string]string{}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Debug("No pod labels file", "path", path)
+		return labels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		key, quoted, ok := strings.Cut(line, "=")
+		value, err := strconv.Unquote(quoted)
+		if !ok || key == "" || err != nil {
+			return nil, fmt.Errorf("expected key=\"value\", got %q", line)
+		}
+		labels[key] = value
+	}
+	return labels, scanner.Err()
+}
+
+// Once ctx ends, report not ready and, with -shutdown-timeout, exit if the
+// shutdown has not finished in time, before Kubernetes kills the container
+// at the end of its terminationGracePeriodSeconds
+func watchShutdown(ctx context.Context) {
+	<-ctx.Done()
+	shuttingDown.Store(true)
+	if shutdownTimeout <= 0 {
+		return
+	}
+	time.AfterFunc(shutdownTimeout, func() {
+		slog.Error("Shutdown did not finish within -shutdown-timeout; exiting", "timeout", shutdownTimeout)
+		os.Exit(1)
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApplyEnvFlags(t *testing.T) {
+	fs := flag.NewFlagSet("sidecar", flag.ContinueOnError)
+	host := fs.String("host", "", "")
+	user := fs.String("user", "", "")
+	passwordFile := fs.String("password-file", "", "")
+	interval := fs.Duration("interval", 5*time.Second, "")
+	t.Setenv("REPLICA_MONITOR_HOST", "from-env.example.com")
+	t.Setenv("REPLICA_MONITOR_USER", "from-env")
+	t.Setenv("REPLICA_MONITOR_PASSWORD_FILE", "/etc/replica-monitor/password")
+	if err := fs.Parse([]string{"-user", "from-args"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnvFlags(fs); err != nil {
+		t.Fatal(err)
+	}
+	if *host != "from-env.example.com" || *passwordFile != "/etc/replica-monitor/password" {
+		t.Errorf("-host %q, -password-file %q; want them from the environment", *host, *passwordFile)
+	}
+	if *user != "from-args" {
+		t.Errorf("-user = %q; want the command line over the environment", *user)
+	}
+	if *interval != 5*time.Second {
+		t.Errorf("-interval = %s without REPLICA_MONITOR_INTERVAL", *interval)
+	}
+
+	t.Setenv("REPLICA_MONITOR_INTERVAL", "soon")
+	if err := applyEnvFlags(fs); err == nil {
+		t.Error("invalid REPLICA_MONITOR_INTERVAL accepted")
+	}
+}
+
+func TestReadPodLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels")
+	labels, err := readPodLabels(path)
+	if err != nil || len(labels) != 0 {
+		t.Errorf("readPodLabels of a missing file = %v, %v; want none", labels, err)
+	}
+
+	os.WriteFile(path, []byte("app=\"orders\"\napp.kubernetes.io/part-of=\"shop \\\"eu\\\"\"\n"), 0o644)
+	labels, err = readPodLabels(path)
+	if err != nil || labels["app"] != "orders" || labels["app.kubernetes.io/part-of"] != `shop "eu"` {
+		t.Errorf("readPodLabels = %v, %v", labels, err)
+	}
+
+	os.WriteFile(path, []byte("app=orders\n"), 0o644)
+	if _, err := readPodLabels(path); err == nil {
+		t.Error("unquoted label value accepted")
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Where lag is measured: SHOW REPLICA STATUS, a pt-heartbeat style table, and
+// CloudWatch's ReplicaLag. Each occasionally lies on its own, e.g.
+// Seconds_Behind_Source reads 0 while the IO thread waits on a dead connection,
+// so with several the monitor reconciles them into one figure.
+var lagSourceNames = []string{"status", "heartbeat", "cloudwatch"}
+
+var (
+	lagSources      = []string{"status"} // -lag-sources, unless a replica's config lists its own
+	heartbeatTable  string               // -heartbeat-table, as schema.table
+	lagDisagreement time.Duration        // -lag-disagreement
+)
+
+// A CloudWatch datapoint older than this is left out of the reconciliation
+const cloudWatchLagMaxAge = 5 * time.Minute
+
+var heartbeatTablePattern = regexp.MustCompile(`^[A-Za-z0-9_$]+\.[A-Za-z0-9_$]+$`)
+
+// Register -lag-sources, -heartbeat-table, and -lag-disagreement on a command
+// that polls replicas
+func addLagSourceFlags(fs *flag.FlagSet) {
+	fs.Func("lag-sources", "Comma-separated lag measurements to reconcile: status (the default), heartbeat (-heartbeat-table), and cloudwatch (-cloudwatch-lag)", func(v string) error {
+		sources := strings.Split(v, ",")
+		if err := checkLagSources(sources); err != nil {
+			return err
+		}
+		lagSources = sources
+		return nil
+	})
+	fs.Func("heartbeat-table", "Table pt-heartbeat --utc updates on the source, as schema.table, for the heartbeat lag source", func(v string) error {
+		if !heartbeatTablePattern.MatchString(v) {
+			return fmt.Errorf("expected schema.table, got %q", v)
+		}
+		heartbeatTable = v
+		return nil
+	})
+	fs.DurationVar(&lagDisagreement, "lag-disagreement", time.Minute, "Alert when a replica's lag sources differ by more than this")
+}
+
+func checkLagSources(sources []string) error {
+	for _, s := range sources {
+		if !slices.Contains(lagSourceNames, s) {
+			return fmt.Errorf("unknown lag source %q; expected %s", s, strings.Join(lagSourceNames, ", "))
+		}
+	}
+	return nil
+}
+
+// One source's measurement of a replica's lag
+type lagReading struct {
+	source  string
+	seconds float64
+}
+
+// A replica's lag as each of its sources measured it in the latest poll
+type lagReconciliation struct {
+	readings []lagReading
+	missing  []string // sources that gave no reading in this poll
+	seconds  float64  // the reconciled lag; meaningless without readings
+	spread   float64  // the largest difference between two readings
+	disagree bool     // spread is above -lag-disagreement
+}
+
+// Lag sources of a replica: its own from the config file, or -lag-sources
+func replicaLagSources(r *replica) []string {
+	if len(r.lagSources) > 0 {
+		return r.lagSources
+	}
+	return lagSources
+}
+
+// Combine readings into one lag: the median, taking the higher of the middle
+// two for an even count, since a source reading low is the more dangerous lie
+func reconcileReadings(readings []lagReading) (seconds, spread float64) {
+	if len(readings) == 0 {
+		return 0, 0
+	}
+	values := make([]float64, len(readings))
+	for i, reading := range readings {
+		values[i] = reading.seconds
+	}
+	slices.Sort(values)
+	return values[len(values)/2], values[len(values)-1] - values[0]
+}
+
+// Measure the replica's lag from each of its sources after a poll and replace
+// the lag SHOW REPLICA STATUS reported with the reconciled one, in the replica
+// and its history sample. Alerts once when the sources start to disagree.
+func reconcileLag(ctx context.Context, r *replica, sample *historySample) {
+	sources := replicaLagSources(r)
+	if slices.Equal(sources, []string{"status"}) {
+		r.lagReconciled = nil
+		return
+	}
+	rec := &lagReconciliation{}
+	for _, source := range sources {
+		var seconds float64
+		ok := false
+		switch source {
+		case "status":
+			seconds, ok = r.lagSeconds, r.lagKnown
+		case "heartbeat":
+			var err error
+			seconds, err = readHeartbeatLag(ctx, r)
+			if err != nil {
+				slog.Warn("Failed to read the heartbeat table", "replica", displayName(r), "table", heartbeatTable, "err", err)
+			}
+			ok = err == nil
+		case "cloudwatch":
+			if s := cloudWatchSamples[r]; s != nil && time.Since(s.at) < cloudWatchLagMaxAge {
+				seconds, ok = s.seconds, true
+			}
+		}
+		if ok {
+			rec.readings = append(rec.readings, lagReading{source, seconds})
+		} else {
+			rec.missing = append(rec.missing, source)
+		}
+	}
+	rec.seconds, rec.spread = reconcileReadings(rec.readings)
+	rec.disagree = len(rec.readings) > 1 && rec.spread > lagDisagreement.Seconds()
+
+	previous := r.lagReconciled
+	r.lagReconciled = rec
+	switch {
+	case rec.disagree && (previous == nil || !previous.disagree):
+		sendAlert(r, "lag_sources_disagree", fmt.Sprintf("Lag sources disagree by %s: %s; reconciled lag %s",
+			formatDuration(rec.spread), rec.describeReadings(), formatDuration(rec.seconds)))
+	case !rec.disagree && previous != nil && previous.disagree && len(rec.readings) > 1:
+		slog.Info("Lag sources agree again", "replica", displayName(r), "readings", rec.describeReadings())
+	}
+
+	if len(rec.readings) == 0 {
+		r.lagKnown, sample.SecondsBehind = false, nil
+		return
+	}
+	r.lagSeconds, r.lagKnown = rec.seconds, true
+	seconds := int(math.Round(rec.seconds))
+	sample.SecondsBehind = &seconds
+	sample.LagSources = make(map[string]float64, len(rec.readings))
+	for _, reading := range rec.readings {
+		sample.LagSources[reading.source] = reading.seconds
+	}
+	sample.LagSourcesDisagree = rec.disagree
+}
+
+// Readings as "status 0s, heartbeat 45s"
+func (rec *lagReconciliation) describeReadings() string {
+	parts := make([]string, 0, len(rec.readings))
+	for _, reading := range rec.readings {
+		parts = append(parts, reading.source+" "+formatDuration(reading.seconds))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Lag from the newest row the source's pt-heartbeat wrote into -heartbeat-table,
+// against the replica's UTC clock. Rows written by other servers in a chain are
+// left out when the replica reports its source's server ID.
+func readHeartbeatLag(ctx context.Context, r *replica) (float64, error) {
+	if heartbeatTable == "" {
+		return 0, errors.New("no -heartbeat-table given")
+	}
+	if r.db == nil || r.engine.Driver != "mysql" {
+		return 0, errors.New("heartbeat tables are only read on MySQL and MariaDB")
+	}
+	schema, table, _ := strings.Cut(heartbeatTable, ".")
+	query := fmt.Sprintf("SELECT MAX(ts), UTC_TIMESTAMP(6) FROM `%s`.`%s`", schema, table)
+	var args []any
+	if id, err := strconv.ParseUint(r.lastStatus["Source_Server_Id"], 10, 32); err == nil && id > 0 {
+		query += " WHERE server_id = ?"
+		args = append(args, id)
+	}
+	var ts, now sql.NullString
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&ts, &now); err != nil {
+		return 0, err
+	}
+	if !ts.Valid {
+		return 0, errors.New("no heartbeat rows")
+	}
+	written, err := parseHeartbeatTime(ts.String)
+	if err != nil {
+		return 0, err
+	}
+	current, err := parseHeartbeatTime(now.String)
+	if err != nil {
+		return 0, err
+	}
+	return max(0, current.Sub(written).Seconds()), nil
+}
+
+// pt-heartbeat writes ts as 2006-01-02T15:04:05.999999; UTC_TIMESTAMP(6) has a space
+func parseHeartbeatTime(s string) (time.Time, error) {
+	return time.Parse("2006-01-02 15:04:05.999999", strings.Replace(s, "T", " ", 1))
+}
+
+// Print each source's reading and the reconciled lag under the replica's own lag
+func printReconciledLag(out io.Writer, r *replica) {
+	rec := r.lagReconciled
+	if rec == nil {
+		return
+	}
+	marker := "🧮"
+	if rec.disagree {
+		marker = "⚠️ "
+	}
+	line := fmt.Sprintf("%s Reconciled lag: ", marker)
+	if len(rec.readings) == 0 {
+		line += "unknown"
+	} else {
+		line += fmt.Sprintf("%s from %s", formatDuration(rec.seconds), rec.describeReadings())
+	}
+	if rec.disagree {
+		line += fmt.Sprintf("; sources disagree by %s", formatDuration(rec.spread))
+	}
+	if len(rec.missing) > 0 {
+		line += fmt.Sprintf("; no reading from %s", strings.Join(rec.missing, ", "))
+	}
+	fmt.Fprintln(out, line)
+}
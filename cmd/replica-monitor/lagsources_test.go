@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReconcileLag(t *testing.T) {
+	defer func(sources []string, d time.Duration) { lagSources, lagDisagreement = sources, d }(lagSources, lagDisagreement)
+	lagSources, lagDisagreement = []string{"status", "cloudwatch"}, time.Minute
+
+	r := &replica{name: "replica", lagSeconds: 0, lagKnown: true}
+	defer delete(cloudWatchSamples, r)
+	cloudWatchSamples[r] = &cloudWatchSample{seconds: 240, at: time.Now()}
+
+	// Two sources reconcile to the larger, and a spread above -lag-disagreement alerts
+	var sample historySample
+	reconcileLag(context.Background(), r, &sample)
+	if !r.lagKnown || r.lagSeconds != 240 || sample.SecondsBehind == nil || *sample.SecondsBehind != 240 {
+		t.Errorf("reconciled lag %v (known %v); want 240", r.lagSeconds, r.lagKnown)
+	}
+	if !r.lagReconciled.disagree || r.lastAlert == nil || r.lastAlert.Event != "lag_sources_disagree" {
+		t.Errorf("disagreement %+v, alert %+v; want lag_sources_disagree", r.lagReconciled, r.lastAlert)
+	}
+	r.lastAlert, r.lagSeconds = nil, 10
+	reconcileLag(context.Background(), r, &sample)
+	if r.lastAlert != nil {
+		t.Error("alerted again while the sources still disagree")
+	}
+
+	// A stale CloudWatch datapoint is left out
+	cloudWatchSamples[r].at = time.Now().Add(-time.Hour)
+	r.lagSeconds = 12
+	reconcileLag(context.Background(), r, &sample)
+	if r.lagSeconds != 12 || r.lagReconciled.disagree || len(r.lagReconciled.missing) != 1 {
+		t.Errorf("reconciled %+v; want status alone", r.lagReconciled)
+	}
+
+	for _, tc := range []struct {
+		readings     []float64
+		want, spread float64
+	}{
+		{[]float64{5}, 5, 0},
+		{[]float64{0, 300, 290}, 290, 300},
+		{[]float64{10, 20, 30, 40}, 30, 30},
+	} {
+		var readings []lagReading
+		for _, v := range tc.readings {
+			readings = append(readings, lagReading{"status", v})
+		}
+		if got, spread := reconcileReadings(readings); got != tc.want || spread != tc.spread {
+			t.Errorf("reconcileReadings(%v) = %v, %v; want %v, %v", tc.readings, got, spread, tc.want, tc.spread)
+		}
+	}
+	if ts, err := parseHeartbeatTime("2025-07-24T16:10:46.120000"); err != nil || ts.Nanosecond() != 120000000 {
+		t.Errorf("parseHeartbeatTime = %v, %v", ts, err)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Leader election between redundant monitors through a MySQL advisory lock.
+// GET_LOCK is owned by a session, so the lock is held on one dedicated connection
+// and is released by the server as soon as the leader's session goes away.
+type leaderElector struct {
+	db       *sql.DB
+	conn     *sql.Conn
+	lockName string
+	leader   bool
+}
+
+// Set when -leader-election is enabled; nil means this monitor always acts
+var elector *leaderElector
+
+func newLeaderElector(db *sql.DB, lockName string) *leaderElector {
+	return &leaderElector{db: db, lockName: lockName}
+}
+
+// Whether this monitor may run skip actions and send alerts
+func isLeader() bool {
+	return elector == nil || elector.leader
+}
+
+// Confirm or try to acquire leadership; called once per monitoring cycle
+func (e *leaderElector) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	wasLeader := e.leader
+	held, err := e.hold(ctx)
+	if err != nil {
+		slog.Error("Failed to hold leader lock", "lock", e.lockName, "err", err)
+	}
+	e.leader = held
+
+	if e.leader && !wasLeader {
+		fmt.Fprintf(stdout, "👑 Acquired leader lock %q; this monitor will skip errors and send alerts\n", e.lockName)
+	} else if !e.leader && wasLeader {
+		fmt.Fprintf(stdout, "💤 Lost leader lock %q; standing by\n", e.lockName)
+	}
+}
+
+// Keep the lock, or take it if it is free, starting over on a fresh connection after errors
+func (e *leaderElector) hold(ctx context.Context) (bool, error) {
+	held, err := e.holdsLock(ctx)
+	if err != nil {
+		e.reset()
+		held = false
+	}
+	if !held {
+		held, err = e.acquire(ctx)
+		if err != nil {
+			e.reset()
+		}
+	}
+	return held, err
+}
+
+func (e *leaderElector) holdsLock(ctx context.Context) (bool, error) {
+	if e.conn == nil {
+		return false, nil
+	}
+	var held sql.NullBool
+	err := e.conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", e.lockName).Scan(&held)
+	return held.Valid && held.Bool, err
+}
+
+func (e *leaderElector) acquire(ctx context.Context) (bool, error) {
+	if e.conn == nil {
+		conn, err := e.db.Conn(ctx)
+		if err != nil {
+			return false, err
+		}
+		e.conn = conn
+	}
+	var got sql.NullInt64
+	if err := e.conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", e.lockName).Scan(&got); err != nil {
+		return false, err
+	}
+	return got.Valid && got.Int64 == 1, nil
+}
+
+// Drop the lock session so the next check starts over on a fresh connection
+func (e *leaderElector) reset() {
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn = nil
+	}
+}
+
+// Release the lock on shutdown so a standby can take over immediately
+func (e *leaderElector) release() {
+	if e.conn == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	e.conn.ExecContext(ctx, "DO RELEASE_LOCK(?)", e.lockName)
+	e.reset()
+}
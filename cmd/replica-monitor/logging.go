@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Operational log encoding from -log-format: text or json
+var logFormat = "text"
+
+var logFormats = []string{"text", "json"}
+
+// Shared by every handler so the level survives a change of log destination; set by -log-level
+var logLevel = new(slog.LevelVar)
+
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// Log file from -log-file, rotated by size and age and compressed once rotated
+var (
+	logFile       string
+	logMaxSizeMB  int
+	logMaxAgeDays int
+	logFileWriter *lumberjack.Logger
+)
+
+// Connect to the journal and the other log sinks, once, before setupLogging
+// routes records to them
+func openLogDestinations() error {
+	if journald == nil && useJournald() {
+		h, err := newJournaldHandler()
+		if err != nil {
+			return fmt.Errorf("connect to journald at %s: %w", journaldSocket, err)
+		}
+		journald = h
+	}
+	if logSinks == nil {
+		sinks, err := openLogSinks()
+		if err != nil {
+			return err
+		}
+		logSinks = sinks
+	}
+	return nil
+}
+
+// Route operational logs to w, or to -log-file when set; the report itself always goes to stdout
+func setupLogging(w io.Writer) {
+	if logFile != "" {
+		w = logFileOutput()
+	}
+	if verbosity >= 2 {
+		logLevel.Set(slog.LevelDebug)
+	}
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler
+	if journald != nil && w == os.Stderr {
+		handler = journald
+	} else if logFormat == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	if len(logSinks) > 0 {
+		handler = fanoutHandler(append([]slog.Handler{handler}, logSinks...))
+	}
+	handler = redactHandler{correlationHandler{handler}}
+	slog.SetDefault(slog.New(handler))
+	// The driver logs on its own logger otherwise
+	mysql.SetLogger(slog.NewLogLogger(handler, slog.LevelError))
+}
+
+// -log-file, opened on first use
+func logFileOutput() io.Writer {
+	if logFileWriter == nil {
+		logFileWriter = &lumberjack.Logger{
+			Filename: logFile,
+			MaxSize:  logMaxSizeMB,
+			MaxAge:   logMaxAgeDays,
+			Compress: true,
+		}
+	}
+	return logFileWriter
+}
+
+// Windows Event Log source from -eventlog; alerts and errors are written there
+var eventLogSource string
+
+// Replaces standard error when logging to the journal
+var journald *journaldHandler
+
+// Additional destinations every operational log record is copied to, opened once
+var logSinks []slog.Handler
+
+func openLogSinks() ([]slog.Handler, error) {
+	sinks := []slog.Handler{}
+	if syslogAddr != "" {
+		h, err := newSyslogHandler(syslogAddr)
+		if err != nil {
+			return nil, fmt.Errorf("connect to syslog at %s: %w", syslogAddr, err)
+		}
+		sinks = append(sinks, h)
+	}
+	if lokiURL != "" {
+		loki = startLoki(lokiURL)
+		sinks = append(sinks, &lokiHandler{})
+	}
+	if eventLogSource != "" {
+		h, err := newEventLogHandler(eventLogSource)
+		if err != nil {
+			return nil, fmt.Errorf("open the Windows Event Log source %s: %w", eventLogSource, err)
+		}
+		sinks = append(sinks, h)
+	}
+	return sinks, nil
+}
+
+// Hands each record to every handler that wants it
+type fanoutHandler []slog.Handler
+
+func (f fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanoutHandler) Handle(ctx context.Context, rec slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, rec.Level) {
+			errs = append(errs, h.Handle(ctx, rec.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := make(fanoutHandler, len(f))
+	for i, h := range f {
+		next[i] = h.WithAttrs(attrs)
+	}
+	return next
+}
+
+func (f fanoutHandler) WithGroup(name string) slog.Handler {
+	next := make(fanoutHandler, len(f))
+	for i, h := range f {
+		next[i] = h.WithGroup(name)
+	}
+	return next
+}
+
+// Debug records are wanted, so tracing is worth its overhead
+func debugLogging() bool {
+	return logLevel.Level() <= slog.LevelDebug
+}
+
+// Log at error level and exit, the slog counterpart of log.Fatalf
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Loki base URL from -loki-url, e.g. http://loki:3100; -loki-polls also pushes every poll
+var (
+	lokiURL   string
+	lokiPolls bool
+)
+
+const (
+	lokiFlushInterval = 2 * time.Second
+	// Entries kept while Loki is unreachable; the oldest are dropped beyond this
+	lokiMaxPending = 10000
+)
+
+type lokiEntry struct {
+	labels map[string]string
+	time   time.Time
+	line   string
+}
+
+// Batches entries and pushes them to /loki/api/v1/push in the background
+type lokiClient struct {
+	url      string
+	hostname string
+	client   *http.Client
+
+	mu      sync.Mutex
+	pending monitor.Ring[lokiEntry]
+	failing bool
+}
+
+var loki *lokiClient
+
+func startLoki(baseURL string) *lokiClient {
+	c := &lokiClient{
+		url:     strings.TrimSuffix(baseURL, "/") + "/loki/api/v1/push",
+		client:  &http.Client{Timeout: 10 * time.Second},
+		pending: monitor.NewRing[lokiEntry](lokiMaxPending),
+	}
+	c.hostname, _ = os.Hostname()
+	go func() {
+		for range time.Tick(lokiFlushInterval) {
+			c.flush()
+		}
+	}()
+	return c
+}
+
+// Queue a line under the job, host, and severity labels
+func (c *lokiClient) add(host, severity string, t time.Time, line string) {
+	if host == "" {
+		host = c.hostname
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending.Push(lokiEntry{
+		labels: map[string]string{"job": "replica-monitor", "host": host, "severity": severity},
+		time:   t,
+		line:   line,
+	})
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (c *lokiClient) flush() {
+	c.mu.Lock()
+	entries := c.pending.Values()
+	c.pending = monitor.NewRing[lokiEntry](lokiMaxPending)
+	c.mu.Unlock()
+	if len(entries) == 0 {
+		return
+	}
+
+	streams := make(map[string]*lokiStream)
+	var keys []string
+	for _, e := range entries {
+		key := e.labels["host"] + "\x00" + e.labels["severity"]
+		s, ok := streams[key]
+		if !ok {
+			s = &lokiStream{Stream: e.labels}
+			streams[key] = s
+			keys = append(keys, key)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+	}
+	sort.Strings(keys)
+	body := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range keys {
+		body.Streams = append(body.Streams, streams[key])
+	}
+
+	err := c.push(body)
+	c.mu.Lock()
+	wasFailing := c.failing
+	c.failing = err != nil
+	if err != nil {
+		// Keep the batch for the next attempt ahead of newer entries, within the pending limit
+		newer := c.pending.Values()
+		c.pending = monitor.NewRing[lokiEntry](lokiMaxPending)
+		for _, e := range append(entries, newer...) {
+			c.pending.Push(e)
+		}
+	}
+	c.mu.Unlock()
+	// Report only changes so an unreachable Loki does not flood the other sinks
+	if err != nil && !wasFailing {
+		slog.Error("Failed to push to Loki", "url", c.url, "err", err)
+	} else if err == nil && wasFailing {
+		slog.Info("Pushing to Loki again", "url", c.url)
+	}
+}
+
+func (c *lokiClient) push(body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Post(c.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("push rejected: %s", resp.Status)
+	}
+	return nil
+}
+
+// Queue a logfmt line per polled replica; called once per monitoring cycle with -loki-polls
+func pushLokiPolls(replicas []*replica) {
+	if loki == nil || !lokiPolls {
+		return
+	}
+	for _, r := range replicas {
+		if r.polledAt.IsZero() {
+			continue
+		}
+		var b strings.Builder
+		if r.lagKnown {
+			fmt.Fprintf(&b, "lag_seconds=%g ", r.lagSeconds)
+		}
+		if !r.aurora {
+			fmt.Fprintf(&b, "io_running=%s sql_running=%s error_matched=%t skips=%d ", orDash(r.ioRunning), orDash(r.sqlRunning), r.errorMatched, r.skips)
+		}
+		severity := "info"
+		if r.errorMatched || (!r.aurora && (r.ioRunning != "Yes" || r.sqlRunning != "Yes")) {
+			severity = "warn"
+		}
+		loki.add(displayName(r), severity, r.polledAt, "msg=poll "+strings.TrimSpace(b.String()))
+	}
+}
+
+// Sends operational log records to Loki as logfmt lines, labeled by the
+// record's replica and level
+type lokiHandler struct {
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (h *lokiHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= logLevel.Level()
+}
+
+func (h *lokiHandler) Handle(_ context.Context, rec slog.Record) error {
+	var b strings.Builder
+	b.WriteString("msg=" + strconv.Quote(rec.Message))
+	host := ""
+	add := func(a slog.Attr) {
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		if a.Key == "replica" {
+			host = a.Value.String()
+		}
+		fmt.Fprintf(&b, " %s=%s", h.prefix+a.Key, strconv.Quote(a.Value.String()))
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	rec.Attrs(func(a slog.Attr) bool {
+		add(a)
+		return true
+	})
+	loki.add(host, strings.ToLower(rec.Level.String()), rec.Time, b.String())
+	return nil
+}
+
+func (h *lokiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &next
+}
+
+func (h *lokiHandler) WithGroup(name string) slog.Handler {
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
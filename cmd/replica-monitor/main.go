@@ -0,0 +1,725 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Command line flags
+var (
+	host     string
+	user     string
+	password string
+	port     int
+	history  string
+
+	// -engine: auto, or one of monitor.Engines()
+	engineName = "auto"
+
+	queryTimeout time.Duration
+
+	discoverRDS      bool
+	discoverTags     keyValueFlags
+	sourceInstance   string
+	discoverInterval time.Duration
+	auroraCluster    string
+	topology         bool
+	assumeYes        bool
+	configPath       string
+	labelFlags       keyValueFlags
+	alertWebhook     string
+	lagThreshold     time.Duration
+	leaderElection   bool
+	leaderLockHost   string
+	leaderLockName   string
+	sourceHost       string
+	sourcePort       int
+	httpAddr         string
+	grpcAddr         string
+	tuiMode          bool
+	quiet            bool
+	fieldList        commaListFlag
+	diffMode         bool
+	outputFormat     string
+	sparklineWidth   int
+	refreshMode      bool
+	wide             bool
+	interval         time.Duration
+	jitter           time.Duration
+)
+
+// Labels attached to every replica, from the pod, the config file, and -label flags
+var globalLabels map[string]string
+
+// Ended by the Windows service control manager to stop the monitor as SIGTERM
+// would; never elsewhere
+var serviceStop = context.Background()
+
+func main() {
+	defer redactPanic()
+	if runAsService() {
+		return
+	}
+	if code := runCommand(os.Args[1:]); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// Returned by connectTargets after printing usage when nothing to monitor was
+// given, or when the operator declined to monitor a discovered topology
+var errNothingToMonitor = errors.New("nothing to monitor")
+
+// Connect to the monitored replicas, or find them all through the RDS API,
+// reporting progress to out
+func connectTargets(fs *flag.FlagSet, out io.Writer) ([]*replica, *rdsDiscovery, error) {
+	cfg := &fileConfig{}
+	if configPath != "" {
+		var err error
+		cfg, err = loadConfig(configPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load config: %w", err)
+		}
+		if err := validateSettings(cfg); err != nil {
+			return nil, nil, fmt.Errorf("load config: %s: %w", configPath, err)
+		}
+		rememberCommandLine(fs)
+		if _, err := applySettings(cfg); err != nil {
+			return nil, nil, fmt.Errorf("load config: %w", err)
+		}
+	}
+	globalLabels = mergeLabels(podLabels, cfg.Labels, labelFlags)
+
+	if simulate {
+		if simulateReplicas < 1 || simulateSpeed <= 0 {
+			fs.Usage()
+			return nil, nil, errors.New("-simulate-replicas and -simulate-speed must be positive")
+		}
+		replicas := simulatedReplicas()
+		fmt.Fprintf(out, "Simulating %d replicas; no database is contacted\n", len(replicas))
+		return replicas, nil, nil
+	}
+
+	if err := loadPasswordFile(); err != nil {
+		return nil, nil, err
+	}
+	if err := checkActionUser(); err != nil {
+		return nil, nil, err
+	}
+
+	// Validate required parameters
+	if (host == "" && !discoverRDS && auroraCluster == "" && len(cfg.allReplicas()) == 0) || user == "" || (password == "" && authMode != "iam") {
+		fs.Usage()
+		return nil, nil, errNothingToMonitor
+	}
+	if _, err := tlsPolicy(); err != nil {
+		return nil, nil, err
+	}
+
+	var replicas []*replica
+	var discovery *rdsDiscovery
+	if discoverRDS || auroraCluster != "" {
+		var err error
+		discovery, err = newRDSDiscovery(discoverTags, sourceInstance, auroraCluster)
+		if err != nil {
+			return nil, nil, fmt.Errorf("set up RDS discovery: %w", err)
+		}
+		replicas = discovery.reconcile(replicas)
+		if len(replicas) == 0 {
+			fmt.Fprintln(out, "No matching replicas found yet; will re-scan every", discoverInterval)
+		}
+	} else if topology {
+		root := discoverTopology(host, port)
+		printTopology(out, root)
+		replicas = root.replicas()
+		if len(replicas) == 0 {
+			fmt.Fprintln(out, "No reachable downstream replicas found")
+			return nil, nil, errNothingToMonitor
+		}
+		if !assumeYes && !confirm(fmt.Sprintf("Monitor all %d downstream replicas?", len(replicas))) {
+			for _, r := range replicas {
+				r.close()
+			}
+			return nil, nil, errNothingToMonitor
+		}
+		fmt.Fprintln(out)
+	} else if len(cfg.allReplicas()) > 0 {
+		for _, rc := range cfg.allReplicas() {
+			r, err := connectReplica(rc.Name, rc.Host, rc.Port)
+			if err != nil {
+				for _, r := range replicas {
+					r.close()
+				}
+				return nil, nil, fmt.Errorf("connect to %s:%d: %w", rc.Host, rc.Port, err)
+			}
+			labels := rc.Labels
+			if rc.tenant != "" {
+				r.tenant = tenants[rc.tenant]
+				labels = mergeLabels(cfg.tenant(rc.tenant).Labels, rc.Labels, map[string]string{"tenant": rc.tenant})
+			}
+			r.labels = mergeLabels(r.labels, labels)
+			r.lagSources = rc.LagSources
+			replicas = append(replicas, r)
+			fmt.Fprintf(out, "Successfully connected to %s database at %s:%d%s\n", r.engine.Title, rc.Host, rc.Port, viaProxy(r))
+		}
+	} else {
+		r, err := connectReplica("", host, port)
+		if err != nil {
+			return nil, nil, fmt.Errorf("connect to %s:%d: %w", host, port, err)
+		}
+		replicas = append(replicas, r)
+		fmt.Fprintf(out, "Successfully connected to %s database at %s:%d%s\n", r.engine.Title, host, port, viaProxy(r))
+	}
+	// Discovery checks the replicas it connects to itself
+	if discovery == nil {
+		for _, r := range replicas {
+			if err := checkPrivileges(r); err != nil {
+				for _, r := range replicas {
+					r.close()
+				}
+				return nil, nil, fmt.Errorf("check privileges: %w", err)
+			}
+		}
+	}
+	return replicas, discovery, nil
+}
+
+// Continuously monitor the replicas until interrupted; serve mode discards the
+// scrolling report and only answers on the API listeners. A startup failure is
+// returned once everything opened so far, the PID file included, is closed.
+func runMonitor(fs *flag.FlagSet, serve bool) error {
+	if interval <= 0 || jitter < 0 {
+		return usageError(fs, "-interval must be positive and -jitter not negative")
+	}
+	if minInterval < 0 || maxInterval < 0 || (minInterval > 0 && maxInterval > 0 && minInterval > maxInterval) {
+		return usageError(fs, "-min-interval and -max-interval must be positive, with -min-interval no longer than -max-interval")
+	}
+	deadline, err := runDeadline()
+	if err != nil {
+		return usageError(fs, err.Error())
+	}
+	if readOnly && (leaderElection || skipLockMode == "mysql") {
+		return usageError(fs, "-leader-election and -skip-lock mysql take locks on the database, which -read-only refuses")
+	}
+	if serve && httpAddr == "" && grpcAddr == "" {
+		return usageError(fs, "serve needs -http or -grpc")
+	}
+
+	if daemonMode && !daemonized {
+		return startDaemon()
+	}
+	removePIDFile, err := writePIDFile()
+	if err != nil {
+		return fmt.Errorf("write PID file %s: %w", pidFile, err)
+	}
+	defer removePIDFile()
+
+	replicas, discovery, err := connectTargets(fs, stdout)
+	if errors.Is(err, errNothingToMonitor) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, r := range replicas {
+			r.close()
+		}
+	}()
+
+	// Join leader election before acting on anything
+	if leaderElection {
+		lockHost := leaderLockHost
+		if lockHost == "" {
+			lockHost = host
+		}
+		if lockHost == "" {
+			return errors.New("-leader-election needs -leader-lock-host when -host is not set")
+		}
+		lockDB, err := connectReplica("leader-lock", lockHost, port)
+		if err != nil {
+			return fmt.Errorf("connect to leader lock host %s: %w", lockHost, err)
+		}
+		defer lockDB.close()
+		elector = newLeaderElector(lockDB.db, leaderLockName)
+		defer elector.release()
+	}
+
+	// Watch the source as well when asked to
+	if sourceHost != "" {
+		conn, err := connectReplica("source", sourceHost, sourcePort)
+		if err != nil {
+			return fmt.Errorf("connect to source %s:%d: %w", sourceHost, sourcePort, err)
+		}
+		defer conn.close()
+		sourceMonitor = &sourceHealth{conn: conn}
+	}
+
+	// Open the history store and metrics exporters if requested
+	if err := startExporters(); err != nil {
+		return fmt.Errorf("set up exporters: %w", err)
+	}
+	if eventsFile != "" {
+		if err := openEvents(eventsFile); err != nil {
+			return fmt.Errorf("open events file %s: %w", eventsFile, err)
+		}
+		defer closeEvents()
+	}
+	if err := openAudit(); err != nil {
+		return fmt.Errorf("open audit log %s: %w", auditLog, err)
+	}
+	defer closeAudit()
+	if eventBridgeBus != "" {
+		if err := startEventBridge(); err != nil {
+			return fmt.Errorf("set up EventBridge bus %s: %w", eventBridgeBus, err)
+		}
+	}
+	if err := startStreams(); err != nil {
+		return fmt.Errorf("set up Kinesis or Firehose: %w", err)
+	}
+
+	stopTracing, err := startTracing()
+	if err != nil {
+		return fmt.Errorf("start OTLP trace export to %s: %w", otlpEndpoint, err)
+	}
+	defer stopTracing()
+
+	if err := setupAPIAuth(); err != nil {
+		return fmt.Errorf("set up API authentication: %w", err)
+	}
+	if httpAddr != "" {
+		if err := startHTTPServer(httpAddr); err != nil {
+			return err
+		}
+	}
+	if grpcAddr != "" {
+		if err := startGRPCServer(grpcAddr); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(stdout, "Starting replica status monitoring...")
+	if !daemonized {
+		fmt.Fprintln(stdout, "Press Ctrl+C to stop")
+	}
+	fmt.Fprintln(stdout)
+
+	if quiet && !serve && !tuiMode {
+		startQuiet()
+	}
+	if serve {
+		stdout = io.Discard
+	}
+
+	checkWatchdogInterval(max(interval, maxInterval) + jitter)
+
+	// Stop between cycles on SIGINT, SIGTERM, or a Windows service stop; a
+	// second signal exits at once
+	running, stop := signal.NotifyContext(serviceStop, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if !deadline.IsZero() {
+		fmt.Fprintf(stdout, "⏰ Stopping at %s\n", deadline.Format("2006-01-02 15:04:05"))
+		var cancel context.CancelFunc
+		running, cancel = context.WithDeadlineCause(running, deadline, errScheduledStop)
+		defer cancel()
+	}
+	// Quitting the terminal UI, which takes Ctrl+C as a key, stops the loop too
+	var ui *tui
+	if tuiMode {
+		var quit context.CancelCauseFunc
+		running, quit = context.WithCancelCause(running)
+		ui = startTUI(quit)
+	}
+	go watchShutdown(running)
+	daemonReady()
+	runStart = time.Now()
+	if passwordFile != "" {
+		go watchPasswordFile(running)
+	}
+	if configPath != "" {
+		go watchConfigFile(running)
+	}
+
+	// Main monitoring loop
+	for running.Err() == nil {
+		if discovery != nil && time.Since(discovery.lastScan) >= discoverInterval {
+			replicas = discovery.reconcile(replicas)
+		}
+		if elector != nil {
+			elector.check()
+		}
+		applyConfigReload()
+		runManualSkips(stdout, replicas)
+		checkRDSEvents(replicas)
+		refreshInstanceInfo(replicas)
+		if pollingPaused.Load() {
+			notifySystemd(replicas)
+			waitForNextCycle(running, nextInterval())
+			continue
+		}
+
+		skipped := superviseCycle(func() bool { return runCycle(stdout, replicas, serve) })
+
+		// Re-check immediately after a skip instead of waiting
+		if skipped {
+			continue
+		}
+		waitForNextCycle(running, nextInterval())
+	}
+	stop()
+	if ui != nil {
+		ui.stop()
+	}
+	shutdown(replicas, context.Cause(running))
+	return nil
+}
+
+// Poll every replica, printing the report to out, then announce, publish, and
+// export the results; reports true when a skip ran
+func runCycle(out io.Writer, replicas []*replica, serve bool) bool {
+	cycleStart := time.Now()
+	ctx, cycleSpan := tracer.Start(context.Background(), "cycle", trace.WithAttributes(attribute.Int("replicas", len(replicas))))
+	defer cycleSpan.End()
+	var skipped bool
+	if refreshMode && !quiet && !tuiMode && !serve {
+		skipped = redrawScreen(out, func(w io.Writer) bool { return pollOnce(ctx, w, replicas, !readOnly) })
+	} else {
+		skipped = pollOnce(ctx, out, replicas, !readOnly)
+	}
+	debugf("cycle polled %d replicas in %s", len(replicas), time.Since(cycleStart))
+	checkCloudWatchLag(replicas)
+	checkOSMetrics(replicas)
+	checkInsights(replicas)
+	checkStorage(replicas)
+	updateAdaptiveInterval(replicas)
+	updateThrottle(replicas)
+	_, notifySpan := tracer.Start(ctx, "notify")
+	defer notifySpan.End()
+	openNewIncidents(replicas)
+	announceTransitions(replicas)
+	resolveIncidents(replicas)
+	publishStatus(replicas)
+	reportExceptions(replicas)
+	ringOnTransitions(replicas)
+	exportZabbix(replicas)
+	pushLokiPolls(replicas)
+	notifySystemd(replicas)
+	return skipped
+}
+
+// Poll every replica once and print the per-cycle reports to out. With autoSkip,
+// matched errors are skipped (by the leader only); reports true when a skip ran.
+func pollOnce(ctx context.Context, out io.Writer, replicas []*replica, autoSkip bool) bool {
+	// With -format line the full report is discarded and each poll gets one line
+	report := out
+	if outputFormat == "line" {
+		report = io.Discard
+	}
+	nameWidth := 0
+	for _, r := range replicas {
+		nameWidth = max(nameWidth, len(displayName(r)))
+	}
+
+	if sourceMonitor != nil {
+		sourceMonitor.check(report)
+	}
+
+	skipped := false
+	for _, r := range replicas {
+		if r.aurora {
+			showAuroraReaderStatus(report, r)
+			if r.pollFailures == 0 {
+				checkRestart(ctx, r)
+			}
+			if outputFormat == "line" {
+				printPollLine(out, r, len(replicas) > 1, nameWidth)
+			}
+			continue
+		}
+		pollCtx, span := tracer.Start(ctx, "poll", trace.WithAttributes(attribute.String("replica", displayName(r))))
+		showReplicaStatus(pollCtx, report, r)
+		if r.pollFailures == 0 {
+			checkRestart(pollCtx, r)
+		}
+		if throttle && r.pollFailures == 0 {
+			checkLoad(pollCtx, r)
+		}
+		if outputFormat == "line" {
+			printPollLine(out, r, len(replicas) > 1, nameWidth)
+		}
+		if monitor.ShouldSkip(r.health(), autoSkip) {
+			if !isLeader() {
+				fmt.Fprintln(report, "💤 Standby monitor: leaving the skip to the leader")
+				span.End()
+				continue
+			}
+			if !holdsSkipLock(r) {
+				fmt.Fprintf(report, "💤 Another monitor holds the %s skip lock for %s: leaving the skip to it\n", skipLockMode, displayName(r))
+				span.End()
+				continue
+			}
+			_, skipSpan := tracer.Start(pollCtx, "skip")
+			spanError(skipSpan, r.skipReplError(report, "auto"))
+			skipSpan.End()
+			skipped = true
+		}
+		span.End()
+	}
+	if auroraCluster != "" {
+		showAuroraClusterLag(report, auroraCluster, replicas)
+	}
+	if len(replicas) > 1 {
+		printReplicaComparison(report, replicas)
+		printFleetSummary(report, replicas)
+	}
+	if topology {
+		printChainLag(report, replicas)
+	}
+	return skipped
+}
+
+func showReplicaStatus(ctx context.Context, out io.Writer, r *replica) bool {
+	now := time.Now()
+	r.lagKnown = false
+	r.ioRunning, r.sqlRunning = "", ""
+	r.errorMatched = false
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+	// The replica's monitor reads the status and parses, times, and matches it;
+	// skips are left to pollOnce, which audits and coordinates them
+	m := r.statusMonitor()
+	if err := m.SetErrorPatterns(replicaErrorPatterns(r)); err != nil {
+		slog.Error("Failed to match error pattern", "err", err)
+	}
+	sample, _, err := m.Poll(ctx)
+	if err != nil && sample.Row == nil && !errors.Is(err, monitor.ErrNotReplica) {
+		if errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprintf(out, "\n❌ [%s] %s did not answer SHOW REPLICA STATUS within %s\n", time.Now().Format("2006-01-02 15:04:05"), displayName(r), queryTimeout)
+		}
+		pollFailed(r, "SHOW REPLICA STATUS", err)
+		return false
+	}
+	if err != nil && sample.Row != nil {
+		slog.Error("Failed to match error pattern", "err", err)
+	}
+	pollSucceeded(r)
+	r.polledAt = now
+	r.stats = m.Stats()
+	replicationStats := &r.stats
+	// The span covers printing the row, then recording the matched patterns
+	_, stageSpan := tracer.Start(ctx, "parse")
+	defer func() { stageSpan.End() }()
+
+	if row := sample.Row; row != nil {
+		columns, values := row.Columns, row.Values
+
+		// Keep the whole row for the HTTP status endpoint
+		previousStatus := r.lastStatus
+		r.lastStatus = sample.Status
+
+		// With -diff, only the first poll prints the full report
+		diffOnly := diffMode && previousStatus != nil
+
+		// Print timestamp
+		if !diffOnly {
+			if r.name != "" {
+				fmt.Fprintf(out, "\n[%s] Replica Status (%s):\n", time.Now().Format("2006-01-02 15:04:05"), r.name)
+			} else {
+				fmt.Fprintf(out, "\n[%s] Replica Status:\n", time.Now().Format("2006-01-02 15:04:05"))
+			}
+			if len(r.labels) > 0 {
+				fmt.Fprintf(out, "Labels: %s\n", formatLabels(r.labels))
+			}
+			if r.instance != nil {
+				fmt.Fprintf(out, "Instance: %s\n", r.instance)
+			}
+			fmt.Fprintln(out, strings.Repeat("=", 50))
+		}
+
+		history := historySample{Time: now, Replica: r.name, Host: r.host, Labels: r.labels,
+			IORunning: sample.IORunning, SQLRunning: sample.SQLRunning, LastSQLError: sample.LastSQLError,
+			SecondsBehind: sample.SecondsBehind}
+		if sample.SecondsBehind != nil {
+			r.lagSeconds = float64(*sample.SecondsBehind)
+			r.lagKnown = true
+			r.recordLag(r.lagSeconds)
+		}
+
+		// Print key fields
+		keyFields := []string{
+			"Replica_IO_State",
+			"Source_Host",
+			"Source_Port",
+			"Replica_IO_Running",
+			"Replica_SQL_Running",
+			"Replicate_Do_DB",
+			"Replicate_Ignore_DB",
+			"Last_IO_Error",
+			"Last_SQL_Error",
+			"Seconds_Behind_Source",
+		}
+
+		// -fields replaces the key list; -wide prints every column grouped by category
+		// above the lag line. The fields needed for lag stats and history are always read.
+		shownFields := keyFields
+		if wide {
+			if !diffOnly {
+				printWideStatus(out, row)
+			}
+			shownFields = []string{"Seconds_Behind_Source"}
+		} else if len(fieldList) > 0 {
+			shownFields = fieldList
+		}
+		polledFields := slices.Clone(shownFields)
+		for _, field := range []string{"Replica_IO_Running", "Replica_SQL_Running", "Last_SQL_Error", "Seconds_Behind_Source"} {
+			if !slices.Contains(polledFields, field) {
+				polledFields = append(polledFields, field)
+			}
+		}
+
+		for _, field := range polledFields {
+			w := out
+			if diffOnly || !slices.Contains(shownFields, field) {
+				w = io.Discard
+			} else if !slices.Contains(columns, field) {
+				fmt.Fprintf(w, "%s: not reported by this server\n", field)
+			}
+			for i, col := range columns {
+				if col == field {
+					val := values[i]
+					if val != nil {
+						strVal := row.ValueAt(i)
+
+						// Format Seconds_Behind_Source specially
+						if field == "Seconds_Behind_Source" {
+							if strVal != "NULL" && strVal != "" {
+								if sample.SecondsBehind != nil {
+									seconds := *sample.SecondsBehind
+									if seconds > 0 {
+										fmt.Fprintf(w, "%s: %s\n", field, formatDuration(float64(seconds)))
+									} else {
+										fmt.Fprintf(w, "%s: %s (caught up!)\n", field, formatDuration(0))
+									}
+
+									// Display rates and estimates
+									fmt.Fprintln(w, "📊 Replication Performance:")
+
+									// Short-term rate (like instant MPG)
+									if replicationStats.Rate != 0 {
+										if replicationStats.Rate < 0 {
+											fmt.Fprintf(w, "  🚀 Instant: Catching up at %.2f seconds/second\n", -replicationStats.Rate)
+										} else {
+											fmt.Fprintf(w, "  ⚠️  Instant: Falling behind at %.2f seconds/second\n", replicationStats.Rate)
+										}
+									}
+
+									// Long-term average rate (like average MPG)
+									if replicationStats.AverageRate != 0 {
+										if replicationStats.AverageRate < 0 {
+											fmt.Fprintf(w, "  📈 Average: Catching up at %.2f seconds/second\n", -replicationStats.AverageRate)
+										} else {
+											fmt.Fprintf(w, "  ⚠️  Average: Falling behind at %.2f seconds/second\n", replicationStats.AverageRate)
+										}
+									}
+
+									// One best/worst-case window instead of separate instant and average ETAs
+									if seconds > 0 {
+										printETABand(w, replicationStats, float64(seconds), now)
+									}
+								} else {
+									fmt.Fprintf(w, "%s: %s\n", field, strVal)
+								}
+							} else {
+								fmt.Fprintf(w, "%s: %s\n", field, strVal)
+							}
+						} else {
+							fmt.Fprintf(w, "%s: %s\n", field, strVal)
+						}
+					} else {
+						fmt.Fprintf(w, "%s: NULL\n", field)
+					}
+					break
+				}
+			}
+		}
+
+		checkReplicationTLS(r)
+		reconcileLag(ctx, r, &history)
+		if !diffOnly {
+			printLagTrend(out, r)
+			printCloudWatchLag(out, r)
+			printOSMetrics(out, r)
+			printInsights(out, r)
+			printStorage(out, r)
+			printReconciledLag(out, r)
+			printReplicationTLS(out, r)
+		}
+		if diffOnly {
+			watched := shownFields
+			if wide {
+				watched = columns
+			}
+			printStatusChanges(out, r, previousStatus, watched)
+		}
+
+		// Everything else in the row when running with -v
+		if verbosity >= 1 && !wide && !diffOnly {
+			fmt.Fprintln(out, "Other status fields:")
+			for i, col := range columns {
+				if slices.Contains(shownFields, col) {
+					continue
+				}
+				if values[i] == nil {
+					fmt.Fprintf(out, "  %s: NULL\n", col)
+				} else {
+					fmt.Fprintf(out, "  %s: %s\n", col, row.Value(col))
+				}
+			}
+		}
+
+		// Fold in findings from the source-side health connection
+		if sourceMonitor != nil {
+			sourceMonitor.checkReplica(out, r,
+				row.Value("Source_Log_File"),
+				row.Value("Last_IO_Errno"))
+		}
+		if !diffOnly {
+			fmt.Fprintln(out)
+		}
+
+		stageSpan.End()
+		_, stageSpan = tracer.Start(ctx, "evaluate")
+
+		// Alert on the error patterns the monitor matched
+		for _, pattern := range sample.MatchedPatterns {
+			fmt.Fprintf(out, "🚨 Pattern '%s' found in Last_SQL_Error!\n", pattern)
+			sendAlert(r, "sql_error", fmt.Sprintf("Pattern '%s' found in Last_SQL_Error: %s", pattern, sample.LastSQLError))
+		}
+
+		history.ErrorMatched = sample.Matched()
+		recordHistory(history)
+		r.errorMatched = sample.Matched()
+		r.ioRunning = sample.IORunning
+		r.sqlRunning = sample.SQLRunning
+
+		return sample.Matched()
+	} else {
+		fmt.Fprintf(out, "\n[%s] No replica status found on %s\n", time.Now().Format("2006-01-02 15:04:05"), r.host)
+		return false
+	}
+}
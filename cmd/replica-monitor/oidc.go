@@ -0,0 +1,491 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// -oidc-issuer signs dashboard users in with an OpenID Connect provider, and
+// accepts its ID tokens as bearer tokens; -oidc-allow limits who gets in
+var (
+	oidcIssuer           string
+	oidcClientID         string
+	oidcClientSecretFile string
+	oidcRedirectURL      string
+	oidcAllow            []string
+)
+
+// Set up by setupAPIAuth
+var oidc *oidcProvider
+
+const (
+	// How long a dashboard sign-in lasts
+	oidcSessionLifetime = 8 * time.Hour
+	// How long a user has to sign in at the provider
+	oidcLoginLifetime = 10 * time.Minute
+	// Tokens are accepted this long past their expiry, for clock skew
+	oidcLeeway = time.Minute
+	// Signing keys are fetched again for an unknown key ID at most this often
+	oidcKeyRefresh = time.Minute
+
+	oidcSessionCookie = "replica_monitor_session"
+	oidcLoginCookie   = "replica_monitor_login"
+)
+
+type oidcProvider struct {
+	issuer        string
+	clientID      string
+	clientSecret  string
+	redirectURL   string
+	authEndpoint  string
+	tokenEndpoint string
+	jwksURI       string
+	client        *http.Client
+
+	// Signs session and login cookies; sessions end when the process restarts
+	cookieKey []byte
+
+	keysMu      sync.Mutex
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+// Find the provider's endpoints through its discovery document
+func newOIDCProvider(ctx context.Context) (*oidcProvider, error) {
+	if oidcClientID == "" || oidcRedirectURL == "" {
+		return nil, errors.New("needs -oidc-client-id and -oidc-redirect-url")
+	}
+	if _, err := url.Parse(oidcRedirectURL); err != nil {
+		return nil, fmt.Errorf("-oidc-redirect-url: %w", err)
+	}
+	p := &oidcProvider{
+		issuer:      strings.TrimSuffix(oidcIssuer, "/"),
+		clientID:    oidcClientID,
+		redirectURL: oidcRedirectURL,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		cookieKey:   make([]byte, 32),
+	}
+	rand.Read(p.cookieKey)
+	if oidcClientSecretFile != "" {
+		secret, err := readSecretFile(oidcClientSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("-oidc-client-secret-file: %w", err)
+		}
+		addSecret(secret)
+		p.clientSecret = secret
+	}
+
+	var discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != p.issuer && discovery.Issuer != p.issuer+"/" {
+		return nil, fmt.Errorf("the provider calls itself %q", discovery.Issuer)
+	}
+	p.issuer = discovery.Issuer
+	p.authEndpoint, p.tokenEndpoint, p.jwksURI = discovery.AuthorizationEndpoint, discovery.TokenEndpoint, discovery.JWKSURI
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// The path the provider sends users back to, from -oidc-redirect-url
+func (p *oidcProvider) callbackPath() string {
+	u, _ := url.Parse(p.redirectURL)
+	return u.Path
+}
+
+// Add the sign-in callback and sign-out endpoints, which need no credentials
+func (p *oidcProvider) routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+p.callbackPath(), p.callback)
+	mux.HandleFunc("GET /oauth2/sign-out", p.signOut)
+}
+
+// Fetch the provider's signing keys; RSA and P-256 keys are kept
+func (p *oidcProvider) refreshKeys(ctx context.Context) error {
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.jwksURI, &jwks); err != nil {
+		return err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range jwks.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN == nil && errE == nil {
+				keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+				continue
+			}
+			if key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), slices.Concat([]byte{4}, x, y)); err == nil {
+				keys[k.Kid] = key
+			}
+		}
+	}
+	p.keysMu.Lock()
+	defer p.keysMu.Unlock()
+	p.keys, p.keysFetched = keys, time.Now()
+	return nil
+}
+
+// The key a token was signed with, fetching the keys again when the provider
+// has rotated to one not seen yet
+func (p *oidcProvider) key(kid string) (crypto.PublicKey, error) {
+	p.keysMu.Lock()
+	key, ok := p.keys[kid]
+	stale := time.Since(p.keysFetched) > oidcKeyRefresh
+	p.keysMu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if stale {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := p.refreshKeys(ctx); err != nil {
+			return nil, err
+		}
+		p.keysMu.Lock()
+		key, ok = p.keys[kid]
+		p.keysMu.Unlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// The claims of an ID token the monitor looks at
+type oidcClaims struct {
+	Issuer        string       `json:"iss"`
+	Subject       string       `json:"sub"`
+	Audience      oidcAudience `json:"aud"`
+	Expiry        int64        `json:"exp"`
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified *bool        `json:"email_verified"`
+}
+
+// The aud claim, a string or an array of them
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = oidcAudience{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+// The user a token names: the email, or the subject
+func (c *oidcClaims) user() string {
+	if c.Email != "" {
+		return c.Email
+	}
+	return c.Subject
+}
+
+// Check an ID token's RS256 or ES256 signature and its claims, and the nonce of
+// a sign-in; "" for bearer tokens, whose nonce was checked by whoever signed in
+func (p *oidcProvider) verify(token, nonce string) (*oidcClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("not a JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" || rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) != nil {
+			return nil, errors.New("bad signature")
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" || len(sig) != 64 ||
+			!ecdsa.Verify(k, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			return nil, errors.New("bad signature")
+		}
+	default:
+		return nil, errors.New("unsupported key")
+	}
+
+	var claims oidcClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	switch {
+	case claims.Issuer != p.issuer:
+		return nil, fmt.Errorf("issued by %q", claims.Issuer)
+	case !slices.Contains(claims.Audience, p.clientID):
+		return nil, fmt.Errorf("issued for %q", claims.Audience)
+	case time.Now().After(time.Unix(claims.Expiry, 0).Add(oidcLeeway)):
+		return nil, errors.New("expired")
+	case nonce != "" && claims.Nonce != nonce:
+		return nil, errors.New("nonce mismatch")
+	case !oidcAllowed(&claims):
+		return nil, fmt.Errorf("%s is not allowed by -oidc-allow", claims.user())
+	}
+	return &claims, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Whether -oidc-allow lets the user in: any user when it is empty, otherwise
+// a verified email listed, or at a listed @domain
+func oidcAllowed(c *oidcClaims) bool {
+	if len(oidcAllow) == 0 {
+		return true
+	}
+	if c.Email == "" || (c.EmailVerified != nil && !*c.EmailVerified) {
+		return false
+	}
+	email := strings.ToLower(c.Email)
+	for _, allowed := range oidcAllow {
+		allowed = strings.ToLower(allowed)
+		if email == allowed || strings.HasPrefix(allowed, "@") && strings.HasSuffix(email, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// The user an ID token passed as a bearer token names
+func (p *oidcProvider) verifyBearer(token string) (string, error) {
+	claims, err := p.verify(token, "")
+	if err != nil {
+		return "", err
+	}
+	return claims.user(), nil
+}
+
+// Signed cookie values: JSON, then an HMAC of it
+func (p *oidcProvider) sign(v any) string {
+	data, _ := json.Marshal(v)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	mac := hmac.New(sha256.New, p.cookieKey)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (p *oidcProvider) unsign(value string, v any) bool {
+	payload, sig, _ := strings.Cut(value, ".")
+	mac := hmac.New(sha256.New, p.cookieKey)
+	mac.Write([]byte(payload))
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	return err == nil && hmac.Equal(got, mac.Sum(nil)) && decodeSegment(payload, v) == nil
+}
+
+func (p *oidcProvider) setCookie(w http.ResponseWriter, name, value string, lifetime time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name: name, Value: value, Path: "/", MaxAge: int(lifetime.Seconds()),
+		HttpOnly: true, Secure: strings.HasPrefix(p.redirectURL, "https://"), SameSite: http.SameSiteLaxMode,
+	})
+}
+
+type oidcSession struct {
+	User    string `json:"user"`
+	Expires int64  `json:"exp"`
+}
+
+// The signed-in user of a request's session cookie
+func (p *oidcProvider) session(req *http.Request) (string, bool) {
+	cookie, err := req.Cookie(oidcSessionCookie)
+	if err != nil {
+		return "", false
+	}
+	var s oidcSession
+	if !p.unsign(cookie.Value, &s) || time.Now().Unix() > s.Expires {
+		return "", false
+	}
+	return s.User, true
+}
+
+// A sign-in in progress
+type oidcLogin struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Return   string `json:"return"`
+	Expires  int64  `json:"exp"`
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Send the browser to sign in at the provider, with PKCE, and back to the page
+// it asked for
+func (p *oidcProvider) login(w http.ResponseWriter, req *http.Request) {
+	l := oidcLogin{
+		State: randomString(), Nonce: randomString(), Verifier: randomString(),
+		Return: req.URL.RequestURI(), Expires: time.Now().Add(oidcLoginLifetime).Unix(),
+	}
+	p.setCookie(w, oidcLoginCookie, p.sign(l), oidcLoginLifetime)
+	challenge := sha256.Sum256([]byte(l.Verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.clientID},
+		"redirect_uri":          {p.redirectURL},
+		"scope":                 {"openid email"},
+		"state":                 {l.State},
+		"nonce":                 {l.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(p.authEndpoint, "?") {
+		sep = "&"
+	}
+	http.Redirect(w, req, p.authEndpoint+sep+query.Encode(), http.StatusFound)
+}
+
+// Finish a sign-in: exchange the code for an ID token, check it, and start a
+// session
+func (p *oidcProvider) callback(w http.ResponseWriter, req *http.Request) {
+	cookie, err := req.Cookie(oidcLoginCookie)
+	var l oidcLogin
+	if err != nil || !p.unsign(cookie.Value, &l) || time.Now().Unix() > l.Expires || req.FormValue("state") != l.State {
+		http.Error(w, "sign-in expired or not started here; reload the dashboard", http.StatusBadRequest)
+		return
+	}
+	p.setCookie(w, oidcLoginCookie, "", -1)
+	if e := req.FormValue("error"); e != "" {
+		http.Error(w, "sign-in failed: "+e+" "+req.FormValue("error_description"), http.StatusForbidden)
+		return
+	}
+
+	claims, err := p.exchange(req.Context(), req.FormValue("code"), l)
+	if err != nil {
+		slog.Warn("Dashboard sign-in refused", "err", err, "remote", req.RemoteAddr)
+		http.Error(w, "sign-in refused", http.StatusForbidden)
+		return
+	}
+	slog.Info("Signed in to the dashboard", "user", claims.user(), "remote", req.RemoteAddr)
+	p.setCookie(w, oidcSessionCookie, p.sign(oidcSession{User: claims.user(), Expires: time.Now().Add(oidcSessionLifetime).Unix()}), oidcSessionLifetime)
+	target := l.Return
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+		target = "/"
+	}
+	http.Redirect(w, req, target, http.StatusFound)
+}
+
+// Redeem an authorization code at the token endpoint
+func (p *oidcProvider) exchange(ctx context.Context, code string, l oidcLogin) (*oidcClaims, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"code_verifier": {l.Verifier},
+	}
+	if p.clientSecret == "" {
+		form.Set("client_id", p.clientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if p.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		IDToken     string `json:"id_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("token endpoint: %s: %w", resp.Status, err)
+	}
+	if body.IDToken == "" {
+		return nil, fmt.Errorf("token endpoint: %s %s %s", resp.Status, body.Error, body.Description)
+	}
+	return p.verify(body.IDToken, l.Nonce)
+}
+
+// End the dashboard session
+func (p *oidcProvider) signOut(w http.ResponseWriter, req *http.Request) {
+	p.setCookie(w, oidcSessionCookie, "", -1)
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "Signed out")
+}
@@ -0,0 +1,649 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// operator runs a monitor Deployment, of the sidecar command, for every
+// ReplicaMonitor resource in the cluster, through the Kubernetes API with the
+// pod's service account
+var (
+	operatorNamespace string
+	operatorImage     string
+	operatorResync    time.Duration
+	operatorPrintCRD  bool
+	// Images and service accounts a ReplicaMonitor may name instead of the
+	// operator's, since either lets whoever writes the resource choose what runs
+	// with the monitor's credentials
+	operatorAllowImages          []string
+	operatorAllowServiceAccounts []string
+)
+
+const (
+	crdGroup   = "replica-monitor.io"
+	crdVersion = "v1"
+	// Field manager of the Deployments the operator applies
+	operatorManager = "replica-monitor-operator"
+	// Where a pod finds its service account's credentials
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// Where monitor pods mount their password secrets
+	monitorSecretsDir = "/etc/replica-monitor"
+)
+
+// The ReplicaMonitor custom resource definition, printed by -print-crd
+const replicaMonitorCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: replicamonitors.replica-monitor.io
+spec:
+  group: replica-monitor.io
+  scope: Namespaced
+  names:
+    kind: ReplicaMonitor
+    listKind: ReplicaMonitorList
+    plural: replicamonitors
+    singular: replicamonitor
+    shortNames: [rmon]
+  versions:
+    - name: v1
+      served: true
+      storage: true
+      subresources:
+        status: {}
+      additionalPrinterColumns:
+        - {name: Host, type: string, jsonPath: .spec.host}
+        - {name: Ready, type: boolean, jsonPath: .status.ready}
+        - {name: Message, type: string, jsonPath: .status.message, priority: 1}
+        - {name: Age, type: date, jsonPath: .metadata.creationTimestamp}
+      schema:
+        openAPIV3Schema:
+          type: object
+          required: [spec]
+          properties:
+            spec:
+              type: object
+              required: [host, user]
+              properties:
+                host: {type: string, description: "Replica endpoint, as -host"}
+                port: {type: integer, minimum: 1, maximum: 65535}
+                user: {type: string, description: "Account that polls replica status, as -user"}
+                passwordSecret:
+                  type: object
+                  description: "Secret key holding the password of user, mounted as -password-file; omit under auth iam"
+                  required: [name, key]
+                  properties:
+                    name: {type: string}
+                    key: {type: string}
+                auth: {type: string, enum: [password, iam]}
+                engine: {type: string}
+                actionUser: {type: string, description: "Account that skips errors and starts replication, as -action-user"}
+                actionPasswordSecret:
+                  type: object
+                  required: [name, key]
+                  properties:
+                    name: {type: string}
+                    key: {type: string}
+                notify:
+                  type: array
+                  description: "Alert destinations, each a Secret key holding a notifier URL such as slack://..., mounted as -notify-file"
+                  items:
+                    type: object
+                    required: [name, key]
+                    properties:
+                      name: {type: string}
+                      key: {type: string}
+                interval: {type: string, description: "Time between polls, e.g. 10s"}
+                lagThreshold: {type: string, description: "As -lag-threshold, e.g. 5m"}
+                labels:
+                  type: object
+                  additionalProperties: {type: string}
+                args:
+                  type: array
+                  description: "Further sidecar flags, e.g. [-prometheus, -notify, slack://...]"
+                  items: {type: string}
+                image: {type: string, description: "Monitor image, instead of the operator's -image; one of its -allow-image"}
+                serviceAccountName: {type: string, description: "One of the operator's -allow-service-account"}
+            status:
+              type: object
+              properties:
+                observedGeneration: {type: integer}
+                deployment: {type: string}
+                ready: {type: boolean}
+                message: {type: string}
+                conditions:
+                  type: array
+                  x-kubernetes-list-type: map
+                  x-kubernetes-list-map-keys: [type]
+                  items:
+                    type: object
+                    required: [type, status, lastTransitionTime]
+                    properties:
+                      type: {type: string, description: "Ready or DeploymentAvailable"}
+                      status: {type: string, enum: ["True", "False", "Unknown"]}
+                      reason: {type: string}
+                      message: {type: string}
+                      lastTransitionTime: {type: string, format: date-time}
+                      observedGeneration: {type: integer}
+`
+
+// A Kubernetes object's metadata, as far as the operator reads it
+type objectMeta struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	UID        string `json:"uid,omitempty"`
+	Generation int64  `json:"generation,omitempty"`
+}
+
+// A key of a Secret in the resource's namespace
+type secretKeyRef struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+type replicaMonitor struct {
+	Metadata objectMeta           `json:"metadata"`
+	Spec     replicaMonitorSpec   `json:"spec"`
+	Status   replicaMonitorStatus `json:"status"`
+}
+
+type replicaMonitorSpec struct {
+	Host                 string            `json:"host"`
+	Port                 int               `json:"port,omitempty"`
+	User                 string            `json:"user"`
+	PasswordSecret       *secretKeyRef     `json:"passwordSecret,omitempty"`
+	Auth                 string            `json:"auth,omitempty"`
+	Engine               string            `json:"engine,omitempty"`
+	ActionUser           string            `json:"actionUser,omitempty"`
+	ActionPasswordSecret *secretKeyRef     `json:"actionPasswordSecret,omitempty"`
+	Notify               []secretKeyRef    `json:"notify,omitempty"`
+	Interval             string            `json:"interval,omitempty"`
+	LagThreshold         string            `json:"lagThreshold,omitempty"`
+	Labels               map[string]string `json:"labels,omitempty"`
+	Args                 []string          `json:"args,omitempty"`
+	Image                string            `json:"image,omitempty"`
+	ServiceAccountName   string            `json:"serviceAccountName,omitempty"`
+}
+
+type replicaMonitorStatus struct {
+	ObservedGeneration int64       `json:"observedGeneration"`
+	Deployment         string      `json:"deployment"`
+	Ready              bool        `json:"ready"`
+	Message            string      `json:"message"`
+	Conditions         []condition `json:"conditions,omitempty"`
+}
+
+// A status condition, as Kubernetes API conventions lay them out, so that
+// kubectl wait --for=condition=Ready and generic tooling understand the resource
+type condition struct {
+	Type               string `json:"type"`
+	Status             string `json:"status"` // True, False, or Unknown
+	Reason             string `json:"reason"`
+	Message            string `json:"message"`
+	LastTransitionTime string `json:"lastTransitionTime"` // RFC 3339
+	ObservedGeneration int64  `json:"observedGeneration"`
+}
+
+// Conditions of a ReplicaMonitor
+const (
+	conditionReady               = "Ready"               // the monitor is running against the replica
+	conditionDeploymentAvailable = "DeploymentAvailable" // the monitor Deployment was applied and its pod is ready
+)
+
+func conditionStatus(ok bool) string {
+	if ok {
+		return "True"
+	}
+	return "False"
+}
+
+// Stamp conditions with their last transition time: the previous one's when
+// the condition kept its status, now otherwise
+func transitionConditions(previous []condition, now time.Time, conditions ...condition) []condition {
+	for i, c := range conditions {
+		conditions[i].LastTransitionTime = now.UTC().Format(time.RFC3339)
+		for _, p := range previous {
+			if p.Type == c.Type && p.Status == c.Status && p.LastTransitionTime != "" {
+				conditions[i].LastTransitionTime = p.LastTransitionTime
+			}
+		}
+	}
+	return conditions
+}
+
+func (s replicaMonitorStatus) equal(other replicaMonitorStatus) bool {
+	return s.ObservedGeneration == other.ObservedGeneration && s.Deployment == other.Deployment &&
+		s.Ready == other.Ready && s.Message == other.Message && slices.Equal(s.Conditions, other.Conditions)
+}
+
+// A client of the Kubernetes API server
+type kubeClient struct {
+	base      string
+	tokenFile string // read for every request, since projected tokens rotate
+	client    *http.Client
+}
+
+// A client for the API server of the cluster the pod runs in
+func newInClusterClient() (*kubeClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes pod: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are unset")
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("%s/ca.crt holds no certificates", serviceAccountDir)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	return &kubeClient{
+		base:      "https://" + net.JoinHostPort(host, port),
+		tokenFile: serviceAccountDir + "/token",
+		client:    &http.Client{Transport: transport},
+	}, nil
+}
+
+// Send a request with a JSON body, when given, and decode the JSON answer into
+// out, when given
+func (c *kubeClient) do(ctx context.Context, method, path, contentType string, body, out any) error {
+	resp, err := c.send(ctx, method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Send a request and return the response to a successful one
+func (c *kubeClient) send(ctx context.Context, method, path, contentType string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.tokenFile != "" {
+		token, err := readSecretFile(c.tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		// The API server explains failures in a Status object
+		var status struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&status)
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, status.Message)
+	}
+	return resp, nil
+}
+
+// The path of the ReplicaMonitors in namespace, or in every namespace for ""
+func replicaMonitorsPath(namespace string) string {
+	if namespace == "" {
+		return "/apis/" + crdGroup + "/" + crdVersion + "/replicamonitors"
+	}
+	return "/apis/" + crdGroup + "/" + crdVersion + "/namespaces/" + namespace + "/replicamonitors"
+}
+
+type operator struct {
+	kube                 *kubeClient
+	namespace            string
+	image                string
+	allowImages          []string
+	allowServiceAccounts []string
+	wake                 chan struct{}
+}
+
+func operatorFlags() *flag.FlagSet {
+	fs := newCommandFlags("operator")
+	fs.StringVar(&operatorNamespace, "namespace", "", "Only manage ReplicaMonitors in this namespace (default: every namespace)")
+	fs.StringVar(&operatorImage, "image", "", "Image of the monitor Deployments, unless a ReplicaMonitor names its own (required)")
+	fs.DurationVar(&operatorResync, "resync", 30*time.Second, "Reconcile every ReplicaMonitor this often, besides whenever one changes")
+	fs.BoolVar(&operatorPrintCRD, "print-crd", false, "Print the ReplicaMonitor CustomResourceDefinition and exit")
+	operatorAllowImages, operatorAllowServiceAccounts = nil, nil
+	fs.Func("allow-image", "An image a ReplicaMonitor may run instead of -image; repeatable (default: none)", func(v string) error {
+		operatorAllowImages = append(operatorAllowImages, v)
+		return nil
+	})
+	fs.Func("allow-service-account", "A service account a ReplicaMonitor may run as instead of the namespace's default; repeatable (default: none)", func(v string) error {
+		operatorAllowServiceAccounts = append(operatorAllowServiceAccounts, v)
+		return nil
+	})
+	return fs
+}
+
+func runOperator(fs *flag.FlagSet) error {
+	if err := applyEnvFlags(fs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitWith(2)
+	}
+	if operatorPrintCRD {
+		fmt.Print(replicaMonitorCRD)
+		return nil
+	}
+	if operatorImage == "" || operatorResync <= 0 {
+		return usageError(fs, "")
+	}
+	logFormat = "json"
+	if err := setupOutput(); err != nil {
+		return err
+	}
+
+	kube, err := newInClusterClient()
+	if err != nil {
+		return fmt.Errorf("set up the Kubernetes API client: %w", err)
+	}
+	o := &operator{kube: kube, namespace: operatorNamespace, image: operatorImage,
+		allowImages: operatorAllowImages, allowServiceAccounts: operatorAllowServiceAccounts, wake: make(chan struct{}, 1)}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	slog.Info("Operator started", "namespace", operatorNamespace, "image", operatorImage)
+	go o.watch(ctx)
+	for ctx.Err() == nil {
+		if err := o.reconcile(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to list ReplicaMonitors", "err", err)
+		}
+		select {
+		case <-o.wake:
+		case <-time.After(operatorResync):
+		case <-ctx.Done():
+		}
+	}
+	slog.Info("Operator stopped")
+	return nil
+}
+
+// Wake the reconcile loop whenever a ReplicaMonitor changes, resuming the watch
+// when the API server ends it
+func (o *operator) watch(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := o.watchOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Warn("ReplicaMonitor watch failed", "err", err)
+		}
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (o *operator) watchOnce(ctx context.Context) error {
+	resp, err := o.kube.send(ctx, http.MethodGet, replicaMonitorsPath(o.namespace)+"?watch=true", "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var event struct {
+			Type   string         `json:"type"`
+			Object replicaMonitor `json:"object"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &event) != nil {
+			continue
+		}
+		slog.Debug("ReplicaMonitor changed", "event", event.Type, "namespace", event.Object.Metadata.Namespace, "name", event.Object.Metadata.Name)
+		select {
+		case o.wake <- struct{}{}:
+		default:
+		}
+	}
+	return scanner.Err()
+}
+
+// Bring every ReplicaMonitor's Deployment and status up to date. Deleted
+// ones need nothing: their Deployments are garbage collected with them.
+func (o *operator) reconcile(ctx context.Context) error {
+	var list struct {
+		Items []replicaMonitor `json:"items"`
+	}
+	if err := o.kube.do(ctx, http.MethodGet, replicaMonitorsPath(o.namespace), "", nil, &list); err != nil {
+		return err
+	}
+	for _, rm := range list.Items {
+		if err := o.reconcileOne(ctx, rm); err != nil {
+			slog.Error("Failed to reconcile ReplicaMonitor", "namespace", rm.Metadata.Namespace, "name", rm.Metadata.Name, "err", err)
+		}
+	}
+	return nil
+}
+
+func (o *operator) reconcileOne(ctx context.Context, rm replicaMonitor) error {
+	name := monitorDeploymentName(rm)
+	status := replicaMonitorStatus{ObservedGeneration: rm.Metadata.Generation, Deployment: name}
+	var deployment struct {
+		Status struct {
+			ReadyReplicas int `json:"readyReplicas"`
+		} `json:"status"`
+	}
+	// Server-side apply, which takes JSON as YAML, leaves alone what others
+	// set on the Deployment, such as an autoscaler's annotations
+	path := fmt.Sprintf("/apis/apps/v1/namespaces/%s/deployments/%s?fieldManager=%s&force=true", rm.Metadata.Namespace, name, operatorManager)
+	refused := o.refuseOverrides(rm.Spec)
+	var err error
+	if refused == nil {
+		err = o.kube.do(ctx, http.MethodPatch, path, "application/apply-patch+yaml", monitorDeployment(rm, o.image), &deployment)
+	}
+	available := condition{Type: conditionDeploymentAvailable, ObservedGeneration: rm.Metadata.Generation}
+	switch {
+	case refused != nil:
+		status.Message = refused.Error()
+		available.Reason = "SpecRefused"
+	case err != nil:
+		status.Message = err.Error()
+		available.Reason = "ApplyFailed"
+	case deployment.Status.ReadyReplicas > 0:
+		status.Ready = true
+		status.Message = "monitoring " + rm.Spec.Host
+		available.Reason, available.Message = "PodReady", "the monitor pod is ready"
+	default:
+		status.Message = "waiting for the monitor pod to become ready"
+		available.Reason = "PodNotReady"
+	}
+	available.Status = conditionStatus(status.Ready)
+	if available.Message == "" {
+		available.Message = status.Message
+	}
+	ready := condition{Type: conditionReady, Status: conditionStatus(status.Ready), Reason: "Monitoring", Message: status.Message, ObservedGeneration: rm.Metadata.Generation}
+	if !status.Ready {
+		ready.Reason = "DeploymentUnavailable"
+	}
+	status.Conditions = transitionConditions(rm.Status.Conditions, time.Now(), ready, available)
+	if !status.equal(rm.Status) {
+		statusPath := fmt.Sprintf("%s/%s/status", replicaMonitorsPath(rm.Metadata.Namespace), rm.Metadata.Name)
+		if patchErr := o.kube.do(ctx, http.MethodPatch, statusPath, "application/merge-patch+json", map[string]any{"status": status}, nil); patchErr != nil {
+			return errors.Join(err, patchErr)
+		}
+		if status.Ready != rm.Status.Ready {
+			slog.Info("ReplicaMonitor changed readiness", "namespace", rm.Metadata.Namespace, "name", rm.Metadata.Name, "ready", status.Ready, "message", status.Message)
+		}
+	}
+	return err
+}
+
+// Why a ReplicaMonitor's image or service account may not be used, nil when
+// both are the operator's own or among those it allows
+func (o *operator) refuseOverrides(spec replicaMonitorSpec) error {
+	if spec.Image != "" && !slices.Contains(o.allowImages, spec.Image) {
+		return fmt.Errorf("image %s is not one the operator allows with -allow-image", spec.Image)
+	}
+	if spec.ServiceAccountName != "" && !slices.Contains(o.allowServiceAccounts, spec.ServiceAccountName) {
+		return fmt.Errorf("service account %s is not one the operator allows with -allow-service-account", spec.ServiceAccountName)
+	}
+	return nil
+}
+
+func monitorDeploymentName(rm replicaMonitor) string {
+	return "replica-monitor-" + rm.Metadata.Name
+}
+
+// The sidecar command line for a ReplicaMonitor
+func monitorArgs(spec replicaMonitorSpec) []string {
+	args := []string{"sidecar", "-host", spec.Host, "-user", spec.User}
+	if spec.Port != 0 {
+		args = append(args, "-port", strconv.Itoa(spec.Port))
+	}
+	for _, flag := range [][2]string{{"-auth", spec.Auth}, {"-engine", spec.Engine}, {"-interval", spec.Interval}, {"-lag-threshold", spec.LagThreshold}, {"-action-user", spec.ActionUser}} {
+		if flag[1] != "" {
+			args = append(args, flag[0], flag[1])
+		}
+	}
+	if spec.PasswordSecret != nil {
+		args = append(args, "-password-file", monitorSecretsDir+"/password/password")
+	}
+	if spec.ActionPasswordSecret != nil {
+		args = append(args, "-action-password-file", monitorSecretsDir+"/action-password/password")
+	}
+	for i := range spec.Notify {
+		args = append(args, "-notify-file", fmt.Sprintf("%s/notify-%d/url", monitorSecretsDir, i))
+	}
+	for _, k := range slices.Sorted(maps.Keys(spec.Labels)) {
+		args = append(args, "-label", k+"="+spec.Labels[k])
+	}
+	return append(args, spec.Args...)
+}
+
+// The Deployment running a ReplicaMonitor's monitor: one pod, replaced rather
+// than rolled, so that two monitors never skip errors on the replica at once
+func monitorDeployment(rm replicaMonitor, image string) map[string]any {
+	if rm.Spec.Image != "" {
+		image = rm.Spec.Image
+	}
+	labels := map[string]string{
+		"app.kubernetes.io/name":       "replica-monitor",
+		"app.kubernetes.io/instance":   rm.Metadata.Name,
+		"app.kubernetes.io/managed-by": operatorManager,
+	}
+	selector := map[string]string{"app.kubernetes.io/name": "replica-monitor", "app.kubernetes.io/instance": rm.Metadata.Name}
+
+	volumes := []any{
+		map[string]any{"name": "podinfo", "downwardAPI": map[string]any{"items": []any{
+			map[string]any{"path": "labels", "fieldRef": map[string]any{"fieldPath": "metadata.labels"}},
+		}}},
+	}
+	mounts := []any{map[string]any{"name": "podinfo", "mountPath": "/etc/podinfo", "readOnly": true}}
+	// In a fixed order, since a reordered pod template rolls the Deployment
+	type secretMount struct {
+		name, path string
+		ref        *secretKeyRef
+	}
+	secrets := []secretMount{{"password", "password", rm.Spec.PasswordSecret}, {"action-password", "password", rm.Spec.ActionPasswordSecret}}
+	for i := range rm.Spec.Notify {
+		secrets = append(secrets, secretMount{fmt.Sprintf("notify-%d", i), "url", &rm.Spec.Notify[i]})
+	}
+	for _, secret := range secrets {
+		name, ref := secret.name, secret.ref
+		if ref == nil {
+			continue
+		}
+		volumes = append(volumes, map[string]any{"name": name, "secret": map[string]any{
+			"secretName": ref.Name, "items": []any{map[string]any{"key": ref.Key, "path": secret.path}},
+		}})
+		mounts = append(mounts, map[string]any{"name": name, "mountPath": monitorSecretsDir + "/" + name, "readOnly": true})
+	}
+	probe := func(path string) map[string]any {
+		return map[string]any{"httpGet": map[string]any{"path": path, "port": "http"}, "periodSeconds": 10}
+	}
+	fieldEnv := func(name, path string) map[string]any {
+		return map[string]any{"name": name, "valueFrom": map[string]any{"fieldRef": map[string]any{"fieldPath": path}}}
+	}
+
+	pod := map[string]any{
+		"containers": []any{map[string]any{
+			"name":  "replica-monitor",
+			"image": image,
+			"args":  monitorArgs(rm.Spec),
+			"env": []any{
+				fieldEnv("POD_NAME", "metadata.name"),
+				fieldEnv("POD_NAMESPACE", "metadata.namespace"),
+				fieldEnv("NODE_NAME", "spec.nodeName"),
+			},
+			"ports":          []any{map[string]any{"name": "http", "containerPort": 8080}},
+			"readinessProbe": probe("/readyz"),
+			"livenessProbe":  probe("/healthz"),
+			"volumeMounts":   mounts,
+			"securityContext": map[string]any{
+				"runAsNonRoot": true, "readOnlyRootFilesystem": true, "allowPrivilegeEscalation": false,
+				"capabilities": map[string]any{"drop": []any{"ALL"}},
+			},
+		}},
+		"volumes": volumes,
+		// Within sidecar's -shutdown-timeout of 25s
+		"terminationGracePeriodSeconds": 30,
+	}
+	if rm.Spec.ServiceAccountName != "" {
+		pod["serviceAccountName"] = rm.Spec.ServiceAccountName
+	}
+
+	return map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":      monitorDeploymentName(rm),
+			"namespace": rm.Metadata.Namespace,
+			"labels":    labels,
+			// Deleted along with the ReplicaMonitor
+			"ownerReferences": []any{map[string]any{
+				"apiVersion": crdGroup + "/" + crdVersion, "kind": "ReplicaMonitor",
+				"name": rm.Metadata.Name, "uid": rm.Metadata.UID,
+				"controller": true, "blockOwnerDeletion": true,
+			}},
+		},
+		"spec": map[string]any{
+			"replicas": 1,
+			"strategy": map[string]any{"type": "Recreate"},
+			"selector": map[string]any{"matchLabels": selector},
+			"template": map[string]any{
+				"metadata": map[string]any{"labels": labels},
+				"spec":     pod,
+			},
+		},
+	}
+}
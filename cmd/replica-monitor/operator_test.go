@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMonitorArgs(t *testing.T) {
+	spec := replicaMonitorSpec{
+		Host: "orders.abc123xyz.us-east-1.rds.amazonaws.com", User: "monitor", Port: 3307,
+		PasswordSecret: &secretKeyRef{"orders-db", "password"}, Interval: "10s",
+		Notify: []secretKeyRef{{"orders-alerts", "slack"}, {"orders-alerts", "pagerduty"}},
+		Labels: map[string]string{"team": "orders", "env": "prod"}, Args: []string{"-prometheus"},
+	}
+	want := []string{"sidecar", "-host", spec.Host, "-user", "monitor", "-port", "3307", "-interval", "10s",
+		"-password-file", "/etc/replica-monitor/password/password",
+		"-notify-file", "/etc/replica-monitor/notify-0/url", "-notify-file", "/etc/replica-monitor/notify-1/url", "-label", "env=prod", "-label", "team=orders", "-prometheus"}
+	if got := monitorArgs(spec); !slices.Equal(got, want) {
+		t.Errorf("monitorArgs = %q\nwant %q", got, want)
+	}
+}
+
+// A fake API server holding one ReplicaMonitor
+func TestOperatorReconcile(t *testing.T) {
+	var mu sync.Mutex
+	var applied map[string]any
+	var status map[string]replicaMonitorStatus
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(req.Body)
+		switch {
+		case req.Method == http.MethodGet && req.URL.Path == "/apis/replica-monitor.io/v1/namespaces/shop/replicamonitors":
+			io.WriteString(w, `{"items":[{"metadata":{"name":"orders","namespace":"shop","uid":"u-1","generation":2},
+				"spec":{"host":"orders-replica","user":"monitor","passwordSecret":{"name":"orders-db","key":"password"},
+				"notify":[{"name":"orders-alerts","key":"slack"}]}}]}`)
+		case req.Method == http.MethodPatch && req.URL.Path == "/apis/apps/v1/namespaces/shop/deployments/replica-monitor-orders":
+			if ct := req.Header.Get("Content-Type"); ct != "application/apply-patch+yaml" || req.URL.Query().Get("fieldManager") != operatorManager {
+				t.Errorf("Deployment applied as %s by %q", ct, req.URL.Query().Get("fieldManager"))
+			}
+			json.Unmarshal(body, &applied)
+			io.WriteString(w, `{"status":{"readyReplicas":1}}`)
+		case req.Method == http.MethodPatch && req.URL.Path == "/apis/replica-monitor.io/v1/namespaces/shop/replicamonitors/orders/status":
+			json.Unmarshal(body, &status)
+			io.WriteString(w, `{}`)
+		default:
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	o := &operator{kube: &kubeClient{base: server.URL, client: server.Client()}, namespace: "shop", image: "replica-monitor:1.0"}
+	if err := o.reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	got := status["status"]
+	for _, c := range got.Conditions {
+		if _, err := time.Parse(time.RFC3339, c.LastTransitionTime); err != nil {
+			t.Errorf("%s condition's lastTransitionTime: %v", c.Type, err)
+		}
+	}
+	want := replicaMonitorStatus{ObservedGeneration: 2, Deployment: "replica-monitor-orders", Ready: true, Message: "monitoring orders-replica",
+		Conditions: []condition{
+			{Type: "Ready", Status: "True", Reason: "Monitoring", Message: "monitoring orders-replica", ObservedGeneration: 2},
+			{Type: "DeploymentAvailable", Status: "True", Reason: "PodReady", Message: "the monitor pod is ready", ObservedGeneration: 2},
+		},
+	}
+	for i := range min(len(got.Conditions), len(want.Conditions)) {
+		want.Conditions[i].LastTransitionTime = got.Conditions[i].LastTransitionTime
+	}
+	if !got.equal(want) {
+		t.Errorf("status = %+v\nwant %+v", got, want)
+	}
+	data, _ := json.Marshal(applied)
+	for _, part := range []string{`"uid":"u-1"`, `"image":"replica-monitor:1.0"`, `"secretName":"orders-db"`, `"type":"Recreate"`, `"-password-file"`, `"secretName":"orders-alerts"`, `"-notify-file"`} {
+		if !strings.Contains(string(data), part) {
+			t.Errorf("applied Deployment lacks %s: %s", part, data)
+		}
+	}
+}
+
+func TestTransitionConditions(t *testing.T) {
+	before := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := before.Add(time.Hour)
+	previous := transitionConditions(nil, before,
+		condition{Type: "Ready", Status: "False", Reason: "DeploymentUnavailable"},
+		condition{Type: "DeploymentAvailable", Status: "True", Reason: "PodReady"})
+	got := transitionConditions(previous, now,
+		condition{Type: "Ready", Status: "True", Reason: "Monitoring"},
+		condition{Type: "DeploymentAvailable", Status: "True", Reason: "PodReady"})
+	if got[0].LastTransitionTime != "2024-05-01T13:00:00Z" || got[1].LastTransitionTime != "2024-05-01T12:00:00Z" {
+		t.Errorf("transition times %s and %s; want the changed Ready's now and the unchanged DeploymentAvailable's kept",
+			got[0].LastTransitionTime, got[1].LastTransitionTime)
+	}
+}
+
+func TestRefuseOverrides(t *testing.T) {
+	o := &operator{allowImages: []string{"registry.example.com/replica-monitor:2.0"}, allowServiceAccounts: []string{"orders-monitor"}}
+	for _, tc := range []struct {
+		spec replicaMonitorSpec
+		ok   bool
+	}{
+		{replicaMonitorSpec{}, true},
+		{replicaMonitorSpec{Image: "registry.example.com/replica-monitor:2.0", ServiceAccountName: "orders-monitor"}, true},
+		{replicaMonitorSpec{Image: "attacker.example.com/miner"}, false},
+		{replicaMonitorSpec{ServiceAccountName: "cluster-admin"}, false},
+	} {
+		if err := o.refuseOverrides(tc.spec); (err == nil) != tc.ok {
+			t.Errorf("refuseOverrides(image %q, service account %q) = %v", tc.spec.Image, tc.spec.ServiceAccountName, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+// Fetch Enhanced Monitoring OS metrics for RDS replicas that are behind -lag-threshold
+var enhancedMonitoring bool
+
+// Enhanced Monitoring writes one JSON document per interval to this log group,
+// in a stream named after the instance's resource ID
+const (
+	osMetricsLogGroup = "RDSOSMetrics"
+	osMetricsInterval = time.Minute
+)
+
+// The parts of an Enhanced Monitoring document that tell IO-bound from CPU-bound
+type osMetrics struct {
+	Timestamp      time.Time `json:"timestamp"`
+	NumVCPUs       int       `json:"numVCPUs"`
+	CPUUtilization struct {
+		Total float64 `json:"total"`
+		Wait  float64 `json:"wait"`
+	} `json:"cpuUtilization"`
+	DiskIO []struct {
+		Device      string  `json:"device"`
+		ReadIOsPS   float64 `json:"readIOsPS"`
+		WriteIOsPS  float64 `json:"writeIOsPS"`
+		AvgQueueLen float64 `json:"avgQueueLen"`
+	} `json:"diskIO"`
+	Swap struct {
+		In  float64 `json:"in"`
+		Out float64 `json:"out"`
+	} `json:"swap"`
+
+	fetched time.Time // zero Timestamp when the fetch failed
+}
+
+var (
+	osMetricsSamples = make(map[*replica]*osMetrics)
+	osMetricsClients = make(map[string]*cloudwatchlogs.Client)
+)
+
+// Refresh the OS metrics of every RDS replica above -lag-threshold, at most once
+// a minute each; replicas that caught up drop theirs
+func checkOSMetrics(replicas []*replica) {
+	if !enhancedMonitoring {
+		return
+	}
+	behind := make(map[*replica]bool)
+	for _, r := range replicas {
+		if !r.lagKnown || r.lagSeconds <= replicaLagThreshold(r).Seconds() {
+			continue
+		}
+		id, region, ok := rdsInstanceOf(r)
+		if !ok {
+			continue
+		}
+		behind[r] = true
+		if m := osMetricsSamples[r]; m != nil && time.Since(m.fetched) < osMetricsInterval {
+			continue
+		}
+		m, err := fetchOSMetrics(id, region)
+		if err != nil {
+			slog.Warn("Failed to fetch Enhanced Monitoring metrics", "replica", displayName(r), "instance", id, "err", err)
+			m = &osMetrics{} // try again after osMetricsInterval, not every cycle
+		}
+		m.fetched = time.Now()
+		osMetricsSamples[r] = m
+	}
+	for r := range osMetricsSamples {
+		if !behind[r] {
+			delete(osMetricsSamples, r)
+		}
+	}
+}
+
+// The latest Enhanced Monitoring document for an instance
+func fetchOSMetrics(id, region string) (*osMetrics, error) {
+	cfg, err := loadAWSConfig(region)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+
+	inst, err := describeRDSInstance(ctx, id, region)
+	if err != nil {
+		return nil, err
+	}
+	if aws.ToInt32(inst.MonitoringInterval) == 0 {
+		return nil, errors.New("Enhanced Monitoring is not enabled on the instance")
+	}
+
+	client, ok := osMetricsClients[region]
+	if !ok {
+		client = cloudwatchlogs.NewFromConfig(cfg)
+		osMetricsClients[region] = client
+	}
+	out, err := client.GetLogEvents(ctx, &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(osMetricsLogGroup),
+		LogStreamName: inst.DbiResourceId,
+		Limit:         aws.Int32(1),
+		StartFromHead: aws.Bool(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Events) == 0 {
+		return nil, errors.New("no Enhanced Monitoring data yet")
+	}
+	var m osMetrics
+	if err := json.Unmarshal([]byte(aws.ToString(out.Events[len(out.Events)-1].Message)), &m); err != nil {
+		return nil, fmt.Errorf("parsing Enhanced Monitoring data: %w", err)
+	}
+	return &m, nil
+}
+
+// What the OS metrics say is holding the replica back
+func (m *osMetrics) verdict() string {
+	var queue float64
+	for _, d := range m.DiskIO {
+		queue = max(queue, d.AvgQueueLen)
+	}
+	switch {
+	case m.Swap.In+m.Swap.Out > 0:
+		return "swapping"
+	case m.CPUUtilization.Wait >= 20 || queue >= 10:
+		return "IO-bound"
+	case m.CPUUtilization.Total >= 90:
+		return "CPU-bound"
+	}
+	return "neither CPU- nor IO-bound"
+}
+
+// Print the OS metrics of a replica that is behind, under its lag
+func printOSMetrics(out io.Writer, r *replica) {
+	m := osMetricsSamples[r]
+	if m == nil || m.Timestamp.IsZero() {
+		return
+	}
+	var iops, queue float64
+	for _, d := range m.DiskIO {
+		iops += d.ReadIOsPS + d.WriteIOsPS
+		queue = max(queue, d.AvgQueueLen)
+	}
+	fmt.Fprintf(out, "🖥️  OS (%s): CPU %.0f%% (%.0f%% IO wait, %d vCPUs), disk %.0f IOPS, queue depth %.1f, swap in/out %.0f/%.0f KB/s → %s\n",
+		m.Timestamp.Local().Format("15:04:05"), m.CPUUtilization.Total, m.CPUUtilization.Wait, m.NumVCPUs,
+		iops, queue, m.Swap.In, m.Swap.Out, m.verdict())
+}
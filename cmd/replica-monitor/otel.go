@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OTLP/gRPC collector from -otlp-endpoint: host:port for TLS, or http://host:port
+// for plaintext. OTEL_EXPORTER_OTLP_ENDPOINT is used when the flag is not set.
+var otlpEndpoint string
+
+// Spans go nowhere until startTracing installs an exporting provider
+var tracer = otel.Tracer("replica-monitor")
+
+// Export a trace per monitoring cycle; the returned function flushes pending spans
+func startTracing() (func(), error) {
+	if otlpEndpoint == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" {
+		return func() {}, nil
+	}
+	var opts []otlptracegrpc.Option
+	if strings.Contains(otlpEndpoint, "://") {
+		opts = append(opts, otlptracegrpc.WithEndpointURL(otlpEndpoint))
+	} else if otlpEndpoint != "" {
+		opts = append(opts, otlptracegrpc.WithEndpoint(otlpEndpoint))
+	}
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "replica-monitor"))),
+	)
+	otel.SetTracerProvider(provider)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		provider.Shutdown(ctx)
+	}, nil
+}
+
+// Mark a span failed with err, if any
+func spanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Report output goes through this writer so it can be colored, stripped of emoji,
+// or discarded (-quiet, serve, the TUI) in one place
+var stdout io.Writer = os.Stdout
+
+// Output flags shared by the commands that print reports
+var (
+	colorMode string
+	noEmoji   bool
+)
+
+const (
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+	ansiGreen  = "\033[32m"
+	ansiReset  = "\033[0m"
+)
+
+// Wrap standard output according to -color, NO_COLOR, and -no-emoji. Color is only
+// used on a terminal unless -color always is given. Called once the flags are
+// parsed, it also takes the secret ones out of sight (see protectSecrets) and
+// opens the log destinations.
+func setupOutput() error {
+	protectSecrets()
+	if daemonized {
+		if err := leaveWorkingDir(); err != nil {
+			return fmt.Errorf("leave the working directory: %w", err)
+		}
+	}
+	color := false
+	switch colorMode {
+	case "always":
+		color = true
+	case "never":
+	default:
+		_, noColor := os.LookupEnv("NO_COLOR")
+		color = !noColor && isTerminal(os.Stdout)
+	}
+	out := io.Writer(os.Stdout)
+	if daemonized && logFile != "" {
+		// In the background the report joins the logs, rotating with them
+		out = logFileOutput()
+		stdout = out
+	}
+	if color || noEmoji {
+		stdout = &reportWriter{out: out, color: color, stripEmoji: noEmoji}
+	}
+	stdout = redactWriter{stdout}
+	if err := openLogDestinations(); err != nil {
+		return err
+	}
+	setupLogging(os.Stderr)
+	loggingReady.Store(true)
+	warnShortSecret()
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Colors report lines by the severity marker they start with and optionally strips emoji
+type reportWriter struct {
+	out        io.Writer
+	color      bool
+	stripEmoji bool
+}
+
+func (w *reportWriter) Write(p []byte) (int, error) {
+	lines := bytes.SplitAfter(p, []byte("\n"))
+	var b strings.Builder
+	for _, line := range lines {
+		text := string(line)
+		body := strings.TrimSuffix(text, "\n")
+		color := ""
+		if w.color {
+			color = severityColor(body)
+		}
+		if w.stripEmoji {
+			body = stripEmoji(body)
+		}
+		if color != "" && body != "" {
+			body = color + body + ansiReset
+		}
+		b.WriteString(body)
+		if strings.HasSuffix(text, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	if _, err := io.WriteString(w.out, b.String()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Severity of a report line, judged by the marker emoji it starts with
+func severityColor(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	switch {
+	case strings.HasPrefix(trimmed, "🚨"), strings.HasPrefix(trimmed, "❌"), strings.HasPrefix(trimmed, "❓"):
+		return ansiRed
+	case strings.HasPrefix(trimmed, "⚠"), strings.HasPrefix(trimmed, "💤"):
+		return ansiYellow
+	case strings.HasPrefix(trimmed, "✅"), strings.HasPrefix(trimmed, "🚀"):
+		return ansiGreen
+	}
+	return ""
+}
+
+// Remove emoji, along with the variation selector and padding that follow them
+func stripEmoji(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		if !isEmoji(runes[i]) {
+			b.WriteRune(runes[i])
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] == '\uFE0F' {
+			i++
+		}
+		// Leading markers take all their padding with them, inline ones a single space
+		atStart := strings.TrimSpace(b.String()) == ""
+		for i+1 < len(runes) && runes[i+1] == ' ' {
+			i++
+			if !atStart {
+				break
+			}
+		}
+	}
+	return b.String()
+}
+
+func isEmoji(r rune) bool {
+	return (r >= 0x1F000 && r <= 0x1FAFF) || // pictographs, emoticons, transport, supplemental symbols
+		(r >= 0x2600 && r <= 0x27BF) || // miscellaneous symbols and dingbats
+		(r >= 0x231A && r <= 0x23FF) || // watch, hourglass, alarm clock
+		r == 0x2B50 || r == 0x2B55
+}
+
+// Run one cycle's report into a buffer, then clear the screen and draw it to out
+// in one write so -refresh redraws in place like top instead of scrolling
+func redrawScreen(out io.Writer, cycle func(io.Writer) bool) bool {
+	var report bytes.Buffer
+	result := cycle(&report)
+
+	var screen bytes.Buffer
+	screen.WriteString("\033[H\033[2J")
+	fmt.Fprintf(&screen, "Replica Monitor  %s  every %s  (Ctrl+C to stop)\n", time.Now().Format("2006-01-02 15:04:05"), currentInterval())
+	screen.Write(report.Bytes())
+	out.Write(screen.Bytes())
+	return result
+}
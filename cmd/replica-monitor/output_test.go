@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Records each write separately
+type writeRecorder struct{ writes []string }
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestRedrawScreen(t *testing.T) {
+	var out writeRecorder
+	redrawScreen(&out, func(w io.Writer) bool {
+		fmt.Fprintln(w, "replica-1 lag 3s")
+		fmt.Fprintln(w, "replica-2 lag 5s")
+		return false
+	})
+	if len(out.writes) != 1 {
+		t.Fatalf("screen drawn in %d writes; want 1", len(out.writes))
+	}
+	if screen := out.writes[0]; !strings.HasPrefix(screen, "\033[H\033[2J") || !strings.HasSuffix(screen, "replica-2 lag 5s\n") {
+		t.Errorf("screen = %q; want the clear, the header, and the report", screen)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// -privilege-check: warn (the default) reports missing and excessive grants on
+// connect and carries on, strict also refuses replicas missing any, off skips
+// the check
+var privilegeCheck = "warn"
+
+var privilegeChecks = []string{"warn", "strict", "off"}
+
+// What the running command will do on replicas besides reading their status:
+// watch and serve auto-skip matched errors, skip and start-replica act on request
+var (
+	needSkip  bool
+	needStart bool
+)
+
+// A privilege on an object, as GRANT names it
+type privilege struct {
+	name   string
+	kind   string // PROCEDURE for routines
+	object string
+}
+
+func (p privilege) String() string {
+	if p.kind != "" {
+		return fmt.Sprintf("%s ON %s %s", p.name, p.kind, p.object)
+	}
+	return fmt.Sprintf("%s ON %s", p.name, p.object)
+}
+
+// Something the monitor does on a replica and the privileges that let it,
+// any one of which is enough. Broader ones also do but are more than needed.
+type privilegeNeed struct {
+	purpose    string
+	privileges []privilege
+	broader    []string // global privileges
+}
+
+func (n privilegeNeed) metBy(g monitor.Grants) bool {
+	for _, p := range n.privileges {
+		if g.Allows(p.name, p.kind, p.object) {
+			return true
+		}
+	}
+	for _, name := range n.broader {
+		if g.Allows(name, "", "*.*") {
+			return true
+		}
+	}
+	return false
+}
+
+// The privileges the running command needs on a replica of engine: to read
+// its status, to act on it, or both
+func privilegeNeeds(engine monitor.Engine, status, actions bool) []privilegeNeed {
+	var needs []privilegeNeed
+	switch engine.Name {
+	case "mysql":
+		if status {
+			needs = append(needs, privilegeNeed{"to read replica status", []privilege{{"REPLICATION CLIENT", "", "*.*"}}, []string{"SUPER"}})
+		}
+	case "mariadb":
+		// MariaDB 10.5 moved SHOW REPLICA STATUS from REPLICATION CLIENT to SLAVE MONITOR
+		if status {
+			needs = append(needs, privilegeNeed{"to read replica status",
+				[]privilege{{"SLAVE MONITOR", "", "*.*"}, {"REPLICA MONITOR", "", "*.*"}, {"REPLICATION CLIENT", "", "*.*"}},
+				[]string{"SUPER", "REPLICATION SLAVE ADMIN"}})
+		}
+	default:
+		// Aurora readers report lag in a table anyone can read, and have
+		// nothing to skip or start
+		return nil
+	}
+	if !actions {
+		return needs
+	}
+	if needSkip {
+		needs = append(needs, privilegeNeed{"to skip replication errors", []privilege{{"EXECUTE", "PROCEDURE", "mysql.rds_skip_repl_error"}}, nil})
+	}
+	if needStart {
+		admin := "REPLICATION_SLAVE_ADMIN"
+		if engine.Name == "mariadb" {
+			admin = "REPLICATION SLAVE ADMIN"
+		}
+		needs = append(needs, privilegeNeed{"to start replication",
+			[]privilege{{"EXECUTE", "PROCEDURE", "mysql.rds_start_replication"}, {admin, "", "*.*"}}, []string{"SUPER"}})
+	}
+	return needs
+}
+
+// Grants beyond what needs call for: anything but the exact privileges they
+// list, and GRANT OPTION
+func excessGrants(g monitor.Grants, needs []privilegeNeed) []string {
+	var excess []string
+	for _, grant := range g.Grants {
+		var extra []string
+		for _, name := range grant.Privileges {
+			p := privilege{name, grant.Kind, grant.Object}
+			needed := name == "USAGE" || slices.ContainsFunc(needs, func(n privilegeNeed) bool {
+				return slices.Contains(n.privileges, p)
+			})
+			if !needed {
+				extra = append(extra, name)
+			}
+		}
+		if grant.GrantOption {
+			extra = append(extra, "GRANT OPTION")
+		}
+		if len(extra) > 0 {
+			excess = append(excess, privilege{strings.Join(extra, ", "), grant.Kind, grant.Object}.String())
+		}
+	}
+	return excess
+}
+
+// Read the account's grants on a newly connected replica and report missing
+// and excessive ones; with -privilege-check strict, missing ones are an error.
+// With -action-user, -user is checked for reading status only, and
+// -action-user, logged in for the check, for the actions.
+func checkPrivileges(r *replica) error {
+	if privilegeCheck == "off" || r.db == nil {
+		return nil
+	}
+	if r.engine.Name == "postgres" {
+		slog.Debug("Privileges are not checked on PostgreSQL", "replica", displayName(r))
+		return nil
+	}
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	if actionUser == "" {
+		return checkGrants(ctx, r, r.db, privilegeNeeds(r.engine, true, true))
+	}
+	if err := checkGrants(ctx, r, r.db, privilegeNeeds(r.engine, true, false)); err != nil {
+		return err
+	}
+	needs := privilegeNeeds(r.engine, false, true)
+	if len(needs) == 0 {
+		return nil
+	}
+	db, err := openActionDB(ctx, r)
+	if err != nil {
+		if privilegeCheck == "strict" {
+			return err
+		}
+		slog.Warn("Failed to check the privileges of -action-user", "replica", displayName(r), "err", err)
+		return nil
+	}
+	defer db.Close()
+	return checkGrants(ctx, r, db, needs)
+}
+
+// Compare the grants of the account logged in to db with needs
+func checkGrants(ctx context.Context, r *replica, db *sql.DB, needs []privilegeNeed) error {
+	grants, err := monitor.ReadGrants(ctx, db)
+	if err != nil {
+		slog.Warn("Failed to read the account's grants", "replica", displayName(r), "err", err)
+		return nil
+	}
+
+	var missing []privilegeNeed
+	for _, n := range needs {
+		if !n.metBy(grants) {
+			missing = append(missing, n)
+		}
+	}
+	excess := excessGrants(grants, needs)
+	if len(missing) == 0 && len(excess) == 0 {
+		fmt.Fprintf(stdout, "🔐 %s has exactly the privileges needed on %s\n", grants.User, displayName(r))
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "🔐 Privileges of %s on %s:\n", grants.User, displayName(r))
+	for _, n := range missing {
+		fmt.Fprintf(stdout, "   ❌ Missing %s (%s)\n", n.privileges[0], n.purpose)
+		slog.Warn("Account lacks a privilege", "replica", displayName(r), "user", grants.User, "privilege", n.privileges[0].String(), "purpose", n.purpose)
+	}
+	for _, e := range excess {
+		fmt.Fprintf(stdout, "   ⚠️  More than needed: %s\n", e)
+	}
+	if len(missing) > 0 {
+		account := grants.User
+		if name, host, ok := strings.Cut(grants.User, "@"); ok {
+			account = fmt.Sprintf("'%s'@'%s'", name, host)
+		}
+		for _, n := range missing {
+			fmt.Fprintf(stdout, "   Grant with: GRANT %s TO %s;\n", n.privileges[0], account)
+		}
+		if len(grants.Roles) > 0 && r.engine.Name == "mariadb" {
+			fmt.Fprintln(stdout, "   Privileges from roles were not checked")
+		}
+	}
+	if len(missing) > 0 && privilegeCheck == "strict" {
+		names := make([]string, len(missing))
+		for i, n := range missing {
+			names[i] = n.privileges[0].String()
+		}
+		return fmt.Errorf("%s lacks %s on %s", grants.User, strings.Join(names, " and "), displayName(r))
+	}
+	return nil
+}
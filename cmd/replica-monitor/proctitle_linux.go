@@ -0,0 +1,16 @@
+//go:build linux
+
+package main
+
+import "unsafe"
+
+// Overwrite values in the process's original argument memory, which os.Args
+// shares and /proc/<pid>/cmdline and ps read from
+func scrubArgs(values []string) {
+	for _, v := range values {
+		b := unsafe.Slice(unsafe.StringData(v), len(v))
+		for i := range b {
+			b[i] = '*'
+		}
+	}
+}
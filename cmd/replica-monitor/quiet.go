@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// With -quiet, the per-poll report is discarded and only exceptions are written here
+var quietOut io.Writer
+
+// What -quiet compares between cycles to decide whether anything worth printing happened
+type quietState struct {
+	ioRunning    string
+	sqlRunning   string
+	errorMatched bool
+	lagKnown     bool
+	behind       bool
+}
+
+var quietLast = make(map[*replica]quietState)
+
+// Keep the real standard output for exception lines and discard everything else
+func startQuiet() {
+	quietOut = stdout
+	stdout = io.Discard
+}
+
+func quietNotice(name, format string, args ...interface{}) {
+	if quietOut == nil {
+		return
+	}
+	fmt.Fprintf(quietOut, "[%s] %s: %s\n", time.Now().Format("2006-01-02 15:04:05"), name, fmt.Sprintf(format, args...))
+}
+
+// Print thread state changes, error matches clearing, lag becoming NULL or
+// available, and lag crossing -lag-threshold since the previous cycle
+func reportExceptions(replicas []*replica) {
+	if quietOut == nil {
+		return
+	}
+	seen := make(map[*replica]bool)
+	for _, r := range replicas {
+		seen[r] = true
+		if r.polledAt.IsZero() {
+			continue
+		}
+		cur := quietState{
+			ioRunning:    r.ioRunning,
+			sqlRunning:   r.sqlRunning,
+			errorMatched: r.errorMatched,
+			lagKnown:     r.lagKnown,
+			behind:       r.lagKnown && r.lagSeconds > replicaLagThreshold(r).Seconds(),
+		}
+		prev, ok := quietLast[r]
+		quietLast[r] = cur
+		name := displayName(r)
+		if !ok {
+			quietNotice(name, "monitoring started, %s", quietSummary(r))
+			continue
+		}
+
+		var changes []string
+		if cur.ioRunning != prev.ioRunning {
+			changes = append(changes, fmt.Sprintf("IO thread %s → %s", orDash(prev.ioRunning), orDash(cur.ioRunning)))
+		}
+		if cur.sqlRunning != prev.sqlRunning {
+			changes = append(changes, fmt.Sprintf("SQL thread %s → %s", orDash(prev.sqlRunning), orDash(cur.sqlRunning)))
+		}
+		if prev.errorMatched && !cur.errorMatched {
+			changes = append(changes, "error pattern no longer matched")
+		}
+		if prev.lagKnown && !cur.lagKnown {
+			changes = append(changes, "lag is now NULL")
+		} else if !prev.lagKnown && cur.lagKnown {
+			changes = append(changes, "lag available again ("+formatDuration(r.lagSeconds)+")")
+		}
+		if cur.behind && !prev.behind {
+			changes = append(changes, fmt.Sprintf("lag %s rose above %s", formatDuration(r.lagSeconds), replicaLagThreshold(r)))
+		} else if prev.behind && !cur.behind && cur.lagKnown {
+			changes = append(changes, fmt.Sprintf("lag %s fell below %s", formatDuration(r.lagSeconds), replicaLagThreshold(r)))
+		}
+		if len(changes) > 0 {
+			quietNotice(name, "%s", strings.Join(changes, "; "))
+		}
+	}
+	for r := range quietLast {
+		if !seen[r] {
+			delete(quietLast, r)
+		}
+	}
+}
+
+func quietSummary(r *replica) string {
+	lag := "NULL"
+	if r.lagKnown {
+		lag = formatDuration(r.lagSeconds)
+	}
+	if r.aurora {
+		return "lag " + lag
+	}
+	return fmt.Sprintf("IO %s, SQL %s, lag %s", orDash(r.ioRunning), orDash(r.sqlRunning), lag)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
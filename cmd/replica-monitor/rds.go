@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// Finds read replicas through DescribeDBInstances and keeps the monitored set in sync
+type rdsDiscovery struct {
+	client         *rds.Client
+	tags           map[string]string
+	sourceInstance string
+	auroraCluster  string // when set, discover this cluster's readers instead of read replicas
+	lastScan       time.Time
+}
+
+func newRDSDiscovery(tags map[string]string, sourceInstance, auroraCluster string) (*rdsDiscovery, error) {
+	cfg, err := loadAWSConfig("")
+	if err != nil {
+		return nil, err
+	}
+	return &rdsDiscovery{
+		client:         rds.NewFromConfig(cfg),
+		tags:           tags,
+		sourceInstance: sourceInstance,
+		auroraCluster:  auroraCluster,
+	}, nil
+}
+
+// List the available read replicas matching the configured tags and source
+func (d *rdsDiscovery) findReplicas(ctx context.Context) ([]types.DBInstance, error) {
+	if d.auroraCluster != "" {
+		return d.findAuroraReaders(ctx)
+	}
+
+	var found []types.DBInstance
+	paginator := rds.NewDescribeDBInstancesPaginator(d.client, &rds.DescribeDBInstancesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, inst := range page.DBInstances {
+			if d.matches(inst) {
+				found = append(found, inst)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (d *rdsDiscovery) matches(inst types.DBInstance) bool {
+	source := aws.ToString(inst.ReadReplicaSourceDBInstanceIdentifier)
+	if source == "" || inst.Endpoint == nil || aws.ToString(inst.DBInstanceStatus) != "available" {
+		return false
+	}
+
+	// Cross-region replicas report their source as an ARN
+	if d.sourceInstance != "" && source != d.sourceInstance && !strings.HasSuffix(source, ":db:"+d.sourceInstance) {
+		return false
+	}
+
+	for key, val := range d.tags {
+		matched := false
+		for _, tag := range inst.TagList {
+			if aws.ToString(tag.Key) == key && aws.ToString(tag.Value) == val {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Re-scan RDS, connecting to new replicas and dropping ones that went away.
+// On a failed scan the current set is kept as-is.
+func (d *rdsDiscovery) reconcile(current []*replica) []*replica {
+	d.lastScan = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	instances, err := d.findReplicas(ctx)
+	if err != nil {
+		slog.Error("Failed to discover RDS replicas", "err", err)
+		return current
+	}
+
+	wanted := make(map[string]types.DBInstance)
+	for _, inst := range instances {
+		wanted[aws.ToString(inst.DBInstanceIdentifier)] = inst
+	}
+
+	var next []*replica
+	for _, r := range current {
+		if _, ok := wanted[r.name]; ok {
+			next = append(next, r)
+			delete(wanted, r.name)
+			continue
+		}
+		fmt.Fprintf(stdout, "➖ Replica %s is no longer present, stopped monitoring\n", r.name)
+		r.close()
+	}
+
+	ids := make([]string, 0, len(wanted))
+	for id := range wanted {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		inst := wanted[id]
+		endpoint := aws.ToString(inst.Endpoint.Address)
+		r, err := connectReplica(id, endpoint, int(aws.ToInt32(inst.Endpoint.Port)))
+		if err != nil {
+			slog.Error("Failed to connect to discovered replica", "replica", id, "endpoint", endpoint, "err", err)
+			continue
+		}
+		if err := checkPrivileges(r); err != nil {
+			slog.Error("Not monitoring discovered replica", "replica", id, "err", err)
+			r.close()
+			continue
+		}
+		r.aurora = d.auroraCluster != ""
+		r.region = instanceRegion(inst)
+		fmt.Fprintf(stdout, "➕ Discovered replica %s (%s), started monitoring\n", id, endpoint)
+		next = append(next, r)
+	}
+	return next
+}
+
+// Derive the region from the instance ARN, e.g. arn:aws:rds:us-east-1:123456789012:db:mydb
+func instanceRegion(inst types.DBInstance) string {
+	parts := strings.Split(aws.ToString(inst.DBInstanceArn), ":")
+	if len(parts) > 3 {
+		return parts[3]
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Poll DescribeEvents for the monitored instances (and -source-instance) with -rds-events
+var rdsEvents bool
+
+const (
+	rdsEventInterval = time.Minute
+	// How far back the first check looks, and how long an event can explain a lag spike
+	rdsEventLookback = time.Hour
+	rdsCauseWindow   = 15 * time.Minute
+)
+
+// Event categories in the order they are preferred as the probable cause of lag
+var rdsCauseCategories = []string{"failover", "availability", "maintenance", "configuration change", "low storage", "recovery", "restoration", "backup", "read replica"}
+
+type rdsEvent struct {
+	time       time.Time
+	message    string
+	categories []string
+}
+
+// Recent events of one instance and the time of the last one seen
+type rdsEventHistory struct {
+	region string
+	seen   time.Time
+	events monitor.Ring[rdsEvent]
+}
+
+var (
+	rdsEventLog       = make(map[string]*rdsEventHistory) // by instance identifier
+	rdsEventLastCheck time.Time
+)
+
+// Fetch new RDS events for every replica, and the source with -source-instance,
+// at most once every rdsEventInterval, and print them into the report
+func checkRDSEvents(replicas []*replica) {
+	if !rdsEvents || time.Since(rdsEventLastCheck) < rdsEventInterval {
+		return
+	}
+	rdsEventLastCheck = time.Now()
+
+	wanted := make(map[string]bool)
+	for _, r := range replicas {
+		id, region, ok := rdsInstanceOf(r)
+		if !ok {
+			continue
+		}
+		wanted[id] = true
+		fetchRDSEvents(id, region, displayName(r), r)
+		if sourceInstance != "" && !wanted[sourceInstance] {
+			wanted[sourceInstance] = true
+			fetchRDSEvents(sourceInstance, region, "source "+sourceInstance, nil)
+		}
+	}
+	for id := range rdsEventLog {
+		if !wanted[id] {
+			delete(rdsEventLog, id)
+		}
+	}
+}
+
+// Fetch and announce the events of one instance since the last check; r is nil
+// for the source instance
+func fetchRDSEvents(id, region, name string, r *replica) {
+	h := rdsEventLog[id]
+	if h == nil {
+		h = &rdsEventHistory{region: region, seen: time.Now().Add(-rdsEventLookback), events: monitor.NewRing[rdsEvent](20)}
+		rdsEventLog[id] = h
+	}
+	client, err := rdsClient(region)
+	if err != nil {
+		slog.Error("Failed to set up RDS events client", "region", region, "err", err)
+		return
+	}
+
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	paginator := rds.NewDescribeEventsPaginator(client, &rds.DescribeEventsInput{
+		SourceIdentifier: aws.String(id),
+		SourceType:       types.SourceTypeDbInstance,
+		StartTime:        aws.Time(h.seen.Add(time.Second)),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			slog.Warn("Failed to fetch RDS events", "instance", id, "err", err)
+			return
+		}
+		for _, ev := range page.Events {
+			e := rdsEvent{time: aws.ToTime(ev.Date), message: aws.ToString(ev.Message), categories: ev.EventCategories}
+			if !e.time.After(h.seen) {
+				continue
+			}
+			h.seen = e.time
+			h.events.Push(e)
+			fmt.Fprintf(stdout, "☁️  [%s] RDS event at %s: %s%s\n", name, e.time.Local().Format("2006-01-02 15:04:05"), e.message, formatCategories(e.categories))
+			slog.Info("RDS event", "instance", id, "categories", strings.Join(e.categories, ","), "message", e.message)
+			if r != nil {
+				recordEvent(r, journalEvent{Event: "rds_event", Message: e.message}, 0)
+				if e.time.After(runStart) && restartEvent(e) {
+					noteRestart(r, "RDS event: "+e.message)
+				}
+			}
+		}
+	}
+}
+
+// A failover or reboot of the instance
+func restartEvent(e rdsEvent) bool {
+	msg := strings.ToLower(e.message)
+	return slices.Contains(e.categories, "failover") || strings.Contains(msg, "reboot") || strings.Contains(msg, "restarted")
+}
+
+func formatCategories(categories []string) string {
+	if len(categories) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(categories, ", ") + ")"
+}
+
+// The most telling recent RDS event on the replica or its source, as a phrase
+// for the falling-behind banner, or "" when nothing explains the lag
+func probableLagCause(r *replica, now time.Time) string {
+	type candidate struct {
+		prefix string
+		event  rdsEvent
+	}
+	var candidates []candidate
+	if id, _, ok := rdsInstanceOf(r); ok && rdsEventLog[id] != nil {
+		for _, e := range rdsEventLog[id].events.Values() {
+			candidates = append(candidates, candidate{"", e})
+		}
+	}
+	if h := rdsEventLog[sourceInstance]; sourceInstance != "" && h != nil {
+		for _, e := range h.events.Values() {
+			candidates = append(candidates, candidate{"source ", e})
+		}
+	}
+	for _, category := range rdsCauseCategories {
+		for _, c := range slices.Backward(candidates) {
+			if now.Sub(c.event.time) <= rdsCauseWindow && slices.ContainsFunc(c.event.categories, func(v string) bool { return strings.EqualFold(v, category) }) {
+				return fmt.Sprintf("%s%s at %s: %s", c.prefix, category, c.event.time.Local().Format("15:04:05"), c.event.message)
+			}
+		}
+	}
+	return ""
+}
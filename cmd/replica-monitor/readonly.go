@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// -read-only wraps every database connection so that only read statements
+// reach the server: no skips, no starting or stopping replication, no CALLs,
+// and no locks. Auto-skip is off, and commands that change a replica refuse to
+// run.
+var readOnly bool
+
+// Returned for a statement -read-only refused
+var errReadOnly = errors.New("refused by -read-only")
+
+// Functions a read statement may call: built-ins that only read. Stored
+// functions, which can write, are refused like any function not listed.
+var readOnlyFunctions = map[string]bool{
+	"current_user": true, "connection_id": true, "is_used_lock": true, "version": true,
+	"pg_is_in_recovery": true, "pg_last_wal_receive_lsn": true, "pg_last_wal_replay_lsn": true,
+	"pg_is_wal_replay_paused": true, "pg_last_xact_replay_timestamp": true, "now": true, "extract": true,
+	"utc_timestamp": true, "max": true,
+	// Keywords followed by a parenthesis
+	"in": true, "select": true,
+}
+
+// An unquoted or backtick-quoted identifier
+const sqlIdentifier = "(?:[A-Za-z_$][A-Za-z0-9_$]*|`(?:[^`]|``)*`)"
+
+var (
+	quotedText = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.)*"`)
+	// A function name, possibly qualified and quoted: fn(, db.fn(, `db`.`fn`(
+	functionCall = regexp.MustCompile(`(` + sqlIdentifier + `(?:\s*\.\s*` + sqlIdentifier + `)*)\s*\(`)
+	// A name in double quotes called as a function, as ANSI_QUOTES and
+	// PostgreSQL allow; quotedText has already blanked the name
+	quotedCall    = regexp.MustCompile(`''\s*\(`)
+	writingClause = regexp.MustCompile(`(?i)\b(INTO|FOR\s+UPDATE|FOR\s+SHARE|LOCK\s+IN\s+SHARE\s+MODE)\b`)
+)
+
+// Whether query is a read the monitor may run under -read-only: a single SHOW
+// statement, or a single SELECT calling only readOnlyFunctions, writing
+// nowhere, and taking no locks. Comments are refused, since MySQL runs the
+// text of /*! ... */ ones.
+func readOnlyStatement(query string) bool {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	text := quotedText.ReplaceAllString(query, "''")
+	if strings.ContainsAny(text, ";#") || strings.Contains(text, "/*") || strings.Contains(text, "--") {
+		return false
+	}
+	keyword, _, _ := strings.Cut(strings.ToUpper(strings.Join(strings.Fields(text), " ")), " ")
+	switch keyword {
+	case "SHOW":
+		return true
+	case "SELECT":
+		if writingClause.MatchString(text) || quotedCall.MatchString(text) {
+			return false
+		}
+		for _, m := range functionCall.FindAllStringSubmatch(text, -1) {
+			name := strings.Join(strings.Fields(strings.ReplaceAll(m[1], "`", "")), "")
+			if !readOnlyFunctions[strings.ToLower(name)] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Refuse query unless it is a read
+func checkReadOnly(addr, query string) error {
+	if readOnlyStatement(query) {
+		return nil
+	}
+	slog.Error("Refused a statement under -read-only", "addr", addr, "statement", query)
+	verb, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	return fmt.Errorf("%s %w", strings.ToUpper(verb), errReadOnly)
+}
+
+// Connector whose connections run only what readOnlyStatement allows
+type readOnlyConnector struct {
+	driver.Connector
+	addr string
+}
+
+func (c readOnlyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &readOnlyConn{conn, c.addr}, nil
+}
+
+// Every way database/sql runs a statement goes through a method here; the
+// driver's connection is never handed out
+type readOnlyConn struct {
+	driver.Conn
+	addr string
+}
+
+func (c *readOnlyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := checkReadOnly(c.addr, query); err != nil {
+		return nil, err
+	}
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return q.QueryContext(ctx, query, args)
+}
+
+func (c *readOnlyConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := checkReadOnly(c.addr, query); err != nil {
+		return nil, err
+	}
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *readOnlyConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := checkReadOnly(c.addr, query); err != nil {
+		return nil, err
+	}
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *readOnlyConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// The monitor uses no transactions
+func (c *readOnlyConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return nil, fmt.Errorf("BEGIN %w", errReadOnly)
+}
+
+func (c *readOnlyConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *readOnlyConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *readOnlyConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *readOnlyConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *readOnlyConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
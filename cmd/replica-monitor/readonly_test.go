@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestReadOnlyStatement(t *testing.T) {
+	allowed := []string{
+		"SHOW REPLICA STATUS",
+		"SHOW SLAVE STATUS",
+		"SHOW GLOBAL STATUS LIKE 'Threads_running'",
+		"SHOW GRANTS FOR CURRENT_USER() USING `ops_role`@`%`",
+		"SELECT @@server_uuid",
+		"SELECT @@binlog_expire_logs_seconds AS value",
+		"SELECT @@basedir AS basedir, @@binlog_expire_logs_seconds AS value",
+		"SELECT CURRENT_USER()",
+		"SELECT VERSION()",
+		"SELECT IS_USED_LOCK(?) = CONNECTION_ID()",
+		"SELECT HOST FROM information_schema.PROCESSLIST WHERE COMMAND IN ('Binlog Dump', 'Binlog Dump GTID')",
+		"SELECT REPLICA_LAG_IN_MILLISECONDS FROM information_schema.replica_host_status WHERE SERVER_ID = @@aurora_server_id",
+		"SELECT pg_is_in_recovery()",
+		`SELECT
+		(SELECT status FROM pg_stat_wal_receiver),
+		pg_last_wal_receive_lsn()::text,
+		CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END`,
+		"SELECT 'GET_LOCK(x)' AS text",
+		"SELECT MAX(ts), UTC_TIMESTAMP(6) FROM `heartbeat`.`heartbeat` WHERE server_id = ?",
+		"SELECT `max`(ts) FROM t",
+	}
+	refused := []string{
+		"CALL mysql.rds_skip_repl_error",
+		"CALL mysql.rds_show_configuration",
+		"START REPLICA",
+		"STOP SLAVE SQL_THREAD",
+		"SET GLOBAL sql_slave_skip_counter = 1",
+		"SELECT GET_LOCK(?, 0)",
+		"DO RELEASE_LOCK(?)",
+		"SELECT pg_wal_replay_pause()",
+		"SELECT mysql.some_function()",
+		"SELECT `db`.`fn`()",
+		"SELECT `db` . `fn` ()",
+		"SELECT db.`fn`()",
+		"SELECT `get_lock`('x', 0)",
+		"SELECT `odd``name`()",
+		`SELECT "db"."fn"()`,
+		"SELECT * FROM mysql.user FOR UPDATE",
+		"SELECT * FROM t LOCK IN SHARE MODE",
+		"SELECT @@server_uuid INTO OUTFILE '/tmp/x'",
+		"SELECT 1; DROP TABLE t",
+		"SELECT 1 /*! , GET_LOCK('x', 0) */",
+		"SELECT 1 -- comment",
+		"show replica status; stop replica",
+		"INSERT INTO t VALUES (1)",
+		"",
+	}
+	for _, q := range allowed {
+		if !readOnlyStatement(q) {
+			t.Errorf("refused %q", q)
+		}
+	}
+	for _, q := range refused {
+		if readOnlyStatement(q) {
+			t.Errorf("allowed %q", q)
+		}
+	}
+}
+
+// A driver recording the statements that reach it
+type recordingConnector struct{ seen *[]string }
+
+func (c recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return recordingConn(c), nil
+}
+func (c recordingConnector) Driver() driver.Driver { return nil }
+
+type recordingConn struct{ seen *[]string }
+
+func (c recordingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c recordingConn) Close() error                        { return nil }
+func (c recordingConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c recordingConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	*c.seen = append(*c.seen, query)
+	return emptyRows{}, nil
+}
+
+func (c recordingConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	*c.seen = append(*c.seen, query)
+	return driver.RowsAffected(0), nil
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string              { return []string{"value"} }
+func (emptyRows) Close() error                   { return nil }
+func (emptyRows) Next(dest []driver.Value) error { return io.EOF }
+
+func TestReadOnlyConnector(t *testing.T) {
+	var seen []string
+	db := sql.OpenDB(readOnlyConnector{recordingConnector{&seen}, "replica:3306"})
+	defer db.Close()
+	ctx := context.Background()
+
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if _, err := db.ExecContext(ctx, "CALL mysql.rds_skip_repl_error"); !errors.Is(err, errReadOnly) {
+		t.Errorf("CALL: err = %v", err)
+	}
+	if _, err := db.QueryContext(ctx, "SELECT GET_LOCK(?, 0)", "lock"); !errors.Is(err, errReadOnly) {
+		t.Errorf("GET_LOCK: err = %v", err)
+	}
+	if _, err := db.BeginTx(ctx, nil); !errors.Is(err, errReadOnly) {
+		t.Errorf("BEGIN: err = %v", err)
+	}
+	if want := []string{"SHOW REPLICA STATUS"}; !slices.Equal(seen, want) {
+		t.Errorf("the driver saw %q, want %q", seen, want)
+	}
+}
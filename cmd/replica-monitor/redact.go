@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Shown instead of a secret
+const redacted = "[REDACTED]"
+
+// Values never shown in logs, reports, alerts, events, or API responses: the
+// -password and -influx-token values, in the forms they would appear in
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// Secrets shorter than this are left alone, since replacing them would mangle
+// every report; credentialPatterns still catch them in URLs, DSNs, and
+// password= settings
+const minSecretLen = 4
+
+// Whether a secret too short to redact was registered, warned about once
+// logging is set up
+var (
+	shortSecret        atomic.Bool
+	shortSecretWarning sync.Once
+	loggingReady       atomic.Bool
+)
+
+// Credentials in text that no flag names: passwords in URLs and MySQL DSNs, and
+// password=, token=, or signature= settings, as in IAM authentication tokens
+var credentialPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]*:)[^/\s@]+@`), "${1}" + redacted + "@"},
+	{regexp.MustCompile(`([^\s:@/()]*:)[^\s@]+@((?:tcp|unix)\()`), "${1}" + redacted + "@${2}"},
+	{regexp.MustCompile(`(?i)\b((?:password|passwd|pwd|token|signature)=)[^\s&"']+`), "${1}" + redacted},
+}
+
+// The arguments as given, before protectSecrets scrubbed them, for -daemon to
+// start the monitor again with
+var launchArgs []string
+
+// Copy the secret flags out of the command line, register them for redaction,
+// and scrub them from the process's arguments where the platform allows
+func protectSecrets() {
+	launchArgs = make([]string, len(os.Args)-1)
+	for i, arg := range os.Args[1:] {
+		launchArgs[i] = strings.Clone(arg)
+	}
+	// The flag values share memory with the arguments about to be overwritten
+	password = strings.Clone(password)
+	influxToken = strings.Clone(influxToken)
+	actionPassword = strings.Clone(actionPassword)
+	addSecret(password)
+	addSecret(influxToken)
+	addSecret(actionPassword)
+	scrubArgs(secretArgs(os.Args[1:], "password", "influx-token", "action-password"))
+}
+
+// Register s, along with its JSON-escaped form, to be replaced wherever it would
+// be shown
+func addSecret(s string) {
+	if s == "" {
+		return
+	}
+	if len(s) < minSecretLen {
+		shortSecret.Store(true)
+		if loggingReady.Load() {
+			warnShortSecret()
+		}
+		return
+	}
+	forms := []string{s}
+	if quoted, err := json.Marshal(s); err == nil {
+		if escaped := string(quoted[1 : len(quoted)-1]); escaped != s {
+			forms = append(forms, escaped)
+		}
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets = append(secrets, forms...)
+}
+
+func warnShortSecret() {
+	if !shortSecret.Load() {
+		return
+	}
+	shortSecretWarning.Do(func() {
+		slog.Warn(fmt.Sprintf("A password or token is shorter than %d characters, too short to redact: it is hidden only in URLs, DSNs, and password= or token= settings, and may show elsewhere in reports and logs", minSecretLen))
+	})
+}
+
+// Replace every secret and credential in s
+func redact(s string) string {
+	secretsMu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	secretsMu.RUnlock()
+	if strings.ContainsAny(s, "@=") {
+		for _, p := range credentialPatterns {
+			s = p.re.ReplaceAllString(s, p.repl)
+		}
+	}
+	return s
+}
+
+// The values of the named flags in args, as substrings of args themselves, for
+// both -name value and -name=value
+func secretArgs(args []string, names ...string) []string {
+	var values []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		for _, secret := range names {
+			if name != secret {
+				continue
+			}
+			if hasValue {
+				values = append(values, arg[len(arg)-len(value):])
+			} else if i+1 < len(args) {
+				i++
+				values = append(values, args[i])
+			}
+		}
+	}
+	return values
+}
+
+// Redacts each log record's message and attributes before passing it on
+type redactHandler struct {
+	slog.Handler
+}
+
+func (h redactHandler) Handle(ctx context.Context, rec slog.Record) error {
+	out := slog.NewRecord(rec.Time, rec.Level, redact(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, out)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	for i, a := range attrs {
+		attrs[i] = redactAttr(a)
+	}
+	return redactHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{h.Handler.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(redact(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		attrs := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			attrs[i] = redactAttr(ga)
+		}
+		a.Value = slog.GroupValue(attrs...)
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			a.Value = slog.StringValue(redact(v.Error()))
+		case fmt.Stringer:
+			a.Value = slog.StringValue(redact(v.String()))
+		default:
+			// Other values keep their structure unless they hold a secret
+			if s := fmt.Sprint(v); redact(s) != s {
+				a.Value = slog.StringValue(redact(s))
+			}
+		}
+	}
+	return a
+}
+
+// Redacts everything written through it; each write is redacted on its own, so
+// writers must not split a line across writes
+type redactWriter struct {
+	out io.Writer
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	if _, err := w.out.Write([]byte(redact(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Redacts the bodies of the API endpoints, which carry error messages from the
+// replicas; the dashboard's static files are left alone
+func redactResponses(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		h.ServeHTTP(redactResponseWriter{w}, req)
+	})
+}
+
+type redactResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w redactResponseWriter) Write(p []byte) (int, error) {
+	return redactWriter{w.ResponseWriter}.Write(p)
+}
+
+// Kept for the event stream, which flushes after each event
+func (w redactResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w redactResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Print a panic on the main goroutine with secrets taken out of its value and
+// exit as the runtime would; the stack shows no string contents
+func redactPanic() {
+	if p := recover(); p != nil {
+		fmt.Fprintf(os.Stderr, "panic: %s\n\n%s", redact(fmt.Sprint(p)), debug.Stack())
+		os.Exit(2)
+	}
+}
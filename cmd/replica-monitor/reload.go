@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"replica-monitor/pkg/monitor"
+	"replica-monitor/pkg/notify"
+)
+
+// How long -config's directory must be quiet after a change before the file is
+// read again
+const configSettleDelay = 100 * time.Millisecond
+
+// Last_SQL_Error patterns alerted on and skipped: the config file's
+// error_patterns, or monitor.DefaultErrorPatterns
+var errorPatterns = monitor.DefaultErrorPatterns
+
+// Alert destinations from the config file's notify list, besides -notify's
+var fileNotifyTargets []notify.Target
+
+// Flags given on the command line, which take precedence over the config
+// file's settings, and the command line's -lag-threshold and -alert-webhook,
+// which apply again when the file stops setting them
+var (
+	commandLineFlags  map[string]bool
+	flagLagThreshold  time.Duration
+	flagAlertWebhook  string
+	appliedConfigFile *fileConfig
+)
+
+// Config files read by watchConfigFile, for the loop to apply between cycles
+var configReloads = make(chan *fileConfig, 1)
+
+// Remember the command line the config file's settings are resolved against
+func rememberCommandLine(fs *flag.FlagSet) {
+	commandLineFlags = map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { commandLineFlags[f.Name] = true })
+	flagLagThreshold, flagAlertWebhook = lagThreshold, alertWebhook
+}
+
+// Check the settings of a config file before any of them are applied
+func validateSettings(cfg *fileConfig) error {
+	if cfg.LagThreshold != "" {
+		if d, err := time.ParseDuration(cfg.LagThreshold); err != nil || d <= 0 {
+			return fmt.Errorf("lag_threshold %q is not a positive duration", cfg.LagThreshold)
+		}
+	}
+	for _, pattern := range cfg.ErrorPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("error_patterns: %q: %w", pattern, err)
+		}
+	}
+	for _, rc := range cfg.allReplicas() {
+		if err := checkLagSources(rc.LagSources); err != nil {
+			return fmt.Errorf("replica %s: lag_sources: %w", rc.Host, err)
+		}
+	}
+	return validateTenants(cfg)
+}
+
+// A config file's settings resolved against the command line, with every
+// notify target they name opened, so that applySettings commits all of them or
+// none
+type settingsUpdate struct {
+	threshold time.Duration
+	patterns  []string
+	webhook   string
+	retarget  bool
+	targets   []notify.Target
+	tenants   []tenantUpdate
+}
+
+// Resolve the settings of a config file, validated already, without applying
+// any; flags given on the command line win
+func prepareSettings(cfg *fileConfig) (*settingsUpdate, error) {
+	u := &settingsUpdate{threshold: flagLagThreshold, patterns: monitor.DefaultErrorPatterns, webhook: flagAlertWebhook}
+	if cfg.LagThreshold != "" && !commandLineFlags["lag-threshold"] {
+		u.threshold, _ = time.ParseDuration(cfg.LagThreshold)
+	}
+	if len(cfg.ErrorPatterns) > 0 {
+		u.patterns = cfg.ErrorPatterns
+	}
+	if cfg.AlertWebhook != "" && !commandLineFlags["alert-webhook"] {
+		u.webhook = cfg.AlertWebhook
+	}
+	var oldNotify []string
+	if appliedConfigFile != nil {
+		oldNotify = appliedConfigFile.Notify
+	}
+	u.retarget = u.webhook != alertWebhook || !slices.Equal(cfg.Notify, oldNotify)
+	if u.retarget {
+		u.targets = make([]notify.Target, 0, len(cfg.Notify))
+		for _, url := range cfg.Notify {
+			t, err := notify.Open(url)
+			if err != nil {
+				return nil, fmt.Errorf("notify %s: %w", redact(url), err)
+			}
+			u.targets = append(u.targets, t)
+		}
+	}
+	var err error
+	if u.tenants, err = prepareTenantSettings(cfg); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Apply the settings of a config file, validated already, and report which
+// changed; flags given on the command line win. On an error nothing is
+// applied.
+func applySettings(cfg *fileConfig) ([]string, error) {
+	u, err := prepareSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	if u.threshold != lagThreshold {
+		lagThreshold = u.threshold
+		changed = append(changed, "lag_threshold")
+	}
+	if !slices.Equal(u.patterns, errorPatterns) {
+		errorPatterns = u.patterns
+		changed = append(changed, "error_patterns")
+	}
+	if u.retarget {
+		alertWebhook, fileNotifyTargets = u.webhook, u.targets
+		restartDispatcher()
+		changed = append(changed, "notifiers")
+	}
+	changed = append(changed, applyTenantSettings(cfg, u.tenants)...)
+
+	if appliedConfigFile != nil && (!reflect.DeepEqual(cfg.Replicas, appliedConfigFile.Replicas) || !reflect.DeepEqual(cfg.Labels, appliedConfigFile.Labels)) {
+		slog.Warn("Config file's replicas and labels changed; they take effect on restart", "path", configPath)
+	}
+	appliedConfigFile = cfg
+	return changed, nil
+}
+
+// Send later alerts through a dispatcher for the current destinations,
+// delivering those already queued through the old one
+func restartDispatcher() {
+	old := dispatcher
+	dispatcher, dispatcherOnce = nil, sync.Once{}
+	if old != nil {
+		go old.Drain(time.Minute)
+	}
+}
+
+// Watch -config in the background, queue changed contents for the loop, and
+// wake it to apply them. The file's directory is watched rather than the file:
+// a mounted ConfigMap is updated by swapping its ..data symlink, which replaces
+// the file without writing to it, and editors save by renaming over it.
+func watchConfigFile(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(filepath.Dir(configPath))
+	}
+	if err != nil {
+		slog.Error("Cannot watch the config file; changes take effect on restart", "path", configPath, "err", err)
+		return
+	}
+	defer watcher.Close()
+
+	name := filepath.Base(configPath)
+	last, _ := os.ReadFile(configPath)
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-watcher.Errors:
+			slog.Warn("Config file watch failed; a change may be missed", "path", configPath, "err", err)
+			continue
+		case event := <-watcher.Events:
+			// A write arrives as several events; read the file once they stop
+			if base := filepath.Base(event.Name); base == name || strings.HasPrefix(base, "..") {
+				settle = time.After(configSettleDelay)
+			}
+			continue
+		case <-settle:
+			settle = nil
+		}
+		data, err := os.ReadFile(configPath)
+		if err != nil || bytes.Equal(data, last) {
+			continue
+		}
+		last = data
+		cfg, err := loadConfig(configPath)
+		if err == nil {
+			err = validateSettings(cfg)
+		}
+		if err != nil {
+			slog.Error("Config file changed but cannot be applied; keeping the current settings", "path", configPath, "err", err)
+			continue
+		}
+		// Replace a reload the loop has not applied yet
+		select {
+		case <-configReloads:
+		default:
+		}
+		configReloads <- cfg
+		wakeLoop()
+	}
+}
+
+// Apply a config file queued by watchConfigFile, on the loop's goroutine
+func applyConfigReload() {
+	select {
+	case cfg := <-configReloads:
+		changed, err := applySettings(cfg)
+		if err != nil {
+			slog.Error("Config file changed but cannot be applied; keeping the current settings", "path", configPath, "err", err)
+			return
+		}
+		if len(changed) == 0 {
+			slog.Info("Config file changed; no settings differ", "path", configPath)
+			return
+		}
+		slog.Info("Config file reloaded", "path", configPath, "changed", strings.Join(changed, ", "),
+			"lag_threshold", lagThreshold, "error_patterns", len(errorPatterns))
+		fmt.Fprintf(stdout, "🔁 Reloaded %s: %s\n", configPath, strings.Join(changed, ", "))
+	default:
+	}
+}
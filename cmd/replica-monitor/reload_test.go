@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+func TestApplySettings(t *testing.T) {
+	defer func() {
+		lagThreshold, alertWebhook, errorPatterns = 0, "", monitor.DefaultErrorPatterns
+		fileNotifyTargets, appliedConfigFile, commandLineFlags = nil, nil, nil
+		restartDispatcher()
+	}()
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.DurationVar(&lagThreshold, "lag-threshold", 5*time.Minute, "")
+	fs.StringVar(&alertWebhook, "alert-webhook", "", "")
+	if err := fs.Parse([]string{"-alert-webhook", "http://alerts.example.com/from-flag"}); err != nil {
+		t.Fatal(err)
+	}
+	rememberCommandLine(fs)
+
+	cfg := &fileConfig{LagThreshold: "90s", ErrorPatterns: []string{"Duplicate entry"}, AlertWebhook: "http://alerts.example.com/from-file"}
+	if err := validateSettings(cfg); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := applySettings(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changed, []string{"lag_threshold", "error_patterns"}) {
+		t.Errorf("changed = %q", changed)
+	}
+	if lagThreshold != 90*time.Second || !slices.Equal(errorPatterns, cfg.ErrorPatterns) {
+		t.Errorf("lag threshold %s, patterns %q; want the file's", lagThreshold, errorPatterns)
+	}
+	if alertWebhook != "http://alerts.example.com/from-flag" {
+		t.Errorf("alert webhook %s; want the command line's over the file's", alertWebhook)
+	}
+
+	// Settings removed from the file go back to the command line's and the defaults
+	if changed, _ = applySettings(&fileConfig{}); !slices.Equal(changed, []string{"lag_threshold", "error_patterns"}) {
+		t.Errorf("changed = %q", changed)
+	}
+	if lagThreshold != 5*time.Minute || !slices.Equal(errorPatterns, monitor.DefaultErrorPatterns) {
+		t.Errorf("lag threshold %s, patterns %q; want the defaults back", lagThreshold, errorPatterns)
+	}
+	if changed, _ = applySettings(&fileConfig{}); len(changed) != 0 {
+		t.Errorf("unchanged file changed %q", changed)
+	}
+
+	// A notify target that fails to open, top-level or a tenant's, leaves
+	// every setting as it was
+	tenants = map[string]*tenant{}
+	defer func() { tenants = map[string]*tenant{} }()
+	applied := appliedConfigFile
+	for _, bad := range []*fileConfig{
+		{LagThreshold: "90s", ErrorPatterns: []string{"Duplicate entry"}, Notify: []string{"pigeon://loft"}},
+		{LagThreshold: "90s", Tenants: []tenantConfig{{Name: "checkout", LagThreshold: "30s"}, {Name: "search", Notify: []string{"pigeon://loft"}}}},
+	} {
+		if _, err := applySettings(bad); err == nil {
+			t.Errorf("settings with notify %q applied", bad.Notify)
+		}
+		if lagThreshold != 5*time.Minute || !slices.Equal(errorPatterns, monitor.DefaultErrorPatterns) || len(tenants) != 0 || appliedConfigFile != applied {
+			t.Errorf("failed reload applied lag threshold %s, patterns %q, %d tenants", lagThreshold, errorPatterns, len(tenants))
+		}
+	}
+
+	for _, bad := range []*fileConfig{{LagThreshold: "soon"}, {LagThreshold: "-1m"}, {ErrorPatterns: []string{"Coordinator (stopped"}}} {
+		if err := validateSettings(bad); err == nil {
+			t.Errorf("invalid settings %+v accepted", bad)
+		}
+	}
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	defer func(path string) { configPath = path }(configPath)
+	configPath = filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"lag_threshold": "1m"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchConfigFile(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	// Replaced the way an editor saves it, by renaming a new file over it
+	next := filepath.Join(dir, "config.json.tmp")
+	if err := os.WriteFile(next, []byte(`{"lag_threshold": "2m"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(next, configPath); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-configReloads:
+		if cfg.LagThreshold != "2m" {
+			t.Errorf("queued lag_threshold %q; want the new file's", cfg.LagThreshold)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("changed config file not queued")
+	}
+	select {
+	case <-loopWake:
+	default:
+		t.Error("loop not woken to apply the change")
+	}
+}
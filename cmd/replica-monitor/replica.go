@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// A monitored replica with its own connection and lag statistics
+type replica struct {
+	name   string // shown in report headers when monitoring several replicas
+	host   string
+	port   int
+	region string // AWS region, known for replicas found through the RDS API
+	labels map[string]string
+	tenant *tenant // the config file's tenant listing the replica, nil outside every tenant
+
+	// The replica this one replicates from in a chained topology, nil when that is the source
+	upstream *replica
+	db       *sql.DB
+	// Replica status and skip/start actions for the replica's engine, through db
+	// unless replaced in tests
+	engine monitor.Engine
+	source monitor.StatusSource
+	// Polls source and evaluates its status, created on the first poll; stats
+	// are its catch-up statistics as of the latest one
+	poller *monitor.Monitor
+	stats  monitor.LagStats
+
+	// Results of the most recent poll; lagKnown is false when lag was NULL or the poll failed
+	lagSeconds float64
+	lagKnown   bool
+	ioRunning  string
+	sqlRunning string
+
+	// Full status row and alert state, published on the HTTP status endpoint
+	polledAt       time.Time
+	lastStatus     map[string]string
+	errorMatched   bool
+	replicationTLS replicationTLS     // encryption of the stream from the source, from lastStatus
+	lagSources     []string           // from the config file, overriding -lag-sources
+	lagReconciled  *lagReconciliation // each lag source's reading, nil with only -lag-sources status
+	lastAlert      *alertState
+	skips          int        // successful mysql.rds_skip_repl_error calls since startup
+	pollFailures   int        // consecutive failed polls, reset by a successful one
+	skipLock       skipLocker // from -skip-lock, nil without one
+
+	// Load signals for -throttle: the last SHOW REPLICA STATUS round trip and Threads_running
+	queryRTT       time.Duration
+	threadsRunning int
+
+	// Most recent non-NULL lag samples for the report sparkline
+	recentLags monitor.Ring[float64]
+
+	// Open incident from the first problem until the replica is healthy again, "" when none
+	incident      string
+	incidentStart time.Time
+
+	// Server identity at the last poll and when a restart was last noted
+	identity    serverIdentity
+	restartedAt time.Time
+
+	// RDS instance metadata from -rds-metadata, nil until looked up
+	instance        *monitor.InstanceInfo
+	instanceChecked time.Time
+
+	// Aurora readers report lag through replica_host_status instead of SHOW REPLICA STATUS
+	aurora bool
+	// Reached through an RDS Proxy endpoint, whose addresses and server can change between polls
+	proxy bool
+}
+
+// Open and verify a connection to a replica, through the status source of its
+// engine: -engine, or the one its handshake names
+func connectReplica(name, host string, port int) (*replica, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	engine, err := replicaEngine(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	login := account{user: user, password: currentPassword()}
+	if passwordFile != "" {
+		login.current = currentPassword
+	}
+	db, err := openDB(ctx, host, port, engine, login)
+	if err != nil {
+		return nil, err
+	}
+	proxy := isRDSProxy(host)
+	if engineName == "auto" && engine.Name == "mysql" {
+		// RDS Proxy greets clients as MySQL whatever it is in front of
+		if proxy && monitor.DetectMariaDB(ctx, db) {
+			engine, _ = monitor.LookupEngine("mariadb")
+		} else if monitor.DetectAurora(ctx, db) {
+			engine, _ = monitor.LookupEngine("aurora")
+		}
+	}
+	if passwordFile != "" {
+		registerPool(db, addr)
+	}
+	r := newReplica(name, host, port, db, engine, engine.Open(db))
+	r.proxy = proxy
+	return r, nil
+}
+
+// A database account the monitor logs in as
+type account struct {
+	user     string
+	password string
+	// Reads the password for every new connection when it can change, e.g.
+	// under -password-file; nil logs in with password throughout
+	current func() string
+}
+
+// Open and ping a connection pool to a replica of engine as login; -auth iam
+// logs in with a token for login's user instead of its password
+func openDB(ctx context.Context, host string, port int, engine monitor.Engine, login account) (*sql.DB, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	var db *sql.DB
+	if engine.Driver == "pgx" {
+		cfg, err := pgx.ParseConfig("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		cfg.Host = host
+		cfg.Port = uint16(port)
+		cfg.User = login.user
+		cfg.Password = login.password
+		cfg.Database = "postgres"
+		cfg.ConnectTimeout = queryTimeout
+		if err := postgresTLS(cfg, host, port); err != nil {
+			return nil, err
+		}
+		var opts []stdlib.OptionOpenDB
+		switch {
+		case authMode == "iam":
+			opts = append(opts, stdlib.OptionBeforeConnect(func(ctx context.Context, cfg *pgx.ConnConfig) error {
+				token, err := buildIAMToken(ctx, addr, login.user)
+				cfg.Password = token
+				return err
+			}))
+		case login.current != nil:
+			opts = append(opts, stdlib.OptionBeforeConnect(func(ctx context.Context, cfg *pgx.ConnConfig) error {
+				cfg.Password = login.current()
+				return nil
+			}))
+		}
+		connector := stdlib.GetConnector(*cfg, opts...)
+		if readOnly {
+			connector = readOnlyConnector{connector, addr}
+		}
+		db = sql.OpenDB(connector)
+	} else {
+		cfg := mysql.NewConfig()
+		cfg.User = login.user
+		cfg.Passwd = login.password
+		cfg.Net = "tcp"
+		cfg.Addr = addr
+		cfg.Timeout = queryTimeout
+		cfg.AllowCleartextPasswords = allowCleartext || authMode == "iam"
+		if serverPublicKey != "" {
+			if err := loadServerPublicKey(); err != nil {
+				return nil, fmt.Errorf("load -server-public-key: %w", err)
+			}
+			cfg.ServerPubKey = serverPublicKeyName
+		}
+
+		tlsConfig, fallback, err := databaseTLS(host)
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLS = tlsConfig
+		cfg.AllowFallbackToPlaintext = fallback
+
+		var connector driver.Connector
+		switch {
+		case authMode == "iam":
+			connector = loginConnector{cfg, func(ctx context.Context) (string, error) {
+				return buildIAMToken(ctx, cfg.Addr, cfg.User)
+			}}
+		case login.current != nil:
+			connector = loginConnector{cfg, func(context.Context) (string, error) {
+				return login.current(), nil
+			}}
+		default:
+			if connector, err = mysql.NewConnector(cfg); err != nil {
+				return nil, fmt.Errorf("failed to connect to database: %w", err)
+			}
+		}
+		if readOnly {
+			connector = readOnlyConnector{connector, cfg.Addr}
+		}
+		if debugLogging() {
+			connector = tracingConnector{connector, cfg.Addr}
+		}
+		db = sql.OpenDB(connector)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		if hint := authHint(err, host); hint != "" {
+			return nil, fmt.Errorf("failed to ping database: %w (%s)", err, hint)
+		}
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return db, nil
+}
+
+// A replica read through source; db is nil for simulated ones
+func newReplica(name, host string, port int, db *sql.DB, engine monitor.Engine, source monitor.StatusSource) *replica {
+	r := &replica{name: name, host: host, port: port, db: db, engine: engine, source: source, labels: mergeLabels(globalLabels)}
+	// Two samples at least, to tell whether lag is moving
+	r.recentLags = monitor.NewRing[float64](max(sparklineWidth, 2))
+	r.stats = monitor.NewLagStats(etaRateWindow)
+	r.skipLock = newSkipLock(r)
+	return r
+}
+
+// The engine named by -engine, or detected from the server's handshake
+func replicaEngine(ctx context.Context, addr string) (monitor.Engine, error) {
+	name := engineName
+	if name == "auto" {
+		var err error
+		if name, err = monitor.DetectEngine(ctx, addr); err != nil {
+			return monitor.Engine{}, fmt.Errorf("failed to detect the database engine: %w", err)
+		}
+		slog.Debug("Detected database engine", "addr", addr, "engine", name)
+	}
+	engine, ok := monitor.LookupEngine(name)
+	if !ok {
+		return monitor.Engine{}, fmt.Errorf("unknown engine %q", name)
+	}
+	return engine, nil
+}
+
+// Bound a statement by -query-timeout, so a replica that stops answering (for
+// example during crash recovery) cannot hang the monitoring loop
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, queryTimeout)
+}
+
+// The replica's monitor, created on the first poll so that it sees the flags
+// and the replica's final name
+func (r *replica) statusMonitor() *monitor.Monitor {
+	if r.poller == nil {
+		// New fails only without a source or with a bad pattern; each poll
+		// sets the replica's patterns
+		r.poller, _ = monitor.New(monitor.Config{
+			Source:        pollSource{r.source, r},
+			Name:          displayName(r),
+			ErrorPatterns: []string{},
+			RateWindow:    etaRateWindow,
+		})
+	}
+	return r.poller
+}
+
+// A replica's source as its monitor reads it: retried through transient
+// errors, traced, and timed for the query's round trip
+type pollSource struct {
+	monitor.StatusSource
+	r *replica
+}
+
+func (s pollSource) ReplicaStatus(ctx context.Context) (*monitor.StatusRow, error) {
+	_, span := tracer.Start(ctx, "query")
+	defer span.End()
+	start := time.Now()
+	var row *monitor.StatusRow
+	err := retryTransient(ctx, s.r, func() error {
+		var err error
+		row, err = s.StatusSource.ReplicaStatus(ctx)
+		return err
+	})
+	spanError(span, err)
+	if err == nil {
+		s.r.queryRTT = time.Since(start)
+	}
+	return row, err
+}
+
+// What the latest poll found, for the checks, verdicts, and transitions
+// pkg/monitor evaluates
+func (r *replica) health() monitor.Health {
+	return monitor.Health{
+		Polled:       !r.polledAt.IsZero(),
+		Failing:      r.pollFailures > 0,
+		NoThreads:    r.aurora,
+		IORunning:    r.ioRunning,
+		SQLRunning:   r.sqlRunning,
+		ErrorMatched: r.errorMatched,
+		LagKnown:     r.lagKnown,
+		LagSeconds:   r.lagSeconds,
+		TLSKnown:     r.replicationTLS.known,
+		TLSEncrypted: r.replicationTLS.encrypted,
+		TLSStream:    r.replicationTLS.String(),
+	}
+}
+
+// Remember a lag sample, keeping the last -sparkline samples
+func (r *replica) recordLag(seconds float64) {
+	r.recentLags.Push(seconds)
+}
+
+func (r *replica) close() {
+	if r.skipLock != nil {
+		r.skipLock.release()
+	}
+	if r.db != nil {
+		unregisterPool(r.db)
+		r.db.Close()
+	}
+}
+
+// Run mysql.rds_skip_repl_error against the replica; trigger says who asked
+// for it in the audit log: auto, cli, or tui
+func (r *replica) skipReplError(out io.Writer, trigger string) error {
+	fmt.Fprintln(out, "⚠️  WARNING: SQL Error detected!")
+	skipped := r.lastStatus["Last_SQL_Error"]
+	if err := auditIntent(r, "skip", trigger, skipped); err != nil {
+		fmt.Fprintf(out, "⛔ Not skipping: %v\n", err)
+		return err
+	}
+	fmt.Fprintln(out, "🔄 Executing mysql.rds_skip_repl_error...")
+
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	source, done, err := r.actionSource(ctx)
+	if err == nil {
+		err = source.SkipError(ctx)
+		done()
+	}
+	if err != nil {
+		slog.Error("mysql.rds_skip_repl_error failed", "replica", displayName(r), "err", err)
+		audit(r, "skip_failed", trigger, fmt.Sprintf("%v; error left in place: %s", err, skipped))
+		sendAlert(r, "skip_failed", fmt.Sprintf("mysql.rds_skip_repl_error failed: %v", err))
+		return err
+	}
+	fmt.Fprintln(out, "✅ Successfully executed mysql.rds_skip_repl_error")
+	r.skips++
+	err = audit(r, "skip", trigger, skipped)
+	recordTimeline(r, "skip", "Executed mysql.rds_skip_repl_error")
+	recordEvent(r, journalEvent{Event: "skip", Message: "Executed mysql.rds_skip_repl_error"}, 0)
+	if err != nil {
+		return fmt.Errorf("skipped, but not recorded in -audit-log: %w", err)
+	}
+	return nil
+}
+
+// Start the replication threads, through mysql.rds_start_replication on RDS and
+// START REPLICA elsewhere; trigger is as for skipReplError
+func (r *replica) startReplication(out io.Writer, trigger string) error {
+	if err := auditIntent(r, "start_replication", trigger, ""); err != nil {
+		fmt.Fprintf(out, "⛔ Not starting replication: %v\n", err)
+		return err
+	}
+	fmt.Fprintln(out, "🔄 Starting replication...")
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	source, done, err := r.actionSource(ctx)
+	if err == nil {
+		// Falls back to START REPLICA when the server is not RDS
+		err = source.StartReplication(ctx)
+		done()
+	}
+	if err != nil {
+		slog.Error("Failed to start replication", "replica", displayName(r), "err", err)
+		audit(r, "start_replication_failed", trigger, err.Error())
+		return err
+	}
+	fmt.Fprintln(out, "✅ Replication started")
+	if err := audit(r, "start_replication", trigger, ""); err != nil {
+		return fmt.Errorf("started, but not recorded in -audit-log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Alert with -require-replication-tls when a replica pulls binlogs from its
+// source unencrypted, which TLS on the monitor's own connections says nothing about
+var requireReplicationTLS bool
+
+// How a replica's connection to its source is protected, from the Source_SSL_*
+// and Source_TLS_Version columns of its status row
+type replicationTLS struct {
+	known     bool // false for engines and replicas whose status has no Source_SSL_Allowed
+	encrypted bool
+	allowed   string // Source_SSL_Allowed: Yes, No, or Ignored when the replica was built without TLS
+	verified  bool   // Source_SSL_Verify_Server_Cert
+	version   string // Source_TLS_Version; empty allows any the server supports
+	cipher    string // Source_SSL_Cipher; empty allows any
+}
+
+func replicationTLSOf(status map[string]string) replicationTLS {
+	allowed, ok := status["Source_SSL_Allowed"]
+	if !ok || allowed == "" {
+		return replicationTLS{}
+	}
+	return replicationTLS{
+		known:     true,
+		encrypted: strings.EqualFold(allowed, "Yes"),
+		allowed:   allowed,
+		verified:  strings.EqualFold(status["Source_SSL_Verify_Server_Cert"], "Yes"),
+		version:   status["Source_TLS_Version"],
+		cipher:    status["Source_SSL_Cipher"],
+	}
+}
+
+func (t replicationTLS) String() string {
+	if !t.encrypted {
+		return fmt.Sprintf("unencrypted (Source_SSL_Allowed: %s)", t.allowed)
+	}
+	parts := []string{"encrypted"}
+	if t.version != "" {
+		parts = append(parts, t.version)
+	}
+	if t.cipher != "" {
+		parts = append(parts, "cipher "+t.cipher)
+	}
+	if t.verified {
+		parts = append(parts, "verifying the source's certificate")
+	} else {
+		parts = append(parts, "not verifying the source's certificate")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Read the replication stream's TLS settings from the latest poll and, with
+// -require-replication-tls, alert once when the stream becomes unencrypted
+func checkReplicationTLS(r *replica) {
+	stream := replicationTLSOf(r.lastStatus)
+	previous := r.replicationTLS
+	r.replicationTLS = stream
+	if !stream.known || !requireReplicationTLS {
+		return
+	}
+	switch {
+	case !stream.encrypted && (!previous.known || previous.encrypted):
+		sendAlert(r, "replication_unencrypted", fmt.Sprintf("Replication from %s is %s", sourceOf(r), stream))
+	case stream.encrypted && previous.known && !previous.encrypted:
+		slog.Info("Replication stream is encrypted again", "replica", displayName(r), "tls", stream.String())
+	}
+}
+
+// The source host and port a replica reports, for messages
+func sourceOf(r *replica) string {
+	if h := r.lastStatus["Source_Host"]; h != "" {
+		return h + ":" + r.lastStatus["Source_Port"]
+	}
+	return "the source"
+}
+
+// Print the replication stream's encryption under the lag with
+// -require-replication-tls; -wide shows the Source_SSL_* columns without it
+func printReplicationTLS(out io.Writer, r *replica) {
+	stream := r.replicationTLS
+	if !requireReplicationTLS || !stream.known {
+		return
+	}
+	marker := "🔒"
+	if !stream.encrypted {
+		marker = "🔓"
+	}
+	fmt.Fprintf(out, "%s Replication stream: %s\n", marker, stream)
+}
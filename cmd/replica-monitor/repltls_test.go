@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestCheckReplicationTLS(t *testing.T) {
+	defer func() { requireReplicationTLS = false }()
+	requireReplicationTLS = true
+	plain := map[string]string{"Source_Host": "source.example.com", "Source_Port": "3306", "Source_SSL_Allowed": "No"}
+	encrypted := map[string]string{"Source_SSL_Allowed": "Yes", "Source_SSL_Verify_Server_Cert": "Yes", "Source_TLS_Version": "TLSv1.3"}
+
+	r := &replica{name: "replica"}
+	alerts := 0
+	for i, status := range []map[string]string{plain, plain, encrypted, plain, {}} {
+		r.lastStatus, r.lastAlert = status, nil
+		checkReplicationTLS(r)
+		if r.lastAlert != nil {
+			alerts++
+			if r.lastAlert.Event != "replication_unencrypted" {
+				t.Errorf("poll %d raised %s", i, r.lastAlert.Event)
+			}
+		}
+	}
+	// Once when first seen unencrypted, and again after being encrypted in between
+	if alerts != 2 {
+		t.Errorf("%d alerts; want 2", alerts)
+	}
+	if r.replicationTLS.known {
+		t.Error("status without Source_SSL_Allowed reported a known stream")
+	}
+	if got := replicationTLSOf(encrypted).String(); got != "encrypted, TLSv1.3, verifying the source's certificate" {
+		t.Errorf("encrypted stream described as %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// What identifies the server behind a replica's endpoint; a change means it
+// restarted or failed over
+type serverIdentity struct {
+	uuid   string
+	uptime time.Duration
+	addrs  string // resolved endpoint addresses, sorted
+}
+
+// Restarts noticed within this long of the last one are the same restart seen
+// twice, e.g. through the uptime and an RDS event
+const restartDedupWindow = 10 * time.Minute
+
+// Compare the server's UUID, uptime, and endpoint addresses with the previous
+// poll; called after each successful poll
+func checkRestart(ctx context.Context, r *replica) {
+	if r.db == nil {
+		return
+	}
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+	var id serverIdentity
+	if err := r.db.QueryRowContext(ctx, "SELECT @@server_uuid").Scan(&id.uuid); err != nil {
+		slog.Debug("Failed to read server_uuid", "replica", displayName(r), "err", err)
+		return
+	}
+	var name, value string
+	if err := r.db.QueryRowContext(ctx, "SHOW GLOBAL STATUS LIKE 'Uptime'").Scan(&name, &value); err != nil {
+		slog.Debug("Failed to read Uptime", "replica", displayName(r), "err", err)
+		return
+	}
+	seconds, _ := strconv.Atoi(value)
+	id.uptime = time.Duration(seconds) * time.Second
+	if addrs, err := net.DefaultResolver.LookupHost(ctx, r.host); err == nil {
+		slices.Sort(addrs)
+		id.addrs = strings.Join(addrs, ",")
+	}
+
+	prev := r.identity
+	r.identity = id
+	if prev.uuid == "" {
+		return
+	}
+	var reasons []string
+	if id.uptime < prev.uptime {
+		reasons = append(reasons, fmt.Sprintf("uptime reset to %s", formatDuration(id.uptime.Seconds())))
+	}
+	if id.uuid != prev.uuid {
+		reasons = append(reasons, fmt.Sprintf("server UUID changed from %s to %s", prev.uuid, id.uuid))
+	}
+	if r.proxy && id.uuid != prev.uuid && id.uptime >= prev.uptime {
+		// A proxy in front of several servers answers from any of them
+		slog.Warn("RDS Proxy answered from a different server; monitor each instance's own endpoint to follow one", "replica", displayName(r), "from", prev.uuid, "to", id.uuid)
+		return
+	}
+	// A proxy's addresses change without the server behind it changing
+	if !r.proxy && prev.addrs != "" && id.addrs != "" && id.addrs != prev.addrs {
+		reasons = append(reasons, fmt.Sprintf("endpoint address changed from %s to %s", prev.addrs, id.addrs))
+	}
+	if len(reasons) > 0 {
+		noteRestart(r, strings.Join(reasons, ", "))
+	}
+}
+
+// Announce that a replica restarted or failed over and start its catch-up
+// statistics over, since rates across the gap would mislead the ETA
+func noteRestart(r *replica, reason string) {
+	now := time.Now()
+	if !r.restartedAt.IsZero() && now.Sub(r.restartedAt) < restartDedupWindow {
+		slog.Debug("Restart already noted", "replica", displayName(r), "reason", reason)
+		return
+	}
+	r.restartedAt = now
+	banner("🔁", fmt.Sprintf("%s: replica restarted (%s); catch-up statistics start over", displayName(r), reason))
+	slog.Warn("Replica restarted", "replica", displayName(r), "reason", reason)
+	recordTimeline(r, "replica_restarted", reason)
+	recordEvent(r, journalEvent{Event: "replica_restarted", Message: reason}, 0)
+	r.stats = monitor.NewLagStats(etaRateWindow)
+	if r.poller != nil {
+		r.poller.ResetStats()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+const (
+	pollRetries      = 2                      // extra attempts within a cycle for transient errors
+	pollRetryBackoff = 250 * time.Millisecond // doubled after each attempt
+	// Consecutive failed cycles before a transient failure is reported and alerted on
+	pollFailureThreshold = 3
+)
+
+// Run fn, retrying transient failures with backoff within the cycle. Our own
+// -query-timeout expiring is not retried, since a wedged replica would only
+// multiply the wait.
+func retryTransient(ctx context.Context, r *replica, fn func() error) error {
+	backoff := pollRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == pollRetries || !monitor.ClassifyError(err).Transient() || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		slog.Debug("Retrying after transient error", "replica", displayName(r), "attempt", attempt+1, "err", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// Count a failed poll, surfacing it at once when it is not transient and only
+// after pollFailureThreshold cycles in a row otherwise. Statistics are left as
+// they are so rates and ETAs resume where they left off.
+func pollFailed(r *replica, query string, err error) {
+	class := monitor.ClassifyError(err)
+	r.pollFailures++
+	switch {
+	case !class.Transient():
+		slog.Error(query+" failed", "replica", displayName(r), "class", class, "err", err)
+		if r.pollFailures == 1 {
+			sendAlert(r, "poll_failed", fmt.Sprintf("%s failed (%s): %v", query, class, err))
+		}
+	case r.pollFailures < pollFailureThreshold:
+		slog.Warn(query+" failed, will retry next cycle", "replica", displayName(r), "class", class, "failures", r.pollFailures, "err", err)
+	default:
+		slog.Error(query+" keeps failing", "replica", displayName(r), "class", class, "failures", r.pollFailures, "err", err)
+		if r.pollFailures == pollFailureThreshold {
+			sendAlert(r, "poll_failed", fmt.Sprintf("%s failed %d cycles in a row (%s): %v", query, r.pollFailures, class, err))
+		}
+	}
+}
+
+// Reset the failure count after a successful poll
+func pollSucceeded(r *replica) {
+	if r.pollFailures >= pollFailureThreshold {
+		slog.Info("Replica answering again", "replica", displayName(r), "failures", r.pollFailures)
+	}
+	r.pollFailures = 0
+}
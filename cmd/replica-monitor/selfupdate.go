@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version of this build, and the base64 Ed25519 public key release checksums
+// are signed with, both set when releases are built:
+//
+//	go build -ldflags "-X main.version=v1.4.0 -X main.releaseKey=MCowBQYDK2VwAyEA..."
+var (
+	version    = "dev"
+	releaseKey string
+)
+
+const (
+	releaseRepo      = "timbaileyjones/rds-replica-monitor"
+	releaseChecksums = "checksums.txt"
+
+	// Largest release asset downloaded, well above the binary's size
+	maxReleaseAsset = 256 << 20
+)
+
+var releaseClient = &http.Client{Timeout: 5 * time.Minute}
+
+// A GitHub release and the files attached to it
+type release struct {
+	Tag    string `json:"tag_name"`
+	Assets []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+
+	apiHost string // host of -github-api, the only one GITHUB_TOKEN is sent to
+}
+
+func (rel *release) assetURL(name string) string {
+	for _, a := range rel.Assets {
+		if a.Name == name {
+			return a.URL
+		}
+	}
+	return ""
+}
+
+// Name of the release binary for this platform, e.g. replica-monitor-linux-amd64
+func releaseAssetName() string {
+	name := "replica-monitor-" + runtime.GOOS + "-" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func versionFlags() *flag.FlagSet {
+	return newCommandFlags("version")
+}
+
+func runVersion(fs *flag.FlagSet) error {
+	fmt.Printf("replica-monitor %s (%s %s/%s)\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return nil
+}
+
+// Flags of self-update
+var (
+	updateRepo          string
+	updateAPI           string
+	updateTag           string
+	updateCheckOnly     bool
+	updatePublicKey     string
+	updateForce         bool
+	updateSkipSignature bool
+)
+
+func selfUpdateFlags() *flag.FlagSet {
+	fs := newCommandFlags("self-update")
+	fs.StringVar(&updateRepo, "repo", releaseRepo, "GitHub repository, owner/name, whose releases to install")
+	fs.StringVar(&updateAPI, "github-api", "https://api.github.com", "GitHub API URL, for GitHub Enterprise or a mirror")
+	fs.StringVar(&updateTag, "version", "", "Release tag to install, e.g. v1.4.0, even if older; the latest release by default")
+	fs.BoolVar(&updateCheckOnly, "check", false, "Only report whether a newer release exists, exiting 1 if one does")
+	fs.StringVar(&updatePublicKey, "public-key", "", "PEM Ed25519 public key the release's "+releaseChecksums+" is signed with, instead of the one built in")
+	fs.BoolVar(&updateForce, "force", false, "Install even when this build is the same release or was built from source")
+	fs.BoolVar(&updateSkipSignature, "insecure-skip-signature", false, "Install a release whose "+releaseChecksums+" signature cannot be checked, in a build without a release key and given no -public-key")
+	fs.BoolVar(&assumeYes, "yes", false, "Replace the binary without asking")
+	return fs
+}
+
+func runSelfUpdate(fs *flag.FlagSet) error {
+	pub, err := releasePublicKey(updatePublicKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-public-key: %v\n", err)
+		return exitWith(2)
+	}
+	// A checksum from the same download proves nothing about who built the binary
+	if pub == nil && !updateCheckOnly && !updateSkipSignature {
+		fmt.Fprintln(os.Stderr, "This build has no release key to check the release's signature with; give -public-key, or -insecure-skip-signature to install it unsigned")
+		return exitWith(2)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	rel, err := fetchRelease(ctx, updateAPI, updateRepo, updateTag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to look up the release: %v\n", err)
+		return exitWith(1)
+	}
+
+	newer := compareVersions(rel.Tag, version) > 0
+	if updateCheckOnly {
+		if newer {
+			fmt.Printf("⬆️  %s is available; this is %s\n", rel.Tag, version)
+			return exitWith(1)
+		}
+		fmt.Printf("✅ %s is the latest release\n", version)
+		return nil
+	}
+	switch {
+	case updateForce:
+	case version == "dev":
+		fmt.Fprintf(os.Stderr, "This build has no version, probably built from source; use -force to replace it with %s\n", rel.Tag)
+		return exitWith(2)
+	case rel.Tag == version:
+		fmt.Printf("✅ Already running %s\n", version)
+		return nil
+	case !newer && updateTag == "":
+		fmt.Printf("✅ %s is newer than the latest release, %s\n", version, rel.Tag)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		return fmt.Errorf("find this binary: %w", err)
+	}
+	if !assumeYes && !confirm(fmt.Sprintf("Replace %s (%s) with %s?", exe, version, rel.Tag)) {
+		return nil
+	}
+	signed, err := installRelease(ctx, rel, exe, pub, updateSkipSignature)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Update to %s failed; %s is unchanged: %v\n", rel.Tag, exe, err)
+		return exitWith(1)
+	}
+	verified := "checksum verified, signature not checked (-insecure-skip-signature)"
+	if signed {
+		verified = "checksum and signature verified"
+	}
+	fmt.Printf("✅ Updated %s from %s to %s, %s; restart running monitors to use it\n", exe, version, rel.Tag, verified)
+	return nil
+}
+
+// The key given with -public-key, else the one built in, else none
+func releasePublicKey(path string) (ed25519.PublicKey, error) {
+	if path != "" {
+		key, err := readPEMKey(path, x509.ParsePKIXPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("not an Ed25519 public key")
+		}
+		return pub, nil
+	}
+	if releaseKey == "" {
+		return nil, nil
+	}
+	der, err := base64.StdEncoding.DecodeString(releaseKey)
+	if err != nil {
+		return nil, fmt.Errorf("built-in release key: %w", err)
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("built-in release key: %w", err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("built-in release key is not an Ed25519 public key")
+	}
+	return pub, nil
+}
+
+// Look up a release by tag, or the latest one
+func fetchRelease(ctx context.Context, apiURL, repo, tag string) (*release, error) {
+	path := "/repos/" + repo + "/releases/latest"
+	if tag != "" {
+		path = "/repos/" + repo + "/releases/tags/" + tag
+	}
+	api, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("-github-api: %w", err)
+	}
+	body, err := releaseGet(ctx, api.Host, strings.TrimSuffix(apiURL, "/")+path, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	var rel release
+	if err := json.Unmarshal(body, &rel); err != nil {
+		return nil, fmt.Errorf("decoding release: %w", err)
+	}
+	if rel.Tag == "" {
+		return nil, errors.New("release has no tag")
+	}
+	rel.apiHost = api.Host
+	return &rel, nil
+}
+
+// GET url, reading at most limit bytes. GITHUB_TOKEN, if set for private
+// repositories and higher rate limits, is only sent when url is on apiHost: a
+// mirror's release JSON can point the downloads anywhere.
+func releaseGet(ctx context.Context, apiHost, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && strings.EqualFold(req.URL.Host, apiHost) {
+		addSecret(token)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := releaseClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%s: larger than %d bytes", url, limit)
+	}
+	return body, nil
+}
+
+// Download this platform's binary from rel, check it against the release's
+// checksums and their signature, make sure it runs, and only then put it in
+// place of exe. Without a key the signature is skipped only given
+// allowUnsigned. Reports whether the signature was checked.
+func installRelease(ctx context.Context, rel *release, exe string, pub ed25519.PublicKey, allowUnsigned bool) (bool, error) {
+	if pub == nil && !allowUnsigned {
+		return false, fmt.Errorf("no public key to check the signature of %s with", releaseChecksums)
+	}
+	name := releaseAssetName()
+	binURL, sumsURL := rel.assetURL(name), rel.assetURL(releaseChecksums)
+	if binURL == "" {
+		return false, fmt.Errorf("%s has no %s", rel.Tag, name)
+	}
+	if sumsURL == "" {
+		return false, fmt.Errorf("%s has no %s to verify %s with", rel.Tag, releaseChecksums, name)
+	}
+	sums, err := releaseGet(ctx, rel.apiHost, sumsURL, 1<<20)
+	if err != nil {
+		return false, err
+	}
+	if pub != nil {
+		sigURL := rel.assetURL(releaseChecksums + ".sig")
+		if sigURL == "" {
+			return false, fmt.Errorf("%s has no %s.sig to check against the public key", rel.Tag, releaseChecksums)
+		}
+		sig, err := releaseGet(ctx, rel.apiHost, sigURL, 1<<10)
+		if err != nil {
+			return false, err
+		}
+		if err := verifyChecksumsSignature(sums, sig, pub); err != nil {
+			return false, err
+		}
+	}
+	want, err := releaseChecksum(sums, name)
+	if err != nil {
+		return false, err
+	}
+	bin, err := releaseGet(ctx, rel.apiHost, binURL, maxReleaseAsset)
+	if err != nil {
+		return false, err
+	}
+	if sum := sha256.Sum256(bin); !bytes.Equal(sum[:], want) {
+		return false, fmt.Errorf("%s does not match its checksum", name)
+	}
+
+	// Write next to exe so that the rename replacing it stays on one file system
+	info, err := os.Stat(exe)
+	if err != nil {
+		return false, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".replica-monitor-update-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(bin)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	if err != nil {
+		return false, err
+	}
+	out, err := exec.CommandContext(ctx, tmp.Name(), "version").Output()
+	if err != nil {
+		return false, fmt.Errorf("the new binary does not run: %w", err)
+	}
+	if !strings.Contains(string(out), rel.Tag) {
+		return false, fmt.Errorf("the new binary reports %q, not %s", strings.TrimSpace(string(out)), rel.Tag)
+	}
+	return pub != nil, replaceExecutable(exe, tmp.Name())
+}
+
+// Check an Ed25519 signature of a checksums file, raw or base64
+func verifyChecksumsSignature(sums, sig []byte, pub ed25519.PublicKey) error {
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil {
+			return fmt.Errorf("%s.sig is neither a raw nor a base64 signature", releaseChecksums)
+		}
+		sig = decoded
+	}
+	if !ed25519.Verify(pub, sums, sig) {
+		return fmt.Errorf("%s is not signed by the public key", releaseChecksums)
+	}
+	return nil
+}
+
+// Find name's SHA-256 in a checksums file written by sha256sum
+func releaseChecksum(sums []byte, name string) ([]byte, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			sum, err := hex.DecodeString(fields[0])
+			if err != nil || len(sum) != sha256.Size {
+				return nil, fmt.Errorf("%s: bad checksum for %s", releaseChecksums, name)
+			}
+			return sum, nil
+		}
+	}
+	return nil, fmt.Errorf("%s lists no checksum for %s", releaseChecksums, name)
+}
+
+// Rename the new binary over exe. Windows cannot replace a running binary
+// but can rename it, so the old one is moved aside first and removed on the
+// next update.
+func replaceExecutable(exe, newPath string) error {
+	if runtime.GOOS != "windows" {
+		return os.Rename(newPath, exe)
+	}
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(newPath, exe); err != nil {
+		os.Rename(old, exe)
+		return err
+	}
+	return nil
+}
+
+// Compare two vMAJOR.MINOR.PATCH versions, -1, 0, or 1; anything after a
+// hyphen sorts before the release itself, and "dev" before every release
+func compareVersions(a, b string) int {
+	pa, pb := parseVersion(a), parseVersion(b)
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// The numbers of a version, and 1 for a release or 0 for a pre-release
+func parseVersion(v string) [4]int {
+	var parts [4]int
+	core, pre, _ := strings.Cut(strings.TrimPrefix(v, "v"), "-")
+	if pre == "" {
+		parts[3] = 1
+	}
+	for i, s := range strings.SplitN(core, ".", 3) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return [4]int{}
+		}
+		parts[i] = n
+	}
+	return parts
+}
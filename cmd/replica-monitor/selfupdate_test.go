@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"v1.4.0", "v1.3.9", 1},
+		{"v1.10.0", "v1.9.2", 1},
+		{"v1.4.0", "v1.4.0", 0},
+		{"v1.4.0-rc.1", "v1.4.0", -1},
+		{"v1.4.0-rc.1", "v1.3.0", 1},
+		{"v0.1.0", "dev", 1},
+	} {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+// Serve a release of a stand-in binary that prints its version, as the real
+// one's version command does, with checksums signed by priv
+func serveTestRelease(t *testing.T, tag string, bin []byte, priv ed25519.PrivateKey) *release {
+	t.Helper()
+	name := releaseAssetName()
+	sum := sha256.Sum256(bin)
+	sums := []byte(fmt.Sprintf("%s  other-binary\n%s  %s\n", hex.EncodeToString(make([]byte, 32)), hex.EncodeToString(sum[:]), name))
+	files := map[string][]byte{
+		name:                      bin,
+		releaseChecksums:          sums,
+		releaseChecksums + ".sig": ed25519.Sign(priv, sums),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/example/replica-monitor/releases/latest" {
+			json.NewEncoder(w).Encode(map[string]any{"tag_name": tag, "assets": []map[string]string{
+				{"name": name, "browser_download_url": "http://" + r.Host + "/download/" + name},
+				{"name": releaseChecksums, "browser_download_url": "http://" + r.Host + "/download/" + releaseChecksums},
+				{"name": releaseChecksums + ".sig", "browser_download_url": "http://" + r.Host + "/download/" + releaseChecksums + ".sig"},
+			}})
+			return
+		}
+		data, ok := files[filepath.Base(r.URL.Path)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	rel, err := fetchRelease(context.Background(), srv.URL, "example/replica-monitor", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rel
+}
+
+func TestInstallRelease(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stand-in binary is a shell script")
+	}
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	newBin := []byte("#!/bin/sh\necho replica-monitor v1.4.0\n")
+
+	exe := filepath.Join(t.TempDir(), "replica-monitor")
+	if err := os.WriteFile(exe, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	rel := serveTestRelease(t, "v1.4.0", newBin, priv)
+	if _, err := installRelease(context.Background(), rel, exe, otherPub, false); err == nil {
+		t.Error("release signed by another key installed")
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old" {
+		t.Fatal("binary replaced after a failed update")
+	}
+	signed, err := installRelease(context.Background(), rel, exe, pub, false)
+	if err != nil || !signed {
+		t.Fatalf("installRelease = %v, %v; want a signed update", signed, err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != string(newBin) {
+		t.Errorf("binary holds %q after the update", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(exe)); len(entries) != 1 {
+		t.Errorf("update left %d files beside the binary", len(entries))
+	}
+
+	// Without a key, nothing is downloaded or run unless unsigned releases are allowed
+	dir := t.TempDir()
+	ran := filepath.Join(dir, "ran")
+	unsignedBin := []byte("#!/bin/sh\ntouch " + ran + "\necho replica-monitor v1.4.0\n")
+	rel = serveTestRelease(t, "v1.4.0", unsignedBin, priv)
+	if _, err := installRelease(context.Background(), rel, exe, nil, false); err == nil {
+		t.Error("release installed without a key to check its signature")
+	}
+	if _, err := os.Stat(ran); err == nil {
+		t.Error("unverified binary run")
+	}
+	if signed, err := installRelease(context.Background(), rel, exe, nil, true); err != nil || signed {
+		t.Errorf("installRelease = %v, %v; want an unsigned update with allowUnsigned", signed, err)
+	}
+
+	// A binary that does not report the release's version is not installed
+	rel = serveTestRelease(t, "v1.5.0", newBin, priv)
+	if _, err := installRelease(context.Background(), rel, exe, pub, false); err == nil {
+		t.Error("binary reporting another version installed")
+	}
+}
+
+// GITHUB_TOKEN goes to the -github-api host and not to downloads on other hosts
+func TestReleaseGetSendsTokenOnlyToAPIHost(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "release-token")
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+
+	if _, err := releaseGet(context.Background(), u.Host, srv.URL+"/repos/example/replica-monitor/releases/latest", 1<<10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := releaseGet(context.Background(), "api.github.com", srv.URL+"/download/replica-monitor", 1<<10); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Bearer release-token", ""}; !slices.Equal(sent, want) {
+		t.Errorf("Authorization sent %q; want %q", sent, want)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Sensu Go event, trimmed to what the check command fills in; the agent adds the entity
+type sensuEvent struct {
+	Check   sensuCheck   `json:"check"`
+	Metrics sensuMetrics `json:"metrics"`
+}
+
+type sensuCheck struct {
+	Metadata sensuMetadata `json:"metadata"`
+	Status   int           `json:"status"`
+	Output   string        `json:"output"`
+	Executed int64         `json:"executed"`
+}
+
+type sensuMetadata struct {
+	Name string `json:"name"`
+}
+
+type sensuMetrics struct {
+	Points []sensuPoint `json:"points"`
+}
+
+type sensuPoint struct {
+	Name      string     `json:"name"`
+	Value     float64    `json:"value"`
+	Timestamp int64      `json:"timestamp"`
+	Tags      []sensuTag `json:"tags"`
+}
+
+type sensuTag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Check name used in Sensu events
+var sensuCheckName string
+
+// Write one Sensu event covering every replica: the worst state as status, one
+// output line per replica, and lag and thread-state metric points. A failure to
+// connect is reported as unknown.
+func writeSensuEvent(w io.Writer, results []checkResult, failure error) error {
+	event := sensuEvent{
+		Check: sensuCheck{
+			Metadata: sensuMetadata{Name: sensuCheckName},
+			Status:   worstState(results),
+			Executed: time.Now().Unix(),
+		},
+		Metrics: sensuMetrics{Points: []sensuPoint{}},
+	}
+
+	var output []string
+	for _, res := range results {
+		r := res.replica
+		output = append(output, fmt.Sprintf("%s %s: %s", checkStateNames[res.state], displayName(r), res.message))
+		if r.polledAt.IsZero() {
+			continue
+		}
+		tags := []sensuTag{{"replica", displayName(r)}, {"host", r.host}}
+		for k, v := range r.labels {
+			tags = append(tags, sensuTag{k, v})
+		}
+		point := func(name string, value float64) {
+			event.Metrics.Points = append(event.Metrics.Points, sensuPoint{Name: name, Value: value, Timestamp: r.polledAt.Unix(), Tags: tags})
+		}
+		if r.lagKnown {
+			point("replica.lag_seconds", r.lagSeconds)
+		}
+		if !r.aurora {
+			point("replica.io_running", float64(zabbixBool(r.ioRunning == "Yes")))
+			point("replica.sql_running", float64(zabbixBool(r.sqlRunning == "Yes")))
+		}
+	}
+	if failure != nil {
+		event.Check.Status = checkUnknown
+		output = append(output, fmt.Sprintf("UNKNOWN: failed to %v", failure))
+	}
+	event.Check.Output = strings.Join(output, "\n")
+	return json.NewEncoder(w).Encode(event)
+}
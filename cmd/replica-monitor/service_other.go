@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Only Windows has a service control manager to run under
+func runAsService() bool { return false }
+
+func runService(fs *flag.FlagSet) error {
+	fmt.Fprintln(os.Stderr, "service is only available on Windows; elsewhere, run the monitor under systemd or another supervisor")
+	return exitWith(2)
+}
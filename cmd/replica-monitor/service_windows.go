@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// How long service stop waits for the monitor's own shutdown
+const serviceStopTimeout = 30 * time.Second
+
+// When the service control manager started this process, run the command line
+// it was installed with under its control; reports whether it did
+func runAsService() bool {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false
+	}
+	// The name is only used by services sharing a process
+	if err := svc.Run("", serviceHandler{}); err != nil {
+		slog.Error("Failed to run as a Windows service", "err", err)
+		os.Exit(1)
+	}
+	return true
+}
+
+type serviceHandler struct{}
+
+// Run the monitor until it stops on its own or the service is stopped, which
+// ends it as SIGTERM does elsewhere
+func (serviceHandler) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serviceStop = ctx
+	done := make(chan struct{})
+	var code int
+	go func() {
+		defer close(done)
+		code = runCommand(os.Args[1:])
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case <-done:
+			// A failure is reported as a service-specific exit code
+			return code != 0, uint32(code)
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(serviceStopTimeout / time.Millisecond)}
+				cancel()
+				select {
+				case <-done:
+					return code != 0, uint32(code)
+				case <-time.After(serviceStopTimeout):
+					slog.Error("Monitor did not stop in time; exiting", "timeout", serviceStopTimeout)
+				}
+				return false, 0
+			}
+		}
+	}
+}
+
+func runService(fs *flag.FlagSet) error {
+	if fs.NArg() == 0 {
+		return usageError(fs, "")
+	}
+	action, rest := fs.Arg(0), fs.Args()[1:]
+
+	m, err := mgr.Connect()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to the service control manager (run as administrator): %v\n", err)
+		return exitWith(1)
+	}
+	defer m.Disconnect()
+	switch action {
+	case "install":
+		if len(rest) == 0 {
+			fmt.Fprintln(os.Stderr, "service install needs the command line to run, e.g. service install serve -config C:\\replica-monitor\\replicas.json -http :8080")
+			return exitWith(2)
+		}
+		err = installService(m, serviceName, serviceDisplayName, rest)
+	case "uninstall":
+		err = uninstallService(m, serviceName)
+	case "start":
+		err = withService(m, serviceName, func(s *mgr.Service) error { return s.Start() })
+	case "stop":
+		err = withService(m, serviceName, stopService)
+	case "status":
+		err = withService(m, serviceName, func(s *mgr.Service) error {
+			st, err := s.Query()
+			if err == nil {
+				fmt.Printf("%s: %s\n", serviceName, serviceStateName(st.State))
+			}
+			return err
+		})
+	default:
+		return usageError(fs, "")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "service %s %s: %v\n", action, serviceName, err)
+		return exitWith(1)
+	}
+	if action != "status" {
+		fmt.Printf("✅ service %s %s\n", action, serviceName)
+	}
+	return nil
+}
+
+// Register the service to start with Windows and restart after a failure, and
+// its event log source; the monitor logs there unless args name another
+func installService(m *mgr.Mgr, name, displayName string, args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return errors.New("already installed")
+	}
+	namesSource := func(arg string) bool {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		return strings.HasPrefix(arg, "-") && name == "eventlog"
+	}
+	if !slices.ContainsFunc(args, namesSource) {
+		args = append(args, "-eventlog", name)
+	}
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: displayName,
+		Description: "Monitors MySQL replicas and skips matched replication errors",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: time.Minute}
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, uint32((24 * time.Hour).Seconds())); err != nil {
+		slog.Warn("Failed to set the service to restart after failures", "err", err)
+	}
+	err = eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info)
+	if err != nil && !strings.Contains(err.Error(), "registry key already exists") {
+		slog.Warn("Failed to register the event log source", "source", name, "err", err)
+	}
+	return nil
+}
+
+func uninstallService(m *mgr.Mgr, name string) error {
+	err := withService(m, name, func(s *mgr.Service) error {
+		if st, err := s.Query(); err == nil && st.State != svc.Stopped {
+			if err := stopService(s); err != nil {
+				return err
+			}
+		}
+		return s.Delete()
+	})
+	if err != nil {
+		return err
+	}
+	if err := eventlog.Remove(name); err != nil {
+		slog.Warn("Failed to remove the event log source", "source", name, "err", err)
+	}
+	return nil
+}
+
+// Ask the service to stop and wait until it has
+func stopService(s *mgr.Service) error {
+	st, err := s.Control(svc.Stop)
+	if errors.Is(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+		return nil
+	}
+	deadline := time.Now().Add(serviceStopTimeout + 5*time.Second)
+	for err == nil && st.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("still %s after %s", serviceStateName(st.State), serviceStopTimeout)
+		}
+		time.Sleep(300 * time.Millisecond)
+		st, err = s.Query()
+	}
+	return err
+}
+
+func withService(m *mgr.Mgr, name string, f func(*mgr.Service) error) error {
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("not installed: %w", err)
+	}
+	defer s.Close()
+	return f(s)
+}
+
+func serviceStateName(state svc.State) string {
+	switch state {
+	case svc.Stopped:
+		return "stopped"
+	case svc.StartPending:
+		return "starting"
+	case svc.StopPending:
+		return "stopping"
+	case svc.Running:
+		return "running"
+	case svc.Paused, svc.PausePending, svc.ContinuePending:
+		return "paused"
+	}
+	return fmt.Sprintf("state %d", state)
+}
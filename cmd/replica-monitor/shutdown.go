@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// When the monitoring loop started, for the end-of-run summary
+var runStart = time.Now()
+
+// -duration and -until stop the monitor on its own, so a watch started during an
+// incident does not run forever on a forgotten bastion; -notify-on-stop sends the
+// run summary to -alert-webhook and -notify when it stops
+var (
+	runDuration  time.Duration
+	runUntil     string
+	notifyOnStop bool
+)
+
+// Cause of the loop's context ending when -duration or -until is reached
+var errScheduledStop = errors.New("scheduled stop reached")
+
+// The earlier of now + -duration and -until, zero when neither is set
+func runDeadline() (time.Time, error) {
+	var deadline time.Time
+	if runDuration > 0 {
+		deadline = time.Now().Add(runDuration)
+	}
+	if runUntil != "" {
+		until, err := parseWindowTime(runUntil)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("-until: %w", err)
+		}
+		if !until.After(time.Now()) {
+			return time.Time{}, fmt.Errorf("-until %s is in the past", runUntil)
+		}
+		if deadline.IsZero() || until.Before(deadline) {
+			deadline = until
+		}
+	}
+	return deadline, nil
+}
+
+// Push what is still queued and print the end-of-run summary; called once the
+// loop has stopped after SIGINT, SIGTERM, a scheduled stop, or quitting -tui. Deferred cleanup
+// in runMonitor then closes the history and event files, the trace exporter, and
+// the connections.
+func shutdown(replicas []*replica, cause error) {
+	reason := "signal"
+	switch {
+	case errors.Is(cause, errScheduledStop):
+		reason = "scheduled"
+	case errors.Is(cause, errTUIQuit):
+		reason = "quit"
+	}
+	slog.Info("Shutting down", "reason", reason, "ran", time.Since(runStart).Round(time.Second))
+	out := stdout
+	if quietOut != nil {
+		out = quietOut
+	}
+	if reason == "scheduled" {
+		fmt.Fprintf(out, "\n⏰ Scheduled stop reached at %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	}
+	var summary strings.Builder
+	printRunSummary(&summary, replicas)
+	fmt.Fprint(out, summary.String())
+	if notifyOnStop {
+		sendAlert(selfReplica, "monitor_stopped", fmt.Sprintf("Monitor stopped (%s).%s", reason, strings.TrimRight(summary.String(), "\n")))
+	}
+	if dispatcher != nil {
+		dispatcher.Drain(10 * time.Second)
+	}
+	drainTenants(10 * time.Second)
+	if eventBridge != nil {
+		eventBridge.Close(10 * time.Second)
+	}
+	closeStreams()
+	closeExporters()
+	if loki != nil {
+		loki.flush()
+	}
+}
+
+func printRunSummary(w io.Writer, replicas []*replica) {
+	fmt.Fprintf(w, "\n📋 Run summary (monitored for %s):\n", formatDuration(time.Since(runStart).Seconds()))
+	for _, r := range replicas {
+		stats := &r.stats
+		var b strings.Builder
+		if stats.StartTime.IsZero() {
+			b.WriteString("lag never reported")
+		} else {
+			fmt.Fprintf(&b, "lag %s → %s", formatDuration(float64(stats.StartSecondsBehind)), formatDuration(float64(stats.LastSecondsBehind)))
+			if stats.AverageRate != 0 {
+				fmt.Fprintf(&b, " (average %s)", formatRate(stats.AverageRate))
+			}
+		}
+		if r.skips > 0 {
+			fmt.Fprintf(&b, ", %d skips", r.skips)
+		}
+		if r.lastAlert != nil {
+			fmt.Fprintf(&b, ", last alert %s at %s", r.lastAlert.Event, r.lastAlert.Time.Format("15:04:05"))
+		}
+		fmt.Fprintf(w, "  %s: %s\n", displayName(r), b.String())
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// -simulate monitors made-up replicas instead of connecting to any database, so
+// alert routing, dashboards, and templates can be tried out end to end
+var (
+	simulate         bool
+	simulateReplicas int
+	simulateSpeed    float64
+)
+
+var simulatedEngine = monitor.Engine{Name: "simulated", Title: "simulated MySQL", DefaultPort: 3306}
+
+// Replicas whose status comes from a monitor.SimulatedSource each, replicating
+// from one simulated source
+func simulatedReplicas() []*replica {
+	var replicas []*replica
+	for i := 1; i <= simulateReplicas; i++ {
+		name := fmt.Sprintf("replica-%d", i)
+		source := &monitor.SimulatedSource{Speed: simulateSpeed, SourceHost: "source.simulated"}
+		replicas = append(replicas, newReplica(name, name+".simulated", simulatedEngine.DefaultPort, nil, simulatedEngine, source))
+	}
+	return replicas
+}
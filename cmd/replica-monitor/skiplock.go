@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// -skip-lock: none (the default), file, or mysql. With a lock, only the monitor
+// holding it skips errors on a replica, automatically or from the TUI, so two
+// monitors watching the same replica cannot both skip and swallow an extra
+// transaction. The skip command, run by hand, takes no lock.
+var skipLockMode = "none"
+
+var skipLockModes = []string{"none", "file", "mysql"}
+
+// Held for the rest of the run once acquired
+type skipLocker interface {
+	// Whether this monitor holds the lock, taking it if it is free
+	hold() bool
+	release()
+}
+
+func newSkipLock(r *replica) skipLocker {
+	switch skipLockMode {
+	case "file":
+		name := strings.NewReplacer(":", "_", "/", "_", `\`, "_").Replace(fmt.Sprintf("replica-monitor-skip-%s-%d.lock", r.host, r.port))
+		return &fileSkipLock{path: filepath.Join(os.TempDir(), name)}
+	case "mysql":
+		// Simulated replicas have no server to take the lock on
+		if r.db == nil {
+			return nil
+		}
+		return &mysqlSkipLock{newLeaderElector(r.db, "replica-monitor-skip")}
+	}
+	return nil
+}
+
+// Whether this monitor may skip errors on the replica
+func holdsSkipLock(r *replica) bool {
+	if r.skipLock == nil {
+		return true
+	}
+	return r.skipLock.hold()
+}
+
+// An exclusive lock on a file named after the replica, for monitors on one host
+type fileSkipLock struct {
+	path string
+	f    *os.File
+}
+
+func (l *fileSkipLock) hold() bool {
+	if l.f != nil {
+		return true
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		slog.Error("Failed to open skip lock file", "path", l.path, "err", err)
+		return false
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return false
+	}
+	l.f = f
+	return true
+}
+
+func (l *fileSkipLock) release() {
+	if l.f != nil {
+		l.f.Close()
+		l.f = nil
+	}
+}
+
+// A GET_LOCK advisory lock on the replica itself, for monitors on different hosts
+type mysqlSkipLock struct {
+	*leaderElector
+}
+
+func (l *mysqlSkipLock) hold() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	held, err := l.leaderElector.hold(ctx)
+	if err != nil {
+		slog.Error("Failed to hold skip lock", "lock", l.lockName, "err", err)
+	}
+	return held
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Second connection to the replication source, used to explain replica problems
+// that originate upstream: binary logging turned off, binlogs purged before a
+// replica read them, and read_only flipping on the source.
+type sourceHealth struct {
+	conn *replica
+
+	reachable bool
+	logBin    string
+	readOnly  string
+	binlogs   map[string]bool // binary log files still present on the source
+	purged    map[*replica]bool
+
+	// Binlog retention from mysql.rds_show_configuration or binlog_expire_logs_seconds,
+	// re-read every retentionInterval; 0 with retentionKnown means purged as soon as possible
+	retention        time.Duration
+	retentionKnown   bool
+	retentionChecked time.Time
+	retentionHidden  bool // an RDS source under -read-only, whose retention only the CALL reads
+	atRisk           map[*replica]bool
+}
+
+const (
+	retentionInterval = 10 * time.Minute
+	// A replica lagging by this share of the retention period risks losing binlogs
+	retentionRiskShare = 0.8
+)
+
+// Set when -source-host is given
+var sourceMonitor *sourceHealth
+
+// Refresh the source's state and report changes; called once per monitoring cycle
+func (s *sourceHealth) check(out io.Writer) {
+	vars, err := queryStrings(s.conn.db, "SHOW GLOBAL VARIABLES WHERE Variable_name IN ('log_bin', 'read_only')")
+	if err != nil {
+		if s.reachable || s.binlogs == nil {
+			slog.Error("Source health check failed", "source", s.conn.host, "err", err)
+			sendAlert(s.conn, "source_unreachable", fmt.Sprintf("Source health check failed: %v", err))
+		}
+		s.reachable = false
+		s.binlogs = map[string]bool{}
+		return
+	}
+	s.reachable = true
+
+	previousLogBin, previousReadOnly := s.logBin, s.readOnly
+	for _, row := range vars {
+		switch row["Variable_name"] {
+		case "log_bin":
+			s.logBin = row["Value"]
+		case "read_only":
+			s.readOnly = row["Value"]
+		}
+	}
+
+	s.binlogs = make(map[string]bool)
+	if s.logBin == "ON" {
+		logs, err := queryStrings(s.conn.db, "SHOW BINARY LOGS")
+		if err != nil {
+			slog.Error("Failed to list binary logs on source", "source", s.conn.host, "err", err)
+		}
+		for _, row := range logs {
+			s.binlogs[row["Log_name"]] = true
+		}
+	}
+
+	if time.Since(s.retentionChecked) >= retentionInterval {
+		s.retentionChecked = time.Now()
+		s.readRetention()
+	}
+
+	fmt.Fprintf(out, "\n[%s] Source Health (%s): log_bin=%s read_only=%s binlogs=%d%s\n",
+		time.Now().Format("2006-01-02 15:04:05"), s.conn.host, s.logBin, s.readOnly, len(s.binlogs), s.formatRetention())
+
+	if s.logBin != "ON" {
+		fmt.Fprintln(out, "⚠️  Binary logging is disabled on the source; replicas will receive no new events")
+		if previousLogBin != s.logBin {
+			sendAlert(s.conn, "source_binlog_disabled", "Binary logging is disabled on the source")
+		}
+	}
+	if previousReadOnly != "" && previousReadOnly != s.readOnly {
+		msg := fmt.Sprintf("Source read_only changed from %s to %s", previousReadOnly, s.readOnly)
+		fmt.Fprintf(out, "⚠️  %s\n", msg)
+		sendAlert(s.conn, "source_read_only_changed", msg)
+	}
+}
+
+// Read binlog retention hours on RDS, or binlog_expire_logs_seconds elsewhere.
+// On RDS binlog_expire_logs_seconds is not what purges binlogs, so under
+// -read-only, which refuses the CALL, an RDS source's retention stays unknown.
+func (s *sourceHealth) readRetention() {
+	var rows []map[string]string
+	var err error
+	if !readOnly {
+		rows, err = queryStrings(s.conn.db, "CALL mysql.rds_show_configuration")
+	}
+	var mysqlErr *mysql.MySQLError
+	if readOnly || errors.As(err, &mysqlErr) && mysqlErr.Number == 1305 { // procedure does not exist
+		rows, err = queryStrings(s.conn.db, "SELECT @@basedir AS basedir, @@binlog_expire_logs_seconds AS value")
+		if err != nil || len(rows) == 0 {
+			return
+		}
+		if readOnly && s.onRDS(rows[0]["basedir"]) {
+			s.retentionKnown, s.retentionHidden = false, true
+			return
+		}
+		seconds, _ := strconv.Atoi(rows[0]["value"])
+		s.retention, s.retentionKnown = time.Duration(seconds)*time.Second, true
+		return
+	}
+	if err != nil {
+		slog.Warn("Failed to read binlog retention on source", "source", s.conn.host, "err", err)
+		return
+	}
+	for _, row := range rows {
+		if row["name"] == "binlog retention hours" {
+			hours, _ := strconv.Atoi(row["value"]) // NULL: purge as soon as possible
+			s.retention, s.retentionKnown = time.Duration(hours)*time.Hour, true
+		}
+	}
+}
+
+// Whether the source is an RDS instance: RDS installs MySQL under /rdsdbbin,
+// and its endpoints name the instance
+func (s *sourceHealth) onRDS(basedir string) bool {
+	if strings.HasPrefix(basedir, "/rdsdbbin/") {
+		return true
+	}
+	_, _, ok := rdsInstanceOf(s.conn)
+	return ok
+}
+
+func (s *sourceHealth) formatRetention() string {
+	switch {
+	case s.retentionHidden:
+		return " binlog_retention=unknown (RDS retention needs mysql.rds_show_configuration, which -read-only refuses)"
+	case !s.retentionKnown:
+		return ""
+	case s.retention == 0:
+		return " binlog_retention=none"
+	}
+	return " binlog_retention=" + formatDuration(s.retention.Seconds())
+}
+
+// Add source-side findings to a replica's status report
+func (s *sourceHealth) checkReplica(out io.Writer, r *replica, sourceLogFile, ioErrno string) {
+	if !s.reachable {
+		fmt.Fprintln(out, "🔎 Source: unreachable, no source-side findings")
+		return
+	}
+	if s.purged == nil {
+		s.purged = make(map[*replica]bool)
+	}
+
+	var findings []string
+	if s.logBin != "ON" {
+		findings = append(findings, "binary logging is disabled on the source")
+	}
+	purged := ioErrno == "1236" ||
+		(sourceLogFile != "" && len(s.binlogs) > 0 && !s.binlogs[sourceLogFile] && sourceLogFile < oldestBinlog(s.binlogs))
+	if purged {
+		findings = append(findings, fmt.Sprintf("source no longer has binlog %s this replica needs (errno 1236); the replica must be rebuilt", sourceLogFile))
+	}
+	if s.readOnly == "ON" {
+		findings = append(findings, "source is read_only")
+	}
+	risk := ""
+	if !purged {
+		risk = s.retentionRisk(r, sourceLogFile)
+	}
+	if risk != "" {
+		findings = append(findings, risk)
+	}
+
+	if len(findings) == 0 {
+		fmt.Fprintln(out, "🔎 Source: OK")
+	}
+	for _, f := range findings {
+		fmt.Fprintf(out, "🔎 Source: ⚠️  %s\n", f)
+	}
+
+	// Alert once when a replica starts depending on purged binlogs
+	if purged && !s.purged[r] {
+		sendAlert(r, "source_binlog_purged", fmt.Sprintf("Source %s purged binlog %s still needed by this replica", s.conn.host, sourceLogFile))
+	}
+	s.purged[r] = purged
+
+	// And once when it starts to risk that
+	if s.atRisk == nil {
+		s.atRisk = make(map[*replica]bool)
+	}
+	if risk != "" && !s.atRisk[r] {
+		sendAlert(r, "binlog_retention_risk", fmt.Sprintf("Source %s may purge binlogs this replica still needs: %s", s.conn.host, risk))
+	}
+	s.atRisk[r] = risk != ""
+}
+
+// Why the source may soon purge a binlog the replica still needs, "" when it
+// is safe: the replica still reads the oldest binlog the source keeps, or
+// lags by most of the retention period
+func (s *sourceHealth) retentionRisk(r *replica, sourceLogFile string) string {
+	if sourceLogFile != "" && len(s.binlogs) > 1 && sourceLogFile == oldestBinlog(s.binlogs) {
+		return fmt.Sprintf("replica still reads %s, the oldest binlog the source keeps and the next to be purged", sourceLogFile)
+	}
+	if s.retentionKnown && s.retention > 0 && r.lagKnown && r.lagSeconds >= retentionRiskShare*s.retention.Seconds() {
+		return fmt.Sprintf("lag %s is close to the source's binlog retention of %s", formatDuration(r.lagSeconds), formatDuration(s.retention.Seconds()))
+	}
+	return ""
+}
+
+// Binlog names sort lexically within one server, e.g. mysql-bin-changelog.001234
+func oldestBinlog(binlogs map[string]bool) string {
+	oldest := ""
+	for name := range binlogs {
+		if oldest == "" || name < oldest {
+			oldest = name
+		}
+	}
+	return oldest
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Keep idle event streams open through proxies that drop silent connections
+const sseKeepAlive = 15 * time.Second
+
+// Stream every poll ("poll" events) and every state change ("state" events)
+// as Server-Sent Events. Each event's data is one replica's status as in /status.
+func handleEvents(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	updates, unsubscribe := subscribeStatus()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	previous := make(map[string]replicaStatus)
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case snapshot := <-updates:
+			for _, rs := range snapshot.Replicas {
+				prev, seen := previous[rs.Name]
+				previous[rs.Name] = rs
+				if err := writeSSE(w, "poll", rs); err != nil {
+					return
+				}
+				if seen && stateChanged(prev, rs) {
+					if err := writeSSE(w, "state", rs); err != nil {
+						return
+					}
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Categories for -wide (-all) output, matched by exact column name or by prefix ending in "_"
+var statusCategories = []struct {
+	name    string
+	columns []string
+}{
+	{"Connection", []string{"Replica_IO_State", "Source_Host", "Source_User", "Source_Port", "Connect_Retry", "Source_Retry_Count",
+		"Source_Bind", "Source_UUID", "Source_Server_Id", "Source_Info_File", "Channel_Name", "Network_Namespace",
+		"Get_Source_public_key", "Source_public_key_path", "Source_Compression_Algorithm", "Source_Zstd_Compression_Level"}},
+	{"Threads", []string{"Replica_IO_Running", "Replica_SQL_Running", "Replica_SQL_Running_State"}},
+	{"Coordinates", []string{"Source_Log_File", "Read_Source_Log_Pos", "Relay_Log_File", "Relay_Log_Pos", "Relay_Source_Log_File",
+		"Exec_Source_Log_Pos", "Relay_Log_Space", "Until_Condition", "Until_Log_File", "Until_Log_Pos"}},
+	{"GTID", []string{"Retrieved_Gtid_Set", "Executed_Gtid_Set", "Auto_Position"}},
+	{"Filters", []string{"Replicate_Do_DB", "Replicate_Ignore_DB", "Replicate_Do_Table", "Replicate_Ignore_Table",
+		"Replicate_Wild_Do_Table", "Replicate_Wild_Ignore_Table", "Replicate_Ignore_Server_Ids", "Replicate_Rewrite_DB"}},
+	{"Errors", []string{"Last_Errno", "Last_Error", "Skip_Counter", "Last_IO_Errno", "Last_IO_Error", "Last_IO_Error_Timestamp",
+		"Last_SQL_Errno", "Last_SQL_Error", "Last_SQL_Error_Timestamp"}},
+	{"Delays", []string{"SQL_Delay", "SQL_Remaining_Delay"}},
+	{"TLS", []string{"Source_SSL_", "Source_TLS_"}},
+}
+
+// Print every column of a status row under its category; the lag is left to the
+// formatted Seconds_Behind_Source line that follows
+func printWideStatus(out io.Writer, row *monitor.StatusRow) {
+	columns, values := row.Columns, row.Values
+	grouped := make(map[string][]int)
+	for i, col := range columns {
+		if col == "Seconds_Behind_Source" {
+			continue
+		}
+		category := "Other"
+		for _, c := range statusCategories {
+			if categoryHas(c.columns, col) {
+				category = c.name
+				break
+			}
+		}
+		grouped[category] = append(grouped[category], i)
+	}
+
+	// Align values across all sections
+	width := 0
+	for _, col := range columns {
+		width = max(width, len(col))
+	}
+
+	names := make([]string, 0, len(statusCategories)+1)
+	for _, c := range statusCategories {
+		names = append(names, c.name)
+	}
+	for _, name := range append(names, "Other") {
+		if len(grouped[name]) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "── %s %s\n", name, strings.Repeat("─", max(0, width-len(name))))
+		for _, i := range grouped[name] {
+			value := "NULL"
+			if values[i] != nil {
+				value = row.ValueAt(i)
+			}
+			fmt.Fprintf(out, "  %-*s  %s\n", width, columns[i]+":", value)
+		}
+	}
+}
+
+func categoryHas(columns []string, col string) bool {
+	for _, c := range columns {
+		if c == col || (strings.HasSuffix(c, "_") && strings.HasPrefix(col, c)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Print the watched fields whose values changed since the previous poll, and
+// nothing at all when none did
+func printStatusChanges(out io.Writer, r *replica, previous map[string]string, watched []string) {
+	var changes []string
+	for _, field := range watched {
+		before, after := previous[field], r.lastStatus[field]
+		if before != after {
+			changes = append(changes, fmt.Sprintf("  %s: %s → %s", field, orDash(before), orDash(after)))
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n[%s] %s changed:\n%s\n", time.Now().Format("2006-01-02 15:04:05"), displayName(r), strings.Join(changes, "\n"))
+}
+
+// One aligned line per poll for -format line, e.g.
+// 2024-06-01T12:00:05 lag=3h42m    rate=-4.2/s  eta=14:32       io=Y sql=Y
+func printPollLine(w io.Writer, r *replica, withName bool, nameWidth int) {
+	var b strings.Builder
+	b.WriteString(time.Now().Format("2006-01-02T15:04:05"))
+	if withName {
+		fmt.Fprintf(&b, " %-*s", nameWidth, displayName(r))
+	}
+	if r.polledAt.IsZero() {
+		fmt.Fprintln(w, b.String()+" poll failed")
+		return
+	}
+
+	lag, rate, eta := "NULL", "-", "-"
+	if r.lagKnown {
+		lag = formatLineDuration(r.lagSeconds)
+		if r.stats.Rate != 0 {
+			rate = fmt.Sprintf("%+.1f/s", r.stats.Rate)
+		}
+		if r.stats.Rate < 0 && !r.stats.EstimatedTime.IsZero() && r.lagSeconds > 0 {
+			eta = r.stats.EstimatedTime.Format("15:04")
+			if time.Until(r.stats.EstimatedTime) > 24*time.Hour {
+				eta = r.stats.EstimatedTime.Format("01-02 15:04")
+			}
+		}
+	}
+	fmt.Fprintf(&b, " lag=%-9s rate=%-9s eta=%-11s", lag, rate, eta)
+	if !r.aurora {
+		fmt.Fprintf(&b, " io=%s sql=%s", yesNo(r.ioRunning), yesNo(r.sqlRunning))
+	}
+	if r.errorMatched {
+		b.WriteString(" error=matched")
+	}
+	if r.skips > 0 {
+		fmt.Fprintf(&b, " skips=%d", r.skips)
+	}
+	fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+}
+
+// Thread state as Y, N, or - when not reported
+func yesNo(state string) string {
+	switch state {
+	case "Yes":
+		return "Y"
+	case "":
+		return "-"
+	default:
+		return "N"
+	}
+}
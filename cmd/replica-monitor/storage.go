@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Watch each RDS replica's FreeStorageSpace with -storage-risk, since relay logs
+// pile up on a replica that is behind
+var storageRisk bool
+
+const (
+	storageInterval = time.Minute
+	// At risk below this share of allocated storage, or when full within storageHorizon at the current rate
+	storageMinFree = 0.10
+	storageHorizon = time.Hour
+)
+
+type storageSample struct {
+	free, allocated float64 // bytes
+	at              time.Time
+	shrinkPerHour   float64 // bytes, over the recent datapoints; 0 when not shrinking
+	atRisk          bool
+	fetched         time.Time
+	recent          monitor.Ring[storagePoint]
+}
+
+type storagePoint struct {
+	free float64
+	at   time.Time
+}
+
+var storageSamples = make(map[*replica]*storageSample)
+
+// Refresh FreeStorageSpace for every RDS replica at most once a minute and alert
+// when one is about to run out; Aurora storage is shared by the cluster and grows
+// on its own
+func checkStorage(replicas []*replica) {
+	if !storageRisk {
+		return
+	}
+	seen := make(map[*replica]bool)
+	for _, r := range replicas {
+		id, region, ok := rdsInstanceOf(r)
+		if !ok || r.aurora {
+			continue
+		}
+		seen[r] = true
+		s := storageSamples[r]
+		if s != nil && time.Since(s.fetched) < storageInterval {
+			continue
+		}
+		if s == nil {
+			s = &storageSample{recent: monitor.NewRing[storagePoint](15)}
+			storageSamples[r] = s
+		}
+		s.fetched = time.Now()
+		if err := s.refresh(id, region); err != nil {
+			slog.Warn("Failed to fetch FreeStorageSpace", "replica", displayName(r), "instance", id, "err", err)
+			continue
+		}
+		reason := s.risk()
+		if reason != "" && !s.atRisk {
+			sendAlert(r, "low_storage", fmt.Sprintf("Replica storage is running out: %s", reason))
+		}
+		s.atRisk = reason != ""
+	}
+	for r := range storageSamples {
+		if !seen[r] {
+			delete(storageSamples, r)
+		}
+	}
+}
+
+func (s *storageSample) refresh(id, region string) error {
+	free, at, err := latestRDSMetric(id, region, "FreeStorageSpace")
+	if err != nil || at.IsZero() || !at.After(s.at) {
+		return err
+	}
+	s.free, s.at = free, at
+	s.recent.Push(storagePoint{free, at})
+	if first := s.recent.At(0); first.at.Before(at) {
+		s.shrinkPerHour = max(0, (first.free-free)/at.Sub(first.at).Hours())
+	}
+
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	inst, err := describeRDSInstance(ctx, id, region)
+	if err != nil {
+		return err
+	}
+	s.allocated = float64(int64(aws.ToInt32(inst.AllocatedStorage)) << 30)
+	return nil
+}
+
+// Why the replica may run out of storage soon, "" when it is not at risk
+func (s *storageSample) risk() string {
+	if s.allocated > 0 && s.free < storageMinFree*s.allocated {
+		return fmt.Sprintf("%s free of %s", formatBytes(s.free), formatBytes(s.allocated))
+	}
+	if s.shrinkPerHour > 0 && s.free/s.shrinkPerHour < storageHorizon.Hours() {
+		return fmt.Sprintf("%s free, shrinking %s/h", formatBytes(s.free), formatBytes(s.shrinkPerHour))
+	}
+	return ""
+}
+
+// Print the replica's free storage under its lag
+func printStorage(out io.Writer, r *replica) {
+	s := storageSamples[r]
+	if s == nil || s.at.IsZero() {
+		return
+	}
+	marker := "💾"
+	if s.atRisk {
+		marker = "⚠️ "
+	}
+	line := fmt.Sprintf("%s Free storage: %s", marker, formatBytes(s.free))
+	if s.allocated > 0 {
+		line += fmt.Sprintf(" of %s (%.0f%%)", formatBytes(s.allocated), 100*s.free/s.allocated)
+	}
+	if s.shrinkPerHour > 0 {
+		line += fmt.Sprintf(", shrinking %s/h, full in about %s", formatBytes(s.shrinkPerHour), formatDuration(s.free/s.shrinkPerHour*3600))
+	}
+	fmt.Fprintln(out, line)
+}
+
+func formatBytes(b float64) string {
+	switch {
+	case b >= 1<<40:
+		return fmt.Sprintf("%.1f TiB", b/(1<<40))
+	case b >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", b/(1<<30))
+	default:
+		return fmt.Sprintf("%.0f MiB", b/(1<<20))
+	}
+}
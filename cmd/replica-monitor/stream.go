@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	firehosetypes "github.com/aws/aws-sdk-go-v2/service/firehose/types"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	kinesistypes "github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+
+	"replica-monitor/pkg/export"
+)
+
+// Kinesis data stream (name or ARN) from -kinesis-stream and Firehose delivery
+// stream from -firehose-stream; every sample and event is written to them
+var (
+	kinesisStream  string
+	firehoseStream string
+)
+
+// One JSON line for the streams, partitioned by replica host
+type streamRecord struct {
+	key  string
+	data []byte
+}
+
+// PutRecords and PutRecordBatch accept up to 500 records per call
+var streamSinks []*export.Batcher[streamRecord]
+
+func startStreams() error {
+	if kinesisStream == "" && firehoseStream == "" {
+		return nil
+	}
+	cfg, err := loadAWSConfig("")
+	if err != nil {
+		return err
+	}
+	if kinesisStream != "" {
+		client := kinesis.NewFromConfig(cfg)
+		input := &kinesis.PutRecordsInput{StreamName: aws.String(kinesisStream)}
+		if arn.IsARN(kinesisStream) {
+			input = &kinesis.PutRecordsInput{StreamARN: aws.String(kinesisStream)}
+		}
+		streamSinks = append(streamSinks, export.NewBatcher("kinesis", 500, func(ctx context.Context, records []streamRecord) error {
+			in := *input
+			for _, rec := range records {
+				in.Records = append(in.Records, kinesistypes.PutRecordsRequestEntry{Data: rec.data, PartitionKey: aws.String(rec.key)})
+			}
+			out, err := client.PutRecords(ctx, &in)
+			if err != nil {
+				return err
+			}
+			if n := aws.ToInt32(out.FailedRecordCount); n > 0 {
+				return fmt.Errorf("%d of %d records rejected", n, len(records))
+			}
+			return nil
+		}))
+	}
+	if firehoseStream != "" {
+		client := firehose.NewFromConfig(cfg)
+		streamSinks = append(streamSinks, export.NewBatcher("firehose", 500, func(ctx context.Context, records []streamRecord) error {
+			in := &firehose.PutRecordBatchInput{DeliveryStreamName: aws.String(firehoseStream)}
+			for _, rec := range records {
+				in.Records = append(in.Records, firehosetypes.Record{Data: rec.data})
+			}
+			out, err := client.PutRecordBatch(ctx, in)
+			if err != nil {
+				return err
+			}
+			if n := aws.ToInt32(out.FailedPutCount); n > 0 {
+				return fmt.Errorf("%d of %d records rejected", n, len(records))
+			}
+			return nil
+		}))
+	}
+	return nil
+}
+
+// Write a history sample or journal event to the streams as a JSON line with
+// its type, "sample" or "event"
+func writeStreams(kind, host string, v any) {
+	if len(streamSinks) == 0 {
+		return
+	}
+	fields, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("Failed to encode stream record", "type", kind, "err", err)
+		return
+	}
+	// Prepend the type to the object's own fields
+	data := append([]byte(fmt.Sprintf(`{"type":%q,`, kind)), fields[1:]...)
+	rec := streamRecord{key: host, data: append(data, '\n')}
+	if rec.key == "" {
+		rec.key = kind
+	}
+	for _, s := range streamSinks {
+		s.Add(rec)
+	}
+}
+
+func closeStreams() {
+	for _, s := range streamSinks {
+		s.Close(10 * time.Second)
+	}
+}
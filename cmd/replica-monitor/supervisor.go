@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+)
+
+// Stands in for the monitor itself in self-health alerts and events
+var selfReplica = func() *replica {
+	hostname, _ := os.Hostname()
+	return &replica{name: "replica-monitor", host: hostname}
+}()
+
+// Consecutive monitoring cycles that ended in a panic
+var cyclePanics int
+
+// Run one monitoring cycle, recovering from a panic so one malformed status row
+// cannot kill the monitor: the panic is logged with its stack, the first of a
+// run of them raises a monitor_panic alert, and the loop carries on.
+func superviseCycle(cycle func() bool) (skipped bool) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		cyclePanics++
+		slog.Error("Monitoring cycle panicked, resuming with the next cycle", "panic", p, "consecutive", cyclePanics, "stack", string(debug.Stack()))
+		if cyclePanics == 1 {
+			sendAlert(selfReplica, "monitor_panic", fmt.Sprintf("Monitoring cycle panicked: %v", p))
+		}
+		skipped = false
+	}()
+	skipped = cycle()
+	if cyclePanics > 0 {
+		slog.Info("Monitoring cycle completed again", "panics", cyclePanics)
+		cyclePanics = 0
+	}
+	return skipped
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog destination from -syslog: "local", udp://host:port, or tcp://host:port
+var syslogAddr string
+
+const (
+	syslogFacilityDaemon = 3
+	// Private enterprise number reserved for documentation (RFC 5612)
+	syslogEnterprise = "32473"
+)
+
+// Formats records as RFC 5424 messages; attributes become structured data and an
+// "event" attribute, such as an alert's, becomes the MSGID
+type syslogHandler struct {
+	conn   *syslogConn
+	attrs  []slog.Attr
+	prefix string
+}
+
+// Shared by every handler derived through WithAttrs so writes stay serialized
+type syslogConn struct {
+	mu       sync.Mutex
+	network  string
+	addr     string
+	conn     net.Conn
+	hostname string
+}
+
+func newSyslogHandler(target string) (*syslogHandler, error) {
+	c := &syslogConn{}
+	switch {
+	case target == "local":
+		c.network, c.addr = "unixgram", "/dev/log"
+	case strings.HasPrefix(target, "udp://"):
+		c.network, c.addr = "udp", strings.TrimPrefix(target, "udp://")
+	case strings.HasPrefix(target, "tcp://"):
+		c.network, c.addr = "tcp", strings.TrimPrefix(target, "tcp://")
+	default:
+		return nil, fmt.Errorf("expected local, udp://host:port, or tcp://host:port, got %q", target)
+	}
+	c.hostname, _ = os.Hostname()
+	if c.hostname == "" {
+		c.hostname = "-"
+	}
+	if err := c.dial(); err != nil {
+		return nil, err
+	}
+	return &syslogHandler{conn: c}, nil
+}
+
+func (c *syslogConn) dial() error {
+	conn, err := net.DialTimeout(c.network, c.addr, 5*time.Second)
+	if err != nil && c.network == "unixgram" {
+		conn, err = net.DialTimeout("unix", c.addr, 5*time.Second)
+	}
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+// Write one message, framing it with its length over TCP (RFC 6587) and
+// redialing once if the collector went away
+func (c *syslogConn) send(msg string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.network == "tcp" {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	if c.conn != nil {
+		if _, err := c.conn.Write([]byte(msg)); err == nil {
+			return nil
+		}
+		c.conn.Close()
+		c.conn = nil
+	}
+	if err := c.dial(); err != nil {
+		return err
+	}
+	_, err := c.conn.Write([]byte(msg))
+	return err
+}
+
+func (h *syslogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= logLevel.Level()
+}
+
+func (h *syslogHandler) Handle(_ context.Context, rec slog.Record) error {
+	var params strings.Builder
+	msgID := "-"
+	addParam := func(a slog.Attr) {
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		if a.Key == "event" {
+			msgID = syslogParamName(a.Value.String())
+		}
+		fmt.Fprintf(&params, ` %s="%s"`, syslogParamName(h.prefix+a.Key), syslogEscape(a.Value.String()))
+	}
+	for _, a := range h.attrs {
+		addParam(a)
+	}
+	rec.Attrs(func(a slog.Attr) bool {
+		addParam(a)
+		return true
+	})
+	data := "-"
+	if params.Len() > 0 {
+		data = "[meta@" + syslogEnterprise + params.String() + "]"
+	}
+	ts := rec.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s replica-monitor %d %s %s %s",
+		syslogFacilityDaemon*8+syslogSeverity(rec.Level), ts.Format(time.RFC3339Nano), h.conn.hostname,
+		os.Getpid(), msgID, data, rec.Message)
+	return h.conn.send(msg)
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &next
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
+
+// RFC 5424 severities: error 3, warning 4, informational 6, debug 7
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3
+	case level >= slog.LevelWarn:
+		return 4
+	case level >= slog.LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// SD-NAMEs are printable ASCII without '=', ' ', ']', or '"', at most 32 characters
+func syslogParamName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+func syslogEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Whether READY=1 has been sent; systemd only needs it once
+var sdReadySent bool
+
+// Send a state string to systemd's notification socket. Without NOTIFY_SOCKET
+// (not running as a Type=notify service) this does nothing.
+func sdNotify(state string) error {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if socketPath[0] == '@' {
+		socketPath = "\x00" + socketPath[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// Warn when the poll interval is too slow to keep the systemd watchdog fed
+func checkWatchdogInterval(pollInterval time.Duration) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return
+	}
+	if watchdog := time.Duration(usec) * time.Microsecond; pollInterval >= watchdog/2 {
+		slog.Warn("WatchdogSec is less than twice the poll interval; systemd may restart a healthy monitor", "watchdog", watchdog, "interval", pollInterval)
+	}
+}
+
+// Report readiness, feed the watchdog, and publish the current lag; called once per monitoring cycle
+func notifySystemd(replicas []*replica) {
+	state := "WATCHDOG=1\nSTATUS=" + systemdStatus(replicas)
+	if !sdReadySent && anyPolled(replicas) {
+		state = "READY=1\n" + state
+		sdReadySent = true
+	}
+	if err := sdNotify(state); err != nil {
+		slog.Error("Failed to notify systemd", "err", err)
+	}
+}
+
+// At least one replica answered SHOW REPLICA STATUS in this run
+func anyPolled(replicas []*replica) bool {
+	for _, r := range replicas {
+		if !r.polledAt.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+func systemdStatus(replicas []*replica) string {
+	if pollingPaused.Load() {
+		return "polling paused"
+	}
+	var worst *replica
+	for _, r := range replicas {
+		if r.lagKnown && (worst == nil || r.lagSeconds > worst.lagSeconds) {
+			worst = r
+		}
+	}
+	switch {
+	case len(replicas) == 0:
+		return "no replicas monitored"
+	case worst == nil:
+		return "lag unknown"
+	case len(replicas) == 1:
+		return "lag " + formatDuration(worst.lagSeconds)
+	default:
+		return fmt.Sprintf("worst lag %s (%s) across %d replicas", formatDuration(worst.lagSeconds), displayName(worst), len(replicas))
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"replica-monitor/pkg/notify"
+)
+
+// A team's section of the config file: its replicas, the settings that apply to
+// them, and the destinations that receive their alerts and nobody else's
+type tenantConfig struct {
+	Name     string            `json:"name"`
+	Labels   map[string]string `json:"labels"`
+	Replicas []replicaConfig   `json:"replicas"`
+
+	// Applied again whenever the file changes, like the top-level settings;
+	// the threshold and patterns default to those, the destinations do not
+	LagThreshold  string   `json:"lag_threshold"`
+	ErrorPatterns []string `json:"error_patterns"`
+	AlertWebhook  string   `json:"alert_webhook"`
+	Notify        []string `json:"notify"`
+}
+
+// Tenant names become a label value and appear in logs, so keep them plain
+var tenantNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Settings of a tenant in effect, from the config file last applied
+type tenant struct {
+	name          string
+	lagThreshold  time.Duration // zero when the tenant uses the top-level threshold
+	errorPatterns []string      // nil when the tenant uses the top-level patterns
+	alertWebhook  string
+	notify        []string // URLs of targets, to tell whether a reload changes them
+	targets       []notify.Target
+
+	// Started on the tenant's first alert, as the top-level dispatcher is
+	dispatcher     *notify.Dispatcher
+	dispatcherOnce sync.Once
+}
+
+// Tenants by name, from the config file last applied
+var tenants = map[string]*tenant{}
+
+// A replica from the config file with the name of the tenant listing it, empty
+// for the top-level replicas
+type configuredReplica struct {
+	replicaConfig
+	tenant string
+}
+
+// Every replica in the config file, the top-level ones first
+func (cfg *fileConfig) allReplicas() []configuredReplica {
+	var all []configuredReplica
+	for _, rc := range cfg.Replicas {
+		all = append(all, configuredReplica{rc, ""})
+	}
+	for _, tc := range cfg.Tenants {
+		for _, rc := range tc.Replicas {
+			all = append(all, configuredReplica{rc, tc.Name})
+		}
+	}
+	return all
+}
+
+// The section of the named tenant
+func (cfg *fileConfig) tenant(name string) *tenantConfig {
+	for i := range cfg.Tenants {
+		if cfg.Tenants[i].Name == name {
+			return &cfg.Tenants[i]
+		}
+	}
+	return nil
+}
+
+// Check the tenants' sections: names must be unique, and no replica may be
+// listed twice, since its alerts would then reach two teams
+func validateTenants(cfg *fileConfig) error {
+	names := map[string]bool{}
+	for _, tc := range cfg.Tenants {
+		if !tenantNamePattern.MatchString(tc.Name) {
+			return fmt.Errorf("tenant name %q must be letters, digits, '.', '_', or '-'", tc.Name)
+		}
+		if names[tc.Name] {
+			return fmt.Errorf("tenant %q is listed twice", tc.Name)
+		}
+		names[tc.Name] = true
+		if tc.LagThreshold != "" {
+			if d, err := time.ParseDuration(tc.LagThreshold); err != nil || d <= 0 {
+				return fmt.Errorf("tenant %s: lag_threshold %q is not a positive duration", tc.Name, tc.LagThreshold)
+			}
+		}
+		for _, pattern := range tc.ErrorPatterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("tenant %s: error_patterns: %q: %w", tc.Name, pattern, err)
+			}
+		}
+	}
+	owners := map[string]string{}
+	for _, rc := range cfg.allReplicas() {
+		key := fmt.Sprintf("%s:%d", rc.Host, rc.Port)
+		if owner, ok := owners[key]; ok && (owner != "" || rc.tenant != "") {
+			return fmt.Errorf("replica %s is listed by both %s and %s", key, tenantOrTopLevel(owner), tenantOrTopLevel(rc.tenant))
+		}
+		owners[key] = rc.tenant
+	}
+	return nil
+}
+
+func tenantOrTopLevel(name string) string {
+	if name == "" {
+		return "the top level"
+	}
+	return "tenant " + name
+}
+
+// A tenant's settings from a config file, resolved and with its notify
+// targets opened, ready for applyTenantSettings
+type tenantUpdate struct {
+	config    tenantConfig
+	threshold time.Duration
+	patterns  []string
+	retarget  bool
+	targets   []notify.Target
+}
+
+// Resolve the tenants' settings from a config file, validated already, opening
+// the notify targets of those whose destinations changed; nothing is applied,
+// so a target that fails to open leaves every tenant as it was
+func prepareTenantSettings(cfg *fileConfig) ([]tenantUpdate, error) {
+	updates := make([]tenantUpdate, 0, len(cfg.Tenants))
+	for _, tc := range cfg.Tenants {
+		u := tenantUpdate{config: tc}
+		if tc.LagThreshold != "" {
+			u.threshold, _ = time.ParseDuration(tc.LagThreshold)
+		}
+		if len(tc.ErrorPatterns) > 0 {
+			u.patterns = tc.ErrorPatterns
+		}
+		t := tenants[tc.Name]
+		u.retarget = t == nil || tc.AlertWebhook != t.alertWebhook || !slices.Equal(tc.Notify, t.notify)
+		if u.retarget {
+			for _, url := range tc.Notify {
+				target, err := notify.Open(url)
+				if err != nil {
+					return nil, fmt.Errorf("tenant %s: notify %s: %w", tc.Name, redact(url), err)
+				}
+				u.targets = append(u.targets, target)
+			}
+			if tc.AlertWebhook != "" {
+				u.targets = append(u.targets, notify.Target{Name: "webhook", Notifier: notify.Webhook{URL: tc.AlertWebhook}})
+			}
+		}
+		updates = append(updates, u)
+	}
+	return updates, nil
+}
+
+// Apply the tenants' settings resolved by prepareTenantSettings and report
+// which tenants' settings changed
+func applyTenantSettings(cfg *fileConfig, updates []tenantUpdate) []string {
+	next := make(map[string]*tenant, len(updates))
+	var changed []string
+	for _, u := range updates {
+		t := tenants[u.config.Name]
+		if t == nil {
+			t = &tenant{name: u.config.Name}
+		} else if u.retarget || u.threshold != t.lagThreshold || !slices.Equal(u.patterns, t.errorPatterns) {
+			changed = append(changed, "tenant "+u.config.Name)
+		}
+		t.lagThreshold, t.errorPatterns = u.threshold, u.patterns
+		if u.retarget {
+			t.alertWebhook, t.notify, t.targets = u.config.AlertWebhook, u.config.Notify, u.targets
+			t.restartDispatcher()
+		}
+		next[u.config.Name] = t
+	}
+	for name, t := range tenants {
+		if next[name] == nil {
+			t.restartDispatcher()
+		}
+	}
+	tenants = next
+
+	if appliedConfigFile != nil && !sameTenantMembership(cfg.Tenants, appliedConfigFile.Tenants) {
+		slog.Warn("Config file's tenants, their replicas, or their labels changed; they take effect on restart", "path", configPath)
+	}
+	return changed
+}
+
+// Whether two versions of the config file list the same tenants with the same
+// replicas and labels, which are only read at startup
+func sameTenantMembership(a, b []tenantConfig) bool {
+	return slices.EqualFunc(a, b, func(x, y tenantConfig) bool {
+		return x.Name == y.Name && reflect.DeepEqual(x.Replicas, y.Replicas) && reflect.DeepEqual(x.Labels, y.Labels)
+	})
+}
+
+// Send the tenant's later alerts through a dispatcher for its current
+// destinations, delivering those already queued through the old one
+func (t *tenant) restartDispatcher() {
+	old := t.dispatcher
+	t.dispatcher, t.dispatcherOnce = nil, sync.Once{}
+	if old != nil {
+		go old.Drain(time.Minute)
+	}
+}
+
+// The dispatcher for a replica's alerts, nil when they have nowhere to go: a
+// tenant's replicas alert only its destinations, and the top-level
+// destinations hear only from replicas outside every tenant
+func alertDispatcher(r *replica) *notify.Dispatcher {
+	if r.tenant == nil {
+		if !alertsConfigured() {
+			return nil
+		}
+		dispatcherOnce.Do(func() { dispatcher = startDispatcher() })
+		return dispatcher
+	}
+	t := r.tenant
+	if len(t.targets) == 0 {
+		return nil
+	}
+	t.dispatcherOnce.Do(func() { t.dispatcher = newDispatcher(t.targets) })
+	return t.dispatcher
+}
+
+// Lag above which a replica counts as behind
+func replicaLagThreshold(r *replica) time.Duration {
+	if r.tenant != nil && r.tenant.lagThreshold > 0 {
+		return r.tenant.lagThreshold
+	}
+	return lagThreshold
+}
+
+// Last_SQL_Error patterns alerted on and skipped for a replica
+func replicaErrorPatterns(r *replica) []string {
+	if r.tenant != nil && r.tenant.errorPatterns != nil {
+		return r.tenant.errorPatterns
+	}
+	return errorPatterns
+}
+
+// Deliver the alerts queued for every tenant, waiting up to timeout for each
+func drainTenants(timeout time.Duration) {
+	for _, t := range tenants {
+		if t.dispatcher != nil {
+			t.dispatcher.Drain(timeout)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+func TestTenants(t *testing.T) {
+	defer func() {
+		lagThreshold, alertWebhook, errorPatterns = 0, "", monitor.DefaultErrorPatterns
+		fileNotifyTargets, appliedConfigFile, commandLineFlags = nil, nil, nil
+		tenants = map[string]*tenant{}
+		restartDispatcher()
+	}()
+	lagThreshold = 5 * time.Minute
+	cfg := &fileConfig{
+		AlertWebhook: "http://alerts.example.com/dba",
+		Replicas:     []replicaConfig{{Name: "shared", Host: "shared.example.com", Port: 3306}},
+		Tenants: []tenantConfig{
+			{Name: "checkout", Replicas: []replicaConfig{{Host: "checkout.example.com", Port: 3306}}, LagThreshold: "30s", AlertWebhook: "http://checkout.example.com/alerts"},
+			{Name: "search", Replicas: []replicaConfig{{Host: "search.example.com", Port: 3306}}, ErrorPatterns: []string{"Duplicate entry"}},
+		},
+	}
+	if err := validateSettings(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applySettings(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(cfg.allReplicas()); n != 3 {
+		t.Errorf("%d replicas in the file; want 3", n)
+	}
+
+	shared := &replica{}
+	checkout := &replica{tenant: tenants["checkout"]}
+	search := &replica{tenant: tenants["search"]}
+	if replicaLagThreshold(checkout) != 30*time.Second || replicaLagThreshold(search) != 5*time.Minute {
+		t.Errorf("thresholds %s and %s; want the tenant's and the top-level one", replicaLagThreshold(checkout), replicaLagThreshold(search))
+	}
+	if !slices.Equal(replicaErrorPatterns(search), []string{"Duplicate entry"}) || !slices.Equal(replicaErrorPatterns(checkout), monitor.DefaultErrorPatterns) {
+		t.Errorf("patterns %q and %q; want the tenant's and the top-level ones", replicaErrorPatterns(search), replicaErrorPatterns(checkout))
+	}
+
+	// Each replica alerts only its own tenant's destinations
+	top, own := alertDispatcher(shared), alertDispatcher(checkout)
+	if top == nil || own == nil || top == own {
+		t.Errorf("dispatchers %p and %p; want separate ones", top, own)
+	}
+	if d := alertDispatcher(search); d != nil {
+		t.Error("tenant without destinations alerts the top-level ones")
+	}
+
+	// A reload changes a tenant's settings in place
+	cfg.Tenants[0].LagThreshold = "1m"
+	changed, err := applySettings(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changed, []string{"tenant checkout"}) || replicaLagThreshold(checkout) != time.Minute {
+		t.Errorf("changed %q, threshold %s; want checkout's threshold reloaded", changed, replicaLagThreshold(checkout))
+	}
+
+	for _, bad := range []*fileConfig{
+		{Tenants: []tenantConfig{{Name: ""}}},
+		{Tenants: []tenantConfig{{Name: "a b"}}},
+		{Tenants: []tenantConfig{{Name: "checkout"}, {Name: "checkout"}}},
+		{Tenants: []tenantConfig{{Name: "checkout", LagThreshold: "soon"}}},
+		{Replicas: cfg.Replicas, Tenants: []tenantConfig{{Name: "checkout", Replicas: cfg.Replicas}}},
+		{Tenants: []tenantConfig{{Name: "a", Replicas: cfg.Replicas}, {Name: "b", Replicas: cfg.Replicas}}},
+	} {
+		if err := validateSettings(bad); err == nil {
+			t.Errorf("invalid tenants %+v accepted", bad.Tenants)
+		}
+	}
+}
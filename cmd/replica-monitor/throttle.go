@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// -throttle stretches the poll interval while a replica looks overloaded: its
+// SHOW REPLICA STATUS round trip exceeds -throttle-rtt or Threads_running
+// exceeds -throttle-threads
+var (
+	throttle        bool
+	throttleRTT     time.Duration
+	throttleThreads int
+)
+
+// The interval is multiplied by throttleFactor, doubled per overloaded cycle up
+// to maxThrottleFactor and halved per calm one. Only the loop changes it; it is
+// atomic because /readyz reads it.
+const maxThrottleFactor = 8
+
+var throttleFactor atomic.Int64
+
+func init() { throttleFactor.Store(1) }
+
+// Read Threads_running for -throttle; called after each successful poll
+func checkLoad(ctx context.Context, r *replica) {
+	if r.db == nil {
+		return
+	}
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+	var name, value string
+	err := r.db.QueryRowContext(ctx, "SHOW GLOBAL STATUS LIKE 'Threads_running'").Scan(&name, &value)
+	if err != nil {
+		slog.Debug("Failed to read Threads_running", "replica", displayName(r), "err", err)
+		r.threadsRunning = 0
+		return
+	}
+	r.threadsRunning, _ = strconv.Atoi(value)
+}
+
+// Why the replica counts as overloaded, "" when it does not
+func overloadReason(r *replica) string {
+	switch {
+	case throttleRTT > 0 && r.queryRTT > throttleRTT:
+		return fmt.Sprintf("%s answered SHOW REPLICA STATUS in %s", displayName(r), r.queryRTT.Round(time.Millisecond))
+	case throttleThreads > 0 && r.threadsRunning > throttleThreads:
+		return fmt.Sprintf("%s has %d threads running", displayName(r), r.threadsRunning)
+	}
+	return ""
+}
+
+// Adjust throttleFactor after a cycle and announce changes; called once per monitoring cycle
+func updateThrottle(replicas []*replica) {
+	if !throttle {
+		return
+	}
+	reason := ""
+	for _, r := range replicas {
+		if reason = overloadReason(r); reason != "" {
+			break
+		}
+	}
+	previous := throttleFactor.Load()
+	factor := previous
+	if reason != "" {
+		factor = min(factor*2, maxThrottleFactor)
+	} else if factor > 1 {
+		factor /= 2
+	}
+	if factor == previous {
+		return
+	}
+	throttleFactor.Store(factor)
+	every := currentInterval() * time.Duration(factor)
+	if factor > previous {
+		fmt.Fprintf(stdout, "⚠️  Replica load is high (%s), polling every %s\n", reason, every)
+		slog.Warn("Throttling polls, replica load is high", "reason", reason, "interval", every)
+	} else {
+		fmt.Fprintf(stdout, "✅ Replica load is back to normal, polling every %s\n", every)
+		slog.Info("Easing poll throttling", "interval", every)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"crypto/fips140"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// TLS policy for database connections and the -http and -grpc listeners:
+// -tls-min-version, -tls-ciphers, and -tls-fips, which allows only
+// FIPS-approved versions, cipher suites, and curves
+var (
+	tlsMinVersion uint16 = tls.VersionTLS12
+	tlsCiphers    []uint16
+	tlsFIPS       bool
+)
+
+// -db-tls: auto (the default) leaves PostgreSQL to PGSSLMODE, preferring TLS,
+// and encrypts MySQL only under -auth iam, which needs it; off never does, preferred
+// encrypts when the server supports it without verifying it, required verifies
+// the server's certificate against -db-tls-ca or the system roots, and
+// skip-verify encrypts without verifying
+var (
+	dbTLS   = "auto"
+	dbTLSCA string
+)
+
+var dbTLSModes = []string{"auto", "off", "preferred", "required", "skip-verify"}
+
+// -tls-cert and -tls-key serve -http and -grpc over TLS; -tls-client-ca
+// requires clients to present a certificate it signed
+var tlsCert, tlsKey, tlsClientCA string
+
+var tlsVersions = map[string]uint16{"1.0": tls.VersionTLS10, "1.1": tls.VersionTLS11, "1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13}
+
+// FIPS 140-3 approved TLS 1.2 cipher suites; Go chooses TLS 1.3 suites itself,
+// and limits them to AES-GCM in FIPS 140-3 mode
+var fipsCiphers = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// Parse -tls-min-version
+func setTLSMinVersion(v string) error {
+	version, ok := tlsVersions[v]
+	if !ok {
+		return errors.New("expected 1.0, 1.1, 1.2, or 1.3")
+	}
+	tlsMinVersion = version
+	return nil
+}
+
+// Parse -tls-ciphers, a comma-separated list of IANA cipher suite names
+func setTLSCiphers(v string) error {
+	tlsCiphers = nil
+	suites := append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(suites, func(s *tls.CipherSuite) bool { return s.Name == name })
+		if i < 0 {
+			return fmt.Errorf("unknown cipher suite %q", name)
+		}
+		tlsCiphers = append(tlsCiphers, suites[i].ID)
+	}
+	return nil
+}
+
+var fipsWarning sync.Once
+
+// The settings every TLS connection and listener starts from
+func tlsPolicy() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tlsMinVersion, CipherSuites: slices.Clone(tlsCiphers)}
+	if !tlsFIPS {
+		return cfg, nil
+	}
+	if tlsMinVersion < tls.VersionTLS12 {
+		return nil, errors.New("-tls-fips needs -tls-min-version 1.2 or 1.3")
+	}
+	for _, id := range tlsCiphers {
+		if !slices.Contains(fipsCiphers, id) && tls.CipherSuiteName(id) != "" && !isTLS13Suite(id) {
+			return nil, fmt.Errorf("-tls-fips does not allow %s", tls.CipherSuiteName(id))
+		}
+	}
+	if cfg.CipherSuites == nil {
+		cfg.CipherSuites = slices.Clone(fipsCiphers)
+	}
+	cfg.CurvePreferences = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+	fipsWarning.Do(func() {
+		if !fips140.Enabled() {
+			slog.Warn("-tls-fips limits TLS to FIPS-approved algorithms, but Go's FIPS 140-3 module is off; run with GODEBUG=fips140=on to use it")
+		}
+	})
+	return cfg, nil
+}
+
+// Hold cfg, made elsewhere, to the policy's versions, cipher suites, and curves
+func restrictTLS(cfg *tls.Config) error {
+	policy, err := tlsPolicy()
+	if err != nil {
+		return err
+	}
+	cfg.MinVersion = policy.MinVersion
+	cfg.CipherSuites = policy.CipherSuites
+	cfg.CurvePreferences = policy.CurvePreferences
+	return nil
+}
+
+func isTLS13Suite(id uint16) bool {
+	for _, s := range tls.CipherSuites() {
+		if s.ID == id {
+			return slices.Equal(s.SupportedVersions, []uint16{tls.VersionTLS13})
+		}
+	}
+	return false
+}
+
+// TLS for a database connection to host, nil for none; fallback allows
+// unencrypted connections to servers without TLS
+func databaseTLS(host string) (cfg *tls.Config, fallback bool, err error) {
+	mode := dbTLS
+	if authMode == "iam" {
+		// The token is sent in clear text, which only TLS makes safe
+		switch mode {
+		case "off":
+			return nil, false, errors.New("-auth iam sends the token in clear text and cannot be used with -db-tls off")
+		case "auto", "preferred":
+			mode = "required"
+		}
+	} else if mode == "auto" {
+		mode = "off"
+	}
+	if mode == "off" {
+		return nil, false, nil
+	}
+	if cfg, err = tlsPolicy(); err != nil {
+		return nil, false, err
+	}
+	switch mode {
+	case "preferred":
+		cfg.InsecureSkipVerify = true
+		fallback = true
+	case "skip-verify":
+		cfg.InsecureSkipVerify = true
+	case "required":
+		cfg.ServerName = host
+		if dbTLSCA != "" {
+			if cfg.RootCAs, err = loadCertPool(dbTLSCA); err != nil {
+				return nil, false, fmt.Errorf("-db-tls-ca: %w", err)
+			}
+		}
+	}
+	return cfg, fallback, nil
+}
+
+// Set a PostgreSQL connection's TLS from -db-tls; under auto, what PGSSLMODE
+// asks for, held to the policy
+func postgresTLS(cfg *pgx.ConnConfig, host string, port int) error {
+	if dbTLS == "auto" && authMode != "iam" {
+		for _, c := range append([]*tls.Config{cfg.TLSConfig}, fallbackTLS(cfg.Fallbacks)...) {
+			if c != nil {
+				if err := restrictTLS(c); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	tlsConfig, fallback, err := databaseTLS(host)
+	if err != nil {
+		return err
+	}
+	cfg.TLSConfig = tlsConfig
+	cfg.Fallbacks = nil
+	if fallback {
+		cfg.Fallbacks = []*pgconn.FallbackConfig{{Host: host, Port: uint16(port)}}
+	}
+	return nil
+}
+
+func fallbackTLS(fallbacks []*pgconn.FallbackConfig) []*tls.Config {
+	configs := make([]*tls.Config, len(fallbacks))
+	for i, f := range fallbacks {
+		configs[i] = f.TLSConfig
+	}
+	return configs
+}
+
+// TLS for the -http and -grpc listeners, nil without -tls-cert
+func serverTLS() (*tls.Config, error) {
+	if tlsCert == "" && tlsKey == "" && tlsClientCA == "" {
+		return nil, nil
+	}
+	if tlsCert == "" || tlsKey == "" {
+		return nil, errors.New("-tls-cert and -tls-key must be given together, and with -tls-client-ca")
+	}
+	cfg, err := tlsPolicy()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Certificates = []tls.Certificate{cert}
+	if tlsClientCA != "" {
+		if cfg.ClientCAs, err = loadCertPool(tlsClientCA); err != nil {
+			return nil, fmt.Errorf("-tls-client-ca: %w", err)
+		}
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// The CA certificates in a PEM bundle
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s holds no PEM certificates", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTLSPolicy(t *testing.T) {
+	defer func() {
+		tlsMinVersion, tlsCiphers, tlsFIPS = tls.VersionTLS12, nil, false
+	}()
+
+	if err := setTLSMinVersion("1.4"); err == nil {
+		t.Error("accepted -tls-min-version 1.4")
+	}
+	if err := setTLSCiphers("TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, TLS_NOT_A_SUITE"); err == nil {
+		t.Error("accepted an unknown cipher suite")
+	}
+	if err := setTLSCiphers("TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := tlsPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256}
+	if cfg.MinVersion != tls.VersionTLS12 || !slices.Equal(cfg.CipherSuites, want) {
+		t.Errorf("policy = version %x, suites %v", cfg.MinVersion, cfg.CipherSuites)
+	}
+
+	tlsFIPS = true
+	if _, err := tlsPolicy(); err == nil {
+		t.Error("-tls-fips allowed ChaCha20-Poly1305")
+	}
+	tlsCiphers = nil
+	if cfg, err = tlsPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.CipherSuites, fipsCiphers) || !slices.Equal(cfg.CurvePreferences, []tls.CurveID{tls.CurveP256, tls.CurveP384}) {
+		t.Errorf("-tls-fips policy = suites %v, curves %v", cfg.CipherSuites, cfg.CurvePreferences)
+	}
+	if err := setTLSMinVersion("1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tlsPolicy(); err == nil {
+		t.Error("-tls-fips allowed TLS 1.1")
+	}
+}
+
+func TestDatabaseTLS(t *testing.T) {
+	defer func() { dbTLS, authMode = "auto", "password" }()
+	host := "mydb.abc123xyz.us-east-1.rds.amazonaws.com"
+
+	tests := []struct {
+		mode, auth       string
+		encrypted        bool
+		verified         bool
+		fallback, failed bool
+	}{
+		{mode: "auto", auth: "password"},
+		{mode: "auto", auth: "iam", encrypted: true, verified: true},
+		{mode: "off", auth: "iam", failed: true},
+		{mode: "preferred", auth: "password", encrypted: true, fallback: true},
+		{mode: "preferred", auth: "iam", encrypted: true, verified: true},
+		{mode: "required", auth: "password", encrypted: true, verified: true},
+		{mode: "skip-verify", auth: "iam", encrypted: true},
+	}
+	for _, tt := range tests {
+		dbTLS, authMode = tt.mode, tt.auth
+		cfg, fallback, err := databaseTLS(host)
+		if (err != nil) != tt.failed {
+			t.Errorf("%s with -auth %s: err = %v", tt.mode, tt.auth, err)
+			continue
+		}
+		if (cfg != nil) != tt.encrypted || fallback != tt.fallback {
+			t.Errorf("%s with -auth %s: encrypted %v, fallback %v", tt.mode, tt.auth, cfg != nil, fallback)
+			continue
+		}
+		if cfg != nil && (!cfg.InsecureSkipVerify && cfg.ServerName == host) != tt.verified {
+			t.Errorf("%s with -auth %s: verified %v", tt.mode, tt.auth, !tt.verified)
+		}
+	}
+}
+
+// A CA, and a certificate it signs for localhost, as PEM files in dir
+func writeTestCerts(t *testing.T, dir, name string) (ca *x509.Certificate, cert, key string) {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: name + " CA"},
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour),
+		IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign,
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	ca, _ = x509.ParseCertificate(der)
+	writePEM(t, filepath.Join(dir, name+"-ca.pem"), "CERTIFICATE", der)
+
+	k, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: name},
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour),
+		DNSNames: []string{"localhost"}, IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, _ = x509.CreateCertificate(rand.Reader, template, ca, &k.PublicKey, caKey)
+	keyDER, _ := x509.MarshalECPrivateKey(k)
+	cert, key = filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	writePEM(t, cert, "CERTIFICATE", der)
+	writePEM(t, key, "EC PRIVATE KEY", keyDER)
+	return ca, cert, key
+}
+
+func writePEM(t *testing.T, path, kind string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerClientCertificates(t *testing.T) {
+	defer func() { tlsCert, tlsKey, tlsClientCA = "", "", "" }()
+	dir := t.TempDir()
+	serverCA, serverCert, serverKey := writeTestCerts(t, dir, "server")
+	_, clientCert, clientKey := writeTestCerts(t, dir, "client")
+	_, strangerCert, strangerKey := writeTestCerts(t, dir, "stranger")
+	tlsCert, tlsKey, tlsClientCA = serverCert, serverKey, filepath.Join(dir, "client-ca.pem")
+
+	cfg, err := serverTLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(handleHealthz))
+	srv.TLS = cfg
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA)
+	get := func(certFile, keyFile string) error {
+		clientTLS := &tls.Config{RootCAs: roots}
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clientTLS.Certificates = []tls.Certificate{cert}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+		resp, err := client.Get(srv.URL + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	if err := get(clientCert, clientKey); err != nil {
+		t.Errorf("client certificate refused: %v", err)
+	}
+	if err := get("", ""); err == nil {
+		t.Error("served a client without a certificate")
+	}
+	if err := get(strangerCert, strangerKey); err == nil {
+		t.Error("served a client with a certificate from another CA")
+	}
+
+	tlsCert, tlsKey = "", ""
+	if _, err := serverTLS(); err == nil {
+		t.Error("-tls-client-ca accepted without -tls-cert")
+	}
+}
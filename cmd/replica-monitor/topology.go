@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Limit on replication chain depth, guards against misreported topologies
+const maxTopologyDepth = 8
+
+// One server in the replication tree discovered from the source downwards
+type topologyNode struct {
+	host     string
+	port     int
+	serverID string
+	conn     *replica // open connection, nil for unreachable nodes
+	err      error
+	children []*topologyNode
+}
+
+func (n *topologyNode) addr() string {
+	return net.JoinHostPort(n.host, strconv.Itoa(n.port))
+}
+
+// Connect to the source and walk every downstream replica, including chained ones
+func discoverTopology(host string, port int) *topologyNode {
+	root := &topologyNode{host: host, port: port}
+	visited := map[string]bool{root.addr(): true}
+	root.explore(visited, 0)
+	return root
+}
+
+func (n *topologyNode) explore(visited map[string]bool, depth int) {
+	n.conn, n.err = connectReplica(n.addr(), n.host, n.port)
+	if n.err != nil || depth >= maxTopologyDepth {
+		return
+	}
+
+	downstream, err := listDownstream(n.conn.db, n.port)
+	if err != nil {
+		n.err = err
+		return
+	}
+	for _, child := range downstream {
+		if visited[child.addr()] {
+			continue
+		}
+		visited[child.addr()] = true
+		n.children = append(n.children, child)
+		child.explore(visited, depth+1)
+	}
+}
+
+// Find the replicas connected to a server, from SHOW REPLICAS (which only lists
+// replicas that set report_host) and from the binlog dump threads in the processlist.
+// Processlist entries carry an ephemeral client port, so those replicas are assumed
+// to listen on the same port as their source.
+func listDownstream(db *sql.DB, defaultPort int) ([]*topologyNode, error) {
+	var nodes []*topologyNode
+	seenHosts := make(map[string]bool)
+
+	rows, err := queryStrings(db, "SHOW REPLICAS")
+	if err != nil {
+		// Servers older than 8.0.22 only know the old spelling
+		rows, err = queryStrings(db, "SHOW SLAVE HOSTS")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing replicas: %w", err)
+	}
+	for _, row := range rows {
+		if row["Host"] == "" {
+			continue
+		}
+		p, err := strconv.Atoi(row["Port"])
+		if err != nil || p == 0 {
+			p = defaultPort
+		}
+		serverID := row["Server_Id"]
+		if serverID == "" {
+			serverID = row["Server_id"] // SHOW SLAVE HOSTS spelling
+		}
+		nodes = append(nodes, &topologyNode{host: row["Host"], port: p, serverID: serverID})
+		seenHosts[row["Host"]] = true
+	}
+
+	dumps, err := queryStrings(db, "SELECT HOST FROM information_schema.PROCESSLIST WHERE COMMAND IN ('Binlog Dump', 'Binlog Dump GTID')")
+	if err != nil {
+		return nil, fmt.Errorf("reading processlist: %w", err)
+	}
+	for _, row := range dumps {
+		h, _, err := net.SplitHostPort(row["HOST"])
+		if err != nil {
+			h = row["HOST"]
+		}
+		if h == "" || seenHosts[h] {
+			continue
+		}
+		nodes = append(nodes, &topologyNode{host: h, port: defaultPort})
+		seenHosts[h] = true
+	}
+	return nodes, nil
+}
+
+// Run a query and return every row as a column name → string map
+func queryStrings(db *sql.DB, query string) ([]map[string]string, error) {
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]sql.NullString, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	var result []map[string]string
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(columns))
+		for i, col := range columns {
+			row[col] = values[i].String
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// Print the topology to out as a tree rooted at the source
+func printTopology(out io.Writer, root *topologyNode) {
+	fmt.Fprintln(out, "🌳 Replication topology:")
+	fmt.Fprintf(out, "%s (source)\n", root.addr())
+	if root.err != nil {
+		fmt.Fprintf(out, "  ⚠️  %v\n", root.err)
+	}
+	printTopologyChildren(out, root.children, "")
+}
+
+func printTopologyChildren(out io.Writer, nodes []*topologyNode, indent string) {
+	for i, n := range nodes {
+		branch, next := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, next = "└── ", "    "
+		}
+		line := n.addr()
+		if n.serverID != "" {
+			line += fmt.Sprintf(" (server_id %s)", n.serverID)
+		}
+		if n.err != nil {
+			line += fmt.Sprintf(" ⚠️  %v", n.err)
+		}
+		fmt.Fprintln(out, indent+branch+line)
+		printTopologyChildren(out, n.children, indent+next)
+	}
+}
+
+// Collect every reachable downstream replica, linking chained replicas to their
+// upstream; the source's own connection is closed
+func (n *topologyNode) replicas() []*replica {
+	var result []*replica
+	var walk func(nodes []*topologyNode, upstream *replica)
+	walk = func(nodes []*topologyNode, upstream *replica) {
+		for _, n := range nodes {
+			if n.conn != nil {
+				n.conn.upstream = upstream
+				result = append(result, n.conn)
+			}
+			walk(n.children, n.conn)
+		}
+	}
+	walk(n.children, nil)
+	if n.conn != nil {
+		n.conn.close()
+	}
+	return result
+}
+
+// Ask a yes/no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	fmt.Fprintf(stdout, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Verbosity from -v (raw status fields) and -vv (SQL and connection tracing, implying -log-level debug)
+var verbosity int
+
+func debugf(format string, args ...interface{}) {
+	if debugLogging() {
+		slog.Debug(fmt.Sprintf(format, args...))
+	}
+}
+
+// Connector that logs every connection attempt, statement, and timing at debug level
+type tracingConnector struct {
+	driver.Connector
+	addr string
+}
+
+func (c tracingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	start := time.Now()
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		debugf("%s: connect failed after %s: %v", c.addr, time.Since(start), err)
+		return nil, err
+	}
+	debugf("%s: opened new connection in %s", c.addr, time.Since(start))
+	return &tracingConn{conn, c.addr}, nil
+}
+
+// Wraps the MySQL driver's connection, forwarding every optional interface it implements
+type tracingConn struct {
+	driver.Conn
+	addr string
+}
+
+func (c *tracingConn) trace(what string, start time.Time, err error) {
+	if err != nil {
+		debugf("%s: %s failed after %s: %v", c.addr, what, time.Since(start), err)
+		return
+	}
+	debugf("%s: %s took %s", c.addr, what, time.Since(start))
+}
+
+func (c *tracingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+	c.trace(query, start, err)
+	return rows, err
+}
+
+func (c *tracingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	result, err := c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+	c.trace(query, start, err)
+	return result, err
+}
+
+func (c *tracingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := time.Now()
+	stmt, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	c.trace("prepare "+query, start, err)
+	return stmt, err
+}
+
+func (c *tracingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *tracingConn) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := c.Conn.(driver.Pinger).Ping(ctx)
+	c.trace("ping", start, err)
+	return err
+}
+
+func (c *tracingConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return c.Conn.(driver.NamedValueChecker).CheckNamedValue(nv)
+}
+
+// database/sql calls these before reusing a pooled connection; a failure means it reconnects
+func (c *tracingConn) ResetSession(ctx context.Context) error {
+	err := c.Conn.(driver.SessionResetter).ResetSession(ctx)
+	if err != nil {
+		debugf("%s: discarding pooled connection: %v", c.addr, err)
+	}
+	return err
+}
+
+func (c *tracingConn) IsValid() bool {
+	valid := c.Conn.(driver.Validator).IsValid()
+	if !valid {
+		debugf("%s: pooled connection is no longer valid, will reconnect", c.addr)
+	}
+	return valid
+}
+
+func (c *tracingConn) Close() error {
+	debugf("%s: closing connection", c.addr)
+	return c.Conn.Close()
+}
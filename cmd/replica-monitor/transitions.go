@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// When each tracked condition of a replica last changed, to report how long it lasted
+var transitionLast = make(map[*replica]*monitor.Transitions)
+
+// Announce thread stops and restarts, error matches appearing and clearing, and
+// replicas falling behind -lag-threshold or catching up, with how long the previous
+// state lasted
+func announceTransitions(replicas []*replica) {
+	now := time.Now()
+	seen := make(map[*replica]bool)
+	for _, r := range replicas {
+		seen[r] = true
+		st := transitionLast[r]
+		if st == nil {
+			st = &monitor.Transitions{}
+			transitionLast[r] = st
+		}
+		for _, t := range st.Observe(r.health(), now, replicaLagThreshold(r)) {
+			announceTransition(r, t, now)
+		}
+	}
+	for r := range transitionLast {
+		if !seen[r] {
+			delete(transitionLast, r)
+		}
+	}
+}
+
+func announceTransition(r *replica, t monitor.Transition, now time.Time) {
+	name := displayName(r)
+	lasted := formatDuration(t.Lasted.Seconds())
+	event := journalEvent{Event: string(t.Kind)}
+	switch t.Kind {
+	case monitor.EventIOThreadStopped, monitor.EventSQLThreadStopped:
+		thread := strings.ToUpper(strings.TrimSuffix(string(t.Kind), "_thread_stopped"))
+		banner("❌", fmt.Sprintf("%s: %s thread stopped (%s) after running for %s", name, thread, orDash(t.State), lasted))
+		event.Message = orDash(t.State)
+	case monitor.EventIOThreadStarted, monitor.EventSQLThreadStarted:
+		thread := strings.ToUpper(strings.TrimSuffix(string(t.Kind), "_thread_started"))
+		banner("✅", fmt.Sprintf("%s: %s thread running again, was stopped for %s", name, thread, lasted))
+	case monitor.EventErrorMatched:
+		banner("🚨", fmt.Sprintf("%s: error pattern matched in Last_SQL_Error", name))
+	case monitor.EventErrorCleared:
+		banner("✅", fmt.Sprintf("%s: error cleared after %s", name, lasted))
+	case monitor.EventFellBehind:
+		threshold := replicaLagThreshold(r)
+		message := fmt.Sprintf("%s: fell behind, lag %s is above %s", name, formatDuration(r.lagSeconds), formatDuration(threshold.Seconds()))
+		event.Message = fmt.Sprintf("lag above %s", threshold)
+		if cause := probableLagCause(r, now); cause != "" {
+			message += "; probable cause: RDS " + cause
+			event.Message += "; probable cause: RDS " + cause
+		}
+		banner("⚠️", message)
+	case monitor.EventCaughtUp:
+		banner("✅", fmt.Sprintf("%s: caught up after being behind for %s", name, lasted))
+	}
+	recordEvent(r, event, t.Lasted)
+}
+
+// Print a message framed so it stands out from the scrolling report; every line
+// starts with the marker so severity coloring covers the whole banner
+func banner(marker, message string) {
+	rule := strings.Repeat("═", len([]rune(message))+2)
+	fmt.Fprintf(stdout, "\n%s %s\n%s  %s\n%s %s\n", marker, rule, marker, message, marker, rule)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Lag samples kept per replica for the TUI sparkline
+const tuiSparklineWidth = 60
+
+// Interactive terminal UI fed by the published status snapshots. The regular
+// report output is discarded while it runs and operational logs go to its log panel.
+type tui struct {
+	app     *tview.Application
+	done    chan struct{} // closed once app.Run has returned
+	stdout  io.Writer     // the report's writer before the UI took the terminal
+	unsub   func()
+	pages   *tview.Pages
+	header  *tview.TextView
+	panels  *tview.Flex
+	logView *tview.TextView
+
+	views   map[string]*tview.TextView
+	lags    map[string][]float64
+	current statusResponse
+}
+
+// Cause of the loop's context ending when the UI is quit with q or Ctrl+C
+var errTUIQuit = errors.New("quit from the terminal UI")
+
+// Take over the terminal; quitting the UI calls quit, so that the loop stops
+// and runMonitor shuts down as it does after a signal
+func startTUI(quit context.CancelCauseFunc) *tui {
+	t := &tui{
+		app:   tview.NewApplication(),
+		done:  make(chan struct{}),
+		views: make(map[string]*tview.TextView),
+		lags:  make(map[string][]float64),
+	}
+
+	t.header = tview.NewTextView().SetDynamicColors(true)
+	t.panels = tview.NewFlex().SetDirection(tview.FlexRow)
+	t.logView = tview.NewTextView().SetDynamicColors(false).SetMaxLines(200)
+	t.logView.SetBorder(true).SetTitle(" Log ")
+	t.logView.SetChangedFunc(func() { t.app.Draw() })
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(t.header, 1, 0, false).
+		AddItem(t.panels, 0, 1, false).
+		AddItem(t.logView, 8, 0, false)
+	t.pages = tview.NewPages().AddPage("main", layout, true, true)
+	t.app.SetRoot(t.pages, true).SetInputCapture(t.handleKey)
+	t.renderHeader()
+
+	// Keep report output off the screen and route logs into the log panel
+	t.stdout, stdout = stdout, io.Discard
+	setupLogging(t.logView)
+
+	updates, unsubscribe := subscribeStatus()
+	t.unsub = unsubscribe
+	go func() {
+		for snapshot := range updates {
+			t.app.QueueUpdateDraw(func() { t.update(snapshot) })
+		}
+	}()
+
+	go func() {
+		defer close(t.done)
+		if err := t.app.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Terminal UI failed: %v\n", err)
+		}
+		quit(errTUIQuit)
+	}()
+	return t
+}
+
+// Close the UI, if it is still open, and give the terminal back to the report
+// and logs for the shutdown summary; called on the loop's goroutine
+func (t *tui) stop() {
+	t.unsub()
+	t.app.Stop()
+	<-t.done
+	stdout = t.stdout
+	setupLogging(os.Stderr)
+}
+
+func (t *tui) handleKey(ev *tcell.EventKey) *tcell.EventKey {
+	if front, _ := t.pages.GetFrontPage(); front != "main" {
+		return ev
+	}
+	switch ev.Rune() {
+	case 'q':
+		t.app.Stop()
+		return nil
+	case 'p':
+		if pollingPaused.Load() {
+			audit(nil, "resume_polling", "tui", "")
+		} else {
+			audit(nil, "pause_polling", "tui", "")
+		}
+		pollingPaused.Store(!pollingPaused.Load())
+		wakeLoop()
+	case 'a':
+		if alertsSilenced.Load() {
+			audit(nil, "unsilence_alerts", "tui", "")
+		} else {
+			audit(nil, "silence_alerts", "tui", "")
+		}
+		alertsSilenced.Store(!alertsSilenced.Load())
+	case 's':
+		t.chooseSkipTarget()
+		return nil
+	default:
+		return ev
+	}
+	t.renderHeader()
+	return nil
+}
+
+func (t *tui) renderHeader() {
+	polling := "[green]polling[-]"
+	if pollingPaused.Load() {
+		polling = "[yellow]PAUSED[-]"
+	}
+	alerts := "[green]alerts on[-]"
+	if alertsSilenced.Load() {
+		alerts = "[yellow]alerts SILENCED[-]"
+	}
+	t.header.SetText(fmt.Sprintf(" [::b]Replica Monitor[::-]  %s  %s   [gray]p[-] pause  [gray]s[-] skip  [gray]a[-] silence  [gray]q[-] quit", polling, alerts))
+}
+
+// Ask which replica to skip on (when there are several), then confirm
+func (t *tui) chooseSkipTarget() {
+	var names []string
+	for _, rs := range t.current.Replicas {
+		names = append(names, rs.Name)
+	}
+	switch len(names) {
+	case 0:
+		return
+	case 1:
+		t.confirmSkip(names[0])
+		return
+	}
+
+	list := tview.NewList().ShowSecondaryText(false)
+	for _, name := range names {
+		name := name
+		list.AddItem(name, "", 0, func() {
+			t.pages.RemovePage("choose")
+			t.confirmSkip(name)
+		})
+	}
+	list.SetDoneFunc(func() { t.pages.RemovePage("choose") })
+	list.SetBorder(true).SetTitle(" Skip replication error on… (Esc cancels) ")
+	t.pages.AddPage("choose", centered(list, 60, len(names)+2), true, true)
+}
+
+func (t *tui) confirmSkip(name string) {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Run mysql.rds_skip_repl_error on %s?\n\nThe current failing transaction will be skipped and not applied.", name)).
+		AddButtons([]string{"Cancel", "Skip"}).
+		SetDoneFunc(func(_ int, label string) {
+			t.pages.RemovePage("confirm")
+			if label == "Skip" {
+				requestManualSkip(name)
+			}
+		})
+	t.pages.AddPage("confirm", modal, true, true)
+}
+
+func centered(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 0, true).
+			AddItem(nil, 0, 1, false), width, 0, true).
+		AddItem(nil, 0, 1, false)
+}
+
+// Refresh every replica panel from a new snapshot
+func (t *tui) update(snapshot statusResponse) {
+	t.current = snapshot
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(snapshot.Replicas))
+	for _, rs := range snapshot.Replicas {
+		seen[rs.Name] = true
+		names = append(names, rs.Name)
+
+		lag := 0.0
+		if rs.SecondsBehind != nil {
+			lag = *rs.SecondsBehind
+		}
+		lags := append(t.lags[rs.Name], lag)
+		if len(lags) > tuiSparklineWidth {
+			lags = lags[len(lags)-tuiSparklineWidth:]
+		}
+		t.lags[rs.Name] = lags
+
+		view := t.views[rs.Name]
+		if view == nil {
+			view = tview.NewTextView().SetDynamicColors(true)
+			view.SetBorder(true)
+			t.views[rs.Name] = view
+		}
+		view.SetTitle(" " + rs.Name + " ")
+		view.SetText(t.renderReplica(rs))
+	}
+
+	// Forget replicas that are no longer monitored and rebuild the layout in name order
+	for name := range t.views {
+		if !seen[name] {
+			delete(t.views, name)
+			delete(t.lags, name)
+		}
+	}
+	sort.Strings(names)
+	t.panels.Clear()
+	for _, name := range names {
+		t.panels.AddItem(t.views[name], 0, 1, false)
+	}
+	t.renderHeader()
+}
+
+func (t *tui) renderReplica(rs replicaStatus) string {
+	var b strings.Builder
+	lag := "[yellow]NULL[-]"
+	if rs.SecondsBehind != nil {
+		lag = formatDuration(*rs.SecondsBehind)
+		if *rs.SecondsBehind == 0 {
+			lag = "[green]0s (caught up)[-]"
+		}
+	}
+	fmt.Fprintf(&b, "Lag: [::b]%s[::-]   IO: %s   SQL: %s   Polled: %s\n",
+		lag, threadState(rs.IORunning), threadState(rs.SQLRunning), rs.PolledAt.Format("15:04:05"))
+	fmt.Fprintf(&b, "Instant: %s   Average: %s", formatRate(rs.RatePerSecond), formatRate(rs.AverageRatePerSecond))
+	if rs.AverageETA != nil {
+		fmt.Fprintf(&b, "   ETA: %s", formatETA(*rs.AverageETA))
+	}
+	fmt.Fprintf(&b, "\n[blue]%s[-]\n", sparkline(t.lags[rs.Name]))
+	if e := rs.Status["Last_SQL_Error"]; e != "" {
+		fmt.Fprintf(&b, "[red]Last_SQL_Error:[-] %s\n", tview.Escape(e))
+	}
+	if e := rs.Status["Last_IO_Error"]; e != "" {
+		fmt.Fprintf(&b, "[red]Last_IO_Error:[-] %s\n", tview.Escape(e))
+	}
+	if rs.LastAlert != nil {
+		fmt.Fprintf(&b, "[yellow]Last alert:[-] %s %s\n", rs.LastAlert.Time.Format("15:04:05"), tview.Escape(rs.LastAlert.Message))
+	}
+	return b.String()
+}
+
+func threadState(state string) string {
+	switch state {
+	case "Yes":
+		return "[green]Yes[-]"
+	case "":
+		return "-"
+	default:
+		return "[red]" + state + "[-]"
+	}
+}
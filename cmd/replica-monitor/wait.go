@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Exit codes of the wait command; 2 is a usage error, as for every command
+const (
+	waitCaughtUp = 0
+	waitTimedOut = 1
+	waitStopped  = 3 // replication stopped on a replica, which will not catch up on its own
+	waitFailed   = 4 // the replicas could not be connected to, or one is not a replica
+)
+
+// Flags of wait
+var (
+	waitMaxLag  time.Duration
+	waitTimeout time.Duration
+)
+
+func waitFlags() *flag.FlagSet {
+	fs := newCommandFlags("wait")
+	addConnectionFlags(fs)
+	fs.DurationVar(&waitMaxLag, "max-lag", 10*time.Second, "Lag every replica must be at or under")
+	fs.DurationVar(&waitTimeout, "timeout", 30*time.Minute, "Give up after this long (0 waits indefinitely)")
+	fs.DurationVar(&interval, "interval", 5*time.Second, "Time between polls")
+	addLagSourceFlags(fs)
+	return fs
+}
+
+// Block until every replica's lag is at most -max-lag, for deploy pipelines
+// that must not move read traffic or run a migration onto a lagging replica
+func runWait(fs *flag.FlagSet) error {
+	if waitMaxLag < 0 || waitTimeout < 0 || interval <= 0 {
+		return usageError(fs, "-max-lag and -timeout must not be negative and -interval must be positive")
+	}
+	assumeYes = true // a deploy gate never prompts, e.g. to confirm -topology
+	// nor alerts the config file's destinations about what watch reports already
+	alertsSilenced.Store(true)
+
+	// Only progress and the verdict go to standard output
+	if err := setupOutput(); err != nil {
+		return &exitError{code: waitFailed, err: err}
+	}
+	report := stdout
+	stdout = io.Discard
+	replicas, _, err := connectTargets(fs, io.Discard)
+	stdout = report
+	if errors.Is(err, errNothingToMonitor) {
+		return exitWith(2)
+	}
+	if err != nil {
+		fmt.Fprintf(stdout, "❌ Failed to %v\n", err)
+		return exitWith(waitFailed)
+	}
+	return exitWith(waitForCatchUp(context.Background(), replicas, waitMaxLag, waitTimeout))
+}
+
+// Poll the replicas every -interval until they are all caught up, replication
+// stops on one of them, or timeout passes, and return the exit code
+func waitForCatchUp(ctx context.Context, replicas []*replica, maxLag, timeout time.Duration) int {
+	defer func() {
+		for _, r := range replicas {
+			r.close()
+		}
+	}()
+	start := time.Now()
+	for {
+		pollOnce(ctx, io.Discard, replicas, false)
+
+		code, waiting := waitVerdict(replicas, maxLag)
+		waited := time.Since(start)
+		elapsed := waited.Round(time.Second)
+		switch {
+		case code == waitCaughtUp:
+			fmt.Fprintf(stdout, "✅ Caught up after %s: %s\n", elapsed, strings.Join(waiting, "; "))
+			return code
+		case code == waitStopped:
+			fmt.Fprintf(stdout, "❌ Replication stopped after %s: %s\n", elapsed, strings.Join(waiting, "; "))
+			return code
+		case code == waitFailed:
+			fmt.Fprintf(stdout, "❌ Not replicating: %s\n", strings.Join(waiting, "; "))
+			return code
+		case timeout > 0 && waited >= timeout:
+			fmt.Fprintf(stdout, "⌛ Timed out after %s waiting for lag of at most %s: %s\n", elapsed, maxLag, strings.Join(waiting, "; "))
+			return waitTimedOut
+		}
+		fmt.Fprintf(stdout, "⏳ %s: %s\n", elapsed, strings.Join(waiting, "; "))
+
+		// Poll one last time at the deadline rather than give up a poll early
+		sleep := interval
+		if timeout > 0 {
+			sleep = min(interval, timeout-waited)
+		}
+		select {
+		case <-ctx.Done():
+			return waitTimedOut
+		case <-time.After(sleep):
+		}
+	}
+}
+
+// Decide from the latest poll whether waiting is over: waitCaughtUp with every
+// replica's lag, waitFailed with those that are not replicas, waitStopped with
+// the stopped ones, or -1 with those still behind
+func waitVerdict(replicas []*replica, maxLag time.Duration) (int, []string) {
+	var caughtUp, behind, stopped, failed []string
+	for _, r := range replicas {
+		state, why := monitor.CatchUp(r.health(), maxLag, formatDuration)
+		why = displayName(r) + " " + why
+		switch state {
+		case monitor.NoStatus:
+			failed = append(failed, why)
+		case monitor.Stopped:
+			stopped = append(stopped, why)
+		case monitor.Behind:
+			behind = append(behind, why)
+		default:
+			caughtUp = append(caughtUp, why)
+		}
+	}
+	switch {
+	case len(failed) > 0:
+		return waitFailed, failed
+	case len(stopped) > 0:
+		return waitStopped, stopped
+	case len(behind) > 0:
+		return -1, behind
+	}
+	return waitCaughtUp, caughtUp
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+func TestWaitVerdict(t *testing.T) {
+	polled := time.Now()
+	caughtUp := &replica{name: "a", polledAt: polled, ioRunning: "Yes", sqlRunning: "Yes", lagKnown: true, lagSeconds: 4}
+	behind := &replica{name: "b", polledAt: polled, ioRunning: "Yes", sqlRunning: "Yes", lagKnown: true, lagSeconds: 90}
+	unknown := &replica{name: "c", polledAt: polled, ioRunning: "Yes", sqlRunning: "Yes"}
+	stopped := &replica{name: "d", polledAt: polled, ioRunning: "Yes", sqlRunning: "No", lagKnown: true}
+	ioStopped := &replica{name: "f", polledAt: polled, ioRunning: "No", sqlRunning: "Yes"}
+	reconnecting := &replica{name: "g", polledAt: polled, ioRunning: "Connecting", sqlRunning: "Yes", lagKnown: true}
+	unpolled := &replica{name: "e"}
+	notReplica := &replica{name: "h", polledAt: polled}
+	for _, tc := range []struct {
+		replicas []*replica
+		want     int
+		lines    int
+	}{
+		{[]*replica{caughtUp}, waitCaughtUp, 1},
+		{[]*replica{caughtUp, behind}, -1, 1},
+		{[]*replica{caughtUp, unknown, unpolled}, -1, 2},
+		{[]*replica{behind, stopped}, waitStopped, 1},
+		{[]*replica{caughtUp, ioStopped}, waitStopped, 1},
+		// A reconnecting IO thread recovers by itself, so wait keeps waiting
+		{[]*replica{caughtUp, reconnecting}, -1, 1},
+		// A server with no replica status will never catch up
+		{[]*replica{behind, notReplica}, waitFailed, 1},
+	} {
+		code, lines := waitVerdict(tc.replicas, 10*time.Second)
+		if code != tc.want || len(lines) != tc.lines {
+			t.Errorf("waitVerdict = %d, %q; want %d with %d lines", code, lines, tc.want, tc.lines)
+		}
+	}
+}
+
+func TestWaitForCatchUp(t *testing.T) {
+	defer func(d time.Duration, w io.Writer) { interval, stdout = d, w }(interval, stdout)
+	interval, stdout = 10*time.Millisecond, io.Discard
+	engine, _ := monitor.LookupEngine("mysql")
+	row := func(lag any) *monitor.StatusRow {
+		return monitor.MockRow("Replica_IO_Running", "Yes", "Replica_SQL_Running", "Yes", "Seconds_Behind_Source", lag, "Last_SQL_Error", "")
+	}
+
+	// Lag falls under the threshold on the third poll
+	r := &replica{name: "catching-up", engine: engine, source: &monitor.MockSource{Rows: []*monitor.StatusRow{row(120), row(40), row(5)}}}
+	if code := waitForCatchUp(context.Background(), []*replica{r}, 10*time.Second, time.Minute); code != waitCaughtUp {
+		t.Errorf("waitForCatchUp = %d; want caught up", code)
+	}
+
+	// The last poll comes at the deadline even when it falls short of -interval
+	interval = time.Hour
+	r = &replica{name: "deadline", engine: engine, source: &monitor.MockSource{Rows: []*monitor.StatusRow{row(120), row(5)}}}
+	if code := waitForCatchUp(context.Background(), []*replica{r}, 10*time.Second, 50*time.Millisecond); code != waitCaughtUp {
+		t.Errorf("waitForCatchUp = %d; want caught up on the poll at the deadline", code)
+	}
+	interval = 10 * time.Millisecond
+
+	r = &replica{name: "stuck", engine: engine, source: &monitor.MockSource{Rows: []*monitor.StatusRow{row(nil)}}}
+	if code := waitForCatchUp(context.Background(), []*replica{r}, 10*time.Second, 50*time.Millisecond); code != waitTimedOut {
+		t.Errorf("waitForCatchUp = %d; want timed out", code)
+	}
+}
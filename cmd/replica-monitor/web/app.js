@@ -0,0 +1,175 @@
+// Built-in dashboard: live lag chart, per-replica cards, and the skip/alert timeline.
+// Data comes from /status on load, /events (SSE) while open, and /api/v1/timeline.
+"use strict";
+
+const MAX_POINTS = 720; // one hour at the default 5s poll interval
+const COLORS = ["#5fa8e8", "#e8a15f", "#7fd18b", "#d17fc4", "#e8e05f", "#5fe0d8", "#e86f5f", "#a99ae8"];
+
+const series = new Map(); // replica name -> [{t, lag}]
+const latest = new Map(); // replica name -> status
+
+function formatDuration(seconds) {
+  if (seconds == null) return "NULL";
+  if (seconds > 0 && seconds < 1) return Math.round(seconds * 1000) + "ms";
+  let s = Math.floor(seconds);
+  const d = Math.floor(s / 86400); s %= 86400;
+  const h = Math.floor(s / 3600); s %= 3600;
+  const m = Math.floor(s / 60); s %= 60;
+  if (d > 0) return `${d}d ${h}h ${m}m ${s}s`;
+  if (h > 0) return `${h}h ${m}m ${s}s`;
+  if (m > 0) return `${m}m ${s}s`;
+  return `${s}s`;
+}
+
+function formatRate(rate) {
+  if (!rate) return "–";
+  return rate < 0 ? `catching up ${(-rate).toFixed(2)} s/s` : `falling behind ${rate.toFixed(2)} s/s`;
+}
+
+function formatTime(iso) {
+  return iso ? new Date(iso).toLocaleString() : "–";
+}
+
+function escapeHTML(s) {
+  return String(s).replace(/[&<>"']/g, c => ({ "&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;", "'": "&#39;" }[c]));
+}
+
+function colorFor(name) {
+  const names = [...series.keys()].sort();
+  return COLORS[names.indexOf(name) % COLORS.length];
+}
+
+function addStatus(rs) {
+  latest.set(rs.name, rs);
+  if (!series.has(rs.name)) series.set(rs.name, []);
+  const points = series.get(rs.name);
+  const t = new Date(rs.polled_at).getTime();
+  if (points.length && points[points.length - 1].t === t) return;
+  points.push({ t, lag: rs.seconds_behind });
+  if (points.length > MAX_POINTS) points.shift();
+}
+
+function renderCards() {
+  const el = document.getElementById("replicas");
+  el.innerHTML = [...latest.values()].map(rs => {
+    const stopped = (rs.io_running && rs.io_running !== "Yes") || (rs.sql_running && rs.sql_running !== "Yes");
+    const cls = rs.error_matched || stopped ? "bad" : rs.seconds_behind > 0 ? "warn" : "";
+    const labels = Object.entries(rs.labels || {}).map(([k, v]) => `${k}=${v}`).join(", ");
+    return `
+      <div class="card ${cls}">
+        <h3>${escapeHTML(rs.name)}</h3>
+        ${labels ? `<div class="labels">${escapeHTML(labels)}</div>` : ""}
+        <dl>
+          <dt>Lag</dt><dd>${formatDuration(rs.seconds_behind)}</dd>
+          <dt>IO / SQL</dt><dd>${escapeHTML(rs.io_running || "–")} / ${escapeHTML(rs.sql_running || "–")}</dd>
+          <dt>Instant</dt><dd>${formatRate(rs.rate_per_second)}</dd>
+          <dt>Average</dt><dd>${formatRate(rs.average_rate_per_second)}</dd>
+          <dt>Instant ETA</dt><dd>${formatTime(rs.instant_eta)}</dd>
+          <dt>Average ETA</dt><dd>${formatTime(rs.average_eta)}</dd>
+          <dt>Polled</dt><dd>${formatTime(rs.polled_at)}</dd>
+          ${rs.last_alert ? `<dt>Last alert</dt><dd>${escapeHTML(rs.last_alert.message)}</dd>` : ""}
+        </dl>
+      </div>`;
+  }).join("");
+}
+
+function renderChart() {
+  const canvas = document.getElementById("chart");
+  const ratio = window.devicePixelRatio || 1;
+  const width = canvas.clientWidth, height = canvas.clientHeight;
+  canvas.width = width * ratio;
+  canvas.height = height * ratio;
+  const ctx = canvas.getContext("2d");
+  ctx.scale(ratio, ratio);
+  ctx.clearRect(0, 0, width, height);
+
+  let tMin = Infinity, tMax = -Infinity, lagMax = 1;
+  for (const points of series.values()) {
+    for (const p of points) {
+      tMin = Math.min(tMin, p.t);
+      tMax = Math.max(tMax, p.t);
+      if (p.lag != null) lagMax = Math.max(lagMax, p.lag);
+    }
+  }
+  if (!isFinite(tMin)) return;
+  if (tMax === tMin) tMax = tMin + 1000;
+
+  const left = 80, right = 10, top = 10, bottom = 24;
+  const x = t => left + (t - tMin) / (tMax - tMin) * (width - left - right);
+  const y = lag => top + (1 - lag / lagMax) * (height - top - bottom);
+
+  ctx.strokeStyle = "#2a3240";
+  ctx.fillStyle = "#8791a1";
+  ctx.font = "11px sans-serif";
+  for (let i = 0; i <= 4; i++) {
+    const lag = lagMax * i / 4;
+    ctx.beginPath();
+    ctx.moveTo(left, y(lag));
+    ctx.lineTo(width - right, y(lag));
+    ctx.stroke();
+    ctx.fillText(formatDuration(lag), 4, y(lag) + 4);
+  }
+  ctx.fillText(new Date(tMin).toLocaleTimeString(), left, height - 6);
+  const endLabel = new Date(tMax).toLocaleTimeString();
+  ctx.fillText(endLabel, width - right - ctx.measureText(endLabel).width, height - 6);
+
+  for (const [name, points] of series) {
+    ctx.strokeStyle = colorFor(name);
+    ctx.lineWidth = 2;
+    ctx.beginPath();
+    let drawing = false;
+    for (const p of points) {
+      if (p.lag == null) { drawing = false; continue; }
+      if (drawing) ctx.lineTo(x(p.t), y(p.lag));
+      else ctx.moveTo(x(p.t), y(p.lag));
+      drawing = true;
+    }
+    ctx.stroke();
+  }
+
+  document.getElementById("legend").innerHTML = [...series.keys()].sort()
+    .map(name => `<span style="--color:${colorFor(name)}">${escapeHTML(name)}</span>`).join("");
+}
+
+async function loadTimeline() {
+  try {
+    const events = await (await fetch("api/v1/timeline")).json();
+    document.getElementById("timeline").innerHTML = events.reverse().map(e => `
+      <li><time>${formatTime(e.time)}</time><span class="kind ${e.kind === "skip" ? "skip" : "alert"}">${escapeHTML(e.kind)}</span>
+      <strong>${escapeHTML(e.replica)}</strong> ${escapeHTML(e.message)}</li>`).join("");
+  } catch (err) {
+    console.error("loading timeline", err);
+  }
+}
+
+function render() {
+  renderCards();
+  renderChart();
+  document.getElementById("updated").textContent = "updated " + new Date().toLocaleTimeString();
+}
+
+async function init() {
+  try {
+    const status = await (await fetch("status")).json();
+    status.replicas.forEach(addStatus);
+    render();
+  } catch (err) {
+    console.error("loading status", err);
+  }
+  loadTimeline();
+
+  const badge = document.getElementById("connection");
+  const source = new EventSource("events");
+  let pending = null;
+  source.onopen = () => { badge.textContent = "live"; badge.className = "badge ok"; };
+  source.onerror = () => { badge.textContent = "disconnected"; badge.className = "badge bad"; };
+  source.addEventListener("poll", ev => {
+    addStatus(JSON.parse(ev.data));
+    // Redraw once per monitoring cycle rather than once per replica
+    clearTimeout(pending);
+    pending = setTimeout(() => { render(); loadTimeline(); }, 100);
+  });
+  window.addEventListener("resize", renderChart);
+}
+
+init();
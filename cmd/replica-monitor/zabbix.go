@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Zabbix host name written in sender lines; "-" lets zabbix_sender use the agent's Hostname
+var zabbixHost string
+
+// File or FIFO receiving zabbix_sender lines after every watch cycle
+var zabbixOutput string
+
+// Write the latest values of every replica as zabbix_sender input lines. With
+// timestamps, the lines are meant for "zabbix_sender -T -i".
+func writeZabbixLines(w io.Writer, replicas []*replica, timestamps bool) {
+	for _, r := range replicas {
+		if r.polledAt.IsZero() {
+			continue
+		}
+		name := displayName(r)
+		item := func(key string, value interface{}) {
+			line := zabbixQuote(zabbixHost) + " " + zabbixQuote(fmt.Sprintf("replica.%s[%s]", key, zabbixKeyParam(name)))
+			if timestamps {
+				line += fmt.Sprintf(" %d", r.polledAt.Unix())
+			}
+			fmt.Fprintf(w, "%s %v\n", line, value)
+		}
+		if r.lagKnown {
+			item("lag", fmt.Sprintf("%.3f", r.lagSeconds))
+		}
+		if !r.aurora {
+			item("io_running", zabbixBool(r.ioRunning == "Yes"))
+			item("sql_running", zabbixBool(r.sqlRunning == "Yes"))
+			item("error_matched", zabbixBool(r.errorMatched))
+			item("skips", r.skips)
+		}
+	}
+}
+
+// Low-level discovery JSON for a "replica.discovery" item
+func writeZabbixDiscovery(w io.Writer, replicas []*replica) error {
+	data := []map[string]string{}
+	for _, r := range replicas {
+		data = append(data, map[string]string{
+			"{#REPLICA}": displayName(r),
+			"{#HOST}":    r.host,
+			"{#PORT}":    fmt.Sprint(r.port),
+		})
+	}
+	return json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+// Append this cycle's lines to -zabbix-output, which may be a FIFO read by zabbix_sender
+func exportZabbix(replicas []*replica) {
+	if zabbixOutput == "" {
+		return
+	}
+	f, err := os.OpenFile(zabbixOutput, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		slog.Error("Failed to open Zabbix output", "path", zabbixOutput, "err", err)
+		return
+	}
+	defer f.Close()
+	var b strings.Builder
+	writeZabbixLines(&b, replicas, true)
+	if _, err := io.WriteString(f, b.String()); err != nil {
+		slog.Error("Failed to write Zabbix output", "path", zabbixOutput, "err", err)
+	}
+}
+
+func zabbixBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Quote an item key parameter when it contains characters with meaning in keys
+func zabbixKeyParam(s string) string {
+	if !strings.ContainsAny(s, ",[]\" ") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// Quote a zabbix_sender input field containing spaces or quotes
+func zabbixQuote(s string) string {
+	if !strings.ContainsAny(s, " \"\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
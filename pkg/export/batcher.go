@@ -0,0 +1,111 @@
+// Package export ships replication samples and events to external systems in
+// the background, batching records so a slow or failing destination never holds
+// up monitoring.
+package export
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Batcher collects records in the background and sends them in batches of up to
+// maxBatch at least once a second.
+type Batcher[T any] struct {
+	name     string
+	maxBatch int
+	send     func(ctx context.Context, records []T) error
+
+	queue  chan T
+	done   chan struct{}
+	mu     sync.Mutex // guards closed against records written during shutdown
+	closed bool
+}
+
+// QueueSize is how many records may wait to be sent before new ones are dropped.
+const QueueSize = 1000
+
+// NewBatcher starts a batcher handing records to send; name identifies it in logs.
+func NewBatcher[T any](name string, maxBatch int, send func(ctx context.Context, records []T) error) *Batcher[T] {
+	b := &Batcher[T]{
+		name:     name,
+		maxBatch: maxBatch,
+		send:     send,
+		queue:    make(chan T, QueueSize),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Add queues a record without blocking, dropping it when the queue is full.
+func (b *Batcher[T]) Add(record T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	select {
+	case b.queue <- record:
+	default:
+		slog.Warn("Send queue is full, dropping record", "sink", b.name)
+	}
+}
+
+func (b *Batcher[T]) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var batch []T
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		// A panicking sink loses this batch, not the process
+		defer func() {
+			if p := recover(); p != nil {
+				slog.Error("Sink panicked sending records", "sink", b.name, "records", len(batch), "panic", p)
+			}
+			batch = nil
+		}()
+		if err := b.send(ctx, batch); err != nil {
+			slog.Warn("Failed to send records", "sink", b.name, "records", len(batch), "err", err)
+		}
+	}
+	for {
+		select {
+		case record, ok := <-b.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= b.maxBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Close stops taking records and waits up to timeout for the queued ones to be
+// sent.
+func (b *Batcher[T]) Close(timeout time.Duration) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.queue)
+	b.mu.Unlock()
+	select {
+	case <-b.done:
+	case <-time.After(timeout):
+		slog.Warn("Gave up waiting for queued records", "sink", b.name)
+	}
+}
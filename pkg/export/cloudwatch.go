@@ -0,0 +1,54 @@
+package export
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// CloudWatch publishes samples as custom metrics in Namespace, with a Replica
+// dimension.
+type CloudWatch struct {
+	Client    *cloudwatch.Client
+	Namespace string
+}
+
+func (e *CloudWatch) Name() string { return "cloudwatch" }
+
+// PutMetricData takes up to 1000 data points per call
+const cloudWatchMaxData = 1000
+
+func (e *CloudWatch) Export(ctx context.Context, samples []Sample) error {
+	var data []types.MetricDatum
+	for _, s := range samples {
+		dims := []types.Dimension{{Name: aws.String("Replica"), Value: aws.String(s.Name())}}
+		datum := func(name string, value float64, unit types.StandardUnit) {
+			data = append(data, types.MetricDatum{
+				MetricName: aws.String(name),
+				Dimensions: dims,
+				Timestamp:  aws.Time(s.Time),
+				Value:      aws.Float64(value),
+				Unit:       unit,
+			})
+		}
+		if s.SecondsBehind != nil {
+			datum("ReplicaLag", float64(*s.SecondsBehind), types.StandardUnitSeconds)
+		}
+		datum("IORunning", float64(boolInt(s.IORunning == "Yes")), types.StandardUnitCount)
+		datum("SQLRunning", float64(boolInt(s.SQLRunning == "Yes")), types.StandardUnitCount)
+		datum("ErrorMatched", float64(boolInt(s.ErrorMatched)), types.StandardUnitCount)
+	}
+	for len(data) > 0 {
+		n := min(len(data), cloudWatchMaxData)
+		if _, err := e.Client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(e.Namespace),
+			MetricData: data[:n],
+		}); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
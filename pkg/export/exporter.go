@@ -0,0 +1,88 @@
+package export
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Sample is one poll of a replica, as every exporter receives it.
+type Sample struct {
+	Time time.Time `json:"time"`
+	// Replica is the name shown in reports, when one was given
+	Replica       string            `json:"replica,omitempty"`
+	Host          string            `json:"host"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	SecondsBehind *int              `json:"seconds_behind"` // nil when the replica reported NULL
+	IORunning     string            `json:"io_running"`
+	SQLRunning    string            `json:"sql_running"`
+	LastSQLError  string            `json:"last_sql_error,omitempty"`
+	ErrorMatched  bool              `json:"error_matched,omitempty"`
+	// Each lag source's reading when lag was reconciled from several; SecondsBehind
+	// is then the reconciled lag
+	LagSources         map[string]float64 `json:"lag_sources,omitempty"`
+	LagSourcesDisagree bool               `json:"lag_sources_disagree,omitempty"`
+}
+
+// Name identifies the sample's replica: its name, or its host without one.
+func (s Sample) Name() string {
+	if s.Replica != "" {
+		return s.Replica
+	}
+	return s.Host
+}
+
+// Exporter sends samples to a metrics system or store. An exporter that also
+// implements io.Closer is closed when its Fanout is.
+type Exporter interface {
+	// Name identifies the exporter in logs.
+	Name() string
+	// Export sends a batch of samples, oldest first.
+	Export(ctx context.Context, samples []Sample) error
+}
+
+// Fanout hands every sample to each of its exporters. Each exporter has a queue
+// and goroutine of its own, so one that is slow, failing, or panicking delays
+// or loses only its own samples.
+type Fanout struct {
+	exporters []Exporter
+	batchers  []*Batcher[Sample]
+}
+
+// NewFanout starts delivering to exporters.
+func NewFanout(exporters ...Exporter) *Fanout {
+	f := &Fanout{exporters: exporters}
+	for _, e := range exporters {
+		f.batchers = append(f.batchers, NewBatcher(e.Name(), 100, e.Export))
+	}
+	return f
+}
+
+// Add queues a sample for every exporter without blocking.
+func (f *Fanout) Add(sample Sample) {
+	for _, b := range f.batchers {
+		b.Add(sample)
+	}
+}
+
+// Close waits up to timeout for each exporter's queued samples to be sent, then
+// closes the exporters that can be.
+func (f *Fanout) Close(timeout time.Duration) {
+	for i, b := range f.batchers {
+		b.Close(timeout)
+		if c, ok := f.exporters[i].(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				slog.Warn("Failed to close exporter", "exporter", f.exporters[i].Name(), "err", err)
+			}
+		}
+	}
+}
+
+// 1 for true and 0 for false, as metrics systems want them
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
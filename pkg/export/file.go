@@ -0,0 +1,41 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+)
+
+// File appends each sample as a JSON line to a file, the history format that
+// replica-monitor's history commands read back.
+type File struct {
+	f *os.File
+}
+
+// OpenFile opens path for appending, creating it when missing.
+func OpenFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &File{f: f}, nil
+}
+
+func (e *File) Name() string { return "file" }
+
+func (e *File) Export(ctx context.Context, samples []Sample) error {
+	var buf []byte
+	for _, s := range samples {
+		line, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	_, err := e.f.Write(buf)
+	return err
+}
+
+func (e *File) Close() error {
+	return e.f.Close()
+}
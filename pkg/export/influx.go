@@ -0,0 +1,76 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Influx writes samples in line protocol to an InfluxDB write endpoint: the
+// full URL, for example http://influx:8086/api/v2/write?org=ops&bucket=replicas
+// or a 1.x http://influx:8086/write?db=replicas.
+type Influx struct {
+	URL    string
+	Token  string // sent as "Authorization: Token ...", when set
+	Client *http.Client
+}
+
+func (e *Influx) Name() string { return "influx" }
+
+func (e *Influx) Export(ctx context.Context, samples []Sample) error {
+	var body bytes.Buffer
+	for _, s := range samples {
+		body.WriteString("replica_status,replica=")
+		body.WriteString(influxEscape.Replace(s.Name()))
+		body.WriteString(",host=")
+		body.WriteString(influxEscape.Replace(s.Host))
+		keys := make([]string, 0, len(s.Labels))
+		for k := range s.Labels {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if s.Labels[k] == "" || k == "replica" || k == "host" {
+				continue // line protocol has no empty tag values
+			}
+			fmt.Fprintf(&body, ",%s=%s", influxEscape.Replace(k), influxEscape.Replace(s.Labels[k]))
+		}
+		fmt.Fprintf(&body, " io_running=%t,sql_running=%t,error_matched=%t", s.IORunning == "Yes", s.SQLRunning == "Yes", s.ErrorMatched)
+		if s.SecondsBehind != nil {
+			fmt.Fprintf(&body, ",seconds_behind=%di", *s.SecondsBehind)
+		}
+		body.WriteByte(' ')
+		body.WriteString(strconv.FormatInt(s.Time.UnixNano(), 10))
+		body.WriteByte('\n')
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if e.Token != "" {
+		req.Header.Set("Authorization", "Token "+e.Token)
+	}
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("rejected: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Commas, spaces, and equals signs are escaped in tag keys and values
+var influxEscape = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`, "\n", `\n`)
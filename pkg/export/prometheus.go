@@ -0,0 +1,108 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Prometheus keeps the latest sample of each replica and serves them in the
+// Prometheus text format as an http.Handler, for a /metrics endpoint.
+type Prometheus struct {
+	mu     sync.Mutex
+	latest map[string]Sample // by replica name
+}
+
+func NewPrometheus() *Prometheus {
+	return &Prometheus{latest: make(map[string]Sample)}
+}
+
+func (p *Prometheus) Name() string { return "prometheus" }
+
+func (p *Prometheus) Export(ctx context.Context, samples []Sample) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range samples {
+		p.latest[s.Name()] = s
+	}
+	return nil
+}
+
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	p.mu.Lock()
+	samples := make([]Sample, 0, len(p.latest))
+	for _, s := range p.latest {
+		samples = append(samples, s)
+	}
+	p.mu.Unlock()
+	slices.SortFunc(samples, func(a, b Sample) int { return strings.Compare(a.Name(), b.Name()) })
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writePrometheus(w, samples)
+}
+
+func writePrometheus(w io.Writer, samples []Sample) {
+	gauge := func(name, help string, value func(Sample) (float64, bool)) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, s := range samples {
+			if v, ok := value(s); ok {
+				fmt.Fprintf(w, "%s{%s} %g\n", name, promLabels(s), v)
+			}
+		}
+	}
+	gauge("replica_monitor_lag_seconds", "Seconds the replica is behind its source; absent while unknown.", func(s Sample) (float64, bool) {
+		if s.SecondsBehind == nil {
+			return 0, false
+		}
+		return float64(*s.SecondsBehind), true
+	})
+	fmt.Fprintf(w, "# HELP replica_monitor_lag_source_seconds Seconds behind as each lag source measured it, when lag is reconciled from several.\n# TYPE replica_monitor_lag_source_seconds gauge\n")
+	for _, s := range samples {
+		for _, source := range slices.Sorted(maps.Keys(s.LagSources)) {
+			fmt.Fprintf(w, "replica_monitor_lag_source_seconds{%s,lag_source=\"%s\"} %g\n", promLabels(s), promEscape.Replace(source), s.LagSources[source])
+		}
+	}
+	gauge("replica_monitor_lag_sources_disagree", "Whether the lag sources differ by more than -lag-disagreement; absent with a single source.", func(s Sample) (float64, bool) {
+		return float64(boolInt(s.LagSourcesDisagree)), len(s.LagSources) > 1
+	})
+	gauge("replica_monitor_io_running", "Whether the replication IO thread is running.", func(s Sample) (float64, bool) {
+		return float64(boolInt(s.IORunning == "Yes")), true
+	})
+	gauge("replica_monitor_sql_running", "Whether the replication SQL thread is running.", func(s Sample) (float64, bool) {
+		return float64(boolInt(s.SQLRunning == "Yes")), true
+	})
+	gauge("replica_monitor_error_matched", "Whether Last_SQL_Error matches an error pattern.", func(s Sample) (float64, bool) {
+		return float64(boolInt(s.ErrorMatched)), true
+	})
+	gauge("replica_monitor_last_poll_timestamp_seconds", "When the replica was last polled.", func(s Sample) (float64, bool) {
+		return float64(s.Time.UnixMilli()) / 1000, true
+	})
+}
+
+var promInvalid = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// The replica, host, and sample labels, with label names made valid and values escaped
+func promLabels(s Sample) string {
+	pairs := []string{`replica="` + promEscape.Replace(s.Name()) + `"`, `host="` + promEscape.Replace(s.Host) + `"`}
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		name := promInvalid.ReplaceAllString(k, "_")
+		if name == "replica" || name == "host" || name == "" || (name[0] >= '0' && name[0] <= '9') || strings.HasPrefix(name, "__") {
+			name = "label_" + name
+		}
+		pairs = append(pairs, name+`="`+promEscape.Replace(s.Labels[k])+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Label values escape backslashes, quotes, and newlines
+var promEscape = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
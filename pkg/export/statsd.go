@@ -0,0 +1,88 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+// StatsD sends each sample as gauges over UDP, tagged DogStatsD style with the
+// replica, host, and labels, which Datadog, Telegraf, and the StatsD exporter
+// for Prometheus understand.
+type StatsD struct {
+	Prefix string // metric name prefix, "replica_monitor" when empty
+	conn   net.Conn
+}
+
+// DialStatsD sets up sending to a StatsD server at addr (host:port).
+func DialStatsD(addr, prefix string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "" {
+		prefix = "replica_monitor"
+	}
+	return &StatsD{Prefix: prefix, conn: conn}, nil
+}
+
+func (e *StatsD) Name() string { return "statsd" }
+
+// Lines are packed into datagrams that fit a typical Ethernet MTU
+const statsdMaxDatagram = 1432
+
+func (e *StatsD) Export(ctx context.Context, samples []Sample) error {
+	var lines []string
+	for _, s := range samples {
+		tags := statsdTags(s)
+		gauge := func(name string, value int) {
+			lines = append(lines, fmt.Sprintf("%s.%s:%d|g|#%s", e.Prefix, name, value, tags))
+		}
+		if s.SecondsBehind != nil {
+			gauge("lag_seconds", *s.SecondsBehind)
+		}
+		gauge("io_running", boolInt(s.IORunning == "Yes"))
+		gauge("sql_running", boolInt(s.SQLRunning == "Yes"))
+		gauge("error_matched", boolInt(s.ErrorMatched))
+	}
+	var datagram strings.Builder
+	for _, line := range lines {
+		if datagram.Len() > 0 && datagram.Len()+1+len(line) > statsdMaxDatagram {
+			if _, err := e.conn.Write([]byte(datagram.String())); err != nil {
+				return err
+			}
+			datagram.Reset()
+		}
+		if datagram.Len() > 0 {
+			datagram.WriteByte('\n')
+		}
+		datagram.WriteString(line)
+	}
+	if datagram.Len() > 0 {
+		_, err := e.conn.Write([]byte(datagram.String()))
+		return err
+	}
+	return nil
+}
+
+func (e *StatsD) Close() error {
+	return e.conn.Close()
+}
+
+// Tags may not contain the separators of the line format
+var statsdEscape = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", " ")
+
+func statsdTags(s Sample) string {
+	tags := []string{"replica:" + statsdEscape.Replace(s.Name()), "host:" + statsdEscape.Replace(s.Host)}
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		tags = append(tags, statsdEscape.Replace(k)+":"+statsdEscape.Replace(s.Labels[k]))
+	}
+	return strings.Join(tags, ",")
+}
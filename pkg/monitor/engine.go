@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnsupported is returned for a replication action the engine has no
+// equivalent of.
+var ErrUnsupported = errors.New("not supported by this engine")
+
+// Engine is a kind of server replicas can run on.
+type Engine struct {
+	// Name selects the engine, for example "mysql"
+	Name string
+	// Title is the name shown to people, for example "MySQL"
+	Title string
+	// Driver is the database/sql driver to connect with, "mysql" or "pgx"
+	Driver      string
+	DefaultPort int
+	// Open returns the status source for a server of this engine
+	Open func(db *sql.DB) StatusSource
+}
+
+var (
+	enginesMu sync.RWMutex
+	engines   = make(map[string]Engine)
+)
+
+func init() {
+	RegisterEngine(Engine{Name: "mysql", Title: "MySQL", Driver: "mysql", DefaultPort: 3306,
+		Open: func(db *sql.DB) StatusSource { return &SQLSource{DB: db} }})
+	RegisterEngine(Engine{Name: "mariadb", Title: "MariaDB", Driver: "mysql", DefaultPort: 3306,
+		Open: func(db *sql.DB) StatusSource { return &MariaDBSource{SQLSource: SQLSource{DB: db}} }})
+	RegisterEngine(Engine{Name: "aurora", Title: "Aurora MySQL", Driver: "mysql", DefaultPort: 3306,
+		Open: func(db *sql.DB) StatusSource { return AuroraSource{DB: db} }})
+	RegisterEngine(Engine{Name: "postgres", Title: "PostgreSQL", Driver: "pgx", DefaultPort: 5432,
+		Open: func(db *sql.DB) StatusSource { return PostgresSource{DB: db} }})
+}
+
+// RegisterEngine makes an engine available to LookupEngine. It panics when the
+// name is taken.
+func RegisterEngine(e Engine) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+	if _, ok := engines[e.Name]; ok {
+		panic("monitor: RegisterEngine called twice for " + e.Name)
+	}
+	engines[e.Name] = e
+}
+
+// LookupEngine returns the engine registered under name.
+func LookupEngine(name string) (Engine, bool) {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	e, ok := engines[name]
+	return e, ok
+}
+
+// Engines returns the names of the registered engines, sorted.
+func Engines() []string {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// DetectEngine tells the engine of the server at addr from its handshake,
+// before logging in. MySQL-protocol servers greet the client with their
+// version, which names MariaDB; PostgreSQL waits for the client and answers an
+// SSL request with a single byte. Aurora MySQL cannot be told from MySQL this
+// way; DetectAurora checks a connection for it.
+func DetectEngine(ctx context.Context, addr string) (string, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// Header of the greeting: 3 bytes of length and a sequence number
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err == nil {
+		length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+		payload := make([]byte, min(length, 256))
+		if _, err := io.ReadFull(conn, payload); err != nil {
+			return "", fmt.Errorf("read handshake: %w", err)
+		}
+		// Protocol version 10, then the NUL-terminated server version; an
+		// error packet (0xff) says nothing of the version
+		if len(payload) > 1 && payload[0] == 10 {
+			version, _, _ := strings.Cut(string(payload[1:]), "\x00")
+			if strings.Contains(strings.ToLower(version), "mariadb") {
+				return "mariadb", nil
+			}
+		}
+		return "mysql", nil
+	} else if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		return "", fmt.Errorf("read handshake: %w", err)
+	}
+
+	// Silence: try PostgreSQL's SSLRequest, answered with S or N
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	request := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 8), 80877103)
+	if _, err := conn.Write(request); err != nil {
+		return "", fmt.Errorf("probe for PostgreSQL: %w", err)
+	}
+	answer := make([]byte, 1)
+	if _, err := io.ReadFull(conn, answer); err == nil && (answer[0] == 'S' || answer[0] == 'N') {
+		return "postgres", nil
+	}
+	return "", fmt.Errorf("%s did not answer as MySQL, MariaDB, or PostgreSQL", addr)
+}
+
+// DetectMariaDB reports whether a server detected as MySQL is MariaDB, for
+// connections through a proxy that greets clients in its own name.
+func DetectMariaDB(ctx context.Context, db *sql.DB) bool {
+	var version string
+	return db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version) == nil && strings.Contains(strings.ToLower(version), "mariadb")
+}
+
+// DetectAurora reports whether a server detected as MySQL is Aurora MySQL.
+func DetectAurora(ctx context.Context, db *sql.DB) bool {
+	var version string
+	return db.QueryRowContext(ctx, "SELECT @@aurora_version").Scan(&version) == nil
+}
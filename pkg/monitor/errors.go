@@ -0,0 +1,59 @@
+package monitor
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrorClass is the kind of a polling failure, deciding whether it is retried and
+// when it is surfaced.
+type ErrorClass string
+
+const (
+	ClassTimeout      ErrorClass = "timeout"
+	ClassConnection   ErrorClass = "connection"
+	ClassServerGone   ErrorClass = "server_gone"
+	ClassAccessDenied ErrorClass = "access_denied"
+	ClassOther        ErrorClass = "other"
+)
+
+// ClassifyError tells what kind of failure err from a MySQL query is.
+func ClassifyError(err error) ErrorClass {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1044, 1045, 1142, 1227: // database, user, command, or privilege access denied
+			return ClassAccessDenied
+		case 1053, 1927, 2006, 2013: // shutdown in progress, connection killed, gone away, lost connection
+			return ClassServerGone
+		case 1040: // too many connections
+			return ClassConnection
+		case 1205, 3024: // lock wait timeout, max_execution_time exceeded
+			return ClassTimeout
+		}
+		return ClassOther
+	}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, mysql.ErrInvalidConn),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED),
+		errors.As(err, new(*net.OpError)):
+		return ClassConnection
+	}
+	return ClassOther
+}
+
+// Transient reports whether failures of this kind usually clear up on their own:
+// timeouts, dropped connections, and restarting servers.
+func (c ErrorClass) Transient() bool {
+	return c == ClassTimeout || c == ClassConnection || c == ClassServerGone
+}
@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+)
+
+// Grant is one GRANT statement of SHOW GRANTS.
+type Grant struct {
+	// Privileges are uppercase with column lists dropped, for example
+	// "REPLICATION CLIENT" or "EXECUTE"
+	Privileges []string
+	// Kind is "PROCEDURE" or "FUNCTION" for grants on routines, "" otherwise
+	Kind string
+	// Object is what the privileges apply to, unquoted: *.*, mydb.*, or a
+	// table or routine such as mysql.rds_skip_repl_error
+	Object      string
+	GrantOption bool
+}
+
+// Grants are the privileges of an account on one server.
+type Grants struct {
+	// User is the account as CURRENT_USER() names it, for example admin@%
+	User   string
+	Grants []Grant
+	// Roles are the roles granted to the account, quoted as SHOW GRANTS
+	// quotes them
+	Roles []string
+}
+
+var (
+	privilegeGrant = regexp.MustCompile(`(?is)^GRANT\s+(.+?)\s+ON\s+(?:(PROCEDURE|FUNCTION|TABLE)\s+)?(\S+)\s+TO\s+\S+(.*)$`)
+	roleGrant      = regexp.MustCompile(`(?is)^GRANT\s+(.+?)\s+TO\s+`)
+)
+
+// ParseGrants reads the statements SHOW GRANTS returns, as MySQL and MariaDB
+// print them. Statements it cannot read, such as REVOKE for partial revokes,
+// are left out.
+func ParseGrants(lines []string) Grants {
+	var g Grants
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if m := privilegeGrant.FindStringSubmatch(line); m != nil {
+			kind := strings.ToUpper(m[2])
+			if kind == "TABLE" {
+				kind = ""
+			}
+			g.Grants = append(g.Grants, Grant{
+				Privileges:  splitPrivileges(m[1]),
+				Kind:        kind,
+				Object:      strings.NewReplacer("`", "", "'", "", `"`, "").Replace(m[3]),
+				GrantOption: strings.Contains(strings.ToUpper(m[4]), "WITH GRANT OPTION"),
+			})
+		} else if m := roleGrant.FindStringSubmatch(line); m != nil {
+			for _, role := range strings.Split(m[1], ",") {
+				g.Roles = append(g.Roles, strings.TrimSpace(role))
+			}
+		}
+	}
+	return g
+}
+
+// A comma-separated privilege list, with column lists such as SELECT (a, b)
+// dropped
+func splitPrivileges(list string) []string {
+	var privileges []string
+	depth, start := 0, 0
+	add := func(p string) {
+		if i := strings.IndexByte(p, '('); i >= 0 {
+			p = p[:i]
+		}
+		if p = strings.Join(strings.Fields(strings.ToUpper(p)), " "); p != "" {
+			privileges = append(privileges, p)
+		}
+	}
+	for i, c := range list {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				add(list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	add(list[start:])
+	return privileges
+}
+
+// ReadGrants returns the privileges of the account db is logged in as. On
+// MySQL 8.0 the privileges of the account's roles are included.
+func ReadGrants(ctx context.Context, db *sql.DB) (Grants, error) {
+	var user string
+	if err := db.QueryRowContext(ctx, "SELECT CURRENT_USER()").Scan(&user); err != nil {
+		return Grants{}, err
+	}
+	lines, err := showGrants(ctx, db, "SHOW GRANTS")
+	if err != nil {
+		return Grants{}, err
+	}
+	g := ParseGrants(lines)
+	if len(g.Roles) > 0 {
+		// Only listed when asked for; MariaDB has no USING and its roles stay
+		// unexpanded
+		if lines, err := showGrants(ctx, db, "SHOW GRANTS FOR CURRENT_USER() USING "+strings.Join(g.Roles, ", ")); err == nil {
+			roles := g.Roles
+			g = ParseGrants(lines)
+			g.Roles = roles
+		}
+	}
+	g.User = user
+	return g, nil
+}
+
+func showGrants(ctx context.Context, db *sql.DB, query string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// Allows reports whether the grants include privilege on object, directly,
+// through ALL PRIVILEGES, or through a grant on its database or on *.*. Kind
+// is "PROCEDURE" or "FUNCTION" for routines, "" otherwise.
+func (g Grants) Allows(privilege, kind, object string) bool {
+	db, _, _ := strings.Cut(object, ".")
+	for _, grant := range g.Grants {
+		covers := grant.Object == "*.*" || grant.Object == db+".*" ||
+			(grant.Object == object && grant.Kind == kind)
+		if !covers {
+			continue
+		}
+		for _, p := range grant.Privileges {
+			if p == privilege || p == "ALL" || p == "ALL PRIVILEGES" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package monitor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseGrants(t *testing.T) {
+	g := ParseGrants([]string{
+		"GRANT USAGE ON *.* TO `monitor`@`%`",
+		"GRANT PROCESS, REPLICATION CLIENT ON *.* TO `monitor`@`%`",
+		"GRANT BACKUP_ADMIN,REPLICATION_SLAVE_ADMIN ON *.* TO `monitor`@`%`",
+		"GRANT SELECT (`id`, `name`), INSERT ON `app`.`users` TO `monitor`@`%` WITH GRANT OPTION",
+		"GRANT EXECUTE ON PROCEDURE `mysql`.`rds_skip_repl_error` TO `monitor`@`%`",
+		"GRANT `ops_role`@`%`,`read_role`@`%` TO `monitor`@`%`",
+		"REVOKE INSERT ON `mysql`.* FROM `monitor`@`%`",
+		// MariaDB, which quotes differently and shows the password hash
+		"GRANT SLAVE MONITOR ON *.* TO `monitor`@`%` IDENTIFIED BY PASSWORD '*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19'",
+	})
+	want := []Grant{
+		{Privileges: []string{"USAGE"}, Object: "*.*"},
+		{Privileges: []string{"PROCESS", "REPLICATION CLIENT"}, Object: "*.*"},
+		{Privileges: []string{"BACKUP_ADMIN", "REPLICATION_SLAVE_ADMIN"}, Object: "*.*"},
+		{Privileges: []string{"SELECT", "INSERT"}, Object: "app.users", GrantOption: true},
+		{Privileges: []string{"EXECUTE"}, Kind: "PROCEDURE", Object: "mysql.rds_skip_repl_error"},
+		{Privileges: []string{"SLAVE MONITOR"}, Object: "*.*"},
+	}
+	if !slices.EqualFunc(g.Grants, want, func(a, b Grant) bool {
+		return slices.Equal(a.Privileges, b.Privileges) && a.Kind == b.Kind && a.Object == b.Object && a.GrantOption == b.GrantOption
+	}) {
+		t.Errorf("Grants = %+v, want %+v", g.Grants, want)
+	}
+	if roles := []string{"`ops_role`@`%`", "`read_role`@`%`"}; !slices.Equal(g.Roles, roles) {
+		t.Errorf("Roles = %q, want %q", g.Roles, roles)
+	}
+}
+
+func TestGrantsAllows(t *testing.T) {
+	g := ParseGrants([]string{
+		"GRANT REPLICATION CLIENT ON *.* TO `monitor`@`%`",
+		"GRANT EXECUTE ON `mysql`.* TO `monitor`@`%`",
+		"GRANT ALL PRIVILEGES ON `app`.* TO `monitor`@`%`",
+		"GRANT EXECUTE ON FUNCTION `util`.`lag` TO `monitor`@`%`",
+	})
+	tests := []struct {
+		privilege, kind, object string
+		want                    bool
+	}{
+		{"REPLICATION CLIENT", "", "*.*", true},
+		{"SUPER", "", "*.*", false},
+		{"EXECUTE", "PROCEDURE", "mysql.rds_skip_repl_error", true},
+		{"DROP", "", "app.orders", true},
+		{"DROP", "", "mysql.user", false},
+		{"EXECUTE", "FUNCTION", "util.lag", true},
+		{"EXECUTE", "PROCEDURE", "util.lag", false},
+	}
+	for _, tt := range tests {
+		if got := g.Allows(tt.privilege, tt.kind, tt.object); got != tt.want {
+			t.Errorf("Allows(%q, %q, %q) = %v, want %v", tt.privilege, tt.kind, tt.object, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Health is what the latest poll of a replica found, the input to Check,
+// CatchUp, ShouldSkip, and Transitions.
+type Health struct {
+	// Polled is set once the replica's status has been read; Failing while
+	// the latest poll failed
+	Polled  bool
+	Failing bool
+	// NoThreads is set for replicas without replication threads, such as
+	// Aurora readers, whose IORunning and SQLRunning are not looked at
+	NoThreads  bool
+	IORunning  string
+	SQLRunning string
+	// ErrorMatched is set when Last_SQL_Error matched an error pattern
+	ErrorMatched bool
+	// LagSeconds is the replication lag, when LagKnown
+	LagKnown   bool
+	LagSeconds float64
+	// TLSKnown is set once it is known whether the replica's stream from its
+	// source is encrypted; TLSStream describes the stream
+	TLSKnown     bool
+	TLSEncrypted bool
+	TLSStream    string
+}
+
+// Running reports whether both replication threads run.
+func (h Health) Running() bool {
+	return h.IORunning == "Yes" && h.SQLRunning == "Yes"
+}
+
+// DurationFormat formats a lag in seconds for a message; nil formats it as a
+// time.Duration.
+type DurationFormat func(seconds float64) string
+
+func (f DurationFormat) format(seconds float64) string {
+	if f == nil {
+		return time.Duration(seconds * float64(time.Second)).String()
+	}
+	return f(seconds)
+}
+
+// CheckState is the outcome of Check, numbered as a Nagios plugin's exit status.
+type CheckState int
+
+const (
+	CheckOK       CheckState = 0
+	CheckWarning  CheckState = 1
+	CheckCritical CheckState = 2
+	CheckUnknown  CheckState = 3
+)
+
+func (s CheckState) String() string {
+	switch s {
+	case CheckOK:
+		return "OK"
+	case CheckWarning:
+		return "WARNING"
+	case CheckCritical:
+		return "CRITICAL"
+	case CheckUnknown:
+		return "UNKNOWN"
+	}
+	return fmt.Sprintf("CheckState(%d)", int(s))
+}
+
+// CheckOptions are the thresholds Check holds a replica to; a zero lag
+// threshold is not checked.
+type CheckOptions struct {
+	WarnLag time.Duration
+	MaxLag  time.Duration
+	// RequireTLS makes an unencrypted stream from the source critical
+	RequireTLS     bool
+	FormatDuration DurationFormat
+}
+
+// Check judges a replica's health: critical when a thread is stopped, an error
+// matched, or the lag is above MaxLag; warning when the lag is above WarnLag;
+// unknown when the replica or its lag could not be read. The message lists what
+// is wrong, and the lag.
+func Check(h Health, opts CheckOptions) (CheckState, string) {
+	state := CheckOK
+	var problems []string
+	switch {
+	case !h.Polled:
+		state = CheckUnknown
+		problems = append(problems, "could not be polled")
+	case !h.NoThreads && h.IORunning == "" && h.SQLRunning == "":
+		state = CheckUnknown
+		problems = append(problems, "no replica status")
+	default:
+		if !h.NoThreads && !h.Running() {
+			state = CheckCritical
+			problems = append(problems, fmt.Sprintf("IO thread %s, SQL thread %s", h.IORunning, h.SQLRunning))
+		}
+		if h.ErrorMatched {
+			state = CheckCritical
+			problems = append(problems, "error pattern matched in Last_SQL_Error")
+		}
+		if opts.RequireTLS && h.TLSKnown && !h.TLSEncrypted {
+			state = CheckCritical
+			problems = append(problems, "replication stream "+h.TLSStream)
+		}
+		lag := opts.FormatDuration.format(h.LagSeconds)
+		switch {
+		case !h.LagKnown:
+			if state == CheckOK {
+				state = CheckUnknown
+			}
+			problems = append(problems, "lag is NULL")
+		case opts.MaxLag > 0 && h.LagSeconds > opts.MaxLag.Seconds():
+			state = CheckCritical
+			problems = append(problems, fmt.Sprintf("lag %s exceeds %ds", lag, int(opts.MaxLag.Seconds())))
+		case opts.WarnLag > 0 && h.LagSeconds > opts.WarnLag.Seconds():
+			if state == CheckOK {
+				state = CheckWarning
+			}
+			problems = append(problems, fmt.Sprintf("lag %s exceeds %ds", lag, int(opts.WarnLag.Seconds())))
+		default:
+			problems = append(problems, "lag "+lag)
+		}
+	}
+	return state, strings.Join(problems, "; ")
+}
+
+// WorstState combines the states of several checks: critical outranks unknown,
+// which outranks warning.
+func WorstState(states ...CheckState) CheckState {
+	rank := map[CheckState]int{CheckOK: 0, CheckWarning: 1, CheckUnknown: 2, CheckCritical: 3}
+	worst := CheckOK
+	for _, s := range states {
+		if rank[s] > rank[worst] {
+			worst = s
+		}
+	}
+	return worst
+}
+
+// CatchUpState is where a replica stands while waiting for it to catch up.
+type CatchUpState int
+
+const (
+	Behind   CatchUpState = iota // not caught up yet, or not known to be
+	CaughtUp                     // lag at or under the limit
+	Stopped                      // a replication thread stopped, so waiting is futile
+	NoStatus                     // the server has no replica status, so it is not a replica to wait for
+)
+
+// CatchUp tells whether a replica's lag is at most maxLag, with what the
+// verdict rests on. An IO thread reconnecting to its source, as after a
+// failover, counts as behind rather than stopped, since it retries by itself.
+func CatchUp(h Health, maxLag time.Duration, format DurationFormat) (CatchUpState, string) {
+	switch {
+	case h.Failing || !h.Polled:
+		return Behind, "could not be polled"
+	case !h.NoThreads && h.IORunning == "" && h.SQLRunning == "":
+		return NoStatus, "no replica status"
+	case !h.NoThreads && h.IORunning != "" && ((h.IORunning != "Yes" && h.IORunning != "Connecting") || h.SQLRunning != "Yes"):
+		return Stopped, fmt.Sprintf("IO thread %s, SQL thread %s", h.IORunning, h.SQLRunning)
+	case !h.NoThreads && h.IORunning == "Connecting":
+		return Behind, "IO thread connecting to its source"
+	case !h.LagKnown:
+		return Behind, "lag unknown"
+	case h.LagSeconds > maxLag.Seconds():
+		return Behind, "lag " + format.format(h.LagSeconds)
+	}
+	return CaughtUp, "lag " + format.format(h.LagSeconds)
+}
+
+// ShouldSkip reports whether the failing transaction a poll found is to be
+// skipped: only with autoSkip, and only when Last_SQL_Error matched an error
+// pattern, so that errors nobody chose to skip are left for a person.
+func ShouldSkip(h Health, autoSkip bool) bool {
+	return autoSkip && h.ErrorMatched
+}
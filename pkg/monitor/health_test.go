@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	running := Health{Polled: true, IORunning: "Yes", SQLRunning: "Yes", LagKnown: true, LagSeconds: 30}
+	opts := CheckOptions{WarnLag: 20 * time.Second, MaxLag: time.Minute}
+	tests := []struct {
+		name    string
+		health  func(Health) Health
+		state   CheckState
+		message string
+	}{
+		{"warning lag", func(h Health) Health { return h }, CheckWarning, "lag 30s exceeds 20s"},
+		{"critical lag", func(h Health) Health { h.LagSeconds = 90; return h }, CheckCritical, "lag 1m30s exceeds 60s"},
+		{"not polled", func(Health) Health { return Health{} }, CheckUnknown, "could not be polled"},
+		{"no status", func(Health) Health { return Health{Polled: true} }, CheckUnknown, "no replica status"},
+		{"NULL lag", func(h Health) Health { h.LagKnown = false; return h }, CheckUnknown, "lag is NULL"},
+		{"stopped", func(h Health) Health { h.SQLRunning, h.LagKnown = "No", false; return h }, CheckCritical, "IO thread Yes, SQL thread No; lag is NULL"},
+		{"matched", func(h Health) Health { h.ErrorMatched, h.LagSeconds = true, 5; return h }, CheckCritical, "error pattern matched in Last_SQL_Error; lag 5s"},
+		{"no threads", func(h Health) Health { h.NoThreads, h.IORunning, h.LagSeconds = true, "", 5; return h }, CheckOK, "lag 5s"},
+	}
+	for _, tt := range tests {
+		state, message := Check(tt.health(running), opts)
+		if state != tt.state || message != tt.message {
+			t.Errorf("%s: Check = %s, %q; want %s, %q", tt.name, state, message, tt.state, tt.message)
+		}
+	}
+	if s := WorstState(CheckWarning, CheckCritical, CheckUnknown); s != CheckCritical {
+		t.Errorf("WorstState = %s; want CRITICAL", s)
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	tests := []struct {
+		health Health
+		state  CatchUpState
+		why    string
+	}{
+		{Health{Polled: true, IORunning: "Yes", SQLRunning: "Yes", LagKnown: true, LagSeconds: 5}, CaughtUp, "lag 5s"},
+		{Health{Polled: true, IORunning: "Yes", SQLRunning: "Yes", LagKnown: true, LagSeconds: 60}, Behind, "lag 1m0s"},
+		{Health{Polled: true, IORunning: "Connecting", SQLRunning: "Yes"}, Behind, "IO thread connecting to its source"},
+		{Health{Polled: true, IORunning: "No", SQLRunning: "Yes"}, Stopped, "IO thread No, SQL thread Yes"},
+		{Health{Polled: true, Failing: true}, Behind, "could not be polled"},
+		{Health{Polled: true}, NoStatus, "no replica status"},
+		{Health{Polled: true, NoThreads: true, LagKnown: true}, CaughtUp, "lag 0s"},
+	}
+	for _, tt := range tests {
+		if state, why := CatchUp(tt.health, 10*time.Second, nil); state != tt.state || why != tt.why {
+			t.Errorf("CatchUp(%+v) = %d, %q; want %d, %q", tt.health, state, why, tt.state, tt.why)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package monitor
+
+import "fmt"
+
+// InstanceInfo is what the RDS API says about a replica's instance.
+type InstanceInfo struct {
+	Class         string `json:"class"`
+	StorageType   string `json:"storage_type"`
+	StorageGB     int32  `json:"storage_gb"`
+	IOPS          int32  `json:"iops,omitempty"`
+	MultiAZ       bool   `json:"multi_az"`
+	Engine        string `json:"engine"`
+	EngineVersion string `json:"engine_version"`
+}
+
+// String describes the instance on one line, e.g.
+// "db.r6g.xlarge, gp3 500 GB 12000 IOPS, Single-AZ, mysql 8.0.35".
+func (i *InstanceInfo) String() string {
+	storage := fmt.Sprintf("%s %d GB", i.StorageType, i.StorageGB)
+	if i.IOPS > 0 {
+		storage += fmt.Sprintf(" %d IOPS", i.IOPS)
+	}
+	az := "Single-AZ"
+	if i.MultiAZ {
+		az = "Multi-AZ"
+	}
+	return fmt.Sprintf("%s, %s, %s, %s %s", i.Class, storage, az, i.Engine, i.EngineVersion)
+}
@@ -0,0 +1,26 @@
+package monitor
+
+import (
+	"context"
+)
+
+// MariaDBSource is the StatusSource for MariaDB. Status and starting and
+// stopping work as for MySQL; outside RDS an error is skipped with the skip
+// counter, which MariaDB honors with GTIDs too.
+type MariaDBSource struct {
+	SQLSource
+}
+
+func (s *MariaDBSource) SkipError(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, "CALL mysql.rds_skip_repl_error")
+	if !isMissingProcedure(err) {
+		return err
+	}
+	// The counter is read when the SQL thread starts
+	for _, stmt := range []string{"STOP SLAVE SQL_THREAD", "SET GLOBAL sql_slave_skip_counter = 1", "START SLAVE SQL_THREAD"} {
+		if _, err := s.DB.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+)
+
+// MockSource is a StatusSource that replays canned status rows and counts the
+// actions sent to it, so lag math, error matching, and alerting can be exercised
+// without a server.
+type MockSource struct {
+	mu sync.Mutex
+	// Rows are returned in turn by ReplicaStatus, the last one repeating
+	Rows []*StatusRow
+	// StatusErr is returned by ReplicaStatus instead of a row when set
+	StatusErr error
+	// ActionErr is returned by SkipError, StartReplication, and StopReplication
+	ActionErr error
+
+	polls                int
+	skips, starts, stops int
+}
+
+// MockRow builds a status row from alternating column names and values; a nil
+// value stands for NULL.
+func MockRow(pairs ...any) *StatusRow {
+	row := &StatusRow{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		row.Columns = append(row.Columns, pairs[i].(string))
+		row.Values = append(row.Values, pairs[i+1])
+	}
+	return row
+}
+
+func (m *MockSource) ReplicaStatus(ctx context.Context) (*StatusRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.polls++
+	if m.StatusErr != nil {
+		return nil, m.StatusErr
+	}
+	if len(m.Rows) == 0 {
+		return nil, nil
+	}
+	row := m.Rows[0]
+	if len(m.Rows) > 1 {
+		m.Rows = m.Rows[1:]
+	}
+	return row, nil
+}
+
+func (m *MockSource) SkipError(ctx context.Context) error {
+	return m.action(&m.skips)
+}
+
+func (m *MockSource) StartReplication(ctx context.Context) error {
+	return m.action(&m.starts)
+}
+
+func (m *MockSource) StopReplication(ctx context.Context) error {
+	return m.action(&m.stops)
+}
+
+func (m *MockSource) action(count *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ActionErr != nil {
+		return m.ActionErr
+	}
+	*count++
+	return nil
+}
+
+// Calls returns how many times the status was read and each action succeeded.
+func (m *MockSource) Calls() (polls, skips, starts, stops int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.polls, m.skips, m.starts, m.stops
+}
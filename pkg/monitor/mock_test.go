@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Rate math and error matching driven by canned rows, as the CLI's tests use them
+func TestMockSource(t *testing.T) {
+	m := &MockSource{Rows: []*StatusRow{
+		MockRow("Seconds_Behind_Source", "100", "Last_SQL_Error", ""),
+		MockRow("Seconds_Behind_Source", "40", "Last_SQL_Error", ""),
+		MockRow("Seconds_Behind_Source", nil, "Last_SQL_Error", "Coordinator stopped because there were error(s) in the worker(s)."),
+	}}
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	stats := NewLagStats(5)
+	var values []string
+	for i := range 4 {
+		row, err := m.ReplicaStatus(ctx)
+		if err != nil || row == nil {
+			t.Fatalf("poll %d: %v, %v", i, row, err)
+		}
+		v := row.Value("Seconds_Behind_Source")
+		values = append(values, v)
+		// Polls 30s apart, as the CLI folds them in; NULL lag is skipped
+		if seconds, err := strconv.Atoi(v); err == nil {
+			stats.Update(seconds, start.Add(time.Duration(i)*30*time.Second))
+		}
+	}
+	// The last row repeats, and NULL reads as ""
+	if values[0] != "100" || values[1] != "40" || values[2] != "" || values[3] != "" {
+		t.Errorf("values %q", values)
+	}
+	if stats.Rate != -2 || stats.AverageRate != -2 || stats.LastSecondsBehind != 40 {
+		t.Errorf("rate %v, average %v, last %d; want -2, -2, 40", stats.Rate, stats.AverageRate, stats.LastSecondsBehind)
+	}
+	if want := start.Add(30*time.Second + 20*time.Second); !stats.EstimatedTime.Equal(want) {
+		t.Errorf("ETA %v; want %v", stats.EstimatedTime, want)
+	}
+	row, _ := m.ReplicaStatus(ctx)
+	if matched, err := MatchErrors(DefaultErrorPatterns, row.Value("Last_SQL_Error")); err != nil || len(matched) != 1 {
+		t.Errorf("MatchErrors = %q, %v; want the default pattern", matched, err)
+	}
+
+	if err := m.SkipError(ctx); err != nil {
+		t.Fatal(err)
+	}
+	m.ActionErr = errors.New("denied")
+	if err := m.StartReplication(ctx); !errors.Is(err, m.ActionErr) {
+		t.Errorf("StartReplication = %v; want ActionErr", err)
+	}
+	m.StatusErr = errors.New("gone away")
+	if _, err := m.ReplicaStatus(ctx); !errors.Is(err, m.StatusErr) {
+		t.Errorf("ReplicaStatus = %v; want StatusErr", err)
+	}
+	// Only actions that succeeded are counted
+	if polls, skips, starts, stops := m.Calls(); polls != 6 || skips != 1 || starts != 0 || stops != 0 {
+		t.Errorf("Calls() = %d, %d, %d, %d; want 6, 1, 0, 0", polls, skips, starts, stops)
+	}
+}
@@ -0,0 +1,321 @@
+package monitor
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Config describes what a Monitor watches and how.
+type Config struct {
+	// Source is where the replica's status is read; required
+	Source StatusSource
+	// Name identifies the replica in samples and events
+	Name string
+	// Interval between polls (5s)
+	Interval time.Duration
+	// Timeout for each status read and skip, none when zero
+	Timeout time.Duration
+	// ErrorPatterns are regular expressions matched against Last_SQL_Error
+	// (DefaultErrorPatterns)
+	ErrorPatterns []string
+	// AutoSkip skips the failing transaction whenever an error pattern matches
+	AutoSkip bool
+	// RateWindow is how many recent rates the ETA band is drawn from (10)
+	RateWindow int
+
+	// OnSample and OnEvent, when set, are called synchronously from Run for
+	// every sample and event, in addition to any channel delivery
+	OnSample func(Sample)
+	OnEvent  func(Event)
+}
+
+// Sample is the replica's state at one poll.
+type Sample struct {
+	Time    time.Time
+	Replica string
+	// SecondsBehind is the replication lag, nil when the server reports NULL
+	// (for example with a stopped thread)
+	SecondsBehind *int
+	IORunning     string
+	SQLRunning    string
+	LastSQLError  string
+	// MatchedPatterns are the ErrorPatterns that match LastSQLError
+	MatchedPatterns []string
+	// Catch-up rates in seconds of lag per second, negative while catching up,
+	// and when the replica should catch up at Rate; see LagStats
+	Rate          float64
+	AverageRate   float64
+	EstimatedTime time.Time
+	// Status is every column of the status row, and Row the row itself with
+	// its columns in the server's order
+	Status map[string]string
+	Row    *StatusRow
+}
+
+// EventKind names something that happened to a replica.
+type EventKind string
+
+const (
+	EventPollFailed     EventKind = "poll_failed"     // the status could not be read; Err says why
+	EventPollRecovered  EventKind = "poll_recovered"  // the status was read again after failures
+	EventNotReplica     EventKind = "not_replica"     // the server reports no replica status
+	EventThreadsStopped EventKind = "threads_stopped" // the IO or SQL thread stopped
+	EventThreadsRunning EventKind = "threads_running" // both threads run again
+	EventErrorMatched   EventKind = "error_matched"   // Last_SQL_Error matches an error pattern
+	EventErrorCleared   EventKind = "error_cleared"   // the matched error is gone
+	EventSkipped        EventKind = "skipped"         // AutoSkip skipped the failing transaction
+	EventSkipFailed     EventKind = "skip_failed"     // AutoSkip could not skip; Err says why
+	EventCaughtUp       EventKind = "caught_up"       // the lag fell to zero
+)
+
+// ErrNotReplica is returned by Poll when the server reports no replica status.
+var ErrNotReplica = errors.New("monitor: the server reports no replica status")
+
+// Event reports a change in a replica's state.
+type Event struct {
+	Time    time.Time
+	Replica string
+	Kind    EventKind
+	Message string
+	Err     error
+}
+
+// Monitor polls one replica, turning its status into samples and events.
+type Monitor struct {
+	cfg     Config
+	stats   LagStats
+	samples chan Sample
+	events  chan Event
+
+	// State carried between polls to tell when an event happens
+	failing     bool
+	notReplica  bool
+	stopped     bool
+	lastError   string
+	lastSeconds *int
+}
+
+// New returns a Monitor for cfg, filling in defaults.
+func New(cfg Config) (*Monitor, error) {
+	if cfg.Source == nil {
+		return nil, errors.New("monitor: Config.Source is required")
+	}
+	cfg.Interval = cmp.Or(cfg.Interval, 5*time.Second)
+	cfg.RateWindow = cmp.Or(cfg.RateWindow, 10)
+	m := &Monitor{cfg: cfg, stats: NewLagStats(cfg.RateWindow)}
+	if err := m.SetErrorPatterns(cfg.ErrorPatterns); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SetErrorPatterns replaces the error patterns, DefaultErrorPatterns when nil,
+// for the next poll, as when a program reloads its configuration. It keeps the
+// old ones when a pattern does not compile. Like Stats, it is not safe to call
+// while Run is running.
+func (m *Monitor) SetErrorPatterns(patterns []string) error {
+	if patterns == nil {
+		patterns = DefaultErrorPatterns
+	}
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("monitor: error pattern %q: %w", pattern, err)
+		}
+	}
+	m.cfg.ErrorPatterns = patterns
+	return nil
+}
+
+// Samples returns a channel receiving every sample, closed when Run returns. It
+// must be called before Run; Run waits for each sample to be received, so a
+// caller that asks for the channel has to keep reading it.
+func (m *Monitor) Samples() <-chan Sample {
+	if m.samples == nil {
+		m.samples = make(chan Sample, 16)
+	}
+	return m.samples
+}
+
+// Events returns a channel receiving every event, closed when Run returns. Like
+// Samples, it must be called before Run and read until it closes.
+func (m *Monitor) Events() <-chan Event {
+	if m.events == nil {
+		m.events = make(chan Event, 16)
+	}
+	return m.events
+}
+
+// Stats returns the catch-up statistics so far. It is not safe to call while
+// Run is running; use the rates in each Sample instead.
+func (m *Monitor) Stats() LagStats {
+	return m.stats
+}
+
+// ResetStats starts the catch-up statistics over, as after the replica restarts,
+// when rates across the gap would mislead the ETA. It is not safe to call while
+// Run is running.
+func (m *Monitor) ResetStats() {
+	m.stats = NewLagStats(m.cfg.RateWindow)
+}
+
+// Run polls the replica every Interval until ctx is done, then closes the
+// Samples and Events channels and returns ctx.Err(). Polling failures are
+// reported as events and do not stop it.
+func (m *Monitor) Run(ctx context.Context) error {
+	defer func() {
+		if m.samples != nil {
+			close(m.samples)
+		}
+		if m.events != nil {
+			close(m.events)
+		}
+	}()
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		sample, events, err := m.Poll(ctx)
+		if err == nil {
+			if m.cfg.OnSample != nil {
+				m.cfg.OnSample(sample)
+			}
+			if m.samples != nil {
+				select {
+				case m.samples <- sample:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+		for _, e := range events {
+			if m.cfg.OnEvent != nil {
+				m.cfg.OnEvent(e)
+			}
+			if m.events != nil {
+				select {
+				case m.events <- e:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll reads the replica status once, skipping a matched error with AutoSkip,
+// and returns the sample with the events it caused. err is set, with an
+// EventPollFailed among the events when it is new, when no sample could be
+// taken. Run calls Poll; it is exported for callers with their own schedule.
+func (m *Monitor) Poll(ctx context.Context) (Sample, []Event, error) {
+	now := time.Now()
+	var events []Event
+	event := func(kind EventKind, message string, err error) {
+		events = append(events, Event{Time: now, Replica: m.cfg.Name, Kind: kind, Message: message, Err: err})
+	}
+
+	readCtx, cancel := m.withTimeout(ctx)
+	row, err := m.cfg.Source.ReplicaStatus(readCtx)
+	cancel()
+	if err != nil {
+		if !m.failing {
+			event(EventPollFailed, fmt.Sprintf("reading replica status failed (%s)", ClassifyError(err)), err)
+		}
+		m.failing = true
+		return Sample{}, events, err
+	}
+	if m.failing {
+		m.failing = false
+		event(EventPollRecovered, "replica status can be read again", nil)
+	}
+	if row == nil {
+		if !m.notReplica {
+			event(EventNotReplica, "the server reports no replica status", nil)
+		}
+		m.notReplica = true
+		return Sample{}, events, ErrNotReplica
+	}
+	m.notReplica = false
+
+	sample := Sample{
+		Time:         now,
+		Replica:      m.cfg.Name,
+		IORunning:    row.Value("Replica_IO_Running"),
+		SQLRunning:   row.Value("Replica_SQL_Running"),
+		LastSQLError: row.Value("Last_SQL_Error"),
+		Status:       row.Map(),
+		Row:          row,
+	}
+	if v := row.Value("Seconds_Behind_Source"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			sample.SecondsBehind = &seconds
+			m.stats.Update(seconds, now)
+			sample.Rate = m.stats.Rate
+			sample.AverageRate = m.stats.AverageRate
+			sample.EstimatedTime = m.stats.EstimatedTime
+		}
+	}
+
+	stopped := sample.IORunning != "Yes" || sample.SQLRunning != "Yes"
+	if stopped && !m.stopped {
+		event(EventThreadsStopped, fmt.Sprintf("IO thread %s, SQL thread %s", cmp.Or(sample.IORunning, "unknown"), cmp.Or(sample.SQLRunning, "unknown")), nil)
+	} else if !stopped && m.stopped {
+		event(EventThreadsRunning, "both replication threads are running", nil)
+	}
+	m.stopped = stopped
+
+	sample.MatchedPatterns, err = MatchErrors(m.cfg.ErrorPatterns, sample.LastSQLError)
+	if err != nil {
+		return sample, events, err
+	}
+	if len(sample.MatchedPatterns) > 0 {
+		if sample.LastSQLError != m.lastError {
+			event(EventErrorMatched, sample.LastSQLError, nil)
+		}
+		m.lastError = sample.LastSQLError
+		if m.cfg.AutoSkip {
+			skipCtx, cancel := m.withTimeout(ctx)
+			err := m.cfg.Source.SkipError(skipCtx)
+			cancel()
+			if err != nil {
+				event(EventSkipFailed, "skipping the failing transaction failed", err)
+			} else {
+				event(EventSkipped, "skipped the failing transaction: "+sample.LastSQLError, nil)
+			}
+		}
+	} else if m.lastError != "" {
+		event(EventErrorCleared, "the replication error is gone", nil)
+		m.lastError = ""
+	}
+
+	if sample.SecondsBehind != nil && *sample.SecondsBehind == 0 && m.lastSeconds != nil && *m.lastSeconds > 0 {
+		event(EventCaughtUp, fmt.Sprintf("caught up from %ds behind", *m.lastSeconds), nil)
+	}
+	m.lastSeconds = sample.SecondsBehind
+	return sample, events, nil
+}
+
+// Matched reports whether the sample's Last_SQL_Error matched an error pattern.
+func (s Sample) Matched() bool {
+	return len(s.MatchedPatterns) > 0
+}
+
+// Running reports whether both replication threads are running.
+func (s Sample) Running() bool {
+	return s.IORunning == "Yes" && s.SQLRunning == "Yes"
+}
+
+func (m *Monitor) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.cfg.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, m.cfg.Timeout)
+}
@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// A status row with the columns Poll reads
+func mockStatus(seconds any, sqlRunning, lastError string) *StatusRow {
+	return MockRow(
+		"Replica_IO_Running", "Yes",
+		"Replica_SQL_Running", sqlRunning,
+		"Last_SQL_Error", lastError,
+		"Seconds_Behind_Source", seconds,
+	)
+}
+
+// Kinds of the events one poll returned
+func kinds(events []Event) []EventKind {
+	var k []EventKind
+	for _, e := range events {
+		k = append(k, e.Kind)
+	}
+	return k
+}
+
+func TestMonitorPoll(t *testing.T) {
+	const coordinator = "Coordinator stopped because there were error(s) in the worker(s)."
+	source := &MockSource{Rows: []*StatusRow{
+		mockStatus(nil, "No", coordinator),
+		mockStatus(nil, "No", coordinator),
+		mockStatus("120", "Yes", ""),
+		mockStatus("60", "Yes", ""),
+		mockStatus("0", "Yes", ""),
+		mockStatus("0", "Yes", ""),
+	}}
+	m, err := New(Config{Source: source, Name: "db1", AutoSkip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]EventKind{
+		{EventThreadsStopped, EventErrorMatched, EventSkipped},
+		// The same error again is not reported again, but skipped again
+		{EventSkipped},
+		{EventThreadsRunning, EventErrorCleared},
+		nil,
+		{EventCaughtUp},
+		nil,
+	}
+	for i, w := range want {
+		sample, events, err := m.Poll(context.Background())
+		if err != nil {
+			t.Fatalf("poll %d: %v", i, err)
+		}
+		if got := kinds(events); !slices.Equal(got, w) {
+			t.Errorf("poll %d: events %v; want %v", i, got, w)
+		}
+		if sample.Replica != "db1" || (i < 2) != sample.Matched() || (i < 2) == sample.Running() {
+			t.Errorf("poll %d: sample %+v", i, sample)
+		}
+		for _, e := range events {
+			if e.Kind == EventCaughtUp && e.Message != "caught up from 60s behind" {
+				t.Errorf("caught_up message %q", e.Message)
+			}
+		}
+	}
+	if polls, skips, _, _ := source.Calls(); polls != 6 || skips != 2 {
+		t.Errorf("%d polls, %d skips; want 6 and 2", polls, skips)
+	}
+	if s := m.Stats(); s.StartSecondsBehind != 120 || s.LastSecondsBehind != 0 {
+		t.Errorf("stats from %ds to %ds; want from 120s to 0s", s.StartSecondsBehind, s.LastSecondsBehind)
+	}
+}
+
+func TestMonitorPollSkipFailed(t *testing.T) {
+	denied := errors.New("access denied")
+	source := &MockSource{
+		Rows:      []*StatusRow{mockStatus(nil, "No", "Coordinator stopped")},
+		ActionErr: denied,
+	}
+	m, err := New(Config{Source: source, AutoSkip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, events, err := m.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, w := kinds(events), []EventKind{EventThreadsStopped, EventErrorMatched, EventSkipFailed}; !slices.Equal(got, w) {
+		t.Fatalf("events %v; want %v", got, w)
+	}
+	if !errors.Is(events[2].Err, denied) {
+		t.Errorf("skip_failed error %v; want %v", events[2].Err, denied)
+	}
+
+	// Without AutoSkip a matched error is only reported
+	source = &MockSource{Rows: []*StatusRow{mockStatus(nil, "No", "Coordinator stopped")}}
+	m, _ = New(Config{Source: source})
+	_, events, _ = m.Poll(context.Background())
+	if got, w := kinds(events), []EventKind{EventThreadsStopped, EventErrorMatched}; !slices.Equal(got, w) {
+		t.Errorf("events %v without AutoSkip; want %v", got, w)
+	}
+	if _, skips, _, _ := source.Calls(); skips != 0 {
+		t.Errorf("%d skips without AutoSkip", skips)
+	}
+}
+
+func TestMonitorPollFailed(t *testing.T) {
+	source := &MockSource{StatusErr: context.DeadlineExceeded}
+	m, _ := New(Config{Source: source})
+	for i, w := range [][]EventKind{{EventPollFailed}, nil} {
+		if _, events, err := m.Poll(context.Background()); err == nil || !slices.Equal(kinds(events), w) {
+			t.Errorf("failing poll %d: events %v, err %v; want %v and an error", i, kinds(events), err, w)
+		}
+	}
+	source.StatusErr = nil
+	source.Rows = []*StatusRow{mockStatus("0", "Yes", "")}
+	if _, events, err := m.Poll(context.Background()); err != nil || !slices.Equal(kinds(events), []EventKind{EventPollRecovered}) {
+		t.Errorf("recovered poll: events %v, err %v; want poll_recovered", kinds(events), err)
+	}
+}
+
+// Patterns and statistics a program such as the CLI changes between polls
+func TestMonitorReconfigure(t *testing.T) {
+	const failure = "Duplicate entry '42' for key 'PRIMARY'"
+	source := &MockSource{Rows: []*StatusRow{mockStatus("120", "No", failure), mockStatus("60", "No", failure)}}
+	m, _ := New(Config{Source: source})
+	ctx := context.Background()
+	if s, _, err := m.Poll(ctx); err != nil || s.Matched() || s.Row == nil || s.Row.Value("Last_SQL_Error") != failure {
+		t.Fatalf("first poll: matched %v, row %v, err %v; want the row unmatched by the default patterns", s.MatchedPatterns, s.Row, err)
+	}
+	if err := m.SetErrorPatterns([]string{"("}); err == nil {
+		t.Error("SetErrorPatterns accepted a pattern that does not compile")
+	}
+	if err := m.SetErrorPatterns([]string{"Duplicate entry"}); err != nil {
+		t.Fatal(err)
+	}
+	if s, _, err := m.Poll(ctx); err != nil || !s.Matched() {
+		t.Errorf("after SetErrorPatterns: matched %v, err %v; want the new pattern", s.MatchedPatterns, err)
+	}
+	if st := m.Stats(); st.StartSecondsBehind != 120 {
+		t.Errorf("statistics start at %d; want 120", st.StartSecondsBehind)
+	}
+	m.ResetStats()
+	if st := m.Stats(); !st.StartTime.IsZero() {
+		t.Errorf("statistics after ResetStats: %+v; want them empty", st)
+	}
+
+	source.Rows = nil
+	if _, _, err := m.Poll(ctx); !errors.Is(err, ErrNotReplica) {
+		t.Errorf("Poll without a status row = %v; want ErrNotReplica", err)
+	}
+}
@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// StatusColumns are the columns of MySQL 8.0's SHOW REPLICA STATUS, in the
+// order the server sends them. Status rows are normalized to these names.
+var StatusColumns = []string{
+	"Replica_IO_State", "Source_Host", "Source_User", "Source_Port", "Connect_Retry",
+	"Source_Log_File", "Read_Source_Log_Pos", "Relay_Log_File", "Relay_Log_Pos", "Relay_Source_Log_File",
+	"Replica_IO_Running", "Replica_SQL_Running",
+	"Replicate_Do_DB", "Replicate_Ignore_DB", "Replicate_Do_Table", "Replicate_Ignore_Table",
+	"Replicate_Wild_Do_Table", "Replicate_Wild_Ignore_Table",
+	"Last_Errno", "Last_Error", "Skip_Counter", "Exec_Source_Log_Pos", "Relay_Log_Space",
+	"Until_Condition", "Until_Log_File", "Until_Log_Pos",
+	"Source_SSL_Allowed", "Source_SSL_CA_File", "Source_SSL_CA_Path", "Source_SSL_Cert", "Source_SSL_Cipher", "Source_SSL_Key",
+	"Seconds_Behind_Source", "Source_SSL_Verify_Server_Cert",
+	"Last_IO_Errno", "Last_IO_Error", "Last_SQL_Errno", "Last_SQL_Error",
+	"Replicate_Ignore_Server_Ids", "Source_Server_Id", "Source_UUID", "Source_Info_File",
+	"SQL_Delay", "SQL_Remaining_Delay", "Replica_SQL_Running_State", "Source_Retry_Count", "Source_Bind",
+	"Last_IO_Error_Timestamp", "Last_SQL_Error_Timestamp", "Source_SSL_Crl", "Source_SSL_Crlpath",
+	"Retrieved_Gtid_Set", "Executed_Gtid_Set", "Auto_Position", "Replicate_Rewrite_DB", "Channel_Name",
+	"Source_TLS_Version", "Source_public_key_path", "Get_Source_public_key", "Network_Namespace",
+}
+
+// Columns every Sampler fills in; rows without them are reported
+var requiredColumns = []string{"Replica_IO_Running", "Replica_SQL_Running", "Last_SQL_Error", "Seconds_Behind_Source"}
+
+// StatusColumns by lowercase name
+var knownColumns = func() map[string]string {
+	m := make(map[string]string, len(StatusColumns))
+	for _, col := range StatusColumns {
+		m[strings.ToLower(col)] = col
+	}
+	return m
+}()
+
+// SHOW SLAVE STATUS spellings as SHOW REPLICA STATUS spells them, applied to
+// lowercase names
+var legacyColumns = strings.NewReplacer("slave", "replica", "master", "source")
+
+// ParseStatus names the values of a status row as a server sent it. Columns
+// are matched to StatusColumns exactly, then ignoring case, then in their SHOW
+// SLAVE STATUS spelling, as MySQL before 8.0.22 and MariaDB send them. Other
+// columns are kept, in the new spelling when they have an old one, and
+// returned in unknown.
+//
+// Values keep their positions, and a column is never given a name the row
+// already has: when a server sends both spellings of a column, the one in the
+// new spelling keeps the name and the other keeps its own.
+func ParseStatus(columns []string, values []any) (row *StatusRow, unknown []string, err error) {
+	if len(columns) != len(values) {
+		return nil, nil, errors.New("monitor: status row has a different number of columns and values")
+	}
+	names, unknown := parseColumns(columns)
+	return &StatusRow{Columns: names, Values: values}, unknown, nil
+}
+
+// The names ParseStatus gives columns, and those it did not know
+func parseColumns(columns []string) (names, unknown []string) {
+	names = make([]string, len(columns))
+	taken := make(map[string]bool, len(columns))
+	for i, col := range columns {
+		lower := strings.ToLower(col)
+		name, ok := knownColumns[lower]
+		if !ok {
+			name, ok = knownColumns[legacyColumns.Replace(lower)]
+		}
+		if !ok {
+			unknown = append(unknown, col)
+			name = renameLegacy(col)
+		}
+		names[i] = name
+		// Columns already spelled as wanted claim their names first
+		if name == col {
+			taken[col] = true
+		}
+	}
+	for i, col := range columns {
+		if names[i] == col {
+			continue
+		}
+		if taken[names[i]] {
+			names[i] = col
+		}
+		taken[names[i]] = true
+	}
+	return names, unknown
+}
+
+// A column StatusColumns does not have, in the new spelling where the old one
+// is a whole word, so that Slave_DDL_Groups becomes Replica_DDL_Groups
+func renameLegacy(col string) string {
+	words := strings.Split(col, "_")
+	for i, word := range words {
+		switch word {
+		case "Slave":
+			words[i] = "Replica"
+		case "slave":
+			words[i] = "replica"
+		case "Master":
+			words[i] = "Source"
+		case "master":
+			words[i] = "source"
+		}
+	}
+	return strings.Join(words, "_")
+}
+
+// Column sets and channels already reported, so each is logged once rather than
+// every poll
+var (
+	reportedColumns sync.Map
+	reportChannels  sync.Once
+)
+
+// Log at debug level the columns of a row the parser did not know and the
+// required ones the row lacks
+func reportColumns(row *StatusRow, unknown []string) {
+	var missing []string
+	for _, col := range requiredColumns {
+		if !row.Has(col) {
+			missing = append(missing, col)
+		}
+	}
+	if len(unknown) == 0 && len(missing) == 0 {
+		return
+	}
+	key := strings.Join(unknown, ",") + "|" + strings.Join(missing, ",")
+	if _, seen := reportedColumns.LoadOrStore(key, true); seen {
+		return
+	}
+	if len(unknown) > 0 {
+		slog.Debug("Replica status has columns the parser does not know", "columns", unknown)
+	}
+	if len(missing) > 0 {
+		slog.Debug("Replica status lacks expected columns", "columns", missing)
+	}
+}
@@ -0,0 +1,220 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Read a status row captured with the mysql client's \G, which prints one
+// column per line as name: value; values come back as the driver returns them
+func readCapture(t testing.TB, name string) ([]string, []any) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "status", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var columns []string
+	var values []any
+	for _, line := range strings.Split(string(data), "\n") {
+		col, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, "*") {
+			continue
+		}
+		columns = append(columns, strings.TrimSpace(col))
+		if value = strings.TrimPrefix(value, " "); value == "NULL" {
+			values = append(values, nil)
+		} else {
+			values = append(values, []byte(value))
+		}
+	}
+	return columns, values
+}
+
+func captures(t testing.TB) []string {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join("testdata", "status", "*.txt"))
+	if err != nil || len(names) == 0 {
+		t.Fatalf("no captured status rows: %v", err)
+	}
+	for i := range names {
+		names[i] = filepath.Base(names[i])
+	}
+	return names
+}
+
+func TestParseStatusCaptures(t *testing.T) {
+	mariadbExtras := []string{"Using_Gtid", "Gtid_IO_Pos", "Replicate_Do_Domain_Ids", "Replicate_Ignore_Domain_Ids",
+		"Parallel_Mode", "Slave_DDL_Groups", "Slave_Non_Transactional_Groups", "Slave_Transactional_Groups"}
+	tests := []struct {
+		capture string
+		want    map[string]string
+		unknown []string
+	}{
+		{"mysql-5.7.txt", map[string]string{
+			"Source_Host":           "orders-primary.abc123.us-east-1.rds.amazonaws.com",
+			"Replica_IO_Running":    "Yes",
+			"Replica_SQL_Running":   "Yes",
+			"Seconds_Behind_Source": "0",
+			"Exec_Source_Log_Pos":   "52340917",
+			"Source_UUID":           "7c5d3e0e-8a91-11ee-9f3b-0a1b2c3d4e5f",
+			"Source_TLS_Version":    "",
+		}, nil},
+		{"mysql-8.0-error.txt", map[string]string{
+			"Replica_IO_Running":    "Yes",
+			"Replica_SQL_Running":   "No",
+			"Seconds_Behind_Source": "",
+			"Last_SQL_Errno":        "1062",
+			"Get_Source_public_key": "0",
+		}, nil},
+		{"mysql-8.0-legacy.txt", map[string]string{
+			"Replica_IO_State":      "Connecting to master",
+			"Replica_IO_Running":    "Connecting",
+			"Last_IO_Errno":         "2003",
+			"Get_Source_public_key": "0",
+			"Auto_Position":         "1",
+		}, nil},
+		{"mariadb-10.6.txt", map[string]string{
+			"Seconds_Behind_Source":     "117",
+			"Replica_SQL_Running_State": "Waiting for room in worker thread event queue",
+			"Replica_DDL_Groups":        "12",
+			"Using_Gtid":                "Slave_Pos",
+		}, mariadbExtras},
+		{"mariadb-11.4.txt", map[string]string{
+			"Replicate_Rewrite_DB":  "",
+			"Source_SSL_Allowed":    "Yes",
+			"Seconds_Behind_Source": "0",
+			"Gtid_IO_Pos":           "0-1-931",
+		}, mariadbExtras},
+	}
+	for _, tt := range tests {
+		t.Run(tt.capture, func(t *testing.T) {
+			columns, values := readCapture(t, tt.capture)
+			row, unknown, err := ParseStatus(columns, values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, col := range requiredColumns {
+				if !row.Has(col) {
+					t.Errorf("no %s column", col)
+				}
+			}
+			for col, want := range tt.want {
+				if !row.Has(col) {
+					t.Errorf("no %s column", col)
+				} else if got := row.Value(col); got != want {
+					t.Errorf("%s = %q, want %q", col, got, want)
+				}
+			}
+			if !slices.Equal(unknown, tt.unknown) {
+				t.Errorf("unknown = %q, want %q", unknown, tt.unknown)
+			}
+		})
+	}
+}
+
+func TestParseStatusBothSpellings(t *testing.T) {
+	// Whichever comes first, the column in the new spelling keeps its name and value
+	for _, columns := range [][]string{
+		{"Seconds_Behind_Master", "Seconds_Behind_Source"},
+		{"Seconds_Behind_Source", "Seconds_Behind_Master"},
+		{"seconds_behind_source", "Seconds_Behind_Source"},
+	} {
+		values := []any{"5", "7"}
+		want := "7"
+		if columns[0] == "Seconds_Behind_Source" {
+			want = "5"
+		}
+		row, _, err := ParseStatus(columns, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := row.Value("Seconds_Behind_Source"); got != want {
+			t.Errorf("%q: Seconds_Behind_Source = %q, want %q", columns, got, want)
+		}
+		if !slices.Contains(row.Columns, columns[0]) && !slices.Contains(row.Columns, columns[1]) {
+			t.Errorf("%q: the other column was renamed to %q", columns, row.Columns)
+		}
+	}
+}
+
+func TestParseStatusMismatch(t *testing.T) {
+	if _, _, err := ParseStatus([]string{"Replica_IO_Running", "Replica_SQL_Running"}, []any{"Yes"}); err == nil {
+		t.Fatal("no error for a row with fewer values than columns")
+	}
+}
+
+// Names compared loosely, as a check independent of the parser's own lookups
+var looseName = strings.NewReplacer("slave", "replica", "master", "source")
+
+// FuzzParseStatus mangles captured rows the way other versions and engines
+// differ (dropped, added, reordered, duplicated, and respelled columns) and
+// checks that every value is still reported under a name for its own column.
+func FuzzParseStatus(f *testing.F) {
+	names := captures(f)
+	for i := range names {
+		f.Add(uint8(i), []byte{})
+		f.Add(uint8(i), []byte{0, 3, 2, 0x51, 5, 11, 5, 32})      // drop, swap, respell
+		f.Add(uint8(i), []byte{1, 32, 7, 32, 6, 9, 3, 12, 4, 10}) // twins, extras, case
+	}
+	f.Fuzz(func(t *testing.T, capture uint8, ops []byte) {
+		columns, _ := readCapture(t, names[int(capture)%len(names)])
+		for i := 0; i+1 < len(ops) && len(columns) > 0; i += 2 {
+			op, arg := ops[i]%8, int(ops[i+1])
+			n := arg % len(columns)
+			switch op {
+			case 0: // a column the server does not have
+				columns = slices.Delete(columns, n, n+1)
+			case 1: // the server repeats a column
+				columns = slices.Insert(columns, n, columns[n])
+			case 2: // another order
+				m := (arg >> 4) % len(columns)
+				columns[n], columns[m] = columns[m], columns[n]
+			case 3:
+				columns[n] = strings.ToUpper(columns[n])
+			case 4:
+				columns[n] = strings.ToLower(columns[n])
+			case 5: // the old spelling
+				columns[n] = strings.NewReplacer("Replica", "Slave", "Source", "Master").Replace(columns[n])
+			case 6: // a column from a newer version
+				columns = append(columns, fmt.Sprintf("Future_Column_%d", arg))
+			case 7: // both spellings of one column
+				columns = append(columns, strings.NewReplacer("Replica", "Slave", "Source", "Master").Replace(columns[n]))
+			}
+		}
+		// Values that say which column they came from
+		values := make([]any, len(columns))
+		for i := range values {
+			values[i] = fmt.Sprintf("value %d", i)
+		}
+
+		row, _, err := ParseStatus(columns, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(row.Columns) != len(columns) || len(row.Values) != len(values) {
+			t.Fatalf("parsed %d columns and %d values from %d", len(row.Columns), len(row.Values), len(columns))
+		}
+		producedBy := make(map[string]string)
+		for i, name := range row.Columns {
+			col := columns[i]
+			if row.Values[i] != values[i] {
+				t.Fatalf("%s has %v, want %v", name, row.Values[i], values[i])
+			}
+			if name != col && looseName.Replace(strings.ToLower(name)) != looseName.Replace(strings.ToLower(col)) {
+				t.Fatalf("%s is reported as %s", col, name)
+			}
+			if slices.Contains(StatusColumns, col) && name != col {
+				t.Fatalf("%s, already a status column, is reported as %s", col, name)
+			}
+			// Only a column the server itself repeated may appear twice
+			if prev, ok := producedBy[name]; ok && prev != col {
+				t.Fatalf("both %s and %s are reported as %s", prev, col, name)
+			}
+			producedBy[name] = col
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// DefaultErrorPatterns are the Last_SQL_Error patterns that are alerted on and
+// skipped when no others are configured.
+var DefaultErrorPatterns = []string{
+	"Coordinator stopped",
+}
+
+// MatchErrors returns the regular expressions in patterns that match
+// lastSQLError. Patterns that fail to compile are skipped and reported in err.
+func MatchErrors(patterns []string, lastSQLError string) (matched []string, err error) {
+	if lastSQLError == "" {
+		return nil, nil
+	}
+	var errs []error
+	for _, pattern := range patterns {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pattern %q: %w", pattern, err))
+			continue
+		}
+		if re.MatchString(lastSQLError) {
+			matched = append(matched, pattern)
+		}
+	}
+	return matched, errors.Join(errs...)
+}
+
+// Patterns compiled so far; a replica that stays broken is matched every poll
+var compiledPatterns sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := compiledPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(pattern, re)
+	return re, nil
+}
@@ -0,0 +1,29 @@
+package monitor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchErrors(t *testing.T) {
+	const lastError = "Coordinator stopped because there were error(s) in the worker(s). Error 'Duplicate entry '42' for key 'PRIMARY'' on query."
+	tests := []struct {
+		patterns  []string
+		lastError string
+		want      []string
+		wantErr   bool
+	}{
+		{DefaultErrorPatterns, lastError, []string{"Coordinator stopped"}, false},
+		{[]string{"Coordinator stopped", `Duplicate entry '\d+'`, "Deadlock found"}, lastError, []string{"Coordinator stopped", `Duplicate entry '\d+'`}, false},
+		{[]string{"Deadlock found"}, lastError, nil, false},
+		{DefaultErrorPatterns, "", nil, false},
+		// A broken pattern is reported without hiding the others' matches
+		{[]string{"worker(s", "Duplicate entry"}, lastError, []string{"Duplicate entry"}, true},
+	}
+	for _, tt := range tests {
+		got, err := MatchErrors(tt.patterns, tt.lastError)
+		if !slices.Equal(got, tt.want) || (err != nil) != tt.wantErr {
+			t.Errorf("MatchErrors(%q, %q) = %q, %v; want %q, error %v", tt.patterns, tt.lastError, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
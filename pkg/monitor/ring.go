@@ -0,0 +1,53 @@
+package monitor
+
+// Ring is a fixed-capacity buffer that overwrites its oldest element once full, so
+// in-memory history stays the same size however long the monitor runs. The zero
+// value has no capacity and ignores pushes.
+type Ring[T any] struct {
+	buf   []T
+	start int // index of the oldest element
+	n     int
+}
+
+// NewRing returns an empty Ring holding up to capacity elements.
+func NewRing[T any](capacity int) Ring[T] {
+	return Ring[T]{buf: make([]T, max(capacity, 0))}
+}
+
+// Push appends v, overwriting the oldest element when the ring is full.
+func (b *Ring[T]) Push(v T) {
+	if len(b.buf) == 0 {
+		return
+	}
+	if b.n < len(b.buf) {
+		b.buf[(b.start+b.n)%len(b.buf)] = v
+		b.n++
+		return
+	}
+	b.buf[b.start] = v
+	b.start = (b.start + 1) % len(b.buf)
+}
+
+// Len returns the number of elements held.
+func (b *Ring[T]) Len() int {
+	return b.n
+}
+
+// Cap returns the number of elements the ring can hold.
+func (b *Ring[T]) Cap() int {
+	return len(b.buf)
+}
+
+// At returns the i-th element, oldest first.
+func (b *Ring[T]) At(i int) T {
+	return b.buf[(b.start+i)%len(b.buf)]
+}
+
+// Values returns a copy of the elements, oldest first.
+func (b *Ring[T]) Values() []T {
+	out := make([]T, b.n)
+	for i := range out {
+		out[i] = b.At(i)
+	}
+	return out
+}
@@ -0,0 +1,281 @@
+package monitor
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// SimulatedSource is a StatusSource that makes up a replica's status instead of
+// reading a server: lag that hovers near zero, climbs under bursts of writes and
+// catches up again, SQL errors that stop replication until they are skipped,
+// a source that drops the IO thread, and a replica that stops answering. It lets
+// alert routing, dashboards, and templates be tried out end to end before any
+// database is involved.
+type SimulatedSource struct {
+	// Seed makes the run repeatable; zero picks a random one
+	Seed uint64
+	// Speed runs simulated time this many times faster than real time (1)
+	Speed float64
+	// SourceHost is reported as the replica's Source_Host ("source.simulated")
+	SourceHost string
+
+	mu     sync.Mutex
+	rng    *rand.Rand
+	last   time.Time // real time of the previous read
+	phase  simPhase
+	left   time.Duration // simulated time until the phase ends
+	lag    float64       // seconds the applier is behind
+	rate   float64       // lag gained per second in the phase, negative catching up
+	srcPos int64         // bytes the source has written
+	ioPos  int64         // bytes the IO thread has read
+	errno  int
+	errMsg string
+	errAt  time.Time
+}
+
+type simPhase int
+
+const (
+	simSteady      simPhase = iota // caught up, lag jitters near zero
+	simBurst                       // a burst of writes outpaces the applier
+	simCatchUp                     // the applier works through the backlog
+	simSQLError                    // the SQL thread stopped on an error until skipped
+	simIOError                     // the IO thread lost the source and reconnects
+	simUnreachable                 // the replica does not answer at all
+	simStopped                     // StopReplication was called
+)
+
+// Bytes of binlog the simulated source writes per second, and per file
+const (
+	simWriteRate   = 4096
+	simBinlogBytes = 64 << 20
+)
+
+// SQL errors the simulation stops on; the first matches DefaultErrorPatterns, the
+// second is left for a person
+var simSQLErrors = []struct {
+	errno   int
+	message string
+}{
+	{1062, "Coordinator stopped because there were error(s) in the worker(s). The most recent failure being: Worker 1 failed executing transaction 'ANONYMOUS' at source log %s, end_log_pos %d. See error log and/or performance_schema.replication_applier_status_by_worker table for more details about this failure or others, if any."},
+	{1032, "Could not execute Update_rows event on table shop.orders; Can't find record in 'orders', Error_code: 1032; handler error HA_ERR_KEY_NOT_FOUND; the event's source log %s, end_log_pos %d"},
+}
+
+func (s *SimulatedSource) ReplicaStatus(ctx context.Context) (*StatusRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance()
+	if s.phase == simUnreachable {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	}
+
+	ioRunning, sqlRunning := "Yes", "Yes"
+	ioState, sqlState := "Waiting for source to send event", "Replica has read all relay log; waiting for more updates"
+	var seconds any = int64(s.lag)
+	var ioErrno, sqlErrno int
+	var ioError, sqlError, ioErrorAt, sqlErrorAt string
+	switch s.phase {
+	case simBurst, simCatchUp:
+		sqlState = "Waiting for dependent transaction to commit"
+	case simSQLError:
+		sqlRunning, sqlState, seconds = "No", "", nil
+		sqlErrno, sqlError, sqlErrorAt = s.errno, s.errMsg, s.errAt.Format("060102 15:04:05")
+	case simIOError:
+		ioRunning, ioState, seconds = "Connecting", "Reconnecting after a failed source event read", nil
+		ioErrno, ioError, ioErrorAt = s.errno, s.errMsg, s.errAt.Format("060102 15:04:05")
+	case simStopped:
+		ioRunning, sqlRunning, ioState, sqlState, seconds = "No", "No", "", "", nil
+	}
+	execPos := max(s.ioPos-int64(s.lag*simWriteRate), 0)
+	lastErrno, lastError := cmp.Or(sqlErrno, ioErrno), cmp.Or(sqlError, ioError)
+
+	return MockRow(
+		"Replica_IO_State", ioState,
+		"Source_Host", cmp.Or(s.SourceHost, "source.simulated"),
+		"Source_User", "repl",
+		"Source_Port", 3306,
+		"Connect_Retry", 60,
+		"Source_Log_File", simBinlogFile(s.ioPos),
+		"Read_Source_Log_Pos", simBinlogOffset(s.ioPos),
+		"Relay_Log_File", "relay-bin.000002",
+		"Relay_Log_Pos", simBinlogOffset(execPos),
+		"Relay_Source_Log_File", simBinlogFile(execPos),
+		"Replica_IO_Running", ioRunning,
+		"Replica_SQL_Running", sqlRunning,
+		"Replicate_Do_DB", "",
+		"Replicate_Ignore_DB", "",
+		"Last_Errno", lastErrno,
+		"Last_Error", lastError,
+		"Skip_Counter", 0,
+		"Exec_Source_Log_Pos", simBinlogOffset(execPos),
+		"Relay_Log_Space", s.ioPos-execPos+4,
+		"Seconds_Behind_Source", seconds,
+		"Last_IO_Errno", ioErrno,
+		"Last_IO_Error", ioError,
+		"Last_SQL_Errno", sqlErrno,
+		"Last_SQL_Error", sqlError,
+		"Replica_SQL_Running_State", sqlState,
+		"Last_IO_Error_Timestamp", ioErrorAt,
+		"Last_SQL_Error_Timestamp", sqlErrorAt,
+	), nil
+}
+
+// SkipError clears a simulated SQL error, after which the replica catches up.
+func (s *SimulatedSource) SkipError(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance()
+	if s.phase == simUnreachable {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	}
+	if s.phase == simSQLError {
+		s.enter(simCatchUp)
+	}
+	return nil
+}
+
+func (s *SimulatedSource) StartReplication(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance()
+	if s.phase == simStopped {
+		s.enter(simCatchUp)
+	}
+	return nil
+}
+
+func (s *SimulatedSource) StopReplication(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance()
+	s.enter(simStopped)
+	return nil
+}
+
+// Move the simulation on by the real time since the last call, scaled by Speed
+func (s *SimulatedSource) advance() {
+	now := time.Now()
+	if s.rng == nil {
+		seed := s.Seed
+		if seed == 0 {
+			seed = rand.Uint64()
+		}
+		s.rng = rand.New(rand.NewPCG(seed, seed))
+		s.last = now
+		s.srcPos = s.rng.Int64N(simBinlogBytes)
+		s.ioPos = s.srcPos
+		s.enter(simSteady)
+		return
+	}
+	elapsed := time.Duration(float64(now.Sub(s.last)) * cmp.Or(s.Speed, 1))
+	s.last = now
+
+	// Phases that end on their own may end part way through the elapsed time
+	for elapsed > 0 {
+		step := elapsed
+		if s.left > 0 {
+			step = min(step, s.left)
+		}
+		s.step(step.Seconds())
+		elapsed -= step
+		if s.left > 0 {
+			s.left -= step
+			if s.left <= 0 {
+				s.next()
+			}
+		}
+		if s.phase == simCatchUp && s.lag <= 0 {
+			s.enter(simSteady)
+		}
+	}
+	if s.phase == simSteady {
+		// Caught up, with the odd second of lag
+		s.lag = float64(s.rng.IntN(10) / 9)
+	}
+}
+
+// Advance the binlog positions and lag by dt seconds of the current phase
+func (s *SimulatedSource) step(dt float64) {
+	written := simWriteRate * dt
+	if s.phase == simBurst {
+		written *= 1 + s.rate
+	}
+	s.srcPos += int64(written)
+	if s.phase != simIOError && s.phase != simStopped {
+		s.ioPos = s.srcPos
+	}
+	switch s.phase {
+	case simBurst, simCatchUp:
+		s.lag = max(s.lag+s.rate*dt, 0)
+	case simSQLError, simIOError, simStopped:
+		// Nothing is applied while the source keeps writing
+		s.lag += dt
+	}
+}
+
+// Pick what follows a phase that ran its course
+func (s *SimulatedSource) next() {
+	switch s.phase {
+	case simSteady:
+		switch n := s.rng.IntN(100); {
+		case n < 50:
+			s.enter(simBurst)
+		case n < 75:
+			s.enter(simSQLError)
+		case n < 90:
+			s.enter(simIOError)
+		default:
+			s.enter(simUnreachable)
+		}
+	case simBurst, simIOError:
+		s.enter(simCatchUp)
+	case simSQLError:
+		// Nobody skipped it; someone fixed it by hand
+		s.enter(simCatchUp)
+	case simUnreachable:
+		s.enter(simSteady)
+	}
+}
+
+func (s *SimulatedSource) enter(phase simPhase) {
+	s.phase = phase
+	s.left = 0
+	s.rate = 0
+	between := func(lo, hi time.Duration) time.Duration {
+		return lo + time.Duration(s.rng.Int64N(int64(hi-lo)))
+	}
+	switch phase {
+	case simSteady:
+		s.lag = 0
+		s.left = between(time.Minute, 4*time.Minute)
+	case simBurst:
+		s.rate = 0.3 + 0.6*s.rng.Float64()
+		s.left = between(time.Minute, 3*time.Minute)
+	case simCatchUp:
+		s.rate = -(0.2 + 0.4*s.rng.Float64())
+	case simSQLError:
+		e := simSQLErrors[s.rng.IntN(len(simSQLErrors))]
+		s.errno, s.errMsg, s.errAt = e.errno, fmt.Sprintf(e.message, simBinlogFile(s.ioPos), simBinlogOffset(s.ioPos)), time.Now()
+		s.left = between(3*time.Minute, 6*time.Minute)
+	case simIOError:
+		s.errno, s.errAt = 2003, time.Now()
+		s.errMsg = fmt.Sprintf("error reconnecting to source 'repl@%s:3306' - retry-time: 60 retries: 1 message: Can't connect to MySQL server on '%[1]s:3306' (110)", cmp.Or(s.SourceHost, "source.simulated"))
+		s.left = between(20*time.Second, 90*time.Second)
+	case simUnreachable:
+		s.left = between(15*time.Second, time.Minute)
+	}
+}
+
+func simBinlogFile(pos int64) string {
+	return fmt.Sprintf("mysql-bin.%06d", 1+pos/simBinlogBytes)
+}
+
+func simBinlogOffset(pos int64) int64 {
+	return 4 + pos%simBinlogBytes
+}
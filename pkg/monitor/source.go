@@ -0,0 +1,214 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Sampler reads a replica's replication status, normalized to the column names
+// of MySQL's SHOW REPLICA STATUS whatever the engine, so the same analysis
+// applies to every engine. At least Replica_IO_Running, Replica_SQL_Running,
+// Last_SQL_Error, and Seconds_Behind_Source are filled in.
+type Sampler interface {
+	// ReplicaStatus returns the status row, or nil when the server is not a
+	// replica.
+	ReplicaStatus(ctx context.Context) (*StatusRow, error)
+}
+
+// StatusSource is a Sampler that replication actions can also be sent to. Each
+// engine has one (see RegisterEngine); MockSource stands in for a server in
+// tests. Actions an engine does not support return ErrUnsupported.
+type StatusSource interface {
+	Sampler
+	// SkipError skips the transaction the SQL thread stopped on.
+	SkipError(ctx context.Context) error
+	// StartReplication starts the replication threads.
+	StartReplication(ctx context.Context) error
+	// StopReplication stops the replication threads.
+	StopReplication(ctx context.Context) error
+}
+
+// StatusRow is one SHOW REPLICA STATUS row: the columns in the order the server
+// sent them and their values, nil for NULL.
+type StatusRow struct {
+	Columns []string
+	Values  []any
+}
+
+// Has reports whether the server sent a column.
+func (s *StatusRow) Has(name string) bool {
+	for _, col := range s.Columns {
+		if col == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns a column as a string, "" when it is absent or NULL.
+func (s *StatusRow) Value(name string) string {
+	for i, col := range s.Columns {
+		if col == name && s.Values[i] != nil {
+			return s.ValueAt(i)
+		}
+	}
+	return ""
+}
+
+// ValueAt returns the i-th column as a string, "" when it is NULL.
+func (s *StatusRow) ValueAt(i int) string {
+	switch v := s.Values[i].(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Map returns every column as a string keyed by name.
+func (s *StatusRow) Map() map[string]string {
+	m := make(map[string]string, len(s.Columns))
+	// Backwards, so that as with Value the first non-NULL of a repeated
+	// column wins
+	for i := len(s.Columns) - 1; i >= 0; i-- {
+		if _, ok := m[s.Columns[i]]; !ok || s.Values[i] != nil {
+			m[s.Columns[i]] = s.ValueAt(i)
+		}
+	}
+	return m
+}
+
+// SQLSource is the StatusSource for MySQL, backed by a connection to the
+// replica. Starting and stopping go through the mysql.rds_* procedures on RDS
+// and plain statements elsewhere; skipping needs mysql.rds_skip_repl_error. It
+// keeps buffers between polls, so it is used through a pointer and must not be
+// copied after first use.
+type SQLSource struct {
+	DB *sql.DB
+
+	mu sync.Mutex
+	// Column names ParseStatus gave the last row, kept while the server sends
+	// the same columns
+	layout []string
+	names  []string
+	// Scan destinations and the buffer values are gathered in, reused
+	raw  []sql.RawBytes
+	dest []any
+	buf  []byte
+}
+
+// ReplicaStatus falls back to SHOW SLAVE STATUS on servers older than MySQL
+// 8.0.22. Columns are named by ParseStatus, in the new spelling; values are
+// strings, nil for NULL. Rows share their Columns slice, which must not be
+// modified.
+func (s *SQLSource) ReplicaStatus(ctx context.Context) (*StatusRow, error) {
+	rows, err := s.DB.QueryContext(ctx, "SHOW REPLICA STATUS")
+	legacy := isSyntaxError(err)
+	if legacy {
+		rows, err = s.DB.QueryContext(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Equal(columns, s.layout) {
+		var unknown []string
+		s.layout = slices.Clone(columns)
+		s.names, unknown = parseColumns(columns)
+		reportColumns(&StatusRow{Columns: s.names}, unknown)
+		s.raw = make([]sql.RawBytes, len(columns))
+		s.dest = make([]any, len(columns))
+		for i := range s.raw {
+			s.dest[i] = &s.raw[i]
+		}
+	}
+	// Empty rather than nil, so that only NULL scans as nil
+	for i := range s.raw {
+		s.raw[i] = sql.RawBytes{}
+	}
+	if err := rows.Scan(s.dest...); err != nil {
+		return nil, err
+	}
+	// The scanned bytes belong to the driver until the next row; every value is
+	// copied into one string and sliced from it
+	s.buf = s.buf[:0]
+	for _, v := range s.raw {
+		s.buf = append(s.buf, v...)
+	}
+	text := string(s.buf)
+	values := make([]any, len(columns))
+	for i, v := range s.raw {
+		if v != nil {
+			values[i], text = text[:len(v)], text[len(v):]
+		}
+	}
+
+	// A replica of several sources has a row per channel; the first is the
+	// default channel when there is one
+	if rows.Next() {
+		reportChannels.Do(func() {
+			slog.Debug("Replica has several replication channels; reporting the first")
+		})
+	}
+	return &StatusRow{Columns: s.names, Values: values}, nil
+}
+
+func (s *SQLSource) SkipError(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, "CALL mysql.rds_skip_repl_error")
+	return err
+}
+
+func (s *SQLSource) StartReplication(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, "CALL mysql.rds_start_replication")
+	if isMissingProcedure(err) {
+		_, err = s.DB.ExecContext(ctx, "START REPLICA")
+		if isSyntaxError(err) {
+			_, err = s.DB.ExecContext(ctx, "START SLAVE")
+		}
+	}
+	return err
+}
+
+func (s *SQLSource) StopReplication(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, "CALL mysql.rds_stop_replication")
+	if isMissingProcedure(err) {
+		_, err = s.DB.ExecContext(ctx, "STOP REPLICA")
+		if isSyntaxError(err) {
+			_, err = s.DB.ExecContext(ctx, "STOP SLAVE")
+		}
+	}
+	return err
+}
+
+// The server predates the REPLICA spelling of a statement
+func isSyntaxError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1064
+}
+
+// The server is not RDS, which is where the mysql.rds_* procedures come from
+func isMissingProcedure(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1305
+}
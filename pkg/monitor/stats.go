@@ -0,0 +1,114 @@
+// Package monitor polls MySQL replicas and analyses their replication: a Monitor
+// turns replica status into typed samples and events, built on catch-up
+// statistics and the classification of replication and polling errors, and
+// Check, CatchUp, ShouldSkip, and Transitions judge what a poll found.
+package monitor
+
+import (
+	"math"
+	"time"
+)
+
+// LagStats tracks how fast a replica's lag changes, between consecutive polls
+// and since the first one, for catch-up rates and ETAs. Rates are in seconds of
+// lag per second; a negative rate means the replica is catching up.
+type LagStats struct {
+	LastSecondsBehind int
+	LastCheckTime     time.Time
+	Rate              float64   // over the last interval
+	EstimatedTime     time.Time // when the replica catches up at Rate; zero until it catches up at all
+
+	StartSecondsBehind int
+	StartTime          time.Time
+	AverageRate        float64 // since StartTime
+
+	// Recent values of Rate, for the spread of the ETA band
+	RecentRates Ring[float64]
+}
+
+// NewLagStats returns empty statistics keeping the last rateWindow rates.
+func NewLagStats(rateWindow int) LagStats {
+	return LagStats{RecentRates: NewRing[float64](rateWindow)}
+}
+
+// Update folds in a lag sample taken at now.
+func (s *LagStats) Update(seconds int, now time.Time) {
+	if s.StartTime.IsZero() {
+		s.StartSecondsBehind = seconds
+		s.StartTime = now
+	}
+
+	if !s.LastCheckTime.IsZero() {
+		if elapsed := now.Sub(s.LastCheckTime).Seconds(); elapsed > 0 {
+			s.Rate = float64(seconds-s.LastSecondsBehind) / elapsed
+			s.RecentRates.Push(s.Rate)
+			if s.Rate < 0 {
+				s.EstimatedTime = now.Add(time.Duration(float64(seconds)/-s.Rate) * time.Second)
+			}
+		}
+	}
+
+	if elapsed := now.Sub(s.StartTime).Seconds(); elapsed > 0 {
+		s.AverageRate = float64(seconds-s.StartSecondsBehind) / elapsed
+	}
+
+	s.LastSecondsBehind = seconds
+	s.LastCheckTime = now
+}
+
+// ETABand returns the best- and worst-case catch-up rates from the last rate,
+// the long-term average, and one standard deviation either side of the recent
+// mean. worst is >= 0 when some plausible rate is not catching up at all; ok is
+// false when the lag is not shrinking at any of them.
+func (s *LagStats) ETABand() (best, worst float64, ok bool) {
+	var candidates []float64
+	if s.Rate != 0 {
+		candidates = append(candidates, s.Rate)
+	}
+	if s.AverageRate != 0 {
+		candidates = append(candidates, s.AverageRate)
+	}
+	if n := s.RecentRates.Len(); n >= 3 {
+		var sum, sumSq float64
+		for _, r := range s.RecentRates.Values() {
+			sum += r
+			sumSq += r * r
+		}
+		mean := sum / float64(n)
+		stddev := math.Sqrt(math.Max(0, sumSq/float64(n)-mean*mean))
+		candidates = append(candidates, mean-stddev, mean+stddev)
+	}
+	if len(candidates) == 0 {
+		return 0, 0, false
+	}
+	best, worst = candidates[0], candidates[0]
+	for _, c := range candidates[1:] {
+		best = math.Min(best, c)
+		worst = math.Max(worst, c)
+	}
+	return best, worst, best < 0
+}
+
+// ETAWindow returns when a replica lag seconds behind at now catches up, at the
+// best and the worst rate of ETABand. latest is zero when at the worst rate it
+// is not catching up; ok is false when it is not catching up at any.
+func (s *LagStats) ETAWindow(lag float64, now time.Time) (earliest, latest time.Time, ok bool) {
+	best, worst, ok := s.ETABand()
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	earliest = now.Add(time.Duration(lag / -best * float64(time.Second)))
+	if worst < 0 {
+		latest = now.Add(time.Duration(lag / -worst * float64(time.Second)))
+	}
+	return earliest, latest, true
+}
+
+// AverageETA returns when a replica lag seconds behind at now catches up at the
+// average rate; ok is false when on average it is not catching up.
+func (s *LagStats) AverageETA(lag float64, now time.Time) (eta time.Time, ok bool) {
+	if s.AverageRate >= 0 || lag <= 0 {
+		return time.Time{}, false
+	}
+	return now.Add(time.Duration(lag / -s.AverageRate * float64(time.Second))), true
+}
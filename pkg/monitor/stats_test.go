@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLagStatsUpdate(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := NewLagStats(3)
+
+	s.Update(100, start)
+	if s.StartSecondsBehind != 100 || !s.StartTime.Equal(start) || s.Rate != 0 || !s.EstimatedTime.IsZero() {
+		t.Fatalf("after the first sample: %+v; want only the start recorded", s)
+	}
+
+	// Falling behind: no ETA
+	s.Update(110, start.Add(10*time.Second))
+	if s.Rate != 1 || s.AverageRate != 1 || !s.EstimatedTime.IsZero() {
+		t.Errorf("rate %v, average %v, ETA %v; want 1, 1, and none", s.Rate, s.AverageRate, s.EstimatedTime)
+	}
+
+	// Catching up 2s of lag per second: 90s left takes 45s more
+	now := start.Add(20 * time.Second)
+	s.Update(90, now)
+	if s.Rate != -2 {
+		t.Errorf("rate %v; want -2", s.Rate)
+	}
+	if s.AverageRate != -0.5 {
+		t.Errorf("average rate %v; want -0.5 since the start", s.AverageRate)
+	}
+	if want := now.Add(45 * time.Second); !s.EstimatedTime.Equal(want) {
+		t.Errorf("ETA %v; want %v", s.EstimatedTime, want)
+	}
+	if s.LastSecondsBehind != 90 || !s.LastCheckTime.Equal(now) {
+		t.Errorf("last sample %d at %v; want 90 at %v", s.LastSecondsBehind, s.LastCheckTime, now)
+	}
+
+	// A sample at the same instant changes only the average
+	s.Update(80, now)
+	if s.Rate != -2 || s.AverageRate != -1 || s.RecentRates.Len() != 2 {
+		t.Errorf("rate %v, average %v with %d recent rates; want -2, -1 with 2", s.Rate, s.AverageRate, s.RecentRates.Len())
+	}
+
+	best, worst, ok := s.ETABand()
+	if !ok || best != -2 || worst != -1 {
+		t.Errorf("ETABand() = %v, %v, %v; want -2, -1, true", best, worst, ok)
+	}
+	// 80s behind: 40s away at -2/s, 80s at -1/s
+	earliest, latest, ok := s.ETAWindow(80, now)
+	if !ok || !earliest.Equal(now.Add(40*time.Second)) || !latest.Equal(now.Add(80*time.Second)) {
+		t.Errorf("ETAWindow(80) = %v, %v, %v; want 40s and 80s from now", earliest, latest, ok)
+	}
+}
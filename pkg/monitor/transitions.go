@@ -0,0 +1,106 @@
+package monitor
+
+import "time"
+
+const (
+	EventIOThreadStopped  EventKind = "io_thread_stopped"  // the IO thread stopped; State says how
+	EventIOThreadStarted  EventKind = "io_thread_started"  // the IO thread runs again
+	EventSQLThreadStopped EventKind = "sql_thread_stopped" // the SQL thread stopped; State says how
+	EventSQLThreadStarted EventKind = "sql_thread_started" // the SQL thread runs again
+	EventFellBehind       EventKind = "fell_behind"        // the lag rose above the threshold
+)
+
+// Transition is a change in a replica's health found by Transitions.
+type Transition struct {
+	Kind EventKind
+	// State is the thread's state after a thread transition, such as "No"
+	State string
+	// Lasted is how long the state that just ended held
+	Lasted time.Duration
+}
+
+// Transitions follows a replica's health from poll to poll to tell when a
+// thread stops or runs again, an error match appears or clears, and the lag
+// rises above a threshold or falls back to zero. The first poll only sets the
+// baseline. The zero value is ready to use.
+type Transitions struct {
+	polled bool
+
+	ioRunning, sqlRunning bool
+	ioChanged, sqlChanged time.Time
+	errorMatched          bool
+	errorChanged          time.Time
+	behind                bool
+	behindChanged         time.Time
+}
+
+// Observe folds in the health found by a poll at now, counting the replica as
+// behind while its lag is above threshold, and returns the transitions since the
+// previous poll. Health that was not polled is ignored.
+func (t *Transitions) Observe(h Health, now time.Time, threshold time.Duration) []Transition {
+	if !h.Polled {
+		return nil
+	}
+	first := !t.polled
+	if first {
+		t.polled = true
+		t.ioChanged, t.sqlChanged, t.errorChanged, t.behindChanged = now, now, now, now
+	}
+	var transitions []Transition
+
+	if !h.NoThreads {
+		io, sql := h.IORunning == "Yes", h.SQLRunning == "Yes"
+		if !first {
+			transitions = appendThread(transitions, EventIOThreadStopped, EventIOThreadStarted, t.ioRunning, io, h.IORunning, now.Sub(t.ioChanged))
+			transitions = appendThread(transitions, EventSQLThreadStopped, EventSQLThreadStarted, t.sqlRunning, sql, h.SQLRunning, now.Sub(t.sqlChanged))
+		}
+		if first || io != t.ioRunning {
+			t.ioRunning, t.ioChanged = io, now
+		}
+		if first || sql != t.sqlRunning {
+			t.sqlRunning, t.sqlChanged = sql, now
+		}
+	}
+
+	if h.ErrorMatched != t.errorMatched {
+		if !first {
+			kind := EventErrorCleared
+			if h.ErrorMatched {
+				kind = EventErrorMatched
+			}
+			transitions = append(transitions, Transition{Kind: kind, Lasted: now.Sub(t.errorChanged)})
+		}
+		t.errorMatched, t.errorChanged = h.ErrorMatched, now
+	}
+
+	// Behind means above the threshold; caught up means back to zero
+	if h.LagKnown {
+		switch {
+		case !t.behind && h.LagSeconds > threshold.Seconds():
+			if !first {
+				transitions = append(transitions, Transition{Kind: EventFellBehind, Lasted: now.Sub(t.behindChanged)})
+			}
+			t.behind, t.behindChanged = true, now
+		case t.behind && h.LagSeconds == 0:
+			transitions = append(transitions, Transition{Kind: EventCaughtUp, Lasted: now.Sub(t.behindChanged)})
+			t.behind, t.behindChanged = false, now
+		}
+	}
+	return transitions
+}
+
+// Behind reports whether the replica's lag was last seen above the threshold
+// and has not fallen back to zero since.
+func (t *Transitions) Behind() bool {
+	return t.behind
+}
+
+func appendThread(transitions []Transition, stopped, started EventKind, wasRunning, running bool, state string, lasted time.Duration) []Transition {
+	switch {
+	case wasRunning && !running:
+		return append(transitions, Transition{Kind: stopped, State: state, Lasted: lasted})
+	case !wasRunning && running:
+		return append(transitions, Transition{Kind: started, State: state, Lasted: lasted})
+	}
+	return transitions
+}
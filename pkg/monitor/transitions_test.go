@@ -0,0 +1,47 @@
+package monitor
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTransitions(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	healthy := Health{Polled: true, IORunning: "Yes", SQLRunning: "Yes", LagKnown: true}
+	broken := Health{Polled: true, IORunning: "Yes", SQLRunning: "No", ErrorMatched: true}
+	lagging := healthy
+	lagging.LagSeconds = 600
+	var tr Transitions
+	steps := []struct {
+		health Health
+		want   []EventKind
+	}{
+		{Health{}, nil}, // not polled yet
+		{lagging, nil},  // the baseline, already behind
+		{broken, []EventKind{EventSQLThreadStopped, EventErrorMatched}},
+		{healthy, []EventKind{EventSQLThreadStarted, EventErrorCleared, EventCaughtUp}},
+		{lagging, []EventKind{EventFellBehind}},
+		{lagging, nil},
+	}
+	for i, step := range steps {
+		var kinds []EventKind
+		for _, tn := range tr.Observe(step.health, start.Add(time.Duration(i)*time.Minute), 5*time.Minute) {
+			kinds = append(kinds, tn.Kind)
+		}
+		if !slices.Equal(kinds, step.want) {
+			t.Errorf("poll %d: %v; want %v", i, kinds, step.want)
+		}
+	}
+	if !tr.Behind() {
+		t.Error("not behind after falling behind")
+	}
+
+	// How long the ended state lasted, and the thread's new state
+	tr = Transitions{}
+	tr.Observe(healthy, start, time.Minute)
+	got := tr.Observe(Health{Polled: true, IORunning: "Connecting", SQLRunning: "Yes", LagKnown: true}, start.Add(90*time.Second), time.Minute)
+	if len(got) != 1 || got[0].Kind != EventIOThreadStopped || got[0].State != "Connecting" || got[0].Lasted != 90*time.Second {
+		t.Errorf("transitions %+v; want the IO thread stopped (Connecting) after 90s", got)
+	}
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"sync"
+	"time"
+)
+
+// Breaker stops calling a notifier that keeps failing: after Threshold failures in a
+// row it rejects deliveries for Cooldown, then lets one through to probe the notifier.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// Allow reports whether a delivery may be attempted now.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures < b.Threshold || !time.Now().Before(b.openUntil)
+}
+
+// Record folds in a delivery result and reports whether the circuit just opened
+// or closed.
+func (b *Breaker) Record(err error) (opened, closed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		closed = b.failures >= b.Threshold
+		b.failures = 0
+		return false, closed
+	}
+	b.failures++
+	if b.failures >= b.Threshold {
+		opened = b.failures == b.Threshold
+		b.openUntil = time.Now().Add(b.Cooldown)
+	}
+	return opened, false
+}
@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is reported for an alert dropped because the queue was full.
+var ErrQueueFull = errors.New("queue full")
+
+// Options tune a Dispatcher; zero fields take the defaults in parentheses.
+type Options struct {
+	QueueSize        int           // alerts waiting for delivery before new ones are dropped (100)
+	Workers          int           // alerts delivered concurrently (2)
+	Attempts         int           // tries per alert and target (3)
+	Backoff          time.Duration // before the second try, doubled after each (1s)
+	Timeout          time.Duration // per try (10s)
+	BreakerThreshold int           // failures in a row that open a target's circuit (5)
+	BreakerCooldown  time.Duration // how long the circuit stays open (1m)
+}
+
+// Result is called once per alert and target with the outcome of the delivery:
+// nil, ErrQueueFull, or the last error. It runs on a worker goroutine.
+type Result func(target string, event Event, err error)
+
+type job struct {
+	target  Target
+	breaker *Breaker
+	event   Event
+	result  Result
+}
+
+// Dispatcher delivers alerts to its targets from a bounded queue drained by a
+// pool of workers.
+type Dispatcher struct {
+	opts     Options
+	targets  []Target
+	breakers map[string]*Breaker
+	queue    chan job
+	wg       sync.WaitGroup
+	mu       sync.Mutex // guards closed against late alerts during shutdown
+	closed   bool
+}
+
+// NewDispatcher starts the workers delivering to targets.
+func NewDispatcher(targets []Target, opts Options) *Dispatcher {
+	opts.QueueSize = cmp.Or(opts.QueueSize, 100)
+	opts.Workers = cmp.Or(opts.Workers, 2)
+	opts.Attempts = cmp.Or(opts.Attempts, 3)
+	opts.Backoff = cmp.Or(opts.Backoff, time.Second)
+	opts.Timeout = cmp.Or(opts.Timeout, 10*time.Second)
+	opts.BreakerThreshold = cmp.Or(opts.BreakerThreshold, 5)
+	opts.BreakerCooldown = cmp.Or(opts.BreakerCooldown, time.Minute)
+	d := &Dispatcher{
+		opts:     opts,
+		targets:  targets,
+		breakers: make(map[string]*Breaker),
+		queue:    make(chan job, opts.QueueSize),
+	}
+	for _, t := range targets {
+		d.breakers[t.Name] = &Breaker{Threshold: opts.BreakerThreshold, Cooldown: opts.BreakerCooldown}
+	}
+	for range opts.Workers {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for j := range d.queue {
+				d.run(j)
+			}
+		}()
+	}
+	return d
+}
+
+// Enqueue queues an alert for every target without blocking. result, which may
+// be nil, learns how each delivery went; alerts arriving after Drain are ignored.
+func (d *Dispatcher) Enqueue(event Event, result Result) {
+	if result == nil {
+		result = func(string, Event, error) {}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	for _, t := range d.targets {
+		select {
+		case d.queue <- job{target: t, breaker: d.breakers[t.Name], event: event, result: result}:
+		default:
+			result(t.Name, event, ErrQueueFull)
+		}
+	}
+}
+
+func (d *Dispatcher) run(j job) {
+	backoff := d.opts.Backoff
+	var err error
+	for attempt := 1; attempt <= d.opts.Attempts; attempt++ {
+		if !j.breaker.Allow() {
+			err = fmt.Errorf("circuit open after %d failures", d.opts.BreakerThreshold)
+			break
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), d.opts.Timeout)
+		err = j.target.Notifier.Notify(ctx, j.event)
+		cancel()
+		opened, closed := j.breaker.Record(err)
+		if opened {
+			slog.Error("Alert target keeps failing, pausing deliveries", "target", j.target.Name, "for", d.opts.BreakerCooldown)
+		} else if closed {
+			slog.Info("Alert target recovered", "target", j.target.Name)
+		}
+		if err == nil {
+			break
+		}
+		if attempt < d.opts.Attempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	j.result(j.target.Name, j.event, err)
+}
+
+// Drain stops taking alerts and waits up to timeout for the queued ones to be
+// delivered.
+func (d *Dispatcher) Drain(timeout time.Duration) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.queue)
+	d.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Warn("Gave up waiting for queued alerts", "pending", len(d.queue))
+	}
+}
@@ -0,0 +1,37 @@
+// Package notify delivers alerts about replicas to webhooks and other notifiers
+// from a bounded queue, with retries and a circuit breaker per notifier, so a
+// slow or unreachable destination never holds up monitoring.
+//
+// Destinations are chosen by URL: a package implementing Notifier registers a
+// Factory for its scheme in an init function, and Open builds a notifier from
+// any URL with that scheme. Importing the package for its side effects is all a
+// program needs to offer the new destination.
+package notify
+
+import (
+	"context"
+	"time"
+
+	"replica-monitor/pkg/monitor"
+)
+
+// Event is an alert about a replica, sent as JSON to webhooks.
+type Event struct {
+	Time    time.Time         `json:"time"`
+	Replica string            `json:"replica"`
+	Host    string            `json:"host"`
+	Event   string            `json:"event"`
+	Message string            `json:"message"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	// RDS instance metadata, when it was looked up
+	Instance *monitor.InstanceInfo `json:"instance,omitempty"`
+	// Correlate alerts with the logs and events of the same run and incident
+	Session  string `json:"session"`
+	Incident string `json:"incident,omitempty"`
+}
+
+// Notifier is a destination alerts are delivered to.
+type Notifier interface {
+	// Notify delivers one alert, returning an error when it was not accepted.
+	Notify(ctx context.Context, event Event) error
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Factory builds a notifier from a URL with the scheme it was registered for.
+type Factory func(u *url.URL) (Notifier, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a notifier available to Open under a URL scheme. It is meant
+// to be called from an init function and panics when the scheme is taken.
+func Register(scheme string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	scheme = strings.ToLower(scheme)
+	if _, ok := registry[scheme]; ok {
+		panic("notify: Register called twice for scheme " + scheme)
+	}
+	registry[scheme] = factory
+}
+
+// Schemes returns the registered URL schemes, sorted.
+func Schemes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	schemes := make([]string, 0, len(registry))
+	for scheme := range registry {
+		schemes = append(schemes, scheme)
+	}
+	slices.Sort(schemes)
+	return schemes
+}
+
+// Target is a notifier with the name it is logged and reported under.
+type Target struct {
+	Name     string
+	Notifier Notifier
+}
+
+// Open builds the notifier registered for target's URL scheme. The target's
+// name is its scheme and host, leaving out credentials, paths, and queries that
+// often carry secrets.
+func Open(target string) (Target, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return Target{}, fmt.Errorf("notify: %w", err)
+	}
+	registryMu.RLock()
+	factory, ok := registry[strings.ToLower(u.Scheme)]
+	registryMu.RUnlock()
+	if !ok {
+		return Target{}, fmt.Errorf("notify: no notifier for %q URLs (have %s)", u.Scheme, strings.Join(Schemes(), ", "))
+	}
+	n, err := factory(u)
+	if err != nil {
+		return Target{}, fmt.Errorf("notify: %s: %w", u.Scheme, err)
+	}
+	name := u.Scheme + "://" + u.Host
+	if u.Host == "" {
+		name = u.Scheme
+	}
+	return Target{Name: name, Notifier: n}, nil
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+func init() {
+	Register("http", openWebhook)
+	Register("https", openWebhook)
+}
+
+func openWebhook(u *url.URL) (Notifier, error) {
+	return Webhook{URL: u.String()}, nil
+}
+
+// Webhook POSTs each alert as JSON to URL. It handles http and https URLs.
+type Webhook struct {
+	URL    string
+	Client *http.Client // a client with a 10 second timeout when nil
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+func (w Webhook) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := w.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("rejected: %s", resp.Status)
+	}
+	return nil
+}